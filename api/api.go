@@ -12,30 +12,22 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"path/filepath"
+	"strconv"
 	"synapforest/database"
 	"synapforest/database/dbcommon"
+	"synapforest/database/itemdb"
+	"synapforest/database/settingdb"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
 )
 
-var UploadDir = "uploads"
-
-func ApiInit(uploadDir string) error {
-	UploadDir = filepath.Join(database.DbBaseDir, uploadDir)
-	if _, err := os.Stat(UploadDir); os.IsNotExist(err) {
-		if err := os.Mkdir(UploadDir, os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create upload directory: %v", err)
-		}
-	}
-	return nil
-}
-
 func Uploadfiles(c *gin.Context) {
+	lib := Lib(c)
 	form, err := c.MultipartForm()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get files"})
@@ -46,7 +38,7 @@ func Uploadfiles(c *gin.Context) {
 	var savedFiles []string
 
 	for _, file := range files {
-		dst := filepath.Join(UploadDir, filepath.Base(file.Filename))
+		dst := filepath.Join(lib.UploadDir, filepath.Base(file.Filename))
 		if err := c.SaveUploadedFile(file, dst); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save file %s", file.Filename)})
 			return
@@ -59,18 +51,25 @@ func Uploadfiles(c *gin.Context) {
 
 // ServeImage 根据 id 返回图片
 func ServeThumbnails(c *gin.Context) {
+	lib := Lib(c)
 	id := c.Param("id")
 
 	var Item dbcommon.Item
-	err := database.DB.Unscoped().First(&Item, "id = ?", id).Error
+	err := lib.DB.Unscoped().First(&Item, "id = ?", id).Error
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"status": "error",
 		})
 	}
 	if Item.HaveThumbnail {
-		imagePath := filepath.Join(database.DbBaseDir, "thumbnails", id+".webp")
-		c.File(imagePath)
+		key, err := itemdb.RenditionFile(lib.DB, settingdb.Thumbnail, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": "error",
+			})
+			return
+		}
+		serveBlob(c, key, "", false)
 	} else {
 		// 可能修改为返回通用占位符？
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -80,18 +79,25 @@ func ServeThumbnails(c *gin.Context) {
 }
 
 func ServePreviews(c *gin.Context) {
+	lib := Lib(c)
 	id := c.Param("id")
 
 	var Item dbcommon.Item
-	err := database.DB.Unscoped().First(&Item, "id = ?", id).Error
+	err := lib.DB.Unscoped().First(&Item, "id = ?", id).Error
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"status": "error",
 		})
 	}
 	if Item.HavePreview {
-		imagePath := filepath.Join(database.DbBaseDir, "previews", id+".webp")
-		c.File(imagePath)
+		key, err := itemdb.RenditionFile(lib.DB, settingdb.Preview, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": "error",
+			})
+			return
+		}
+		serveBlob(c, key, "", false)
 	} else {
 		// 可能修改为返回通用占位符？
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -100,24 +106,78 @@ func ServePreviews(c *gin.Context) {
 	}
 }
 
+// RenditionURL 预览图规格的访问路径，内置规格沿用原有路径
+func RenditionURL(ctx context.Context, name string, itemID string) string {
+	prefix := URLPrefix(ctx)
+	switch name {
+	case settingdb.Thumbnail:
+		return prefix + "/public/thumbnails/" + itemID
+	case settingdb.Preview:
+		return prefix + "/public/previews/" + itemID
+	}
+	return prefix + "/public/renditions/" + name + "/" + itemID
+}
+
+// ServeRendition 返回自定义规格的预览图
+func ServeRendition(c *gin.Context) {
+	lib := Lib(c)
+	name := c.Param("name")
+	id := c.Param("id")
+
+	key, err := itemdb.RenditionFile(lib.DB, name, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	serveBlob(c, key, "", false)
+}
+
+// VersionURL 历史版本文件的地址，需要 Authorization 请求头
+func VersionURL(ctx context.Context, versionID uint) string {
+	return URLPrefix(ctx) + "/api/item/versionFile/" + strconv.FormatUint(uint64(versionID), 10)
+}
+
+// ServeVersionFile 下载 item 的历史版本文件。版本 ID 是连续的整数，因此只在私有接口中提供
+func ServeVersionFile(c *gin.Context) {
+	lib := Lib(c)
+	versionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid version ID",
+		})
+		return
+	}
+
+	version, err := itemdb.GetVersion(lib.DB, uint(versionID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": "error",
+		})
+		return
+	}
+	serveBlob(c, itemdb.VersionKey(*version), version.Name+"."+version.Ext, true)
+}
+
 func ServeRawFile(c *gin.Context) {
+	lib := Lib(c)
 	id := c.Param("id")
 
 	var Item dbcommon.Item
-	err := database.DB.Unscoped().First(&Item, "id = ?", id).Error
+	err := lib.DB.Unscoped().First(&Item, "id = ?", id).Error
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"status": "error",
 		})
 	}
-	// 构建图片路径
-	imagePath := filepath.Join(database.DbBaseDir, "raw_files", id, Item.Name+"."+Item.Ext)
-
-	// 检查文件是否存在（可以进一步完善此部分）
-	c.File(imagePath)
+	serveBlob(c, itemdb.RawFileKey(id, Item.Name, Item.Ext), "", false)
 }
 
 func RemoveFolderForItems(c *gin.Context) {
+	db := DB(c)
 	var req struct {
 		ItemIDs  []string `json:"itemIds" binding:"required"`  // 图片 ID 列表
 		FolderID string   `json:"folderId" binding:"required"` // 文件夹 ID
@@ -143,7 +203,7 @@ func RemoveFolderForItems(c *gin.Context) {
 	}
 
 	// 调用批量删除函数
-	if err := database.RemoveFoldersForItems(database.DB, req.ItemIDs, folderID); err != nil {
+	if err := database.RemoveFoldersForItems(db, req.ItemIDs, folderID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
 			"message": "Failed to remove folder associations",
@@ -159,6 +219,7 @@ func RemoveFolderForItems(c *gin.Context) {
 }
 
 func AddFolderForItems(c *gin.Context) {
+	db := DB(c)
 	var req struct {
 		ItemIDs   []string `json:"itemIds" binding:"required"`   // 图片 ID 列表
 		FolderIDs []string `json:"folderIds" binding:"required"` // 文件夹 ID
@@ -185,7 +246,7 @@ func AddFolderForItems(c *gin.Context) {
 		folderIDs = append(folderIDs, folderUUID)
 	}
 
-	if err := database.AddFolderForItems(database.DB, req.ItemIDs, folderIDs); err != nil {
+	if err := database.AddFolderForItems(db, req.ItemIDs, folderIDs); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
 			"message": "Failed to add folder associations",
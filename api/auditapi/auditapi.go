@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package auditapi
+
+import (
+	"net/http"
+	"synapforest/api"
+	"synapforest/database/auditdb"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// List 按条件分页查询审计日志，最新的在前
+func List(c *gin.Context) {
+	lib := api.Lib(c)
+	var req struct {
+		Actor     string     `json:"actor"`     // 用户名或 token 指纹
+		Operation string     `json:"operation"` // 完整的操作名，或以 . 结尾的前缀，如 item.
+		Target    string     `json:"target"`    // 涉及的 item、文件夹或标签 ID
+		Since     *time.Time `json:"since"`
+		Until     *time.Time `json:"until"` // 不包含
+		Offset    int        `json:"offset"`
+		Limit     int        `json:"limit"` // 默认 100，最多 1000
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+	if req.Offset < 0 || req.Limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Offset and limit must not be negative",
+		})
+		return
+	}
+
+	entries, total, err := auditdb.List(lib.DB, auditdb.Filter{
+		Actor:     req.Actor,
+		Operation: req.Operation,
+		Target:    req.Target,
+		Since:     req.Since,
+		Until:     req.Until,
+		Offset:    req.Offset,
+		Limit:     req.Limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   entries,
+		"total":  total,
+	})
+}
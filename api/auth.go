@@ -13,30 +13,45 @@ package api
 
 import (
 	"net/http"
-	"synapforest/database"
+	"synapforest/database/auditdb"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// UserHeader 可选的请求头，审计日志中记录的用户名，没有时记录 token 指纹。
+// 该值由客户端自行填写，服务器不做验证，只有 token 指纹能确认请求方
+const UserHeader = "X-User"
+
+// AuthMiddleware 检查请求头 Authorization 是否为 token
+func AuthMiddleware(token string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := c.GetHeader("Authorization")
+		auth := c.GetHeader("Authorization")
 
-		// if token == "" {
+		// if auth == "" {
 		// 	var json struct {
 		// 		Token string `json:"token"`
 		// 	}
 		// 	if err := c.ShouldBindJSON(&json); err == nil {
-		// 		token = json.Token
+		// 		auth = json.Token
 		// 	}
 		// }
 
-		if token != database.Token {
+		if auth != token {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			c.Abort()
 			return
 		}
 
+		actor := auditdb.Actor{
+			Name:   c.GetHeader(UserHeader),
+			Token:  auditdb.TokenFingerprint(auth),
+			Source: c.Request.Method + " " + c.Request.URL.Path,
+		}
+		if actor.Name == "" {
+			actor.Name = actor.Token
+		}
+		c.Request = c.Request.WithContext(auditdb.NewContext(c.Request.Context(), actor))
+
 		c.Next()
 	}
 }
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package backupapi
+
+import (
+	"net/http"
+	"os"
+	"synapforest/api"
+	"synapforest/backup"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Backup 备份列表中的一项
+type Backup struct {
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Base        string    `json:"base,omitempty"`
+	Renditions  bool      `json:"renditions"`
+	Blobs       int       `json:"blobs"`
+	Stored      int       `json:"stored"`      // 本次归档中实际保存的文件数量
+	StoredBytes int64     `json:"storedBytes"` // 本次归档中实际保存的文件大小
+	Missing     int       `json:"missing"`
+	Size        int64     `json:"size"` // 归档文件大小
+	DownloadURL string    `json:"downloadUrl"`
+}
+
+// List 列出资料库的备份，按创建时间排序
+func List(c *gin.Context) {
+	lib := api.Lib(c)
+	dir := backup.Dir(lib.Dir)
+	manifests, err := backup.List(dir)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	backups := []Backup{}
+	for _, m := range manifests {
+		stored, storedBytes := m.Stored()
+		b := Backup{
+			ID:          m.ID,
+			CreatedAt:   m.CreatedAt,
+			Base:        m.Base,
+			Renditions:  m.Renditions,
+			Blobs:       len(m.Blobs),
+			Stored:      stored,
+			StoredBytes: storedBytes,
+			Missing:     len(m.Missing),
+			DownloadURL: api.URLPrefix(c.Request.Context()) + "/api/backup/download/" + m.ID,
+		}
+		if info, err := os.Stat(backup.ArchivePath(dir, m.ID)); err == nil {
+			b.Size = info.Size()
+		}
+		backups = append(backups, b)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   backups,
+	})
+}
+
+// Download 下载备份归档。增量备份需要同时下载它引用的基准备份才能恢复
+func Download(c *gin.Context) {
+	lib := api.Lib(c)
+	id := c.Param("id")
+	if !backup.ValidID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid backup ID",
+		})
+		return
+	}
+
+	archivePath := backup.ArchivePath(backup.Dir(lib.Dir), id)
+	if _, err := os.Stat(archivePath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Backup not found",
+		})
+		return
+	}
+	c.FileAttachment(archivePath, id+".tar")
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package api
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"synapforest/blobstore"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 返回存储中的文件。本地存储直接发送文件；开启重定向且存储支持临时地址时返回重定向；
+// 否则由服务器转发。attachment 为 true 时按 filename 作为附件下载
+func serveBlob(c *gin.Context, key string, filename string, attachment bool) {
+	lib := Lib(c)
+	if local, ok := lib.Blobs.(*blobstore.Local); ok {
+		filePath, err := local.Path(key)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"status": "error"})
+			return
+		}
+		if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+			c.JSON(http.StatusNotFound, gin.H{"status": "error"})
+			return
+		}
+		if attachment {
+			c.FileAttachment(filePath, filename)
+		} else {
+			c.File(filePath)
+		}
+		return
+	}
+
+	if presigner, ok := lib.Blobs.(blobstore.Presigner); ok && lib.BlobConfig.Redirect {
+		if !attachment {
+			filename = ""
+		}
+		url, err := presigner.PresignGet(key, filename, lib.BlobConfig.Expiry())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+			return
+		}
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+
+	info, err := lib.Blobs.Stat(key)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, blobstore.ErrNotExist) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"status": "error"})
+		return
+	}
+	r, err := lib.Blobs.Get(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+		return
+	}
+	defer r.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	var headers map[string]string
+	if attachment {
+		headers = map[string]string{
+			"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": filename}),
+		}
+	}
+	c.DataFromReader(http.StatusOK, info.Size, contentType, r, headers)
+}
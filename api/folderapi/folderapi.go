@@ -15,6 +15,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"synapforest/api"
 	"synapforest/database"
 	"synapforest/database/dbcommon"
 	"synapforest/database/folderdb"
@@ -46,6 +47,7 @@ type FolderResponse struct {
 }
 
 func CreateFolder(c *gin.Context) {
+	lib := api.Lib(c)
 	var req struct {
 		FolderName *string   `json:"folderName"`
 		Parent     uuid.UUID `json:"parent"`
@@ -59,7 +61,7 @@ func CreateFolder(c *gin.Context) {
 		return
 	}
 
-	folder, err := folderdb.CreateFolder(database.DB, req.FolderName, "", 0, 0, req.Parent, false)
+	folder, err := folderdb.CreateFolder(api.DB(c), req.FolderName, "", 0, 0, req.Parent, false)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -71,8 +73,8 @@ func CreateFolder(c *gin.Context) {
 	resp := FolderResponse{
 		Status: "success",
 	}
-	subfolders, _ := folderdb.GetChildFolderIDs(database.DB, &folder.ID)
-	items, _ := database.GetItemIDsByFolder(database.DB, folder.ID)
+	subfolders, _ := folderdb.GetChildFolderIDs(lib.DB, &folder.ID)
+	items, _ := database.GetItemIDsByFolder(lib.DB, folder.ID)
 	data := Folder{
 		ID:          folder.ID,
 		Name:        folder.Name,
@@ -90,6 +92,7 @@ func CreateFolder(c *gin.Context) {
 }
 
 func ListFolder(c *gin.Context) {
+	lib := api.Lib(c)
 	var req struct {
 		Parent *string `json:"parent"`
 	}
@@ -112,7 +115,7 @@ func ListFolder(c *gin.Context) {
 		parent = &parsedUUID
 	}
 
-	folderIDs, err := folderdb.GetChildFolderIDs(database.DB, parent)
+	folderIDs, err := folderdb.GetChildFolderIDs(lib.DB, parent)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": "failed"})
 		return
@@ -120,14 +123,14 @@ func ListFolder(c *gin.Context) {
 
 	for _, folderid := range folderIDs {
 		var folder dbcommon.Folder
-		if err := database.DB.First(&folder, folderid).Error; err != nil {
+		if err := lib.DB.First(&folder, folderid).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"status": "failed"})
 			return
 		} else {
 			fmt.Printf("Found Folder: %v\n", folder)
 		}
-		subfolders, _ := folderdb.GetChildFolderIDs(database.DB, &folder.ID)
-		items, _ := database.GetItemIDsByFolder(database.DB, folder.ID)
+		subfolders, _ := folderdb.GetChildFolderIDs(lib.DB, &folder.ID)
+		items, _ := database.GetItemIDsByFolder(lib.DB, folder.ID)
 		data := Folder{
 			ID:          folder.ID,
 			Name:        folder.Name,
@@ -146,6 +149,7 @@ func ListFolder(c *gin.Context) {
 }
 
 func UpdateFolder(c *gin.Context) {
+	lib := api.Lib(c)
 	var req struct {
 		FolderID    string  `json:"folderId" binding:"required"` // 使用 string 类型接收 UUID
 		FolderName  *string `json:"folderName"`
@@ -185,7 +189,7 @@ func UpdateFolder(c *gin.Context) {
 		parentID = &parent
 	}
 
-	folder, err := folderdb.UpdateFolder(database.DB, folderID, req.FolderName, req.Description, req.Icon, req.IconColor, parentID)
+	folder, err := folderdb.UpdateFolder(api.DB(c), folderID, req.FolderName, req.Description, req.Icon, req.IconColor, parentID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -197,8 +201,8 @@ func UpdateFolder(c *gin.Context) {
 	resp := FolderResponse{
 		Status: "success",
 	}
-	subfolders, _ := folderdb.GetChildFolderIDs(database.DB, &folder.ID)
-	items, _ := database.GetItemIDsByFolder(database.DB, folder.ID)
+	subfolders, _ := folderdb.GetChildFolderIDs(lib.DB, &folder.ID)
+	items, _ := database.GetItemIDsByFolder(lib.DB, folder.ID)
 	data := Folder{
 		ID:          folder.ID,
 		Name:        folder.Name,
@@ -216,6 +220,7 @@ func UpdateFolder(c *gin.Context) {
 }
 
 func DeleteFolder(c *gin.Context) {
+	db := api.DB(c)
 	var req struct {
 		FolderID    string `json:"folderId" binding:"required"` // 使用 string 类型接收 UUID
 		HardDelete  *bool  `json:"hardDelete"`                  // 由于不知道软删除文件夹有什么意义，暂时忽略该项
@@ -239,7 +244,7 @@ func DeleteFolder(c *gin.Context) {
 		return
 	}
 
-	if err := folderdb.DeleteFolder(database.DB, folderID, req.HardDelete, req.DeleteItems); err != nil {
+	if err := folderdb.DeleteFolder(db, folderID, req.HardDelete, req.DeleteItems); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
 			"message": "Folder delete failed",
@@ -254,6 +259,7 @@ func DeleteFolder(c *gin.Context) {
 }
 
 func UpdateFoldersParent(c *gin.Context) {
+	db := api.DB(c)
 	var req struct {
 		FolderIDs []string `json:"folderIds" binding:"required"` // 要更新的文件夹ID数组
 		NewParent *string  `json:"newParent"`                    // 新的父文件夹ID
@@ -298,7 +304,7 @@ func UpdateFoldersParent(c *gin.Context) {
 	}
 
 	// 调用 folderdb.UpdateFolderParents 进行批量更新
-	err := folderdb.UpdateFolderParents(database.DB, folderIDs, newParentID)
+	err := folderdb.UpdateFolderParents(db, folderIDs, newParentID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -0,0 +1,252 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"math"
+	"net/http"
+	"synapforest/blobstore"
+	"synapforest/database"
+	"synapforest/database/dbcommon"
+	"synapforest/database/itemdb"
+	"synapforest/database/settingdb"
+	"synapforest/imagecache"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nfnt/resize"
+)
+
+// 允许请求的最大边长
+const maxResizeDimension = 4096
+
+var resizeContentTypes = map[string]string{
+	"webp": "image/webp",
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+}
+
+// ServeResized 按请求的尺寸、适配方式和格式返回派生图片，生成结果缓存在磁盘上
+func ServeResized(c *gin.Context) {
+	lib := Lib(c)
+	id := c.Param("id")
+
+	var req struct {
+		Width   int    `form:"w"`       // 目标宽度，0 表示按比例计算
+		Height  int    `form:"h"`       // 目标高度，0 表示按比例计算
+		Fit     string `form:"fit"`     // contain / cover / crop
+		Format  string `form:"format"`  // webp / jpeg / png
+		Quality int    `form:"quality"` // 1-100，png 忽略
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	if req.Fit == "" {
+		req.Fit = "contain"
+	}
+	switch req.Format {
+	case "":
+		req.Format = "webp"
+	case "jpg":
+		req.Format = "jpeg"
+	}
+	if req.Quality == 0 {
+		req.Quality = 80
+	}
+
+	switch {
+	case req.Width < 0 || req.Height < 0 || req.Width > maxResizeDimension || req.Height > maxResizeDimension:
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid size"})
+		return
+	case req.Width == 0 && req.Height == 0:
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Width or height is required"})
+		return
+	case req.Fit != "contain" && req.Fit != "cover" && req.Fit != "crop":
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid fit, must be 'contain', 'cover' or 'crop'"})
+		return
+	case req.Fit == "crop" && (req.Width == 0 || req.Height == 0):
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Crop requires both width and height"})
+		return
+	case resizeContentTypes[req.Format] == "":
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid format, must be 'webp', 'jpeg' or 'png'"})
+		return
+	case req.Quality < 1 || req.Quality > 100:
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid quality"})
+		return
+	}
+
+	var item dbcommon.Item
+	if err := lib.DB.Unscoped().First(&item, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error"})
+		return
+	}
+
+	// 原始文件的修改时间和大小作为版本号，原始文件变化后旧的派生图不再命中
+	rawKey := itemdb.RawFileKey(id, item.Name, item.Ext)
+	version := "0"
+	if info, err := lib.Blobs.Stat(rawKey); err == nil {
+		version = fmt.Sprintf("%x-%x", info.ModTime.UnixNano(), info.Size)
+	}
+	key := imagecache.Key(id, fmt.Sprintf("%dx%d_%s_q%d_%s.%s", req.Width, req.Height, req.Fit, req.Quality, version, req.Format))
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	if f, ok := lib.ImageCache.Get(key); ok {
+		defer f.Close()
+		if info, err := f.Stat(); err == nil {
+			c.Header("Content-Type", resizeContentTypes[req.Format])
+			http.ServeContent(c.Writer, c.Request, "", info.ModTime(), f)
+			return
+		}
+	}
+
+	src, err := loadResizeSource(lib, item, rawKey, req.Width, req.Height, req.Fit)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("No usable source image: %v", err),
+		})
+		return
+	}
+
+	out := resizeImage(src, req.Width, req.Height, req.Fit)
+
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, out, req.Format, req.Quality); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("Failed to encode image: %v", err),
+		})
+		return
+	}
+
+	if _, err := lib.ImageCache.Put(key, buf.Bytes()); err != nil {
+		c.Error(err)
+	}
+	c.Data(http.StatusOK, resizeContentTypes[req.Format], buf.Bytes())
+}
+
+// 依次尝试缩略图、预览图和原始文件，选择不需要放大即可满足请求尺寸的最小来源
+func loadResizeSource(lib *database.Library, item dbcommon.Item, rawKey string, width, height int, fit string) (image.Image, error) {
+	var candidates []string
+	if item.HaveThumbnail {
+		if key, err := itemdb.RenditionFile(lib.DB, settingdb.Thumbnail, item.ID); err == nil {
+			candidates = append(candidates, key)
+		}
+	}
+	if item.HavePreview {
+		if key, err := itemdb.RenditionFile(lib.DB, settingdb.Preview, item.ID); err == nil {
+			candidates = append(candidates, key)
+		}
+	}
+
+	var fallback string
+	for _, key := range candidates {
+		cfg, err := decodeBlobConfig(lib.Blobs, key)
+		if err != nil {
+			continue
+		}
+		fallback = key
+		tw, th := scaledSize(cfg.Width, cfg.Height, width, height, fit)
+		if tw <= cfg.Width && th <= cfg.Height {
+			return decodeBlob(lib.Blobs, key)
+		}
+	}
+
+	limits, err := settingdb.GetImportLimits(lib.DB)
+	if err != nil {
+		return nil, err
+	}
+	rawPath, cleanup, err := blobstore.Fetch(lib.Blobs, rawKey)
+	if err == nil {
+		img, err := itemdb.DecodeImage(rawPath, limits)
+		cleanup()
+		if err == nil {
+			return img, nil
+		}
+	}
+
+	// 原始文件无法解码（例如模型或文本），使用最大的渲染图
+	if fallback != "" {
+		return decodeBlob(lib.Blobs, fallback)
+	}
+	return nil, fmt.Errorf("item has no decodable image")
+}
+
+func decodeBlobConfig(store blobstore.Store, key string) (image.Config, error) {
+	r, err := store.Get(key)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer r.Close()
+	cfg, _, err := image.DecodeConfig(r)
+	return cfg, err
+}
+
+func decodeBlob(store blobstore.Store, key string) (image.Image, error) {
+	r, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// 计算缩放后（裁剪前）的尺寸，宽或高为 0 时按原图比例推算
+func scaledSize(srcW, srcH, width, height int, fit string) (int, int) {
+	if srcW <= 0 || srcH <= 0 {
+		return width, height
+	}
+	if width == 0 {
+		width = int(math.Round(float64(height) * float64(srcW) / float64(srcH)))
+	}
+	if height == 0 {
+		height = int(math.Round(float64(width) * float64(srcH) / float64(srcW)))
+	}
+
+	sx := float64(width) / float64(srcW)
+	sy := float64(height) / float64(srcH)
+	scale := math.Min(sx, sy)
+	if fit != "contain" {
+		scale = math.Max(sx, sy)
+	}
+
+	return max(1, int(math.Round(float64(srcW)*scale))), max(1, int(math.Round(float64(srcH)*scale)))
+}
+
+func resizeImage(src image.Image, width, height int, fit string) image.Image {
+	tw, th := scaledSize(src.Bounds().Dx(), src.Bounds().Dy(), width, height, fit)
+	scaled := resize.Resize(uint(tw), uint(th), src, resize.Lanczos3)
+	if fit != "crop" {
+		return scaled
+	}
+
+	// 居中裁剪到精确的目标尺寸
+	offX := (tw - width) / 2
+	offY := (th - height) / 2
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(out, out.Bounds(), scaled, scaled.Bounds().Min.Add(image.Pt(offX, offY)), draw.Src)
+	return out
+}
+
+func encodeImage(buf *bytes.Buffer, img image.Image, format string, quality int) error {
+	return itemdb.EncodeImage(buf, img, format, quality)
+}
@@ -12,6 +12,7 @@
 package itemapi
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -23,8 +24,12 @@ import (
 	"synapforest/api"
 	"synapforest/database"
 	"synapforest/database/dbcommon"
+	"synapforest/database/folderdb"
 	"synapforest/database/itemdb"
 	"synapforest/database/tagdb"
+	"synapforest/imageedit"
+	"synapforest/imagemeta"
+	"synapforest/palette"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -52,11 +57,70 @@ type Item struct {
 	TagIds    []uuid.UUID `json:"tagIds"`    // Tags ID列表
 	FolderIds []uuid.UUID `json:"folderIds"` // 文件夹ID列表
 
-	// Palettes []uint32 `json:"palettes"` // 色票（这是什么？）
-	Star uint8 `json:"star"` // 星级评分
+	Palettes []Palette `json:"palettes"` // 主色
+	Location *Location `json:"location"` // 拍摄位置，没有时为 null
+	Star     uint8     `json:"star"`     // 星级评分
 
-	HaveThumbnail bool `json:"haveThumbnail"` // 是否有缩略图
-	HavePreview   bool `json:"havePreview"`   // 是否有预览图
+	HaveThumbnail bool   `json:"haveThumbnail"` // 是否有缩略图
+	HavePreview   bool   `json:"havePreview"`   // 是否有预览图
+	Placeholder   string `json:"placeholder"`   // BlurHash 占位图
+	ImportError   string `json:"importError"`   // 导入时解码失败或超出限制的原因
+}
+
+type Palette struct {
+	Color  string  `json:"color"`  // #rrggbb
+	Weight float64 `json:"weight"` // 占比
+}
+
+type Location struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude"` // 海拔（米）
+}
+
+func toItem(item dbcommon.Item) Item {
+	dataItem := Item{
+		ID:         item.ID,
+		CreatedAt:  item.CreatedAt,
+		ImportedAt: item.ImportedAt,
+		ModifiedAt: item.ModifiedAt,
+		DeletedAt:  item.DeletedAt,
+
+		Name: item.Name,
+		Ext:  item.Ext,
+
+		Width:  item.Width,
+		Height: item.Height,
+		Size:   item.Size,
+
+		Url:        item.Url,
+		Annotation: item.Annotation,
+
+		Star: item.Star,
+
+		HaveThumbnail: item.HaveThumbnail,
+		HavePreview:   item.HavePreview,
+		Placeholder:   item.Placeholder,
+		ImportError:   item.ImportError,
+	}
+
+	for _, tag := range item.Tags {
+		dataItem.TagIds = append(dataItem.TagIds, tag.ID)
+	}
+	for _, folder := range item.Folders {
+		dataItem.FolderIds = append(dataItem.FolderIds, folder.ID)
+	}
+	for _, p := range item.Palettes {
+		dataItem.Palettes = append(dataItem.Palettes, Palette{Color: palette.Hex(p.Color), Weight: p.Weight})
+	}
+	if item.Location != nil {
+		dataItem.Location = &Location{
+			Latitude:  item.Location.Latitude,
+			Longitude: item.Location.Longitude,
+			Altitude:  item.Location.Altitude,
+		}
+	}
+	return dataItem
 }
 
 type ItemResponse struct {
@@ -65,6 +129,7 @@ type ItemResponse struct {
 }
 
 func AddFromUrls(c *gin.Context) {
+	lib := api.Lib(c)
 	var req struct {
 		Items []struct {
 			URL              string            `json:"url" binding:"required"` // 图片链接
@@ -104,6 +169,9 @@ func AddFromUrls(c *gin.Context) {
 		folderUUIDs = nil
 	}
 
+	// 先依次下载，再一起并发导入
+	var entries []itemdb.ImportEntry
+	var urls []string
 	for _, item := range req.Items {
 		filePath, err := saveFileFromURL(item.URL, item.Headers)
 		if err != nil {
@@ -113,7 +181,8 @@ func AddFromUrls(c *gin.Context) {
 		defer os.Remove(filePath)
 
 		if req.TagMode == nil {
-			*req.TagMode = "uuid"
+			mode := "uuid"
+			req.TagMode = &mode
 		}
 		var tagUUIDs []uuid.UUID
 		if item.Tags != nil {
@@ -136,14 +205,14 @@ func AddFromUrls(c *gin.Context) {
 				for _, tagName := range item.Tags {
 					// 首先尝试查找现有标签
 					var existingTag dbcommon.Tag
-					result := database.DB.Where("name = ?", tagName).First(&existingTag)
+					result := lib.DB.Where("name = ?", tagName).First(&existingTag)
 
 					if result.Error == nil {
 						// 找到现有标签
 						tagUUIDs = append(tagUUIDs, existingTag.ID)
 					} else if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 						// 标签不存在，创建新标签
-						newTag, err := tagdb.CreateTag(database.DB, &tagName, "", 0, 0, uuid.Nil, false)
+						newTag, err := tagdb.CreateTag(api.DB(c), &tagName, "", 0, 0, uuid.Nil, false)
 						if err != nil {
 							c.JSON(http.StatusInternalServerError, gin.H{
 								"status":  "error",
@@ -171,14 +240,20 @@ func AddFromUrls(c *gin.Context) {
 		}
 
 		star := uint8(0)
-		err = itemdb.AddItem(database.DB, filePath, item.Name, item.Website, item.Annotation, tagUUIDs, folderUUIDs, &star, item.ModificationTime)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to add item: %v", err)})
-			return
-		}
+		entries = append(entries, itemdb.ImportEntry{
+			Path:       filePath,
+			Name:       item.Name,
+			Url:        item.Website,
+			Annotation: item.Annotation,
+			Tags:       tagUUIDs,
+			Folders:    folderUUIDs,
+			Star:       &star,
+			CreatedAt:  item.ModificationTime,
+		})
+		urls = append(urls, item.URL)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+	respondImport(c, urls, itemdb.AddItems(api.DB(c), entries))
 }
 
 // saveFileFromURL 下载文件并保存，返回文件路径
@@ -246,6 +321,7 @@ func getFileNameFromContentDisposition(contentDisposition string) string {
 }
 
 func AddFromPaths(c *gin.Context) {
+	lib := api.Lib(c)
 	var req struct {
 		FileNames []string `json:"fileNames" binding:"required"`
 		FolderIDs []string `json:"folderIds"`
@@ -272,22 +348,256 @@ func AddFromPaths(c *gin.Context) {
 		}
 	}
 
+	var entries []itemdb.ImportEntry
 	for _, filename := range req.FileNames {
-		err := itemdb.AddItem(database.DB, filepath.Join(api.UploadDir, filename), nil, nil, nil, nil, folderUUIDs, nil, nil)
+		entries = append(entries, itemdb.ImportEntry{
+			Path:    filepath.Join(lib.UploadDir, filename),
+			Folders: folderUUIDs,
+		})
+	}
+
+	respondImport(c, req.FileNames, itemdb.AddItems(api.DB(c), entries))
+}
+
+// 汇总批量导入的结果，有失败时逐个返回失败原因
+func respondImport(c *gin.Context, names []string, errs []error) {
+	failed := gin.H{}
+	for i, err := range errs {
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "failed"})
-			return
+			failed[names[i]] = err.Error()
 		}
 	}
 
+	if len(failed) > 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": "failed",
+			"errors": failed,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
 
+// PathNode 文件夹或标签路径上的一级
+type PathNode struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// ItemPath 所属的文件夹或标签，以及从最顶层开始的完整路径
+type ItemPath struct {
+	ID   uuid.UUID  `json:"id"`
+	Name string     `json:"name"`
+	Path []PathNode `json:"path"`
+}
+
+type Rendition struct {
+	Name      string `json:"name"`
+	Format    string `json:"format"`
+	MaxPixels int    `json:"maxPixels"`
+	Url       string `json:"url"`
+}
+
+type VectorStatus struct {
+	Vectorized bool       `json:"vectorized"`
+	ModifiedAt *time.Time `json:"modifiedAt,omitempty"`
+}
+
+type Technical struct {
+	Camera struct {
+		Make  string `json:"make,omitempty"`
+		Model string `json:"model,omitempty"`
+	} `json:"camera"`
+	Lens struct {
+		Make  string `json:"make,omitempty"`
+		Model string `json:"model,omitempty"`
+	} `json:"lens"`
+	Exposure struct {
+		Time        string  `json:"time,omitempty"`        // 曝光时间，如 1/125
+		FNumber     float64 `json:"fNumber,omitempty"`     // 光圈
+		FocalLength float64 `json:"focalLength,omitempty"` // 焦距（毫米）
+		ISO         int     `json:"iso,omitempty"`
+	} `json:"exposure"`
+	ColorSpace   string `json:"colorSpace,omitempty"`
+	ColorProfile string `json:"colorProfile,omitempty"`
+	DPI          struct {
+		X float64 `json:"x,omitempty"`
+		Y float64 `json:"y,omitempty"`
+	} `json:"dpi"`
+	Software         string `json:"software,omitempty"`
+	DateTimeOriginal string `json:"dateTimeOriginal,omitempty"`
+	Orientation      int    `json:"orientation,omitempty"`
+}
+
+type ItemDetail struct {
+	Item
+	Folders    []ItemPath        `json:"folders"`
+	Tags       []ItemPath        `json:"tags"`
+	Renditions []Rendition       `json:"renditions"`
+	Vector     VectorStatus      `json:"vector"`
+	Technical  *Technical        `json:"technical"` // 没有技术信息时为 null
+	Metadata   map[string]string `json:"metadata"`  // 导入时提取的全部元数据
+
+	DerivedFrom *Derivation `json:"derivedFrom"` // 由编辑生成时的来源与操作，否则为 null
+	Derivatives []string    `json:"derivatives"` // 由该 item 编辑生成的 item ID
+}
+
+type Derivation struct {
+	SourceID   string                `json:"sourceId"`
+	Operations []imageedit.Operation `json:"operations"`
+	CreatedAt  time.Time             `json:"createdAt"`
+}
+
+// Info 返回 item 的完整信息
 func Info(c *gin.Context) {
+	lib := api.Lib(c)
+	var req struct {
+		ID string `json:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	var item dbcommon.Item
+	err := lib.DB.Unscoped().Preload("Folders").Preload("Tags").Preload("Palettes", itemdb.PaletteOrder).Preload("Location").First(&item, "id = ?", req.ID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Item not found",
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Item Query Failed",
+		})
+		return
+	}
+
+	detail, err := itemDetail(c.Request.Context(), item)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
 
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   detail,
+	})
+}
+
+func itemDetail(ctx context.Context, item dbcommon.Item) (*ItemDetail, error) {
+	lib := database.FromContext(ctx)
+	detail := &ItemDetail{
+		Item:       toItem(item),
+		Folders:    []ItemPath{},
+		Tags:       []ItemPath{},
+		Renditions: []Rendition{},
+	}
+
+	for _, folder := range item.Folders {
+		path, err := folderdb.GetFolderPath(lib.DB, folder.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query folder path: %v", err)
+		}
+		entry := ItemPath{ID: folder.ID, Name: folder.Name, Path: []PathNode{}}
+		for _, f := range path {
+			entry.Path = append(entry.Path, PathNode{ID: f.ID, Name: f.Name})
+		}
+		detail.Folders = append(detail.Folders, entry)
+	}
+
+	for _, tag := range item.Tags {
+		path, err := tagdb.GetTagPath(lib.DB, tag.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tag path: %v", err)
+		}
+		entry := ItemPath{ID: tag.ID, Name: tag.Name, Path: []PathNode{}}
+		for _, t := range path {
+			entry.Path = append(entry.Path, PathNode{ID: t.ID, Name: t.Name})
+		}
+		detail.Tags = append(detail.Tags, entry)
+	}
+
+	renditions, err := itemdb.AvailableRenditions(lib.DB, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range renditions {
+		detail.Renditions = append(detail.Renditions, Rendition{
+			Name:      r.Name,
+			Format:    r.Format,
+			MaxPixels: r.MaxPixels,
+			Url:       api.RenditionURL(ctx, r.Name, item.ID),
+		})
+	}
+
+	var vector dbcommon.ItemVector
+	err = lib.VectorDB.Select("item_id", "modified_at").First(&vector, "item_id = ?", item.ID).Error
+	if err == nil {
+		detail.Vector = VectorStatus{Vectorized: true, ModifiedAt: &vector.ModifiedAt}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to query vector: %v", err)
+	}
+
+	detail.Metadata, err = itemdb.GetItemMetadata(lib.DB, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if info := imagemeta.FromMetadata(detail.Metadata); info != nil {
+		detail.Technical = toTechnical(info)
+	}
+
+	derivation, ops, err := itemdb.GetDerivation(lib.DB, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if derivation != nil {
+		detail.DerivedFrom = &Derivation{SourceID: derivation.SourceID, Operations: ops, CreatedAt: derivation.CreatedAt}
+	}
+	detail.Derivatives, err = itemdb.GetDerivatives(lib.DB, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if detail.Derivatives == nil {
+		detail.Derivatives = []string{}
+	}
+
+	return detail, nil
+}
+
+func toTechnical(info *imagemeta.Info) *Technical {
+	t := &Technical{
+		ColorSpace:       info.ColorSpace,
+		ColorProfile:     info.ColorProfile,
+		Software:         info.Software,
+		DateTimeOriginal: info.DateTimeOriginal,
+		Orientation:      info.Orientation,
+	}
+	t.Camera.Make = info.Make
+	t.Camera.Model = info.Model
+	t.Lens.Make = info.LensMake
+	t.Lens.Model = info.LensModel
+	t.Exposure.Time = info.ExposureTime
+	t.Exposure.FNumber = info.FNumber
+	t.Exposure.FocalLength = info.FocalLength
+	t.Exposure.ISO = info.ISO
+	t.DPI.X = info.DPIX
+	t.DPI.Y = info.DPIY
+	return t
 }
 
 func MoveToTrash(c *gin.Context) {
+	lib := api.Lib(c)
 	var req struct {
 		ItemIDs    []string `json:"itemIds"`
 		HardDelete *bool    `json:"hardDelete"`
@@ -303,9 +613,12 @@ func MoveToTrash(c *gin.Context) {
 
 	var err error
 	if req.HardDelete != nil && *req.HardDelete {
-		err = itemdb.ItemHardDelete(database.DB, req.ItemIDs)
+		err = itemdb.ItemHardDelete(api.DB(c), req.ItemIDs)
+		if err == nil {
+			err = lib.ImageCache.Invalidate(req.ItemIDs...)
+		}
 	} else {
-		err = itemdb.ItemSoftDelete(database.DB, req.ItemIDs)
+		err = itemdb.ItemSoftDelete(api.DB(c), req.ItemIDs)
 	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": "failed"})
@@ -316,6 +629,7 @@ func MoveToTrash(c *gin.Context) {
 
 // UpdateImage 更新图片属性的 API 函数
 func Update(c *gin.Context) {
+	lib := api.Lib(c)
 	var req struct {
 		ID         string     `json:"id" binding:"required"` // 图片 ID
 		Name       *string    `json:"name"`                  // 图片名称
@@ -338,7 +652,8 @@ func Update(c *gin.Context) {
 	}
 
 	if req.TagMode == nil {
-		*req.TagMode = "uuid"
+		mode := "uuid"
+		req.TagMode = &mode
 	}
 	var tagUUIDs []uuid.UUID
 	if req.Tags != nil {
@@ -361,14 +676,14 @@ func Update(c *gin.Context) {
 			for _, tagName := range req.Tags {
 				// 首先尝试查找现有标签
 				var existingTag dbcommon.Tag
-				result := database.DB.Where("name = ?", tagName).First(&existingTag)
+				result := lib.DB.Where("name = ?", tagName).First(&existingTag)
 
 				if result.Error == nil {
 					// 找到现有标签
 					tagUUIDs = append(tagUUIDs, existingTag.ID)
 				} else if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 					// 标签不存在，创建新标签
-					newTag, err := tagdb.CreateTag(database.DB, &tagName, "", 0, 0, uuid.Nil, false)
+					newTag, err := tagdb.CreateTag(api.DB(c), &tagName, "", 0, 0, uuid.Nil, false)
 					if err != nil {
 						c.JSON(http.StatusInternalServerError, gin.H{
 							"status":  "error",
@@ -412,7 +727,7 @@ func Update(c *gin.Context) {
 		folderUUIDs = nil
 	}
 
-	err := itemdb.UpdateItem(database.DB, req.ID, req.Name, req.Ext, req.URL, req.Annotation, tagUUIDs, folderUUIDs, req.Star, req.CreatedAt)
+	err := itemdb.UpdateItem(api.DB(c), req.ID, req.Name, req.Ext, req.URL, req.Annotation, tagUUIDs, folderUUIDs, req.Star, req.CreatedAt)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -441,6 +756,7 @@ func parseUUIDs(ids []string) ([]uuid.UUID, error) {
 }
 
 func List(c *gin.Context) {
+	lib := api.Lib(c)
 	var req struct {
 		Limit     *int     `json:"limit"`
 		Offset    *int     `json:"offset"`
@@ -450,6 +766,10 @@ func List(c *gin.Context) {
 		TagIDs    []string `json:"tagIds"`
 		FolderIDs []string `json:"folderIds"`
 		IsDeleted *bool    `json:"isDeleted"`
+		Color     *string  `json:"color"`         // 主色筛选，#rrggbb
+		Distance  *float64 `json:"colorDistance"` // 允许的 ΔE 距离
+		BBox      *BBox    `json:"bbox"`          // 拍摄位置在矩形范围内
+		Near      *Near    `json:"near"`          // 拍摄位置在圆形范围内
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -478,7 +798,32 @@ func List(c *gin.Context) {
 		return
 	}
 
-	items, err := itemdb.ItemList(database.DB, req.IsDeleted, req.OrderBy, req.Offset, req.Limit, req.Exts, req.Keyword, tagUUIDs, folderUUIDs)
+	if req.Color != nil && *req.Color != "" {
+		if _, err := palette.ParseHex(*req.Color); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid color",
+			})
+			return
+		}
+	}
+
+	var area itemdb.GeoFilter
+	if req.BBox != nil {
+		area.BBox = req.BBox.toBBox()
+	}
+	if req.Near != nil {
+		area.Radius = &itemdb.Radius{Latitude: req.Near.Latitude, Longitude: req.Near.Longitude, Meters: req.Near.Radius}
+	}
+	if err := area.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	items, err := itemdb.ItemList(lib.DB, req.IsDeleted, req.OrderBy, req.Offset, req.Limit, req.Exts, req.Keyword, tagUUIDs, folderUUIDs, req.Color, req.Distance, &area)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -492,36 +837,234 @@ func List(c *gin.Context) {
 	}
 
 	for _, item := range items {
-		dataItem := Item{
-			ID:         item.ID,
-			CreatedAt:  item.CreatedAt,
-			ImportedAt: item.ImportedAt,
-			ModifiedAt: item.ModifiedAt,
-			DeletedAt:  item.DeletedAt,
+		resp.Data = append(resp.Data, toItem(item))
+	}
+	c.JSON(http.StatusOK, resp)
+}
 
-			Name: item.Name,
-			Ext:  item.Ext,
+// BBox 经纬度矩形范围，west 大于 east 时表示跨越 180° 经线
+type BBox struct {
+	South float64 `json:"south"`
+	West  float64 `json:"west"`
+	North float64 `json:"north"`
+	East  float64 `json:"east"`
+}
 
-			Width:  item.Width,
-			Height: item.Height,
-			Size:   item.Size,
+func (b *BBox) toBBox() *itemdb.BBox {
+	return &itemdb.BBox{South: b.South, West: b.West, North: b.North, East: b.East}
+}
 
-			Url:        item.Url,
-			Annotation: item.Annotation,
+// Near 以某点为中心、radius 米为半径的圆形范围
+type Near struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Radius    float64 `json:"radius"`
+}
 
-			Star: item.Star,
+// GeoClusters 返回地图视野内按缩放级别聚合的拍摄位置
+func GeoClusters(c *gin.Context) {
+	lib := api.Lib(c)
+	var req struct {
+		BBox
+		Zoom int `json:"zoom"`
+	}
 
-			HaveThumbnail: item.HaveThumbnail,
-			HavePreview:   item.HavePreview,
-		}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
 
-		for _, tag := range item.Tags {
-			dataItem.TagIds = append(dataItem.TagIds, tag.ID)
-		}
-		for _, folder := range item.Folders {
-			dataItem.FolderIds = append(dataItem.FolderIds, folder.ID)
-		}
-		resp.Data = append(resp.Data, dataItem)
+	clusters, err := itemdb.GeoClusters(lib.DB, *req.BBox.toBBox(), req.Zoom)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
 	}
-	c.JSON(http.StatusOK, resp)
+
+	data := make([]GeoCluster, 0, len(clusters))
+	for _, cluster := range clusters {
+		data = append(data, GeoCluster{
+			Latitude:  cluster.Latitude,
+			Longitude: cluster.Longitude,
+			Count:     cluster.Count,
+			ItemID:    cluster.ItemID,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   data,
+	})
+}
+
+type GeoCluster struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Count     int     `json:"count"`
+	ItemID    string  `json:"itemId,omitempty"` // 只有一个 item 时为其 ID
+}
+
+// Edit 对 item 应用裁剪、旋转、翻转和缩放，结果作为新 item 导入
+func Edit(c *gin.Context) {
+	db := api.DB(c)
+	var req struct {
+		ID         string                `json:"id" binding:"required"`
+		Operations []imageedit.Operation `json:"operations" binding:"required"`
+		Name       string                `json:"name"`
+		Format     string                `json:"format"`
+		Quality    int                   `json:"quality"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	itemID, err := itemdb.EditItem(db, req.ID, req.Operations, itemdb.EditOptions{
+		Name:    req.Name,
+		Format:  req.Format,
+		Quality: req.Quality,
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Item not found",
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   gin.H{"id": itemID},
+	})
+}
+
+// ReplaceFile 用上传目录中的文件替换 item 的内容，保留组织信息并记录历史版本
+func ReplaceFile(c *gin.Context) {
+	lib := api.Lib(c)
+	var req struct {
+		ID       string `json:"id" binding:"required"`
+		FileName string `json:"fileName" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	itemID, err := itemdb.ReplaceItemFile(api.DB(c), req.ID, filepath.Join(lib.UploadDir, filepath.Base(req.FileName)))
+	respondReplace(c, itemID, err)
+}
+
+// RestoreVersion 把历史版本恢复为 item 的当前文件
+func RestoreVersion(c *gin.Context) {
+	db := api.DB(c)
+	var req struct {
+		ID        string `json:"id" binding:"required"`
+		VersionID uint   `json:"versionId" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	itemID, err := itemdb.RestoreVersion(db, req.ID, req.VersionID)
+	respondReplace(c, itemID, err)
+}
+
+func respondReplace(c *gin.Context, itemID string, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Item not found",
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   gin.H{"id": itemID},
+	})
+}
+
+type Version struct {
+	ID         uint      `json:"id"`
+	FileID     string    `json:"fileId"` // 旧文件的哈希
+	Name       string    `json:"name"`
+	Ext        string    `json:"ext"`
+	Size       uint64    `json:"size"`
+	Width      uint32    `json:"width"`
+	Height     uint32    `json:"height"`
+	ReplacedAt time.Time `json:"replacedAt"`
+	Url        string    `json:"url"` // 下载地址
+}
+
+// Versions 列出 item 的历史版本
+func Versions(c *gin.Context) {
+	lib := api.Lib(c)
+	var req struct {
+		ID string `json:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	versions, err := itemdb.ListVersions(lib.DB, req.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Version Query Failed",
+		})
+		return
+	}
+
+	data := make([]Version, 0, len(versions))
+	for _, v := range versions {
+		data = append(data, Version{
+			ID:         v.ID,
+			FileID:     v.FileID,
+			Name:       v.Name,
+			Ext:        v.Ext,
+			Size:       v.Size,
+			Width:      v.Width,
+			Height:     v.Height,
+			ReplacedAt: v.ReplacedAt,
+			Url:        api.VersionURL(c.Request.Context(), v.ID),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   data,
+	})
 }
@@ -0,0 +1,528 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package jobapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"synapforest/api"
+	"synapforest/backup"
+	"synapforest/database"
+	"synapforest/database/auditdb"
+	"synapforest/database/itemdb"
+	"synapforest/database/settingdb"
+	"synapforest/job"
+	"synapforest/palette"
+	"synapforest/portable"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// 任务所属的资料库和返回地址使用的路径前缀
+type scope struct {
+	lib       *database.Library
+	libraries *database.Registry // 服务器管理的全部资料库
+	prefix    string
+}
+
+// 任务中的修改以启动任务的一方记录到审计日志
+func (s scope) db(ctx context.Context) *gorm.DB {
+	return auditdb.As(s.lib.DB, auditdb.ActorFrom(ctx))
+}
+
+// runner 根据请求参数构造任务函数
+type runner func(s scope, params json.RawMessage) (func(ctx context.Context, j *job.Job) error, error)
+
+// 可通过 /api/job/start 启动的任务类型
+var runners = map[string]runner{
+	"palette_backfill":     paletteBackfill,
+	"placeholder_backfill": placeholderBackfill,
+	"rendition_regenerate": renditionRegenerate,
+	"metadata_backfill":    metadataBackfill,
+	"integrity_check":      integrityCheck,
+	"backup":               backupLibrary,
+	"restore":              restoreLibrary,
+	"export":               exportItems,
+	"import":               importPackage,
+}
+
+func paletteBackfill(s scope, params json.RawMessage) (func(ctx context.Context, j *job.Job) error, error) {
+	var p struct {
+		Force bool `json:"force"` // 重新计算已有主色的 item
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(ctx context.Context, j *job.Job) error {
+		return itemdb.BackfillPalettes(ctx, s.db(ctx), p.Force, j.Progress)
+	}, nil
+}
+
+func placeholderBackfill(s scope, params json.RawMessage) (func(ctx context.Context, j *job.Job) error, error) {
+	var p struct {
+		Force bool `json:"force"` // 重新计算已有占位图的 item
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(ctx context.Context, j *job.Job) error {
+		return itemdb.BackfillPlaceholders(ctx, s.db(ctx), p.Force, j.Progress)
+	}, nil
+}
+
+func renditionRegenerate(s scope, params json.RawMessage) (func(ctx context.Context, j *job.Job) error, error) {
+	var p struct {
+		ItemIDs     []string    `json:"itemIds"`     // 指定 item，为空时不限
+		Exts        []string    `json:"exts"`        // 按扩展名筛选
+		Tags        []uuid.UUID `json:"tags"`        // 按标签筛选
+		Folders     []uuid.UUID `json:"folders"`     // 按文件夹筛选
+		Renditions  []string    `json:"renditions"`  // 要生成的规格名，为空时生成全部
+		MissingOnly bool        `json:"missingOnly"` // 只处理缺少缩略图或预览图的 item
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range p.Renditions {
+		if _, err := settingdb.GetRendition(s.lib.DB, name); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(ctx context.Context, j *job.Job) error {
+		itemIDs, err := itemdb.RenditionTargets(s.lib.DB, p.ItemIDs, p.Exts, p.Tags, p.Folders, p.MissingOnly)
+		if err != nil {
+			return err
+		}
+		return itemdb.RegenerateRenditions(ctx, s.db(ctx), itemIDs, p.Renditions, j.Progress)
+	}, nil
+}
+
+func metadataBackfill(s scope, params json.RawMessage) (func(ctx context.Context, j *job.Job) error, error) {
+	return func(ctx context.Context, j *job.Job) error {
+		return itemdb.BackfillImageMetadata(ctx, s.db(ctx), j.Progress)
+	}, nil
+}
+
+func integrityCheck(s scope, params json.RawMessage) (func(ctx context.Context, j *job.Job) error, error) {
+	p := struct {
+		Repair     bool `json:"repair"`     // 修复发现的问题
+		VerifyHash bool `json:"verifyHash"` // 校验原始文件的 SHA256，默认开启
+	}{VerifyHash: true}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(ctx context.Context, j *job.Job) error {
+		report, err := itemdb.CheckIntegrity(ctx, s.db(ctx), itemdb.IntegrityOptions{
+			Repair:     p.Repair,
+			VerifyHash: p.VerifyHash,
+		}, j.Progress)
+		if err != nil {
+			return err
+		}
+		if err := saveReport(s.lib, j.ID(), report); err != nil {
+			return err
+		}
+
+		j.SetResult(gin.H{
+			"issues":    len(report.Issues),
+			"repaired":  report.Repaired,
+			"summary":   report.Summary,
+			"reportUrl": s.reportURL(j.ID()),
+		})
+		return nil
+	}, nil
+}
+
+func backupLibrary(s scope, params json.RawMessage) (func(ctx context.Context, j *job.Job) error, error) {
+	var p struct {
+		Renditions  bool   `json:"renditions"`  // 同时备份缩略图、预览图和自定义规格
+		Incremental bool   `json:"incremental"` // 以最近一次备份为基准
+		Base        string `json:"base"`        // 指定基准备份 ID
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+	}
+
+	dir := backup.Dir(s.lib.Dir)
+	if p.Base != "" {
+		if !backup.ValidID(p.Base) {
+			return nil, fmt.Errorf("invalid backup id %q", p.Base)
+		}
+		if _, err := os.Stat(backup.ArchivePath(dir, p.Base)); err != nil {
+			return nil, fmt.Errorf("backup %s not found", p.Base)
+		}
+	}
+
+	return func(ctx context.Context, j *job.Job) error {
+		base := ""
+		switch {
+		case p.Base != "":
+			base = backup.ArchivePath(dir, p.Base)
+		case p.Incremental:
+			backups, err := backup.List(dir)
+			if err != nil {
+				return err
+			}
+			if len(backups) > 0 {
+				base = backup.ArchivePath(dir, backups[len(backups)-1].ID)
+			}
+		}
+
+		manifest, err := backup.Create(ctx, s.lib.DB, s.lib.VectorDB, s.lib.Blobs, dir, backup.Options{
+			Renditions: p.Renditions,
+			Base:       base,
+		}, j.Progress)
+		if err != nil {
+			return err
+		}
+
+		stored, size := manifest.Stored()
+		j.SetResult(gin.H{
+			"id":          manifest.ID,
+			"base":        manifest.Base,
+			"blobs":       len(manifest.Blobs),
+			"stored":      stored,
+			"storedBytes": size,
+			"missing":     manifest.Missing,
+			"downloadUrl": s.prefix + "/api/backup/download/" + manifest.ID,
+		})
+		return nil
+	}, nil
+}
+
+func restoreLibrary(s scope, params json.RawMessage) (func(ctx context.Context, j *job.Job) error, error) {
+	var p struct {
+		ID     string `json:"id"`     // 备份 ID
+		Target string `json:"target"` // 恢复到的资料库目录，必须不存在或为空
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+	}
+
+	if !backup.ValidID(p.ID) {
+		return nil, fmt.Errorf("invalid backup id %q", p.ID)
+	}
+	if p.Target == "" {
+		return nil, fmt.Errorf("target is required")
+	}
+	archivePath := backup.ArchivePath(backup.Dir(s.lib.Dir), p.ID)
+	if _, err := os.Stat(archivePath); err != nil {
+		return nil, fmt.Errorf("backup %s not found", p.ID)
+	}
+
+	return func(ctx context.Context, j *job.Job) error {
+		result, err := backup.Restore(ctx, archivePath, p.Target, j.Progress)
+		if err != nil {
+			return err
+		}
+		j.SetResult(result)
+		return nil
+	}, nil
+}
+
+func exportItems(s scope, params json.RawMessage) (func(ctx context.Context, j *job.Job) error, error) {
+	var p struct {
+		Exts          []string    `json:"exts"`
+		Keyword       *string     `json:"keyword"`
+		TagIDs        []uuid.UUID `json:"tagIds"`
+		FolderIDs     []uuid.UUID `json:"folderIds"`
+		Color         *string     `json:"color"`         // 主色筛选，#rrggbb
+		ColorDistance *float64    `json:"colorDistance"` // 允许的 ΔE 距离
+		BBox          *struct {
+			South float64 `json:"south"`
+			West  float64 `json:"west"`
+			North float64 `json:"north"`
+			East  float64 `json:"east"`
+		} `json:"bbox"` // 拍摄位置在矩形范围内
+		Near *struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+			Radius    float64 `json:"radius"`
+		} `json:"near"` // 拍摄位置在圆形范围内
+		Subtree *uuid.UUID `json:"subtree"` // 导出该文件夹及其子文件夹
+		Vectors bool       `json:"vectors"` // 同时导出向量
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Color != nil && *p.Color != "" {
+		if _, err := palette.ParseHex(*p.Color); err != nil {
+			return nil, fmt.Errorf("invalid color")
+		}
+	}
+	var area itemdb.GeoFilter
+	if p.BBox != nil {
+		area.BBox = &itemdb.BBox{South: p.BBox.South, West: p.BBox.West, North: p.BBox.North, East: p.BBox.East}
+	}
+	if p.Near != nil {
+		area.Radius = &itemdb.Radius{Latitude: p.Near.Latitude, Longitude: p.Near.Longitude, Meters: p.Near.Radius}
+	}
+	if err := area.Validate(); err != nil {
+		return nil, err
+	}
+
+	filter := portable.Filter{
+		Exts:          p.Exts,
+		Keyword:       p.Keyword,
+		Tags:          p.TagIDs,
+		Folders:       p.FolderIDs,
+		Color:         p.Color,
+		ColorDistance: p.ColorDistance,
+		Area:          &area,
+		Subtree:       p.Subtree,
+	}
+
+	return func(ctx context.Context, j *job.Job) error {
+		// 导出包以任务 ID 命名
+		id := uuid.FromStringOrNil(j.ID())
+		dir := portable.Dir(s.lib.Dir)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+		manifest, err := portable.Export(ctx, s.lib, filter, portable.Options{Vectors: p.Vectors}, portable.PackagePath(dir, id), j.Progress)
+		if err != nil {
+			return err
+		}
+
+		j.SetResult(gin.H{
+			"id":          id,
+			"items":       len(manifest.Items),
+			"folders":     len(manifest.Folders),
+			"tags":        len(manifest.Tags),
+			"missing":     manifest.Missing,
+			"downloadUrl": s.prefix + "/api/package/download/" + id.String(),
+		})
+		return nil
+	}, nil
+}
+
+func importPackage(s scope, params json.RawMessage) (func(ctx context.Context, j *job.Job) error, error) {
+	var p struct {
+		ID      uuid.UUID `json:"id"`      // 上传或导出的导出包 ID
+		Library string    `json:"library"` // 导出包所在的资料库，默认为当前资料库
+		Folder  uuid.UUID `json:"folder"`  // 包中的顶层文件夹放在该文件夹下
+		Tag     uuid.UUID `json:"tag"`     // 包中的顶层标签放在该标签下
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+	}
+
+	source := s.lib
+	if p.Library != "" {
+		var err error
+		if source, err = s.libraries.Get(p.Library); err != nil {
+			return nil, fmt.Errorf("library %s: %v", p.Library, err)
+		}
+	}
+	packagePath := portable.PackagePath(portable.Dir(source.Dir), p.ID)
+	if _, err := os.Stat(packagePath); err != nil {
+		return nil, fmt.Errorf("package %s not found", p.ID)
+	}
+
+	return func(ctx context.Context, j *job.Job) error {
+		result, err := portable.Import(ctx, s.lib, packagePath, portable.ImportOptions{
+			Folder: p.Folder,
+			Tag:    p.Tag,
+		}, j.Progress)
+		if err != nil {
+			return err
+		}
+		j.SetResult(result)
+		return nil
+	}, nil
+}
+
+// 任务报告保存在资料库的 reports 目录中，任务记录被清理后仍然可以下载
+func reportPath(lib *database.Library, jobID string) string {
+	return filepath.Join(lib.Dir, "reports", jobID+".json")
+}
+
+func (s scope) reportURL(jobID string) string {
+	return s.prefix + "/api/job/report/" + jobID
+}
+
+func saveReport(lib *database.Library, jobID string, report interface{}) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(reportPath(lib, jobID)), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(reportPath(lib, jobID), data, 0644)
+}
+
+// Report 下载任务生成的报告
+func Report(c *gin.Context) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid job ID",
+		})
+		return
+	}
+
+	path := reportPath(api.Lib(c), id.String())
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Report not found",
+		})
+		return
+	}
+	c.FileAttachment(path, "report-"+id.String()+".json")
+}
+
+func StartJob(c *gin.Context) {
+	var req struct {
+		Type   string          `json:"type" binding:"required"`
+		Params json.RawMessage `json:"params"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	newRunner, ok := runners[req.Type]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("Unknown job type: %s", req.Type),
+		})
+		return
+	}
+
+	lib := api.Lib(c)
+	run, err := newRunner(scope{lib: lib, libraries: api.Registry(c), prefix: api.URLPrefix(c.Request.Context())}, req.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": fmt.Sprintf("Invalid job params: %v", err),
+		})
+		return
+	}
+
+	actor := auditdb.ActorFrom(c.Request.Context())
+
+	// 任务运行期间资料库不会被关闭
+	j := lib.Jobs.Start(req.Type, func(ctx context.Context, j *job.Job) error {
+		if !lib.Acquire() {
+			return database.ErrLibraryClosed
+		}
+		defer lib.Release()
+
+		jobActor := actor
+		jobActor.Source = "job " + req.Type + " " + j.ID()
+		return run(auditdb.NewContext(ctx, jobActor), j)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   j.Snapshot(),
+	})
+}
+
+func ListJob(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   api.Lib(c).Jobs.List(),
+	})
+}
+
+func InfoJob(c *gin.Context) {
+	var req struct {
+		ID string `json:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	j, ok := api.Lib(c).Jobs.Get(req.ID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Job not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   j.Snapshot(),
+	})
+}
+
+func CancelJob(c *gin.Context) {
+	var req struct {
+		ID string `json:"id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	j, ok := api.Lib(c).Jobs.Get(req.ID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Job not found",
+		})
+		return
+	}
+	j.Cancel()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Job cancel requested",
+	})
+}
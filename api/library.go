@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"synapforest/database"
+	"synapforest/database/auditdb"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LibraryHeader 不使用路径前缀时用于选择资料库的请求头
+const LibraryHeader = "X-Library"
+
+type urlPrefixKey struct{}
+
+type registryKey struct{}
+
+// RegistryMiddleware 把服务器管理的资料库放入请求上下文，需在 LibraryMiddleware 之前使用
+func RegistryMiddleware(libraries *database.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), registryKey{}, libraries)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Registry 返回服务器管理的资料库
+func Registry(c *gin.Context) *database.Registry {
+	libraries, _ := c.Request.Context().Value(registryKey{}).(*database.Registry)
+	return libraries
+}
+
+// LibraryMiddleware 按路径前缀 /lib/:library 或请求头 X-Library 选择资料库，
+// 都没有时使用默认资料库。请求处理期间资料库不会被关闭
+func LibraryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		libraries := Registry(c)
+		name := c.Param("library")
+		if name == "" {
+			name = c.GetHeader(LibraryHeader)
+		}
+
+		var lib *database.Library
+		var err error
+		if name == "" {
+			lib, err = libraries.Default()
+		} else {
+			lib, err = libraries.Get(name)
+		}
+		if err == nil && !lib.Acquire() {
+			err = database.ErrLibraryClosed
+		}
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, database.ErrLibraryNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, database.ErrLibraryClosed):
+				status = http.StatusConflict
+			}
+			c.AbortWithStatusJSON(status, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
+		defer lib.Release()
+
+		// 返回的文件地址带上资料库前缀，<img> 等无法设置请求头的地方也能访问
+		prefix := ""
+		if name != "" && !libraries.IsDefault(name) {
+			prefix = "/lib/" + name
+		}
+
+		ctx := database.WithLibrary(c.Request.Context(), lib)
+		ctx = context.WithValue(ctx, urlPrefixKey{}, prefix)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Lib 返回请求所属的资料库
+func Lib(c *gin.Context) *database.Library {
+	return database.FromContext(c.Request.Context())
+}
+
+// DB 返回请求所属资料库的数据库连接，通过它的修改以请求方的名义记录到审计日志
+func DB(c *gin.Context) *gorm.DB {
+	return auditdb.As(Lib(c).DB, auditdb.ActorFrom(c.Request.Context()))
+}
+
+// URLPrefix 返回请求所属资料库的路径前缀，默认资料库为空
+func URLPrefix(ctx context.Context) string {
+	prefix, _ := ctx.Value(urlPrefixKey{}).(string)
+	return prefix
+}
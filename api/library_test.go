@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"synapforest/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 路由与服务器相同：未加前缀和 /lib/:library 前缀下注册同一个处理函数，
+// 处理函数返回所用资料库的目录和地址前缀
+func newRoutingTest(t *testing.T, handler gin.HandlerFunc) (*database.Registry, http.Handler) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	root := t.TempDir()
+	libraries := database.NewRegistry(filepath.Join(root, "default"), filepath.Join(root, "libraries"))
+	if err := libraries.OpenAll(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(libraries.CloseAll)
+	if _, err := libraries.Create("photos", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if handler == nil {
+		handler = func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"dir": Lib(c).Dir, "prefix": URLPrefix(c.Request.Context())})
+		}
+	}
+	r := gin.New()
+	r.Use(RegistryMiddleware(libraries))
+	for _, g := range []*gin.RouterGroup{r.Group(""), r.Group("/lib/:library")} {
+		g.GET("/api/which", LibraryMiddleware(), handler)
+	}
+	return libraries, r
+}
+
+func TestLibraryRouting(t *testing.T) {
+	libraries, handler := newRoutingTest(t, nil)
+	def, _ := libraries.Default()
+	photos, _ := libraries.Get("photos")
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		code   int
+		dir    string
+		prefix string
+	}{
+		{"default", "/api/which", "", http.StatusOK, def.Dir, ""},
+		{"prefix", "/lib/photos/api/which", "", http.StatusOK, photos.Dir, "/lib/photos"},
+		{"header", "/api/which", "photos", http.StatusOK, photos.Dir, "/lib/photos"},
+		// 路径前缀优先于请求头
+		{"prefix over header", "/lib/default/api/which", "photos", http.StatusOK, def.Dir, ""},
+		{"default by name", "/lib/default/api/which", "", http.StatusOK, def.Dir, ""},
+		{"unknown prefix", "/lib/missing/api/which", "", http.StatusNotFound, "", ""},
+		{"unknown header", "/api/which", "missing", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set(LibraryHeader, tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.code, w.Body)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var got struct{ Dir, Prefix string }
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Dir != tt.dir || got.Prefix != tt.prefix {
+			t.Errorf("%s: got %+v, want %s %q", tt.name, got, tt.dir, tt.prefix)
+		}
+	}
+
+	// 关闭后两种方式都返回 409
+	if _, err := libraries.Close("photos"); err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/lib/photos/api/which", nil),
+		func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/api/which", nil)
+			req.Header.Set(LibraryHeader, "photos")
+			return req
+		}(),
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s on closed library: %d %s", req.URL, w.Code, w.Body)
+		}
+	}
+}
+
+// 请求处理期间关闭资料库，关闭等待请求结束，请求中的数据库仍然可用，之后的请求返回 409
+func TestCloseLibraryDuringRequest(t *testing.T) {
+	started, finish := make(chan struct{}), make(chan struct{})
+	libraries, handler := newRoutingTest(t, func(c *gin.Context) {
+		close(started)
+		<-finish
+		var n int
+		if err := Lib(c).DB.Raw("SELECT COUNT(*) FROM items").Scan(&n).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "success", "data": n})
+	})
+
+	inFlight := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(inFlight, httptest.NewRequest(http.MethodGet, "/lib/photos/api/which", nil))
+		close(served)
+	}()
+	<-started
+
+	closed := make(chan error, 1)
+	go func() {
+		_, err := libraries.Close("photos")
+		closed <- err
+	}()
+	select {
+	case err := <-closed:
+		t.Fatalf("close returned during a request: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 关闭等待期间的新请求不会分配到该资料库
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/which", nil)
+	req.Header.Set(LibraryHeader, "photos")
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("request while closing: %d %s", w.Code, w.Body)
+	}
+
+	close(finish)
+	<-served
+	if inFlight.Code != http.StatusOK {
+		t.Errorf("in-flight request: %d %s", inFlight.Code, inFlight.Body)
+	}
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("close did not return after the request finished")
+	}
+}
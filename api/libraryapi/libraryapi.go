@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package libraryapi
+
+import (
+	"errors"
+	"net/http"
+	"synapforest/api"
+	"synapforest/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 按错误类型返回状态码
+func respondError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, database.ErrLibraryNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, database.ErrLibraryClosed):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": err.Error(),
+	})
+}
+
+// List 列出注册的资料库
+func List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   api.Registry(c).List(),
+	})
+}
+
+// Create 创建并打开资料库，目录已有资料库时直接打开
+func Create(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+		Dir  string `json:"dir"` // 资料库目录，默认为 libraries/<name>
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	info, err := api.Registry(c).Create(req.Name, req.Dir)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   info,
+	})
+}
+
+// Open 打开已关闭的资料库
+func Open(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	info, err := api.Registry(c).Open(req.Name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   info,
+	})
+}
+
+// Rename 修改资料库名称，使用旧名称前缀的地址随之失效
+func Rename(c *gin.Context) {
+	var req struct {
+		Name    string `json:"name" binding:"required"`
+		NewName string `json:"newName" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	info, err := api.Registry(c).Rename(req.Name, req.NewName)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   info,
+	})
+}
+
+// Close 关闭资料库，有任务正在运行时拒绝
+func Close(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	if lib, err := api.Registry(c).Get(req.Name); err == nil && lib.Jobs.Running() > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  "error",
+			"message": "Library has running jobs",
+		})
+		return
+	}
+
+	info, err := api.Registry(c).Close(req.Name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   info,
+	})
+}
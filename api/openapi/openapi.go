@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Path 规范的访问路径
+const Path = "/api/openapi.json"
+
+// Spec OpenAPI 3 规范，修改路由时需要同步修改
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Serve 返回规范
+func Serve(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", Spec)
+}
+
+type document struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]json.RawMessage `json:"schemas"`
+	} `json:"components"`
+}
+
+var methods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+var (
+	routeParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+	schemaRef  = regexp.MustCompile(`"\$ref":\s*"#/components/schemas/([^"]+)"`)
+)
+
+// Validate 检查规范与路由一一对应，并且引用的 schema 都存在。
+// prefix 下的路由是不带前缀路由的副本，不单独出现在规范中
+func Validate(routes gin.RoutesInfo, prefix string) error {
+	var doc document
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		return fmt.Errorf("invalid OpenAPI spec: %w", err)
+	}
+
+	documented := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			if methods[method] {
+				documented[strings.ToUpper(method)+" "+path] = true
+			}
+		}
+	}
+
+	var problems []string
+	registered := map[string]bool{}
+	for _, r := range routes {
+		if strings.HasPrefix(r.Path, prefix+"/") {
+			continue
+		}
+		op := r.Method + " " + routeParam.ReplaceAllString(r.Path, "{$1}")
+		registered[op] = true
+		if !documented[op] {
+			problems = append(problems, "undocumented route "+op)
+		}
+	}
+	for op := range documented {
+		if !registered[op] {
+			problems = append(problems, "documented route "+op+" is not registered")
+		}
+	}
+	missing := map[string]bool{}
+	for _, m := range schemaRef.FindAllSubmatch(Spec, -1) {
+		name := string(m[1])
+		if _, ok := doc.Components.Schemas[name]; !ok && !missing[name] {
+			missing[name] = true
+			problems = append(problems, "missing schema "+name)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("OpenAPI spec does not match routes: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
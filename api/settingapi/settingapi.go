@@ -0,0 +1,311 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package settingapi
+
+import (
+	"net/http"
+	"synapforest/api"
+	"synapforest/blobstore"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Rendition struct {
+	Name      string `json:"name" binding:"required"`
+	MaxPixels int    `json:"maxPixels" binding:"required"`
+	Format    string `json:"format" binding:"required"`
+	Quality   int    `json:"quality"`
+}
+
+func ListRenditions(c *gin.Context) {
+	lib := api.Lib(c)
+	renditions, err := settingdb.GetRenditions(lib.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var resp []Rendition
+	for _, r := range renditions {
+		resp = append(resp, Rendition{
+			Name:      r.Name,
+			MaxPixels: r.MaxPixels,
+			Format:    r.Format,
+			Quality:   r.Quality,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   resp,
+	})
+}
+
+// UpdateRenditions 整体替换预览图规格，已有文件需通过 rendition_regenerate 任务重新生成，
+// 重新生成之前继续提供旧格式的文件
+func UpdateRenditions(c *gin.Context) {
+	lib := api.Lib(c)
+	var req struct {
+		Renditions []Rendition `json:"renditions" binding:"required,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	var renditions []dbcommon.Rendition
+	for _, r := range req.Renditions {
+		quality := r.Quality
+		if quality == 0 {
+			quality = 90
+		}
+		renditions = append(renditions, dbcommon.Rendition{
+			Name:      r.Name,
+			MaxPixels: r.MaxPixels,
+			Format:    r.Format,
+			Quality:   quality,
+		})
+	}
+
+	if err := settingdb.ValidateRenditions(renditions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := settingdb.SetRenditions(lib.DB, renditions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+	})
+}
+
+type ImportLimits struct {
+	MaxPixels   int64 `json:"maxPixels"`
+	MaxFileSize int64 `json:"maxFileSize"`
+}
+
+func GetImportLimits(c *gin.Context) {
+	lib := api.Lib(c)
+	limits, err := settingdb.GetImportLimits(lib.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": ImportLimits{
+			MaxPixels:   limits.MaxPixels,
+			MaxFileSize: limits.MaxFileSize,
+		},
+	})
+}
+
+// UpdateImportLimits 修改解码限制，未提供的字段保持不变，0 表示不限制
+func UpdateImportLimits(c *gin.Context) {
+	lib := api.Lib(c)
+	var req struct {
+		MaxPixels   *int64 `json:"maxPixels"`
+		MaxFileSize *int64 `json:"maxFileSize"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	limits, err := settingdb.GetImportLimits(lib.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if req.MaxPixels != nil {
+		limits.MaxPixels = *req.MaxPixels
+	}
+	if req.MaxFileSize != nil {
+		limits.MaxFileSize = *req.MaxFileSize
+	}
+	if limits.MaxPixels < 0 || limits.MaxFileSize < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Limits must not be negative",
+		})
+		return
+	}
+
+	if err := settingdb.SetImportLimits(lib.DB, limits); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+	})
+}
+
+type BlobStore struct {
+	Type          string `json:"type"`
+	Redirect      bool   `json:"redirect"`
+	PresignExpiry int    `json:"presignExpiry"`
+	S3            S3     `json:"s3"`
+}
+
+type S3 struct {
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"`
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey,omitempty"`
+	PathStyle bool   `json:"pathStyle"`
+}
+
+// GetBlobStore 返回文件存储设置，不返回 secretKey
+func GetBlobStore(c *gin.Context) {
+	lib := api.Lib(c)
+	cfg, err := settingdb.GetBlobStore(lib.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": BlobStore{
+			Type:          cfg.Type,
+			Redirect:      cfg.Redirect,
+			PresignExpiry: cfg.PresignExpiry,
+			S3: S3{
+				Endpoint:  cfg.S3.Endpoint,
+				Region:    cfg.S3.Region,
+				Bucket:    cfg.S3.Bucket,
+				Prefix:    cfg.S3.Prefix,
+				AccessKey: cfg.S3.AccessKey,
+				PathStyle: cfg.S3.PathStyle,
+			},
+		},
+		"active": lib.BlobConfig.Type,
+	})
+}
+
+// UpdateBlobStore 修改文件存储设置，secretKey 为空时保持不变。
+// 重新打开资料库后生效。已有文件不会迁移，因此资料库中有 item 时不能更换存储位置
+func UpdateBlobStore(c *gin.Context) {
+	lib := api.Lib(c)
+	var req BlobStore
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	current, err := settingdb.GetBlobStore(lib.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	secretKey := req.S3.SecretKey
+	if secretKey == "" {
+		secretKey = current.S3.SecretKey
+	}
+
+	cfg := blobstore.Config{
+		Type:          req.Type,
+		Redirect:      req.Redirect,
+		PresignExpiry: req.PresignExpiry,
+		S3: blobstore.S3Config{
+			Endpoint:  req.S3.Endpoint,
+			Region:    req.S3.Region,
+			Bucket:    req.S3.Bucket,
+			Prefix:    req.S3.Prefix,
+			AccessKey: req.S3.AccessKey,
+			SecretKey: secretKey,
+			PathStyle: req.S3.PathStyle,
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 与当前使用的存储比较，尚未生效的设置可以随意修改
+	if !cfg.SameLocation(lib.BlobConfig) {
+		var count int64
+		if err := lib.DB.Unscoped().Model(&dbcommon.Item{}).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"status":  "error",
+				"message": "Cannot change the blob store location while the library has items, existing files are not migrated",
+			})
+			return
+		}
+	}
+
+	if err := settingdb.SetBlobStore(lib.DB, cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+	})
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package settingapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"synapforest/database"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func updateBlobStore(t *testing.T, lib *database.Library, req BlobStore) int {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/setting/updateBlobStore", bytes.NewReader(body))
+	c.Request = c.Request.WithContext(database.WithLibrary(c.Request.Context(), lib))
+	UpdateBlobStore(c)
+	return w.Code
+}
+
+func TestUpdateBlobStoreRefusesSwitchWithItems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	lib, err := database.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lib.Close() })
+
+	s3 := BlobStore{Type: "s3", S3: S3{
+		Endpoint:  "http://127.0.0.1:9000",
+		Bucket:    "synapforest",
+		AccessKey: "key",
+		SecretKey: "secret",
+		PathStyle: true,
+	}}
+
+	// 空资料库可以更换存储，尚未生效前也可以改回
+	if code := updateBlobStore(t, lib, s3); code != http.StatusOK {
+		t.Fatalf("switch empty library: %d", code)
+	}
+	if code := updateBlobStore(t, lib, BlobStore{Type: "local"}); code != http.StatusOK {
+		t.Fatalf("switch back: %d", code)
+	}
+
+	// 回收站中的 item 同样占用存储
+	item := dbcommon.Item{ID: "item1", Name: "a", Ext: "png"}
+	if err := lib.DB.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := lib.DB.Delete(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if code := updateBlobStore(t, lib, s3); code != http.StatusConflict {
+		t.Errorf("switch with items: %d", code)
+	}
+	cfg, err := settingdb.GetBlobStore(lib.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Type == "s3" {
+		t.Error("refused switch was saved")
+	}
+
+	// 不改变存储位置的设置不受限制
+	if code := updateBlobStore(t, lib, BlobStore{Type: "local", Redirect: true}); code != http.StatusOK {
+		t.Errorf("update without switching: %d", code)
+	}
+}
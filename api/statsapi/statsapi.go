@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package statsapi
+
+import (
+	"net/http"
+	"synapforest/api"
+	"synapforest/database/itemdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Storage 返回资料库的存储统计
+func Storage(c *gin.Context) {
+	lib := api.Lib(c)
+	var req struct {
+		Interval string `json:"interval"` // 导入量的分组方式：day、month（默认）或 year
+		Limit    int    `json:"limit"`    // 返回的最大 item 数量，默认 20
+		Refresh  bool   `json:"refresh"`  // 忽略缓存重新计算
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+		})
+		return
+	}
+	switch req.Interval {
+	case "":
+		req.Interval = "month"
+	case "day", "month", "year":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid interval, must be 'day', 'month' or 'year'",
+		})
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	if req.Limit > itemdb.StatsLargestLimit {
+		req.Limit = itemdb.StatsLargestLimit
+	}
+
+	stats, err := itemdb.GetStorageStats(lib.DB, req.Interval, req.Refresh)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 缓存的结果被多个请求共享，复制后再截取
+	resp := *stats
+	if len(resp.Largest) > req.Limit {
+		resp.Largest = resp.Largest[:req.Limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   resp,
+	})
+}
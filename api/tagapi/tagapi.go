@@ -15,6 +15,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"synapforest/api"
 	"synapforest/database"
 	"synapforest/database/dbcommon"
 	"synapforest/database/tagdb"
@@ -46,6 +47,7 @@ type TagResponse struct {
 }
 
 func CreateTag(c *gin.Context) {
+	lib := api.Lib(c)
 	var req struct {
 		TagName *string   `json:"tagName"`
 		Parent  uuid.UUID `json:"parent"`
@@ -59,7 +61,7 @@ func CreateTag(c *gin.Context) {
 		return
 	}
 
-	tag, err := tagdb.CreateTag(database.DB, req.TagName, "", 0, 0, req.Parent, false)
+	tag, err := tagdb.CreateTag(api.DB(c), req.TagName, "", 0, 0, req.Parent, false)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -71,8 +73,8 @@ func CreateTag(c *gin.Context) {
 	resp := TagResponse{
 		Status: "success",
 	}
-	subtags, _ := tagdb.GetChildTagIDs(database.DB, &tag.ID)
-	items, _ := database.GetItemIDsByTag(database.DB, tag.ID)
+	subtags, _ := tagdb.GetChildTagIDs(lib.DB, &tag.ID)
+	items, _ := database.GetItemIDsByTag(lib.DB, tag.ID)
 	data := Tag{
 		ID:          tag.ID,
 		Name:        tag.Name,
@@ -90,6 +92,7 @@ func CreateTag(c *gin.Context) {
 }
 
 func ListTag(c *gin.Context) {
+	lib := api.Lib(c)
 	var req struct {
 		Parent *string `json:"parent"`
 	}
@@ -112,7 +115,7 @@ func ListTag(c *gin.Context) {
 		parent = &parsedUUID
 	}
 
-	tagIDs, err := tagdb.GetChildTagIDs(database.DB, parent)
+	tagIDs, err := tagdb.GetChildTagIDs(lib.DB, parent)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": "failed"})
 		return
@@ -120,14 +123,14 @@ func ListTag(c *gin.Context) {
 
 	for _, tagid := range tagIDs {
 		var tag dbcommon.Tag
-		if err := database.DB.First(&tag, tagid).Error; err != nil {
+		if err := lib.DB.First(&tag, tagid).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"status": "failed"})
 			return
 		} else {
 			fmt.Printf("Found Tag: %v\n", tag)
 		}
-		subtags, _ := tagdb.GetChildTagIDs(database.DB, &tag.ID)
-		items, _ := database.GetItemIDsByTag(database.DB, tag.ID)
+		subtags, _ := tagdb.GetChildTagIDs(lib.DB, &tag.ID)
+		items, _ := database.GetItemIDsByTag(lib.DB, tag.ID)
 		data := Tag{
 			ID:          tag.ID,
 			Name:        tag.Name,
@@ -146,6 +149,7 @@ func ListTag(c *gin.Context) {
 }
 
 func UpdateTag(c *gin.Context) {
+	lib := api.Lib(c)
 	var req struct {
 		TagID       string  `json:"tagId" binding:"required"` // 使用 string 类型接收 UUID
 		TagName     *string `json:"tagName"`
@@ -185,7 +189,7 @@ func UpdateTag(c *gin.Context) {
 		parentID = &parent
 	}
 
-	tag, err := tagdb.UpdateTag(database.DB, tagID, req.TagName, req.Description, req.Icon, req.IconColor, parentID)
+	tag, err := tagdb.UpdateTag(api.DB(c), tagID, req.TagName, req.Description, req.Icon, req.IconColor, parentID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -197,8 +201,8 @@ func UpdateTag(c *gin.Context) {
 	resp := TagResponse{
 		Status: "success",
 	}
-	subtags, _ := tagdb.GetChildTagIDs(database.DB, &tag.ID)
-	items, _ := database.GetItemIDsByTag(database.DB, tag.ID)
+	subtags, _ := tagdb.GetChildTagIDs(lib.DB, &tag.ID)
+	items, _ := database.GetItemIDsByTag(lib.DB, tag.ID)
 	data := Tag{
 		ID:          tag.ID,
 		Name:        tag.Name,
@@ -216,6 +220,7 @@ func UpdateTag(c *gin.Context) {
 }
 
 func DeleteTag(c *gin.Context) {
+	db := api.DB(c)
 	var req struct {
 		TagID       string `json:"tagId" binding:"required"` // 使用 string 类型接收 UUID
 		HardDelete  *bool  `json:"hardDelete"`               // 由于不知道软删除标签有什么意义，暂时忽略该项
@@ -239,7 +244,7 @@ func DeleteTag(c *gin.Context) {
 		return
 	}
 
-	if err := tagdb.DeleteTag(database.DB, tagID, req.HardDelete, req.DeleteItems); err != nil {
+	if err := tagdb.DeleteTag(db, tagID, req.HardDelete, req.DeleteItems); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
 			"message": "Tag delete failed",
@@ -254,6 +259,7 @@ func DeleteTag(c *gin.Context) {
 }
 
 func UpdateTagsParent(c *gin.Context) {
+	db := api.DB(c)
 	var req struct {
 		TagIDs    []string `json:"tagIds" binding:"required"` // 要更新的标签ID数组
 		NewParent *string  `json:"newParent"`                 // 新的父标签ID
@@ -298,7 +304,7 @@ func UpdateTagsParent(c *gin.Context) {
 	}
 
 	// 调用 tagdb.UpdateTagParents 进行批量更新
-	err := tagdb.UpdateTagParents(database.DB, tagIDs, newParentID)
+	err := tagdb.UpdateTagParents(db, tagIDs, newParentID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
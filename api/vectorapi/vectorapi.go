@@ -14,8 +14,8 @@ package vectorapi
 import (
 	"fmt"
 	"net/http"
-	"path/filepath"
-	"synapforest/database"
+	"synapforest/api"
+	"synapforest/blobstore"
 	"synapforest/database/dbcommon"
 	"synapforest/database/itemdb"
 	"synapforest/vector"
@@ -26,11 +26,12 @@ import (
 
 // 处理向量化请求
 func HandleVectorize(c *gin.Context) {
+	lib := api.Lib(c)
 	// 获取ID
 	id := c.Param("id")
 
 	// 查询Item
-	items, err := itemdb.GetItemsByIDs(database.DB, []string{id})
+	items, err := itemdb.GetItemsByIDs(lib.DB, []string{id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get item: %v", err)})
 		return
@@ -42,8 +43,13 @@ func HandleVectorize(c *gin.Context) {
 	item := items[0]
 
 	// 计算图片向量
-	imagePath := filepath.Join(database.DbBaseDir, "raw_files", item.ID, item.Name+"."+item.Ext)
+	imagePath, cleanup, err := blobstore.Fetch(lib.Blobs, itemdb.RawFileKey(item.ID, item.Name, item.Ext))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read image: %v", err)})
+		return
+	}
 	imageVec, err := vector.VectorizeImage(imagePath)
+	cleanup()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to vectorize image: %v", err)})
 		return
@@ -59,7 +65,7 @@ func HandleVectorize(c *gin.Context) {
 		ModifiedAt: time.Now(),
 	}
 
-	result := database.VectorDB.Create(&itemVector)
+	result := lib.VectorDB.Create(&itemVector)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save vector: %v", result.Error)})
 		return
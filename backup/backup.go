@@ -0,0 +1,457 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+
+// Package backup 把资料库的数据库和文件打包为 tar 归档，并从归档恢复资料库。
+// 归档中 databases/ 下是数据库快照，blobs/ 下按存储的键保存文件，
+// 最后是描述全部内容的 manifest.json。增量备份只保存基准备份中没有的文件，
+// 清单中记录每个文件实际所在的归档，恢复时从同一目录中的其他归档读取
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"synapforest/blobstore"
+	"synapforest/database/dbcommon"
+	"synapforest/database/itemdb"
+	"synapforest/database/settingdb"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// FormatVersion 归档格式版本
+const FormatVersion = 1
+
+// 归档中的路径
+const (
+	manifestName = "manifest.json"
+	databaseDir  = "databases"
+	blobDir      = "blobs"
+)
+
+// 备份的数据库文件名
+var databaseFiles = []string{"files.db", "vectors.db"}
+
+// Entry 归档中的一个文件
+type Entry struct {
+	Key     string `json:"key"` // 数据库为文件名，其他文件为存储中的键
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+	Archive string `json:"archive"` // 保存该文件的备份 ID
+}
+
+// Manifest 备份的内容清单
+type Manifest struct {
+	Format     int       `json:"format"`
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Base       string    `json:"base,omitempty"` // 增量备份的基准备份 ID
+	Renditions bool      `json:"renditions"`     // 是否包含缩略图、预览图和自定义规格
+	Databases  []Entry   `json:"databases"`
+	Blobs      []Entry   `json:"blobs"`
+	Missing    []string  `json:"missing"` // 数据库中引用但存储中不存在的文件
+}
+
+// Stored 本次归档中实际保存的文件数量和大小
+func (m *Manifest) Stored() (count int, size int64) {
+	for _, e := range m.Blobs {
+		if e.Archive == m.ID {
+			count++
+			size += e.Size
+		}
+	}
+	return count, size
+}
+
+// Options 备份选项
+type Options struct {
+	Renditions bool   // 同时备份缩略图、预览图和自定义规格
+	Base       string // 增量备份的基准归档路径，为空时完整备份
+}
+
+var idPattern = regexp.MustCompile(`^\d{8}-\d{6}-[0-9a-f]{8}$`)
+
+// ValidID 判断是否为合法的备份 ID
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// Dir 资料库默认的备份目录
+func Dir(library string) string {
+	return filepath.Join(library, "backups")
+}
+
+// ArchivePath 备份目录中指定备份的归档路径
+func ArchivePath(dir string, id string) string {
+	return filepath.Join(dir, id+".tar")
+}
+
+// 与归档同名的清单文件，用于列出备份和读取基准备份而不必扫描归档
+func sidecarPath(archivePath string) string {
+	return strings.TrimSuffix(archivePath, ".tar") + ".json"
+}
+
+func newID(now time.Time) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix), nil
+}
+
+// 归档中要保存的文件
+type blobRef struct {
+	key    string
+	sha256 string // 内容寻址的文件已知哈希，不必读取就能与基准备份比较
+	skipOK bool   // 文件缺失不记录在 Missing 中
+}
+
+// Create 在 dir 中创建备份。数据库通过 SQLite 在线备份得到一致的快照，
+// 再按快照中的记录读取原始文件、历史版本和可选的预览图，备份期间服务器可以继续写入
+func Create(ctx context.Context, db *gorm.DB, vectorDB *gorm.DB, store blobstore.Store, dir string, opts Options, progress func(done int, total int)) (*Manifest, error) {
+	if progress == nil {
+		progress = func(int, int) {}
+	}
+
+	var base *Manifest
+	if opts.Base != "" {
+		var err error
+		if base, err = LoadManifest(opts.Base); err != nil {
+			return nil, fmt.Errorf("failed to read base backup: %v", err)
+		}
+	}
+
+	now := time.Now()
+	id, err := newID(now)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{
+		Format:     FormatVersion,
+		ID:         id,
+		CreatedAt:  now,
+		Renditions: opts.Renditions,
+		Databases:  []Entry{},
+		Blobs:      []Entry{},
+		Missing:    []string{},
+	}
+	if base != nil {
+		manifest.Base = base.ID
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp(dir, ".snapshot-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	snapshots := map[string]*gorm.DB{"files.db": db, "vectors.db": vectorDB}
+	for _, name := range databaseFiles {
+		if err := dbcommon.Snapshot(ctx, snapshots[name], filepath.Join(tmpDir, name)); err != nil {
+			return nil, fmt.Errorf("failed to snapshot %s: %v", name, err)
+		}
+	}
+
+	refs, err := collectBlobs(filepath.Join(tmpDir, "files.db"), opts.Renditions)
+	if err != nil {
+		return nil, err
+	}
+
+	archivePath := ArchivePath(dir, id)
+	partial := archivePath + ".partial"
+	file, err := os.Create(partial)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(partial)
+	defer file.Close()
+
+	tw := tar.NewWriter(file)
+	total := len(refs) + len(databaseFiles)
+	done := 0
+
+	for _, name := range databaseFiles {
+		entry, err := writeFile(tw, path.Join(databaseDir, name), filepath.Join(tmpDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive %s: %v", name, err)
+		}
+		entry.Key = name
+		entry.Archive = id
+		manifest.Databases = append(manifest.Databases, entry)
+		done++
+		progress(done, total)
+	}
+
+	baseBlobs := map[string]Entry{}
+	if base != nil {
+		for _, e := range base.Blobs {
+			baseBlobs[e.Key] = e
+		}
+	}
+
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if prev, ok := baseBlobs[ref.key]; ok && ref.sha256 != "" && prev.SHA256 == ref.sha256 {
+			manifest.Blobs = append(manifest.Blobs, prev)
+		} else {
+			entry, err := writeBlob(tw, store, ref, baseBlobs)
+			switch {
+			case errors.Is(err, blobstore.ErrNotExist):
+				if !ref.skipOK {
+					manifest.Missing = append(manifest.Missing, ref.key)
+				}
+			case err != nil:
+				return nil, fmt.Errorf("failed to archive %s: %v", ref.key, err)
+			default:
+				if entry.Archive == "" {
+					entry.Archive = id
+				}
+				manifest.Blobs = append(manifest.Blobs, entry)
+			}
+		}
+		done++
+		progress(done, total)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBytes(tw, manifestName, data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partial, archivePath); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(sidecarPath(archivePath), data, 0644); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// 按数据库快照列出需要备份的文件
+func collectBlobs(dbPath string, renditions bool) ([]blobRef, error) {
+	snap, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := snap.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlDB.Close()
+
+	var items []dbcommon.Item
+	if err := snap.Unscoped().Select("id", "name", "ext").Order("id").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to query items: %v", err)
+	}
+	refs := []blobRef{}
+	for _, item := range items {
+		refs = append(refs, blobRef{key: itemdb.RawFileKey(item.ID, item.Name, item.Ext), sha256: item.ID})
+	}
+
+	var versions []dbcommon.ItemVersion
+	if err := snap.Model(&dbcommon.ItemVersion{}).Distinct("file_id", "name", "ext").Order("file_id").Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to query versions: %v", err)
+	}
+	for _, v := range versions {
+		refs = append(refs, blobRef{key: itemdb.VersionKey(v), sha256: v.FileID})
+	}
+
+	if renditions {
+		// 规格可以随时重新生成，不存在的不算缺失
+		specs, err := settingdb.GetRenditions(snap)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range specs {
+			for _, item := range items {
+				refs = append(refs, blobRef{key: itemdb.RenditionKey(r, item.ID), skipOK: true})
+			}
+		}
+	}
+	return refs, nil
+}
+
+// 把存储中的文件写入归档。哈希未知的文件先读入内存，与基准备份相同时不再保存
+func writeBlob(tw *tar.Writer, store blobstore.Store, ref blobRef, baseBlobs map[string]Entry) (Entry, error) {
+	name := path.Join(blobDir, ref.key)
+
+	if ref.sha256 == "" {
+		r, err := store.Get(ref.key)
+		if err != nil {
+			return Entry{}, err
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return Entry{}, err
+		}
+		sum := sha256.Sum256(data)
+		entry := Entry{Key: ref.key, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+		if prev, ok := baseBlobs[ref.key]; ok && prev.SHA256 == entry.SHA256 {
+			return prev, nil
+		}
+		return entry, writeBytes(tw, name, data)
+	}
+
+	info, err := store.Stat(ref.key)
+	if err != nil {
+		return Entry{}, err
+	}
+	r, err := store.Get(ref.key)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer r.Close()
+	entry, err := writeEntry(tw, name, r, info.Size, info.ModTime)
+	entry.Key = ref.key
+	return entry, err
+}
+
+func writeFile(tw *tar.Writer, name string, filePath string) (Entry, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return Entry{}, err
+	}
+	return writeEntry(tw, name, file, info.Size(), info.ModTime())
+}
+
+func writeBytes(tw *tar.Writer, name string, data []byte) error {
+	_, err := writeEntry(tw, name, bytes.NewReader(data), int64(len(data)), time.Now())
+	return err
+}
+
+// 写入一个归档条目并计算哈希
+func writeEntry(tw *tar.Writer, name string, r io.Reader, size int64, modTime time.Time) (Entry, error) {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0644,
+		ModTime:  modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return Entry{}, err
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(tw, io.TeeReader(r, hash))
+	if err != nil {
+		return Entry{}, err
+	}
+	if n != size {
+		return Entry{}, fmt.Errorf("file changed while reading: expected %d bytes, got %d", size, n)
+	}
+	return Entry{Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// LoadManifest 读取归档的清单，优先使用同名的清单文件
+func LoadManifest(archivePath string) (*Manifest, error) {
+	data, err := os.ReadFile(sidecarPath(archivePath))
+	if err != nil {
+		data, err = readArchiveManifest(archivePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if manifest.Format != FormatVersion {
+		return nil, fmt.Errorf("unsupported backup format %d", manifest.Format)
+	}
+	return &manifest, nil
+}
+
+func readArchiveManifest(archivePath string) ([]byte, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s has no manifest", filepath.Base(archivePath))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == manifestName {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// List 列出目录中的备份，按创建时间排序
+func List(dir string) ([]*Manifest, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tar"))
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := []*Manifest{}
+	for _, archivePath := range matches {
+		if !ValidID(strings.TrimSuffix(filepath.Base(archivePath), ".tar")) {
+			continue
+		}
+		manifest, err := LoadManifest(archivePath)
+		if err != nil {
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].CreatedAt.Before(manifests[j].CreatedAt)
+	})
+	return manifests, nil
+}
@@ -0,0 +1,354 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"synapforest/database"
+	"synapforest/database/dbcommon"
+	"synapforest/database/itemdb"
+	"synapforest/database/settingdb"
+)
+
+func openLibrary(t *testing.T, dir string) *database.Library {
+	t.Helper()
+	lib, err := database.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lib.Close() })
+	return lib
+}
+
+// 导入一张内容由 seed 决定的 PNG，返回 item ID
+func addImage(t *testing.T, lib *database.Library, name string, seed uint8) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := 0; i < 16; i++ {
+		img.Set(i, i, color.NRGBA{seed, uint8(i * 16), 0, 255})
+	}
+	path := filepath.Join(t.TempDir(), name+".png")
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	id, err := itemdb.CalculateFileID(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := itemdb.AddItem(lib.DB, path, nil, nil, nil, nil, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func readBlob(t *testing.T, lib *database.Library, key string) []byte {
+	t.Helper()
+	r, err := lib.Blobs.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// 完整备份、增量备份，再把增量备份恢复到新的资料库
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	lib := openLibrary(t, filepath.Join(t.TempDir(), "lib"))
+	dir := t.TempDir()
+	first := addImage(t, lib, "first", 1)
+	second := addImage(t, lib, "second", 2)
+
+	full, err := Create(context.Background(), lib.DB, lib.VectorDB, lib.Blobs, dir, Options{Renditions: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(full.Databases) != 2 || len(full.Missing) != 0 {
+		t.Fatalf("full backup = %+v", full)
+	}
+	fullCount, _ := full.Stored()
+	if fullCount != len(full.Blobs) {
+		t.Errorf("full backup stored %d of %d files", fullCount, len(full.Blobs))
+	}
+
+	third := addImage(t, lib, "third", 3)
+	incr, err := Create(context.Background(), lib.DB, lib.VectorDB, lib.Blobs, dir, Options{Renditions: true, Base: ArchivePath(dir, full.ID)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if incr.Base != full.ID {
+		t.Errorf("base = %q", incr.Base)
+	}
+	// 增量备份只保存新 item 的文件，其余指向完整备份
+	for _, e := range incr.Blobs {
+		wantArchive := full.ID
+		if strings.Contains(e.Key, third) {
+			wantArchive = incr.ID
+		}
+		if e.Archive != wantArchive {
+			t.Errorf("%s archived in %s, want %s", e.Key, e.Archive, wantArchive)
+		}
+	}
+	if count, _ := incr.Stored(); count == 0 || count >= len(incr.Blobs) {
+		t.Errorf("incremental backup stored %d of %d files", count, len(incr.Blobs))
+	}
+
+	manifests, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) != 2 || manifests[0].ID != full.ID || manifests[1].ID != incr.ID {
+		t.Errorf("list = %+v", manifests)
+	}
+
+	target := filepath.Join(t.TempDir(), "restored")
+	result, err := Restore(context.Background(), ArchivePath(dir, incr.ID), target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ID != incr.ID || result.Blobs != len(incr.Blobs) || !result.Renditions {
+		t.Errorf("result = %+v", result)
+	}
+
+	restored := openLibrary(t, target)
+	var items []dbcommon.Item
+	if err := restored.DB.Find(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("restored %d items", len(items))
+	}
+	for _, item := range items {
+		key := itemdb.RawFileKey(item.ID, item.Name, item.Ext)
+		if !bytes.Equal(readBlob(t, restored, key), readBlob(t, lib, key)) {
+			t.Errorf("raw file of %s differs", item.Name)
+		}
+		thumb, err := itemdb.RenditionFile(restored.DB, settingdb.Thumbnail, item.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		readBlob(t, restored, thumb)
+	}
+
+	// 恢复完整备份时没有之后导入的 item
+	target = filepath.Join(t.TempDir(), "restored-full")
+	if _, err := Restore(context.Background(), ArchivePath(dir, full.ID), target, nil); err != nil {
+		t.Fatal(err)
+	}
+	restoredFull := openLibrary(t, target)
+	var ids []string
+	restoredFull.DB.Model(&dbcommon.Item{}).Pluck("id", &ids)
+	if len(ids) != 2 || !(ids[0] == first && ids[1] == second || ids[0] == second && ids[1] == first) {
+		t.Errorf("restored full backup items = %v", ids)
+	}
+
+	// 目标目录不为空
+	if _, err := Restore(context.Background(), ArchivePath(dir, full.ID), target, nil); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("restore into non-empty target: %v", err)
+	}
+}
+
+// 复制归档，edit 可以修改或替换每个条目，返回 nil 时删除该条目
+func rewriteArchive(t *testing.T, src, dst string, edit func(name string, data []byte) []byte) {
+	t.Helper()
+	in, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	var out bytes.Buffer
+	tr, tw := tar.NewReader(in), tar.NewWriter(&out)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data = edit(header.Name, data); data == nil {
+			continue
+		}
+		header.Size = int64(len(data))
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(data)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, out.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 写出只包含给定条目的归档，manifest 为 nil 时不写入清单
+func writeArchive(t *testing.T, path string, manifest *Manifest, entries map[string]string) {
+	t.Helper()
+	var out bytes.Buffer
+	tw := tar.NewWriter(&out)
+	add := func(name string, data []byte) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(data)
+	}
+	for name, data := range entries {
+		add(name, []byte(data))
+	}
+	if manifest != nil {
+		data, _ := json.Marshal(manifest)
+		add(manifestName, data)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 内容与清单不符或缺少基准备份时拒绝恢复，目标目录不被创建
+func TestRestoreRejectsChecksumMismatch(t *testing.T) {
+	lib := openLibrary(t, filepath.Join(t.TempDir(), "lib"))
+	dir := t.TempDir()
+	id := addImage(t, lib, "photo", 1)
+	full, err := Create(context.Background(), lib.DB, lib.VectorDB, lib.Blobs, dir, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addImage(t, lib, "later", 2)
+	incr, err := Create(context.Background(), lib.DB, lib.VectorDB, lib.Blobs, dir, Options{Base: ArchivePath(dir, full.ID)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawName := blobDir + "/" + itemdb.RawFileKey(id, "photo", "png")
+	tests := []struct {
+		name string
+		edit func(name string, data []byte) []byte
+		want string
+	}{
+		{"flipped byte", func(name string, data []byte) []byte {
+			if name == rawName {
+				data = append([]byte{}, data...)
+				data[len(data)/2] ^= 0xFF
+			}
+			return data
+		}, "checksum mismatch"},
+		{"truncated", func(name string, data []byte) []byte {
+			if name == databaseDir+"/files.db" {
+				return data[:len(data)-1]
+			}
+			return data
+		}, "checksum mismatch"},
+		{"removed", func(name string, data []byte) []byte {
+			if name == rawName {
+				return nil
+			}
+			return data
+		}, "not found"},
+	}
+	for _, tt := range tests {
+		archive := ArchivePath(t.TempDir(), full.ID)
+		rewriteArchive(t, ArchivePath(dir, full.ID), archive, tt.edit)
+		target := filepath.Join(t.TempDir(), "restored")
+		_, err := Restore(context.Background(), archive, target, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.want)
+		}
+		if _, err := os.Stat(target); !os.IsNotExist(err) {
+			t.Errorf("%s: target created", tt.name)
+		}
+	}
+
+	// 增量备份的基准备份被篡改
+	tampered := t.TempDir()
+	rewriteArchive(t, ArchivePath(dir, full.ID), ArchivePath(tampered, full.ID), tests[0].edit)
+	rewriteArchive(t, ArchivePath(dir, incr.ID), ArchivePath(tampered, incr.ID), func(_ string, data []byte) []byte { return data })
+	if _, err := Restore(context.Background(), ArchivePath(tampered, incr.ID), filepath.Join(t.TempDir(), "r"), nil); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("tampered base: %v", err)
+	}
+
+	// 基准备份不存在
+	alone := t.TempDir()
+	rewriteArchive(t, ArchivePath(dir, incr.ID), ArchivePath(alone, incr.ID), func(_ string, data []byte) []byte { return data })
+	if _, err := Restore(context.Background(), ArchivePath(alone, incr.ID), filepath.Join(t.TempDir(), "r"), nil); err == nil || !strings.Contains(err.Error(), full.ID) {
+		t.Errorf("missing base: %v", err)
+	}
+}
+
+// 归档条目和清单中的路径不能指向解压目录以外
+func TestRestoreRejectsUnsafePaths(t *testing.T) {
+	const id = "20250101-000000-00000000"
+	manifest := func(databases []Entry, blobs []Entry) *Manifest {
+		return &Manifest{Format: FormatVersion, ID: id, Databases: databases, Blobs: blobs}
+	}
+
+	tests := []struct {
+		name     string
+		manifest *Manifest
+		entries  map[string]string
+		want     string
+	}{
+		{"parent directory", manifest(nil, nil), map[string]string{"../evil": "x"}, "invalid entry"},
+		{"escaping blob", manifest(nil, nil), map[string]string{"blobs/../../evil": "x"}, "invalid entry"},
+		{"absolute", manifest(nil, nil), map[string]string{"/tmp/evil": "x"}, "invalid entry"},
+		{"outside known dirs", manifest(nil, nil), map[string]string{"evil": "x"}, "invalid entry"},
+		{"unclean", manifest(nil, nil), map[string]string{"blobs/./raw_files/x": "x"}, "invalid entry"},
+		{"database name", manifest([]Entry{{Key: "../files.db", Archive: id}}, nil), nil, "invalid database"},
+		{"base archive id", manifest(nil, []Entry{{Key: "raw_files/a/a.png", Archive: "../../etc/passwd"}}), nil, "invalid archive id"},
+		{"no manifest", nil, map[string]string{"blobs/raw_files/a/a.png": "x"}, "no manifest"},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		archive := filepath.Join(root, "backups", "a", id+".tar")
+		if err := os.MkdirAll(filepath.Dir(archive), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		writeArchive(t, archive, tt.manifest, tt.entries)
+		target := filepath.Join(root, "backups", "a", "restored")
+		_, err := Restore(context.Background(), archive, target, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.want)
+		}
+		for _, p := range []string{filepath.Join(root, "backups", "evil"), filepath.Join(root, "evil"), target} {
+			if _, err := os.Stat(p); !os.IsNotExist(err) {
+				t.Errorf("%s: %s was created", tt.name, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package backup
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"synapforest/blobstore"
+	"synapforest/database/settingdb"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// RestoreResult 恢复的结果
+type RestoreResult struct {
+	ID         string   `json:"id"`
+	Target     string   `json:"target"`
+	Databases  int      `json:"databases"`
+	Blobs      int      `json:"blobs"`
+	Renditions bool     `json:"renditions"` // 为 false 时需要重新生成预览图
+	Missing    []string `json:"missing"`    // 备份时就已缺失的文件
+}
+
+// 解压得到的文件
+type extracted struct {
+	size   int64
+	sha256 string
+}
+
+// Restore 从归档恢复资料库到 target，target 必须不存在或为空目录。
+// 增量备份引用的文件从同一目录中的其他归档读取。全部文件的大小和 SHA256
+// 与清单一致后才移动到 target，恢复的资料库使用本地存储
+func Restore(ctx context.Context, archivePath string, target string, progress func(done int, total int)) (*RestoreResult, error) {
+	if progress == nil {
+		progress = func(int, int) {}
+	}
+
+	if entries, err := os.ReadDir(target); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("target %s is not empty", target)
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	parent := filepath.Dir(filepath.Clean(target))
+	if err := os.MkdirAll(parent, os.ModePerm); err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp(parent, ".restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]extracted{}
+	var manifest *Manifest
+	err = extractArchive(ctx, archivePath, tmpDir, nil, files, func(data []byte) error {
+		manifest = &Manifest{}
+		return json.Unmarshal(data, manifest)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%s has no manifest", filepath.Base(archivePath))
+	}
+	if manifest.Format != FormatVersion {
+		return nil, fmt.Errorf("unsupported backup format %d", manifest.Format)
+	}
+	for _, e := range manifest.Databases {
+		if !isDatabaseFile(e.Key) {
+			return nil, fmt.Errorf("invalid database %q in manifest", e.Key)
+		}
+	}
+
+	// 按所在归档分组读取增量备份引用的文件
+	wanted := map[string]map[string]bool{}
+	for _, e := range manifest.Blobs {
+		if e.Archive != manifest.ID {
+			if wanted[e.Archive] == nil {
+				wanted[e.Archive] = map[string]bool{}
+			}
+			wanted[e.Archive][path.Join(blobDir, e.Key)] = true
+		}
+	}
+	for id, names := range wanted {
+		if !ValidID(id) {
+			return nil, fmt.Errorf("invalid archive id %q in manifest", id)
+		}
+		basePath := ArchivePath(filepath.Dir(archivePath), id)
+		if err := extractArchive(ctx, basePath, tmpDir, names, files, nil); err != nil {
+			return nil, fmt.Errorf("failed to read base backup %s: %v", id, err)
+		}
+	}
+
+	// 校验
+	total := len(manifest.Databases) + len(manifest.Blobs)
+	done := 0
+	var failures []string
+	check := func(name string, e Entry) {
+		got, ok := files[name]
+		switch {
+		case !ok:
+			failures = append(failures, name+": not found in archive "+e.Archive)
+		case got.size != e.Size || got.sha256 != e.SHA256:
+			failures = append(failures, name+": checksum mismatch")
+		}
+		done++
+		progress(done, total)
+	}
+	for _, e := range manifest.Databases {
+		check(path.Join(databaseDir, e.Key), e)
+	}
+	for _, e := range manifest.Blobs {
+		check(path.Join(blobDir, e.Key), e)
+	}
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("backup verification failed: %s", strings.Join(failures, "; "))
+	}
+
+	// 数据库在资料库根目录，文件按键保存
+	library := filepath.Join(tmpDir, "library")
+	if err := os.MkdirAll(library, os.ModePerm); err != nil {
+		return nil, err
+	}
+	for _, e := range manifest.Databases {
+		if err := os.Rename(filepath.Join(tmpDir, databaseDir, e.Key), filepath.Join(library, e.Key)); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Rename(filepath.Join(tmpDir, blobDir), filepath.Join(library, blobDir)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := flattenBlobs(library); err != nil {
+		return nil, err
+	}
+	if err := useLocalStore(filepath.Join(library, "files.db")); err != nil {
+		return nil, fmt.Errorf("failed to update blob store settings: %v", err)
+	}
+
+	os.Remove(target)
+	if err := os.Rename(library, target); err != nil {
+		return nil, err
+	}
+
+	return &RestoreResult{
+		ID:         manifest.ID,
+		Target:     target,
+		Databases:  len(manifest.Databases),
+		Blobs:      len(manifest.Blobs),
+		Renditions: manifest.Renditions,
+		Missing:    manifest.Missing,
+	}, nil
+}
+
+// 解压归档中的数据库和文件，names 不为空时只解压其中的条目。
+// 写入时同时计算哈希，manifest.json 交给 onManifest 处理
+func extractArchive(ctx context.Context, archivePath string, dir string, names map[string]bool, files map[string]extracted, onManifest func([]byte) error) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tr := tar.NewReader(file)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if header.Name == manifestName {
+			if onManifest != nil {
+				data, err := io.ReadAll(tr)
+				if err != nil {
+					return err
+				}
+				if err := onManifest(data); err != nil {
+					return fmt.Errorf("invalid manifest: %v", err)
+				}
+			}
+			continue
+		}
+		if names != nil && !names[header.Name] {
+			continue
+		}
+
+		name := path.Clean(header.Name)
+		if name != header.Name || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") ||
+			!(strings.HasPrefix(name, databaseDir+"/") || strings.HasPrefix(name, blobDir+"/")) {
+			return fmt.Errorf("invalid entry %q in archive", header.Name)
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+			return err
+		}
+		out, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		hash := sha256.New()
+		n, err := io.Copy(out, io.TeeReader(tr, hash))
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		files[name] = extracted{size: n, sha256: hex.EncodeToString(hash.Sum(nil))}
+	}
+}
+
+// 把 blobs 目录下的文件移到资料库根目录，与本地存储的目录结构一致
+func flattenBlobs(library string) error {
+	blobs := filepath.Join(library, blobDir)
+	entries, err := os.ReadDir(blobs)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.Rename(filepath.Join(blobs, entry.Name()), filepath.Join(library, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return os.Remove(blobs)
+}
+
+// 恢复的文件在本地，原资料库使用其他存储时改为本地存储
+func useLocalStore(dbPath string) error {
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	cfg, err := settingdb.GetBlobStore(db)
+	if err != nil {
+		return err
+	}
+	if cfg.Type == "" || cfg.Type == "local" {
+		return nil
+	}
+	return settingdb.SetBlobStore(db, blobstore.Config{Type: "local"})
+}
+
+func isDatabaseFile(name string) bool {
+	for _, f := range databaseFiles {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
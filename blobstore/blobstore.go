@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+
+// Package blobstore 保存原始文件、预览图和历史版本等文件。
+// 键是以 / 分隔的相对路径，例如 raw_files/<hash>/<name>.<ext>，
+// 本地实现与原有的资料库目录结构一致
+package blobstore
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotExist 键不存在
+var ErrNotExist = fs.ErrNotExist
+
+// Info 文件的大小和修改时间
+type Info struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Store 文件存储
+type Store interface {
+	// Put 写入文件，size 为内容长度
+	Put(key string, r io.Reader, size int64) error
+	// Get 读取文件，不存在时返回 ErrNotExist
+	Get(key string) (io.ReadCloser, error)
+	// Stat 查询文件信息，不存在时返回 ErrNotExist
+	Stat(key string) (Info, error)
+	// Delete 删除文件，不存在时不报错
+	Delete(key string) error
+	// DeleteDir 删除目录下的全部文件
+	DeleteDir(dir string) error
+	// List 递归列出目录下全部文件的键
+	List(dir string) ([]string, error)
+	// Move 移动文件，目标已存在时覆盖
+	Move(src string, dst string) error
+}
+
+// Presigner 可以生成临时下载地址的存储
+type Presigner interface {
+	// PresignGet 生成有效期为 expires 的下载地址，filename 不为空时作为下载文件名
+	PresignGet(key string, filename string, expires time.Duration) (string, error)
+}
+
+// Config 存储设置
+type Config struct {
+	Type string   `json:"type"` // local 或 s3
+	S3   S3Config `json:"s3"`
+
+	// 对支持的存储，文件接口返回临时地址的重定向而不是由服务器转发
+	Redirect      bool `json:"redirect"`
+	PresignExpiry int  `json:"presign_expiry"` // 临时地址有效期（秒），默认 900
+}
+
+// 临时地址的默认有效期
+const DefaultPresignExpiry = 15 * time.Minute
+
+// Expiry 临时地址的有效期
+func (c Config) Expiry() time.Duration {
+	if c.PresignExpiry <= 0 {
+		return DefaultPresignExpiry
+	}
+	return time.Duration(c.PresignExpiry) * time.Second
+}
+
+// Validate 检查设置是否完整
+func (c Config) Validate() error {
+	switch c.Type {
+	case "", "local":
+		return nil
+	case "s3":
+		return c.S3.validate()
+	}
+	return fmt.Errorf("unknown store type %q", c.Type)
+}
+
+// SameLocation 判断两个设置是否指向同一个存储位置，只比较类型和对象存储的地址
+func (c Config) SameLocation(o Config) bool {
+	typ := func(c Config) string {
+		if c.Type == "" {
+			return "local"
+		}
+		return c.Type
+	}
+	if typ(c) != typ(o) {
+		return false
+	}
+	if typ(c) != "s3" {
+		return true
+	}
+	return strings.TrimRight(c.S3.Endpoint, "/") == strings.TrimRight(o.S3.Endpoint, "/") &&
+		c.S3.Bucket == o.S3.Bucket &&
+		strings.Trim(c.S3.Prefix, "/") == strings.Trim(o.S3.Prefix, "/")
+}
+
+// New 按设置创建存储，本地存储的根目录为 baseDir
+func New(c Config, baseDir string) (Store, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if c.Type == "s3" {
+		return NewS3(c.S3), nil
+	}
+	return NewLocal(baseDir), nil
+}
+
+// PutFile 把本地文件写入存储
+func PutFile(s Store, key string, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return s.Put(key, file, info.Size())
+}
+
+// MoveFile 把本地文件移入存储，本地存储直接重命名
+func MoveFile(s Store, key string, filePath string) error {
+	if local, ok := s.(*Local); ok {
+		return local.moveIn(key, filePath)
+	}
+	if err := PutFile(s, key, filePath); err != nil {
+		return err
+	}
+	return os.Remove(filePath)
+}
+
+// Fetch 返回文件的本地路径，用于只能按路径读取的解码器。
+// 非本地存储会下载到临时文件，使用完后调用 cleanup 删除
+func Fetch(s Store, key string) (filePath string, cleanup func(), err error) {
+	if local, ok := s.(*Local); ok {
+		filePath, err = local.Path(key)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, err := os.Stat(filePath); err != nil {
+			return "", nil, err
+		}
+		return filePath, func() {}, nil
+	}
+
+	r, err := s.Get(key)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+
+	// 保留扩展名，部分解码器按扩展名判断格式
+	tmp, err := os.CreateTemp("", "blob-*"+path.Ext(key))
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { os.Remove(tmp.Name()) }
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return tmp.Name(), cleanup, nil
+}
+
+// Exists 判断文件是否存在
+func Exists(s Store, key string) bool {
+	_, err := s.Stat(key)
+	return err == nil
+}
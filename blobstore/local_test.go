@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package blobstore
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLocalPathContainment(t *testing.T) {
+	root := t.TempDir()
+	l := NewLocal(root)
+
+	for _, key := range []string{"raw_files/a/b.jpg", "/raw_files/a.jpg", "a/../b", ""} {
+		p, err := l.Path(key)
+		if err != nil {
+			t.Errorf("%q: %v", key, err)
+			continue
+		}
+		if !strings.HasPrefix(p, root) {
+			t.Errorf("%q: got %q outside root", key, p)
+		}
+	}
+
+	for _, key := range []string{"..", "../x", "raw_files/../../x", "a/../../../etc/passwd"} {
+		if _, err := l.Path(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%q: got %v, want ErrInvalidKey", key, err)
+		}
+		if err := l.Put(key, strings.NewReader("x"), 1); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put %q: got %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(root), "x")); err == nil {
+		t.Error("file written outside root")
+	}
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	l := NewLocal(t.TempDir())
+
+	if err := l.Put("dir/a.txt", strings.NewReader("hello"), 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Put("dir/sub/b.txt", strings.NewReader("world"), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := l.Get("dir/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "hello" {
+		t.Errorf("got %q", data)
+	}
+
+	info, err := l.Stat("dir/a.txt")
+	if err != nil || info.Size != 5 {
+		t.Errorf("stat: %+v %v", info, err)
+	}
+	if _, err := l.Stat("dir"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("stat dir: got %v", err)
+	}
+
+	if err := l.Move("dir/a.txt", "moved/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := l.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "dir/sub/b.txt,moved/a.txt" {
+		t.Errorf("list: got %v", keys)
+	}
+
+	if err := l.Delete("moved/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Delete("moved/a.txt"); err != nil {
+		t.Errorf("delete missing: %v", err)
+	}
+	if err := l.DeleteDir("dir"); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := l.List(""); len(keys) != 0 {
+		t.Errorf("after delete: got %v", keys)
+	}
+}
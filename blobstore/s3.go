@@ -0,0 +1,270 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package blobstore
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Config S3 兼容对象存储的连接信息
+type S3Config struct {
+	Endpoint  string `json:"endpoint"` // 例如 https://s3.us-east-1.amazonaws.com 或 http://127.0.0.1:9000
+	Region    string `json:"region"`   // 默认 us-east-1
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"` // 所有键的公共前缀，可为空
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	PathStyle bool   `json:"path_style"` // 使用 endpoint/bucket/key 形式的地址，MinIO 等自建服务通常需要
+}
+
+func (c S3Config) validate() error {
+	if c.Endpoint == "" || c.Bucket == "" || c.AccessKey == "" || c.SecretKey == "" {
+		return fmt.Errorf("s3 store requires endpoint, bucket, access_key and secret_key")
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid s3 endpoint %q", c.Endpoint)
+	}
+	return nil
+}
+
+// S3 S3 兼容的对象存储，请求使用 AWS Signature Version 4 签名
+type S3 struct {
+	cfg    S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewS3(cfg S3Config) *S3 {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+	return &S3{cfg: cfg, client: http.DefaultClient, now: time.Now}
+}
+
+func (s *S3) objectKey(key string) string {
+	if s.cfg.Prefix == "" {
+		return key
+	}
+	return s.cfg.Prefix + "/" + key
+}
+
+// 对象的地址，key 为空时为 bucket 本身
+func (s *S3) objectURL(objectKey string) *url.URL {
+	u, _ := url.Parse(s.cfg.Endpoint)
+	if s.cfg.PathStyle {
+		u.Path = "/" + s.cfg.Bucket + "/" + objectKey
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = "/" + objectKey
+	}
+	// 按签名规则编码路径，保证发送的地址与签名时一致
+	u.RawPath = uriEncode(u.Path, false)
+	return u
+}
+
+func (s *S3) do(method string, u *url.URL, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, s.now())
+	return s.client.Do(req)
+}
+
+// 读取错误响应
+func s3Error(resp *http.Response) error {
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+		return fmt.Errorf("s3: %s: %s (%s)", resp.Status, e.Code, e.Message)
+	}
+	return fmt.Errorf("s3: %s", resp.Status)
+}
+
+func (s *S3) Put(key string, r io.Reader, size int64) error {
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	// 空内容时 body 必须为 http.NoBody，否则会使用分块传输
+	if size == 0 {
+		r = http.NoBody
+	}
+	resp, err := s.do(http.MethodPut, s.objectURL(s.objectKey(key)), r, size, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (s *S3) Get(key string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, s.objectURL(s.objectKey(key)), nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotExist
+	}
+	defer resp.Body.Close()
+	return nil, s3Error(resp)
+}
+
+func (s *S3) Stat(key string) (Info, error) {
+	resp, err := s.do(http.MethodHead, s.objectURL(s.objectKey(key)), nil, 0, nil)
+	if err != nil {
+		return Info{}, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+		return Info{Size: resp.ContentLength, ModTime: modTime}, nil
+	case http.StatusNotFound:
+		return Info{}, ErrNotExist
+	}
+	return Info{}, fmt.Errorf("s3: %s", resp.Status)
+}
+
+func (s *S3) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, s.objectURL(s.objectKey(key)), nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (s *S3) DeleteDir(dir string) error {
+	keys, err := s.List(dir)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3) List(dir string) ([]string, error) {
+	// dir 为空时列出全部文件，与本地存储一致
+	prefix := ""
+	if dir = strings.Trim(dir, "/"); dir != "" {
+		prefix = dir + "/"
+	}
+	prefix = s.objectKey(prefix)
+
+	var keys []string
+	token := ""
+	for {
+		u := s.objectURL("")
+		q := url.Values{}
+		q.Set("list-type", "2")
+		q.Set("prefix", prefix)
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = canonicalQuery(q)
+
+		resp, err := s.do(http.MethodGet, u, nil, 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := s3Error(resp)
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3: invalid list response: %v", err)
+		}
+
+		for _, c := range result.Contents {
+			key := c.Key
+			if s.cfg.Prefix != "" {
+				key = strings.TrimPrefix(key, s.cfg.Prefix+"/")
+			}
+			keys = append(keys, key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *S3) Move(src string, dst string) error {
+	header := http.Header{}
+	header.Set("X-Amz-Copy-Source", "/"+s.cfg.Bucket+"/"+uriEncode(s.objectKey(src), false))
+	resp, err := s.do(http.MethodPut, s.objectURL(s.objectKey(dst)), nil, 0, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// 复制失败时也可能返回 200，错误在响应正文中
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotExist
+	}
+	if resp.StatusCode != http.StatusOK || strings.Contains(string(data), "<Error>") {
+		return fmt.Errorf("s3: copy %s failed: %s %s", src, resp.Status, data)
+	}
+	return s.Delete(src)
+}
+
+func (s *S3) PresignGet(key string, filename string, expires time.Duration) (string, error) {
+	u := s.objectURL(s.objectKey(key))
+	q := url.Values{}
+	if filename != "" {
+		q.Set("response-content-disposition", "attachment; filename=\""+strings.ReplaceAll(filename, "\"", "")+"\"")
+	}
+	s.presign(u, q, s.now(), expires)
+	return u.String(), nil
+}
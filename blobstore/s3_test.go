@@ -0,0 +1,463 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package blobstore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	fakeAccessKey = "AKIDEXAMPLE"
+	fakeSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	fakeRegion    = "us-east-1"
+	fakeBucket    = "library"
+	fakePageSize  = 2
+)
+
+var fakeNow = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// 内存中的 S3 服务，独立重新计算每个请求的签名，只支持路径形式的地址
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	pages   int // 列表请求的次数
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func newTestS3(endpoint string, prefix string) *S3 {
+	s := NewS3(S3Config{
+		Endpoint:  endpoint,
+		Region:    fakeRegion,
+		Bucket:    fakeBucket,
+		Prefix:    prefix,
+		AccessKey: fakeAccessKey,
+		SecretKey: fakeSecretKey,
+		PathStyle: true,
+	})
+	s.now = func() time.Time { return fakeNow }
+	return s
+}
+
+func fakeError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verifyRequest(r); err != nil {
+		fakeError(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != fakeBucket {
+		fakeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("list-type") == "2":
+		f.list(w, r.URL.Query())
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		src, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		srcBucket, srcKey, _ := strings.Cut(strings.TrimPrefix(src, "/"), "/")
+		data, ok := f.objects[srcKey]
+		if err != nil || srcBucket != fakeBucket || !ok {
+			fakeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		f.objects[key] = bytes.Clone(data)
+		fmt.Fprint(w, "<CopyObjectResult></CopyObjectResult>")
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(data)) != r.ContentLength {
+			fakeError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = data
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			fakeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if cd := r.URL.Query().Get("response-content-disposition"); cd != "" {
+			w.Header().Set("Content-Disposition", cd)
+		}
+		w.Header().Set("Last-Modified", fakeNow.Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fakeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// ListObjectsV2，每页最多 fakePageSize 个，续页标记为下一个键的序号
+func (f *fakeS3) list(w http.ResponseWriter, q url.Values) {
+	f.pages++
+	prefix := q.Get("prefix")
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if token := q.Get("continuation-token"); token != "" {
+		n, err := strconv.Atoi(token)
+		if err != nil || n > len(keys) {
+			fakeError(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		start = n
+	}
+	end := min(start+fakePageSize, len(keys))
+
+	type content struct {
+		Key string `xml:"Key"`
+	}
+	result := struct {
+		XMLName               xml.Name  `xml:"ListBucketResult"`
+		Contents              []content `xml:"Contents"`
+		IsTruncated           bool      `xml:"IsTruncated"`
+		NextContinuationToken string    `xml:"NextContinuationToken,omitempty"`
+	}{IsTruncated: end < len(keys)}
+	for _, k := range keys[start:end] {
+		result.Contents = append(result.Contents, content{Key: k})
+	}
+	if result.IsTruncated {
+		result.NextContinuationToken = strconv.Itoa(end)
+	}
+	xml.NewEncoder(w).Encode(result)
+}
+
+// 服务端按规范独立计算签名，不使用客户端的签名代码
+func awsEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			strings.IndexByte("-_.~", c) >= 0 || c == '/' && !encodeSlash {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func fakeCanonicalQuery(q url.Values, skip string) string {
+	var parts []string
+	for k, values := range q {
+		if k == skip {
+			continue
+		}
+		for _, v := range values {
+			parts = append(parts, awsEncode(k, true)+"="+awsEncode(v, true))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "&")
+}
+
+func fakeSignature(date time.Time, canonicalRequest string) string {
+	mac := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	day := date.Format("20060102")
+	stringToSign := "AWS4-HMAC-SHA256\n" + date.Format("20060102T150405Z") + "\n" +
+		day + "/" + fakeRegion + "/s3/aws4_request\n" + hex.EncodeToString(sum[:])
+
+	key := mac([]byte("AWS4"+fakeSecretKey), day)
+	key = mac(key, fakeRegion)
+	key = mac(key, "s3")
+	key = mac(key, "aws4_request")
+	return hex.EncodeToString(mac(key, stringToSign))
+}
+
+func verifyRequest(r *http.Request) error {
+	q := r.URL.Query()
+	if q.Get("X-Amz-Signature") != "" {
+		return verifyPresigned(r, q)
+	}
+
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return errors.New("missing authorization")
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ", ") {
+		k, v, _ := strings.Cut(part, "=")
+		fields[k] = v
+	}
+	date, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+	if fields["Credential"] != fakeAccessKey+"/"+date.Format("20060102")+"/"+fakeRegion+"/s3/aws4_request" {
+		return errors.New("bad credential")
+	}
+
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	for k := range r.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") && !slices.Contains(signed, lk) {
+			return fmt.Errorf("header %s not signed", lk)
+		}
+	}
+	if !slices.Contains(signed, "host") {
+		return errors.New("host not signed")
+	}
+	var headers strings.Builder
+	for _, h := range signed {
+		v := r.Header.Get(h)
+		if h == "host" {
+			v = r.Host
+		}
+		headers.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		fakeCanonicalQuery(q, ""),
+		headers.String(),
+		fields["SignedHeaders"],
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	if !hmac.Equal([]byte(fields["Signature"]), []byte(fakeSignature(date, canonicalRequest))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func verifyPresigned(r *http.Request, q url.Values) error {
+	date, err := time.Parse("20060102T150405Z", q.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+	expires, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+	if err != nil || fakeNow.After(date.Add(time.Duration(expires)*time.Second)) {
+		return errors.New("expired")
+	}
+	if r.Method != http.MethodGet || q.Get("X-Amz-SignedHeaders") != "host" {
+		return errors.New("unsupported presigned request")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		fakeCanonicalQuery(q, "X-Amz-Signature"),
+		"host:" + r.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	if !hmac.Equal([]byte(q.Get("X-Amz-Signature")), []byte(fakeSignature(date, canonicalRequest))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func putString(t *testing.T, s Store, key string, data string) {
+	t.Helper()
+	if err := s.Put(key, strings.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("put %s: %v", key, err)
+	}
+}
+
+func getString(t *testing.T, s Store, key string) string {
+	t.Helper()
+	r, err := s.Get(key)
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestS3RoundTrip(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := newTestS3(srv.URL, "/lib/")
+
+	// 包含需要编码的字符，检查签名路径与实际发送的路径一致
+	keys := []string{
+		"raw_files/ab/photo 1 (copy).jpg",
+		"raw_files/ab/照片+1.png",
+		"raw_files/cd/c.webp",
+		"raw_files/ef/d.gif",
+		"renditions/ab/thumb.webp",
+	}
+	for _, key := range keys {
+		putString(t, s, key, "data of "+key)
+	}
+	if _, ok := f.objects["lib/raw_files/cd/c.webp"]; !ok {
+		t.Fatalf("prefix not applied: %v", f.objects)
+	}
+
+	for _, key := range keys {
+		if got := getString(t, s, key); got != "data of "+key {
+			t.Errorf("get %s = %q", key, got)
+		}
+	}
+	info, err := s.Stat(keys[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != int64(len("data of "+keys[1])) || !info.ModTime.Equal(fakeNow) {
+		t.Errorf("stat = %+v", info)
+	}
+	if _, err := s.Stat("raw_files/missing.jpg"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("stat missing: %v", err)
+	}
+	if _, err := s.Get("raw_files/missing.jpg"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("get missing: %v", err)
+	}
+
+	// 4 个键分两页以上返回
+	f.pages = 0
+	listed, err := s.List("raw_files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := keys[:4]; !slices.Equal(listed, want) {
+		t.Errorf("list = %q, want %q", listed, want)
+	}
+	if f.pages != 2 {
+		t.Errorf("list used %d pages, want 2", f.pages)
+	}
+
+	if err := s.Move(keys[0], "versions/ab/v1.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if Exists(s, keys[0]) {
+		t.Error("move left the source")
+	}
+	if got := getString(t, s, "versions/ab/v1.jpg"); got != "data of "+keys[0] {
+		t.Errorf("moved content = %q", got)
+	}
+	if err := s.Move("raw_files/missing.jpg", "versions/x.jpg"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("move missing: %v", err)
+	}
+
+	if err := s.Delete(keys[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(keys[1]); err != nil {
+		t.Errorf("delete missing: %v", err)
+	}
+	if err := s.DeleteDir("raw_files"); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := s.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"renditions/ab/thumb.webp", "versions/ab/v1.jpg"}; !slices.Equal(rest, want) {
+		t.Errorf("after delete = %q, want %q", rest, want)
+	}
+}
+
+func TestS3RejectsBadSignature(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := newTestS3(srv.URL, "")
+	s.cfg.SecretKey = "wrong"
+
+	if err := s.Put("a.txt", strings.NewReader("x"), 1); err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("put with wrong secret: %v", err)
+	}
+	if len(f.objects) != 0 {
+		t.Errorf("objects = %v", f.objects)
+	}
+}
+
+func TestS3PresignGet(t *testing.T) {
+	_, srv := newFakeS3(t)
+	s := newTestS3(srv.URL, "lib")
+	key := "raw_files/ab/my photo.jpg"
+	putString(t, s, key, "jpeg")
+
+	link, err := s.PresignGet(key, `my "photo".jpg`, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(data) != "jpeg" {
+		t.Fatalf("presigned get = %s %q", resp.Status, data)
+	}
+	if cd := resp.Header.Get("Content-Disposition"); cd != `attachment; filename="my photo.jpg"` {
+		t.Errorf("content-disposition = %q", cd)
+	}
+
+	// 修改任意参数都会使签名失效
+	u, _ := url.Parse(link)
+	q := u.Query()
+	q.Set("X-Amz-Expires", "604800")
+	u.RawQuery = q.Encode()
+	resp, err = http.Get(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("tampered presigned get = %s", resp.Status)
+	}
+
+	// 有效期超过 7 天时截断
+	link, _ = s.PresignGet(key, "", 30*24*time.Hour)
+	u, _ = url.Parse(link)
+	if got := u.Query().Get("X-Amz-Expires"); got != "604800" {
+		t.Errorf("expires = %s", got)
+	}
+}
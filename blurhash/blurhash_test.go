@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package blurhash
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+func fill(w, h int, at func(x, y int) color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, at(x, y))
+		}
+	}
+	return img
+}
+
+// decode 按 BlurHash 的解码算法还原 w x h 的图像，用于检查编码结果
+func decode(t *testing.T, hash string, w, h int) *image.NRGBA {
+	t.Helper()
+	decode83 := func(s string) int {
+		v := 0
+		for _, c := range s {
+			v = v*83 + strings.IndexRune(characters, c)
+		}
+		return v
+	}
+	size := decode83(hash[:1])
+	nx, ny := size%9+1, size/9+1
+	if len(hash) != 4+2*nx*ny {
+		t.Fatalf("hash %q has length %d, want %d", hash, len(hash), 4+2*nx*ny)
+	}
+	maxValue := float64(decode83(hash[1:2])+1) / 166
+
+	colors := make([][3]float64, nx*ny)
+	dc := decode83(hash[2:6])
+	colors[0] = [3]float64{srgbToLinear(float64(dc>>16) / 255), srgbToLinear(float64(dc>>8&255) / 255), srgbToLinear(float64(dc&255) / 255)}
+	for i := 1; i < nx*ny; i++ {
+		v := decode83(hash[4+2*i : 6+2*i])
+		for c, q := range []int{v / (19 * 19), v / 19 % 19, v % 19} {
+			colors[i][c] = signPow((float64(q)-9)/9, 2) * maxValue
+		}
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var p [3]float64
+			for j := 0; j < ny; j++ {
+				for i := 0; i < nx; i++ {
+					basis := math.Cos(math.Pi*float64(x*i)/float64(w)) * math.Cos(math.Pi*float64(y*j)/float64(h))
+					for c := range p {
+						p[c] += colors[i+j*nx][c] * basis
+					}
+				}
+			}
+			img.Set(x, y, color.NRGBA{uint8(linearToSRGB(p[0])), uint8(linearToSRGB(p[1])), uint8(linearToSRGB(p[2])), 255})
+		}
+	}
+	return img
+}
+
+// 检查解码后的像素与期望颜色的各通道差不超过 tolerance
+func near(got color.Color, want color.NRGBA, tolerance int) bool {
+	g := color.NRGBAModel.Convert(got).(color.NRGBA)
+	abs := func(v int) int {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+	return abs(int(g.R)-int(want.R)) <= tolerance && abs(int(g.G)-int(want.G)) <= tolerance && abs(int(g.B)-int(want.B)) <= tolerance
+}
+
+// 纯色图像的平均色编码为直流分量，解码后接近原来的颜色
+func TestEncodeSolidColor(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	hash, err := Encode(fill(40, 30, func(x, y int) color.Color { return red }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 横图使用 4x3 分量，平均色 0xFF0000
+	if hash[:1] != "L" || hash[2:6] != "TI:j" {
+		t.Errorf("hash = %q", hash)
+	}
+	if img := decode(t, hash, 40, 30); !near(img.At(20, 15), red, 8) {
+		t.Errorf("decoded center = %v", img.At(20, 15))
+	}
+
+	// 竖图使用 3x4 分量，透明像素合成到白色上
+	hash, err = Encode(fill(30, 40, func(x, y int) color.Color { return color.NRGBA{0, 0, 0, 0} }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash[:1] != "T" || hash[2:6] != "TSUA" {
+		t.Errorf("transparent: hash = %q", hash)
+	}
+}
+
+// 左黑右白的图像解码后左侧暗、右侧亮，上下一致
+func TestEncodeHorizontalEdge(t *testing.T) {
+	img := fill(64, 48, func(x, y int) color.Color {
+		if x < 32 {
+			return color.Black
+		}
+		return color.White
+	})
+	hash, err := EncodeComponents(img, 4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := decode(t, hash, 64, 48)
+	// 只保留低频分量，边缘被模糊，两侧仍然明显区分
+	if !near(decoded.At(4, 24), color.NRGBA{0, 0, 0, 255}, 127) || !near(decoded.At(60, 24), color.NRGBA{255, 255, 255, 255}, 64) {
+		t.Errorf("decoded left %v right %v", decoded.At(4, 24), decoded.At(60, 24))
+	}
+	if !near(decoded.At(16, 10), color.NRGBAModel.Convert(decoded.At(16, 38)).(color.NRGBA), 24) {
+		t.Errorf("decoded top %v bottom %v", decoded.At(16, 10), decoded.At(16, 38))
+	}
+}
+func TestEncodeInvalid(t *testing.T) {
+	if _, err := Encode(image.NewNRGBA(image.Rect(0, 0, 0, 10))); err == nil {
+		t.Error("empty image accepted")
+	}
+	img := fill(4, 4, func(x, y int) color.Color { return color.White })
+	for _, c := range [][2]int{{0, 3}, {4, 10}} {
+		if _, err := EncodeComponents(img, c[0], c[1]); err == nil {
+			t.Errorf("components %v accepted", c)
+		}
+	}
+}
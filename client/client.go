@@ -0,0 +1,216 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+
+// Package client 通过 HTTP 访问 SynapForest 服务器，请求和响应与
+// /api/openapi.json 中的规范一一对应。
+//
+// 不经过服务器直接使用资料库见 sdk 包
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client 服务器的访问地址和凭据，字段在开始使用后不应修改，
+// 可以在多个 goroutine 中同时使用
+type Client struct {
+	BaseURL    string       // 如 http://localhost:41595
+	Token      string       // 私有接口的 Authorization 请求头
+	Library    string       // 资料库名称，为空时使用服务器的默认资料库
+	User       string       // 审计日志中记录的用户名，服务器不验证，为空时记录 token 指纹
+	HTTPClient *http.Client // 为空时使用 http.DefaultClient
+}
+
+// New 创建访问默认资料库的客户端
+func New(baseURL, token string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), Token: token}
+}
+
+// WithLibrary 返回访问另一个资料库的客户端，两者共用 HTTPClient
+func (c *Client) WithLibrary(name string) *Client {
+	cc := *c
+	cc.Library = name
+	return &cc
+}
+
+// Error 服务器返回的错误
+type Error struct {
+	StatusCode int
+	Message    string
+	Errors     map[string]string // 批量导入时失败的文件名或 URL 到失败原因
+}
+
+func (e *Error) Error() string {
+	if len(e.Errors) > 0 {
+		return fmt.Sprintf("%s: %d failed", e.Message, len(e.Errors))
+	}
+	return e.Message
+}
+
+// 错误响应，部分接口只返回 error 字段
+type errorBody struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Error   string            `json:"error"`
+	Errors  map[string]string `json:"errors"`
+}
+
+// 只关心 data 字段的响应
+type dataBody struct {
+	Data interface{} `json:"data"`
+}
+
+// 资料库范围内的路径加上 /lib/<name> 前缀
+func (c *Client) libraryPath(path string) string {
+	if c.Library == "" {
+		return path
+	}
+	return "/lib/" + url.PathEscape(c.Library) + path
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// 发送请求，返回状态码为 2xx 的响应，其余转换为 *Error
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", c.Token)
+	}
+	if c.User != "" {
+		req.Header.Set("X-User", c.User)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var e errorBody
+	json.NewDecoder(resp.Body).Decode(&e)
+	apiErr := &Error{StatusCode: resp.StatusCode, Message: e.Message, Errors: e.Errors}
+	switch {
+	case apiErr.Message != "":
+	case e.Error != "":
+		apiErr.Message = e.Error
+	case len(e.Errors) > 0:
+		apiErr.Message = "import failed"
+	default:
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return nil, apiErr
+}
+
+// 以 JSON 发送 in，把响应解码到 out，in 或 out 为 nil 时忽略
+func (c *Client) call(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+		contentType = "application/json"
+	}
+
+	resp, err := c.do(ctx, method, path, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// 调用资料库范围内的 POST 接口，把 data 字段解码到 data
+func (c *Client) post(ctx context.Context, path string, in, data interface{}) error {
+	var out interface{}
+	if data != nil {
+		out = &dataBody{Data: data}
+	}
+	return c.call(ctx, http.MethodPost, c.libraryPath(path), in, out)
+}
+
+// 下载资料库范围内的文件，调用方负责关闭
+func (c *Client) download(ctx context.Context, path string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.libraryPath(path), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// UploadFile multipart 上传的一个文件
+type UploadFile struct {
+	Name   string
+	Reader io.Reader
+}
+
+// 以 multipart/form-data 上传文件，field 为表单字段名
+func (c *Client) upload(ctx context.Context, path, field string, files []UploadFile, out interface{}) error {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, f := range files {
+		part, err := w.CreateFormFile(field, f.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, f.Reader); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, c.libraryPath(path), &buf, w.FormDataContentType())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// OpenAPI 返回服务器的 OpenAPI 规范
+func (c *Client) OpenAPI(ctx context.Context) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/openapi.json", nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
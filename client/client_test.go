@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"synapforest/server"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+const testToken = "client-test-token"
+
+// 在临时目录中启动服务器，返回访问默认资料库的客户端
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	s, err := server.NewTemp(server.Options{Token: testToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s.Handler)
+	t.Cleanup(func() {
+		srv.Close()
+		s.Close()
+	})
+	return New(srv.URL+"/", testToken)
+}
+
+func testPNG(t *testing.T, c color.RGBA) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// 上传并导入一个文件，返回导入的 item
+func importPNG(t *testing.T, c *Client, name string, col color.RGBA, folders ...uuid.UUID) Item {
+	t.Helper()
+	ctx := context.Background()
+	names, err := c.UploadFiles(ctx, UploadFile{Name: name, Reader: bytes.NewReader(testPNG(t, col))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddFromPaths(ctx, names, folders...); err != nil {
+		t.Fatal(err)
+	}
+	keyword := name[:len(name)-len(".png")]
+	items, err := c.Items(ctx, ListItemsRequest{Keyword: &keyword})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("items named %s = %+v, %v", keyword, items, err)
+	}
+	return items[0]
+}
+
+func TestItems(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	folder, err := c.CreateFolder(ctx, "trip", uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := importPNG(t, c, "red.png", color.RGBA{255, 0, 0, 255}, folder.ID)
+	if item.Name != "red" || item.Ext != "png" || item.Width != 40 || item.Height != 30 || !item.HaveThumbnail {
+		t.Errorf("item = %+v", item)
+	}
+
+	detail, err := c.Item(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detail.Folders) != 1 || detail.Folders[0].ID != folder.ID || len(detail.Renditions) == 0 {
+		t.Errorf("detail = %+v", detail)
+	}
+
+	thumb, err := c.Thumbnail(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(thumb)
+	thumb.Close()
+	if len(data) == 0 {
+		t.Error("empty thumbnail")
+	}
+
+	r, err := c.Image(ctx, item.ID, ImageOptions{Width: 20, Format: "png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(r)
+	r.Close()
+	if err != nil || img.Bounds().Dx() != 20 || img.Bounds().Dy() != 15 {
+		t.Errorf("resized image = %v, %v", img.Bounds(), err)
+	}
+
+	name := "crimson"
+	star := uint8(4)
+	if err := c.UpdateItem(ctx, UpdateItemRequest{ID: item.ID, Name: &name, Star: &star}); err != nil {
+		t.Fatal(err)
+	}
+	detail, _ = c.Item(ctx, item.ID)
+	if detail.Name != "crimson" || detail.Star != 4 {
+		t.Errorf("updated = %+v", detail.Item)
+	}
+
+	if err := c.MoveToTrash(ctx, []string{item.ID}, false); err != nil {
+		t.Fatal(err)
+	}
+	deleted := true
+	trash, err := c.Items(ctx, ListItemsRequest{IsDeleted: &deleted})
+	if err != nil || len(trash) != 1 || trash[0].DeletedAt == nil {
+		t.Errorf("trash = %+v, %v", trash, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	wrong := *c
+	wrong.Token = "wrong"
+	_, err := wrong.Items(ctx, ListItemsRequest{})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong token: %v", err)
+	}
+
+	// 批量导入的失败原因按文件名返回
+	err = c.AddFromPaths(ctx, []string{"missing.png"})
+	if !errors.As(err, &apiErr) || apiErr.Errors["missing.png"] == "" {
+		t.Errorf("missing file: %#v", err)
+	}
+
+	if _, err := c.Item(ctx, "missing"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("missing item: %v", err)
+	}
+
+	// 有 item 时不能更换文件存储
+	importPNG(t, c, "a.png", color.RGBA{1, 2, 3, 255})
+	err = c.UpdateBlobStore(ctx, BlobStore{Type: "s3", S3: S3{Endpoint: "http://127.0.0.1:9000", Bucket: "b", AccessKey: "k", SecretKey: "s"}})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("switch blob store: %v", err)
+	}
+}
+
+func TestLibrariesAndAudit(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	if _, err := c.CreateLibrary(ctx, "archive", ""); err != nil {
+		t.Fatal(err)
+	}
+	libraries, err := c.Libraries(ctx)
+	if err != nil || len(libraries) != 2 {
+		t.Fatalf("libraries = %+v, %v", libraries, err)
+	}
+
+	archive := c.WithLibrary("archive")
+	archive.User = "alice"
+	item := importPNG(t, archive, "old.png", color.RGBA{0, 0, 255, 255})
+	if items, _ := c.Items(ctx, ListItemsRequest{}); len(items) != 0 {
+		t.Errorf("default library items = %+v", items)
+	}
+
+	// 返回的文件地址带有资料库前缀
+	detail, err := archive.Item(ctx, item.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(c.BaseURL + detail.Renditions[0].URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("rendition %s = %s", detail.Renditions[0].URL, resp.Status)
+	}
+
+	entries, total, err := archive.AuditLog(ctx, AuditQuery{Actor: "alice", Operation: "item."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || entries[0].Operation != "item.import" {
+		t.Errorf("audit = %+v", entries)
+	}
+	if _, total, _ := c.AuditLog(ctx, AuditQuery{Actor: "alice"}); total != 0 {
+		t.Errorf("alice in default library = %d", total)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	importPNG(t, c, "a.png", color.RGBA{0, 255, 0, 255})
+
+	job, err := c.StartJob(ctx, "palette_backfill", map[string]bool{"force": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(10 * time.Second); job.Status == "running"; {
+		if time.Now().After(deadline) {
+			t.Fatalf("job = %+v", job)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if job, err = c.Job(ctx, job.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Status != "done" || job.Done != 1 {
+		t.Errorf("job = %+v", job)
+	}
+	jobs, err := c.Jobs(ctx)
+	if err != nil || len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Errorf("jobs = %+v, %v", jobs, err)
+	}
+
+	spec, err := c.OpenAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Paths map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil || doc.Paths["/api/job/start"] == nil {
+		t.Errorf("openapi: %v", err)
+	}
+}
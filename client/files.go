@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 以下为不需要 Authorization 的公开接口，返回的文件由调用方负责关闭
+
+// Thumbnail 下载缩略图
+func (c *Client) Thumbnail(ctx context.Context, id string) (io.ReadCloser, error) {
+	return c.download(ctx, "/public/thumbnails/"+url.PathEscape(id))
+}
+
+// Preview 下载预览图
+func (c *Client) Preview(ctx context.Context, id string) (io.ReadCloser, error) {
+	return c.download(ctx, "/public/previews/"+url.PathEscape(id))
+}
+
+// RawFile 下载原始文件
+func (c *Client) RawFile(ctx context.Context, id string) (io.ReadCloser, error) {
+	return c.download(ctx, "/public/raw_files/"+url.PathEscape(id))
+}
+
+// Rendition 下载自定义规格的预览图
+func (c *Client) Rendition(ctx context.Context, name, id string) (io.ReadCloser, error) {
+	return c.download(ctx, "/public/renditions/"+url.PathEscape(name)+"/"+url.PathEscape(id))
+}
+
+// VersionFile 下载历史版本文件
+func (c *Client) VersionFile(ctx context.Context, versionID uint) (io.ReadCloser, error) {
+	return c.download(ctx, "/api/item/versionFile/"+strconv.FormatUint(uint64(versionID), 10))
+}
+
+// Image 下载缩放到指定尺寸的图片
+func (c *Client) Image(ctx context.Context, id string, opts ImageOptions) (io.ReadCloser, error) {
+	return c.download(ctx, c.ImagePath(id, opts))
+}
+
+// ImagePath 指定尺寸图片的路径，可以拼接在 BaseURL 后直接用于 <img>
+func (c *Client) ImagePath(id string, opts ImageOptions) string {
+	q := url.Values{}
+	if opts.Width > 0 {
+		q.Set("w", strconv.Itoa(opts.Width))
+	}
+	if opts.Height > 0 {
+		q.Set("h", strconv.Itoa(opts.Height))
+	}
+	if opts.Fit != "" {
+		q.Set("fit", opts.Fit)
+	}
+	if opts.Format != "" {
+		q.Set("format", opts.Format)
+	}
+	if opts.Quality > 0 {
+		q.Set("quality", strconv.Itoa(opts.Quality))
+	}
+
+	path := c.libraryPath("/public/images/" + url.PathEscape(id))
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	return path
+}
+
+// Vectorize 计算并保存 item 的图像向量
+func (c *Client) Vectorize(ctx context.Context, id string) (*Vector, error) {
+	var v Vector
+	if err := c.call(ctx, http.MethodPost, c.libraryPath("/public/vectorize/"+url.PathEscape(id)), nil, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// GraphQL 执行 GraphQL 查询，把 data 解码到 out，out 为 nil 时忽略结果
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	err := c.call(ctx, http.MethodPost, c.libraryPath("/graphql"), map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	}, &resp)
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Errors) > 0 {
+		messages := make([]string, len(resp.Errors))
+		for i, e := range resp.Errors {
+			messages[i] = e.Message
+		}
+		return errors.New(strings.Join(messages, "; "))
+	}
+	if out == nil || len(resp.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Data, out)
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package client
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+)
+
+// CreateFolder 在 parent 下创建文件夹，parent 为 uuid.Nil 时在顶层
+func (c *Client) CreateFolder(ctx context.Context, name string, parent uuid.UUID) (*Folder, error) {
+	var folders []Folder
+	err := c.post(ctx, "/api/folder/create", map[string]interface{}{
+		"folderName": name,
+		"parent":     parent,
+	}, &folders)
+	if err != nil {
+		return nil, err
+	}
+	return first(folders), nil
+}
+
+// Folders 列出 parent 的直接子文件夹，parent 为 nil 时列出全部文件夹
+func (c *Client) Folders(ctx context.Context, parent *uuid.UUID) ([]Folder, error) {
+	var folders []Folder
+	err := c.post(ctx, "/api/folder/list", map[string]interface{}{"parent": parent}, &folders)
+	return folders, err
+}
+
+// UpdateFolder 修改文件夹
+func (c *Client) UpdateFolder(ctx context.Context, id uuid.UUID, update NodeUpdate) (*Folder, error) {
+	var folders []Folder
+	err := c.post(ctx, "/api/folder/update", nodeUpdateBody("folderId", "folderName", id, update), &folders)
+	if err != nil {
+		return nil, err
+	}
+	return first(folders), nil
+}
+
+// MoveFolders 把文件夹移动到 parent 下，parent 为 uuid.Nil 时移动到顶层
+func (c *Client) MoveFolders(ctx context.Context, ids []uuid.UUID, parent uuid.UUID) error {
+	return c.post(ctx, "/api/folder/updateParent", map[string]interface{}{
+		"folderIds": ids,
+		"newParent": parent,
+	}, nil)
+}
+
+// DeleteFolder 删除文件夹及其子文件夹
+func (c *Client) DeleteFolder(ctx context.Context, id uuid.UUID, opts DeleteOptions) error {
+	return c.post(ctx, "/api/folder/delete", map[string]interface{}{
+		"folderId":    id,
+		"hardDelete":  opts.HardDelete,
+		"deleteItems": opts.DeleteItems,
+	}, nil)
+}
+
+// CreateTag 在 parent 下创建标签，parent 为 uuid.Nil 时在顶层
+func (c *Client) CreateTag(ctx context.Context, name string, parent uuid.UUID) (*Tag, error) {
+	var tags []Tag
+	err := c.post(ctx, "/api/tag/create", map[string]interface{}{
+		"tagName": name,
+		"parent":  parent,
+	}, &tags)
+	if err != nil {
+		return nil, err
+	}
+	return first(tags), nil
+}
+
+// Tags 列出 parent 的直接子标签，parent 为 nil 时列出全部标签
+func (c *Client) Tags(ctx context.Context, parent *uuid.UUID) ([]Tag, error) {
+	var tags []Tag
+	err := c.post(ctx, "/api/tag/list", map[string]interface{}{"parent": parent}, &tags)
+	return tags, err
+}
+
+// UpdateTag 修改标签
+func (c *Client) UpdateTag(ctx context.Context, id uuid.UUID, update NodeUpdate) (*Tag, error) {
+	var tags []Tag
+	err := c.post(ctx, "/api/tag/update", nodeUpdateBody("tagId", "tagName", id, update), &tags)
+	if err != nil {
+		return nil, err
+	}
+	return first(tags), nil
+}
+
+// MoveTags 把标签移动到 parent 下，parent 为 uuid.Nil 时移动到顶层
+func (c *Client) MoveTags(ctx context.Context, ids []uuid.UUID, parent uuid.UUID) error {
+	return c.post(ctx, "/api/tag/updateParent", map[string]interface{}{
+		"tagIds":    ids,
+		"newParent": parent,
+	}, nil)
+}
+
+// DeleteTag 删除标签及其子标签
+func (c *Client) DeleteTag(ctx context.Context, id uuid.UUID, opts DeleteOptions) error {
+	return c.post(ctx, "/api/tag/delete", map[string]interface{}{
+		"tagId":       id,
+		"hardDelete":  opts.HardDelete,
+		"deleteItems": opts.DeleteItems,
+	}, nil)
+}
+
+// 文件夹和标签的修改请求只有 ID 和名称的字段名不同
+func nodeUpdateBody(idField, nameField string, id uuid.UUID, update NodeUpdate) map[string]interface{} {
+	body := map[string]interface{}{idField: id}
+	if update.Name != nil {
+		body[nameField] = *update.Name
+	}
+	if update.Description != nil {
+		body["description"] = *update.Description
+	}
+	if update.Icon != nil {
+		body["icon"] = *update.Icon
+	}
+	if update.IconColor != nil {
+		body["iconColor"] = *update.IconColor
+	}
+	if update.Parent != nil {
+		body["parent"] = *update.Parent
+	}
+	return body
+}
+
+// 创建和修改接口返回只包含一个节点的列表
+func first[T any](nodes []T) *T {
+	if len(nodes) == 0 {
+		return nil
+	}
+	return &nodes[0]
+}
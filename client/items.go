@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package client
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+)
+
+// UploadFiles 上传文件到服务器的上传目录，返回保存的文件名，
+// 之后可以用 AddFromPaths 或 ReplaceFile 导入
+func (c *Client) UploadFiles(ctx context.Context, files ...UploadFile) ([]string, error) {
+	var resp struct {
+		Files []string `json:"files"`
+	}
+	if err := c.upload(ctx, "/api/uploadfiles", "files", files, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Files, nil
+}
+
+// AddFromPaths 导入上传目录中的文件，有失败时返回的 *Error 中 Errors 为各文件的失败原因
+func (c *Client) AddFromPaths(ctx context.Context, fileNames []string, folderIDs ...uuid.UUID) error {
+	return c.post(ctx, "/api/item/addFromPaths", map[string]interface{}{
+		"fileNames": fileNames,
+		"folderIds": folderIDs,
+	}, nil)
+}
+
+// AddFromURLs 下载并导入文件，有失败时返回的 *Error 中 Errors 为各 URL 的失败原因
+func (c *Client) AddFromURLs(ctx context.Context, req AddFromURLsRequest) error {
+	return c.post(ctx, "/api/item/addFromUrls", req, nil)
+}
+
+// Item 返回 item 详情
+func (c *Client) Item(ctx context.Context, id string) (*ItemDetail, error) {
+	var detail ItemDetail
+	if err := c.post(ctx, "/api/item/info", map[string]string{"id": id}, &detail); err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+// Items 按条件筛选 item
+func (c *Client) Items(ctx context.Context, req ListItemsRequest) ([]Item, error) {
+	var items []Item
+	err := c.post(ctx, "/api/item/list", req, &items)
+	return items, err
+}
+
+// GeoClusters 按拍摄位置聚合 bbox 范围内的 item
+func (c *Client) GeoClusters(ctx context.Context, bbox BBox, zoom int) ([]GeoCluster, error) {
+	var clusters []GeoCluster
+	err := c.post(ctx, "/api/item/geoClusters", map[string]interface{}{
+		"south": bbox.South,
+		"west":  bbox.West,
+		"north": bbox.North,
+		"east":  bbox.East,
+		"zoom":  zoom,
+	}, &clusters)
+	return clusters, err
+}
+
+// UpdateItem 修改 item
+func (c *Client) UpdateItem(ctx context.Context, req UpdateItemRequest) error {
+	return c.post(ctx, "/api/item/update", req, nil)
+}
+
+// MoveToTrash 把 item 移入回收站，hardDelete 时彻底删除
+func (c *Client) MoveToTrash(ctx context.Context, ids []string, hardDelete bool) error {
+	return c.post(ctx, "/api/item/moveToTrash", map[string]interface{}{
+		"itemIds":    ids,
+		"hardDelete": hardDelete,
+	}, nil)
+}
+
+// EditItem 对 item 应用编辑操作并导入为新 item，返回新 item 的 ID
+func (c *Client) EditItem(ctx context.Context, req EditItemRequest) (string, error) {
+	return c.postID(ctx, "/api/item/edit", req)
+}
+
+// ReplaceFile 用上传目录中的文件替换 item 的原始文件，旧文件保存为历史版本，返回新 ID
+func (c *Client) ReplaceFile(ctx context.Context, id, fileName string) (string, error) {
+	return c.postID(ctx, "/api/item/replaceFile", map[string]string{"id": id, "fileName": fileName})
+}
+
+// Versions 列出 item 的历史版本
+func (c *Client) Versions(ctx context.Context, id string) ([]Version, error) {
+	var versions []Version
+	err := c.post(ctx, "/api/item/versions", map[string]string{"id": id}, &versions)
+	return versions, err
+}
+
+// RestoreVersion 恢复历史版本，返回新 ID
+func (c *Client) RestoreVersion(ctx context.Context, id string, versionID uint) (string, error) {
+	return c.postID(ctx, "/api/item/restoreVersion", map[string]interface{}{"id": id, "versionId": versionID})
+}
+
+// AddToFolders 把 item 加入文件夹
+func (c *Client) AddToFolders(ctx context.Context, itemIDs []string, folderIDs ...uuid.UUID) error {
+	return c.post(ctx, "/api/item/add-folder", map[string]interface{}{
+		"itemIds":   itemIDs,
+		"folderIds": folderIDs,
+	}, nil)
+}
+
+// RemoveFromFolder 从文件夹中移除 item
+func (c *Client) RemoveFromFolder(ctx context.Context, itemIDs []string, folderID uuid.UUID) error {
+	return c.post(ctx, "/api/item/remove-folder", map[string]interface{}{
+		"itemIds":  itemIDs,
+		"folderId": folderID,
+	}, nil)
+}
+
+// 返回 data 为 {"id": ...} 的接口
+func (c *Client) postID(ctx context.Context, path string, in interface{}) (string, error) {
+	var data struct {
+		ID string `json:"id"`
+	}
+	if err := c.post(ctx, path, in, &data); err != nil {
+		return "", err
+	}
+	return data.ID, nil
+}
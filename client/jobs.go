@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gofrs/uuid"
+)
+
+// StartJob 启动后台任务，params 为任务参数，可以为 nil
+func (c *Client) StartJob(ctx context.Context, kind string, params interface{}) (*Job, error) {
+	var j Job
+	err := c.post(ctx, "/api/job/start", map[string]interface{}{"type": kind, "params": params}, &j)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// Jobs 列出资料库的任务
+func (c *Client) Jobs(ctx context.Context) ([]Job, error) {
+	var jobs []Job
+	err := c.post(ctx, "/api/job/list", nil, &jobs)
+	return jobs, err
+}
+
+// Job 查询任务状态
+func (c *Client) Job(ctx context.Context, id string) (*Job, error) {
+	var j Job
+	if err := c.post(ctx, "/api/job/info", map[string]string{"id": id}, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// CancelJob 请求取消任务，任务在下一个检查点停止
+func (c *Client) CancelJob(ctx context.Context, id string) error {
+	return c.post(ctx, "/api/job/cancel", map[string]string{"id": id}, nil)
+}
+
+// JobReport 下载任务报告，调用方负责关闭
+func (c *Client) JobReport(ctx context.Context, id string) (io.ReadCloser, error) {
+	return c.download(ctx, "/api/job/report/"+url.PathEscape(id))
+}
+
+// StorageStats 返回存储占用统计
+func (c *Client) StorageStats(ctx context.Context, req StorageStatsRequest) (*StorageStats, error) {
+	var stats StorageStats
+	if err := c.post(ctx, "/api/stats/storage", req, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// AuditLog 查询审计日志，最新的在前，同时返回符合条件的总数
+func (c *Client) AuditLog(ctx context.Context, q AuditQuery) ([]AuditEntry, int64, error) {
+	var resp struct {
+		Data  []AuditEntry `json:"data"`
+		Total int64        `json:"total"`
+	}
+	if err := c.call(ctx, http.MethodPost, c.libraryPath("/api/audit/list"), q, &resp); err != nil {
+		return nil, 0, err
+	}
+	return resp.Data, resp.Total, nil
+}
+
+// Backups 列出备份，备份通过 backup 任务创建
+func (c *Client) Backups(ctx context.Context) ([]Backup, error) {
+	var backups []Backup
+	err := c.post(ctx, "/api/backup/list", nil, &backups)
+	return backups, err
+}
+
+// DownloadBackup 下载备份归档，调用方负责关闭
+func (c *Client) DownloadBackup(ctx context.Context, id string) (io.ReadCloser, error) {
+	return c.download(ctx, "/api/backup/download/"+url.PathEscape(id))
+}
+
+// UploadPackage 上传导出包，返回的 ID 用于 import 任务
+func (c *Client) UploadPackage(ctx context.Context, name string, r io.Reader) (*PackageUpload, error) {
+	var resp struct {
+		Data PackageUpload `json:"data"`
+	}
+	if err := c.upload(ctx, "/api/package/upload", "file", []UploadFile{{Name: name, Reader: r}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
+// DownloadPackage 下载 export 任务生成或上传的导出包，调用方负责关闭
+func (c *Client) DownloadPackage(ctx context.Context, id uuid.UUID) (io.ReadCloser, error) {
+	return c.download(ctx, "/api/package/download/"+id.String())
+}
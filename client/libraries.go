@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// 资料库管理接口不带 /lib/<name> 前缀
+func (c *Client) libraryCall(ctx context.Context, path string, in interface{}, data interface{}) error {
+	return c.call(ctx, http.MethodPost, path, in, &dataBody{Data: data})
+}
+
+// Libraries 列出服务器注册的资料库
+func (c *Client) Libraries(ctx context.Context) ([]LibraryInfo, error) {
+	var libraries []LibraryInfo
+	err := c.libraryCall(ctx, "/api/library/list", nil, &libraries)
+	return libraries, err
+}
+
+// CreateLibrary 注册并打开资料库，dir 为空时为服务器的 libraries/<name>
+func (c *Client) CreateLibrary(ctx context.Context, name, dir string) (*LibraryInfo, error) {
+	var info LibraryInfo
+	err := c.libraryCall(ctx, "/api/library/create", map[string]string{"name": name, "dir": dir}, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// OpenLibrary 打开已注册的资料库
+func (c *Client) OpenLibrary(ctx context.Context, name string) (*LibraryInfo, error) {
+	var info LibraryInfo
+	err := c.libraryCall(ctx, "/api/library/open", map[string]string{"name": name}, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// RenameLibrary 修改资料库名称，目录不变
+func (c *Client) RenameLibrary(ctx context.Context, name, newName string) (*LibraryInfo, error) {
+	var info LibraryInfo
+	err := c.libraryCall(ctx, "/api/library/rename", map[string]string{"name": name, "newName": newName}, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// CloseLibrary 关闭资料库，默认资料库和有任务运行的资料库不能关闭
+func (c *Client) CloseLibrary(ctx context.Context, name string) (*LibraryInfo, error) {
+	var info LibraryInfo
+	err := c.libraryCall(ctx, "/api/library/close", map[string]string{"name": name}, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Renditions 列出预览图规格
+func (c *Client) Renditions(ctx context.Context) ([]Rendition, error) {
+	var renditions []Rendition
+	err := c.post(ctx, "/api/setting/renditions", nil, &renditions)
+	return renditions, err
+}
+
+// UpdateRenditions 替换全部预览图规格，必须包含内置的 thumbnail 和 preview
+func (c *Client) UpdateRenditions(ctx context.Context, renditions []Rendition) error {
+	return c.post(ctx, "/api/setting/updateRenditions", map[string]interface{}{"renditions": renditions}, nil)
+}
+
+// ImportLimits 返回导入时的解码限制
+func (c *Client) ImportLimits(ctx context.Context) (*ImportLimits, error) {
+	var limits ImportLimits
+	if err := c.post(ctx, "/api/setting/importLimits", nil, &limits); err != nil {
+		return nil, err
+	}
+	return &limits, nil
+}
+
+// UpdateImportLimits 修改解码限制
+func (c *Client) UpdateImportLimits(ctx context.Context, limits ImportLimits) error {
+	return c.post(ctx, "/api/setting/updateImportLimits", limits, nil)
+}
+
+// BlobStore 返回保存的文件存储配置和当前使用的存储类型，两者不同时需要重启服务器
+func (c *Client) BlobStore(ctx context.Context) (*BlobStore, string, error) {
+	var resp struct {
+		Data   BlobStore `json:"data"`
+		Active string    `json:"active"`
+	}
+	if err := c.call(ctx, http.MethodPost, c.libraryPath("/api/setting/blobStore"), nil, &resp); err != nil {
+		return nil, "", err
+	}
+	return &resp.Data, resp.Active, nil
+}
+
+// UpdateBlobStore 修改文件存储配置，重启服务器后生效。资料库中有 item 时不能更换存储位置
+func (c *Client) UpdateBlobStore(ctx context.Context, cfg BlobStore) error {
+	return c.post(ctx, "/api/setting/updateBlobStore", cfg, nil)
+}
@@ -0,0 +1,446 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// LibraryInfo 注册的资料库
+type LibraryInfo struct {
+	Name    string `json:"name"`
+	Dir     string `json:"dir"`
+	Open    bool   `json:"open"`
+	Default bool   `json:"default"`
+}
+
+// Folder 文件夹，Parent 为 uuid.Nil 时在顶层
+type Folder struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Items       []string    `json:"items"`
+	Parent      uuid.UUID   `json:"parent"`
+	SubFolders  []uuid.UUID `json:"subFolders"`
+	ModifiedAt  time.Time   `json:"modifiedAt"`
+	Tags        []string    `json:"tags"`
+	IsExpand    bool        `json:"isExpand"`
+}
+
+// Tag 标签，Parent 为 uuid.Nil 时在顶层
+type Tag struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Items       []string    `json:"items"`
+	Parent      uuid.UUID   `json:"parent"`
+	SubTags     []uuid.UUID `json:"subTags"`
+	ModifiedAt  time.Time   `json:"modifiedAt"`
+	Tags        []string    `json:"tags"`
+	IsExpand    bool        `json:"isExpand"`
+}
+
+// NodeUpdate 修改文件夹或标签，为 nil 的字段不修改
+type NodeUpdate struct {
+	Name        *string
+	Description *string
+	Icon        *uint32
+	IconColor   *uint32
+	Parent      *uuid.UUID
+}
+
+// DeleteOptions 删除文件夹或标签时的选项
+type DeleteOptions struct {
+	HardDelete  bool
+	DeleteItems bool // 同时删除其中的 item
+}
+
+// Palette 主色
+type Palette struct {
+	Color  string  `json:"color"` // #rrggbb
+	Weight float64 `json:"weight"`
+}
+
+// Location 拍摄位置
+type Location struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude"` // 海拔（米）
+}
+
+// Item 列表中的 item
+type Item struct {
+	ID         string     `json:"id"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ImportedAt time.Time  `json:"importedAt"`
+	ModifiedAt time.Time  `json:"modifiedAt"`
+	DeletedAt  *time.Time `json:"deletedAt"` // 在回收站中时为移入时间
+
+	Name string `json:"name"`
+	Ext  string `json:"ext"`
+
+	Width  uint32 `json:"width"`
+	Height uint32 `json:"height"`
+	Size   uint64 `json:"size"`
+
+	URL        string `json:"url"`
+	Annotation string `json:"annotation"`
+
+	TagIDs    []uuid.UUID `json:"tagIds"`
+	FolderIDs []uuid.UUID `json:"folderIds"`
+
+	Palettes []Palette `json:"palettes"`
+	Location *Location `json:"location"`
+	Star     uint8     `json:"star"`
+
+	HaveThumbnail bool   `json:"haveThumbnail"`
+	HavePreview   bool   `json:"havePreview"`
+	Placeholder   string `json:"placeholder"` // BlurHash 占位图
+	ImportError   string `json:"importError"`
+}
+
+// PathNode 路径上的文件夹或标签
+type PathNode struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// ItemPath item 所在的文件夹或标签，Path 从顶层到父节点
+type ItemPath struct {
+	ID   uuid.UUID  `json:"id"`
+	Name string     `json:"name"`
+	Path []PathNode `json:"path"`
+}
+
+// ItemRendition 已生成的预览图规格
+type ItemRendition struct {
+	Name      string `json:"name"`
+	Format    string `json:"format"`
+	MaxPixels int    `json:"maxPixels"`
+	URL       string `json:"url"`
+}
+
+// VectorStatus 向量的计算状态
+type VectorStatus struct {
+	Vectorized bool       `json:"vectorized"`
+	ModifiedAt *time.Time `json:"modifiedAt,omitempty"`
+}
+
+// MakeModel 设备的厂商和型号
+type MakeModel struct {
+	Make  string `json:"make,omitempty"`
+	Model string `json:"model,omitempty"`
+}
+
+// Technical 拍摄参数
+type Technical struct {
+	Camera   MakeModel `json:"camera"`
+	Lens     MakeModel `json:"lens"`
+	Exposure struct {
+		Time        string  `json:"time,omitempty"`
+		FNumber     float64 `json:"fNumber,omitempty"`
+		FocalLength float64 `json:"focalLength,omitempty"`
+		ISO         int     `json:"iso,omitempty"`
+	} `json:"exposure"`
+	ColorSpace   string `json:"colorSpace,omitempty"`
+	ColorProfile string `json:"colorProfile,omitempty"`
+	DPI          struct {
+		X float64 `json:"x,omitempty"`
+		Y float64 `json:"y,omitempty"`
+	} `json:"dpi"`
+	Software         string `json:"software,omitempty"`
+	DateTimeOriginal string `json:"dateTimeOriginal,omitempty"`
+	Orientation      int    `json:"orientation,omitempty"`
+}
+
+// Operation 图片编辑操作，Type 为 crop、resize、rotate 或 flip
+type Operation struct {
+	Type      string `json:"type"`
+	X         int    `json:"x,omitempty"`
+	Y         int    `json:"y,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	Angle     int    `json:"angle,omitempty"`     // 顺时针角度，90 的倍数
+	Direction string `json:"direction,omitempty"` // horizontal 或 vertical
+}
+
+// Derivation 编辑生成 item 时的来源与操作
+type Derivation struct {
+	SourceID   string      `json:"sourceId"`
+	Operations []Operation `json:"operations"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+// ItemDetail item 详情
+type ItemDetail struct {
+	Item
+	Folders     []ItemPath        `json:"folders"`
+	Tags        []ItemPath        `json:"tags"`
+	Renditions  []ItemRendition   `json:"renditions"`
+	Vector      VectorStatus      `json:"vector"`
+	Technical   *Technical        `json:"technical"`
+	Metadata    map[string]string `json:"metadata"`
+	DerivedFrom *Derivation       `json:"derivedFrom"`
+	Derivatives []string          `json:"derivatives"`
+}
+
+// Version item 的历史版本
+type Version struct {
+	ID         uint      `json:"id"`
+	FileID     string    `json:"fileId"`
+	Name       string    `json:"name"`
+	Ext        string    `json:"ext"`
+	Size       uint64    `json:"size"`
+	Width      uint32    `json:"width"`
+	Height     uint32    `json:"height"`
+	ReplacedAt time.Time `json:"replacedAt"`
+	URL        string    `json:"url"`
+}
+
+// BBox 经纬度矩形范围
+type BBox struct {
+	South float64 `json:"south"`
+	West  float64 `json:"west"`
+	North float64 `json:"north"`
+	East  float64 `json:"east"`
+}
+
+// Near 以某点为中心的圆形范围，Radius 单位为米
+type Near struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Radius    float64 `json:"radius"`
+}
+
+// GeoCluster 地图上聚合的 item，只有一个时 ItemID 为其 ID
+type GeoCluster struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Count     int     `json:"count"`
+	ItemID    string  `json:"itemId,omitempty"`
+}
+
+// URLImport 从 URL 导入的一个文件
+type URLImport struct {
+	URL              string            `json:"url"`
+	Name             *string           `json:"name,omitempty"`
+	Website          *string           `json:"website,omitempty"`
+	Annotation       *string           `json:"annotation,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+	ModificationTime *time.Time        `json:"modificationTime,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"` // 下载时附加的请求头
+}
+
+// AddFromURLsRequest 从 URL 导入，TagMode 为 name 时 Tags 为标签名称
+type AddFromURLsRequest struct {
+	Items     []URLImport `json:"items"`
+	TagMode   string      `json:"tag_mode,omitempty"`
+	FolderIDs []uuid.UUID `json:"folderIds,omitempty"`
+}
+
+// UpdateItemRequest 修改 item，为 nil 的字段不修改，Tags 和 Folders 非空时替换原有的全部关联
+type UpdateItemRequest struct {
+	ID         string      `json:"id"`
+	Name       *string     `json:"name,omitempty"`
+	Ext        *string     `json:"ext,omitempty"`
+	URL        *string     `json:"url,omitempty"`
+	Annotation *string     `json:"annotation,omitempty"`
+	Tags       []string    `json:"tags,omitempty"`
+	TagMode    string      `json:"tag_mode,omitempty"`
+	Folders    []uuid.UUID `json:"folders,omitempty"`
+	Star       *uint8      `json:"star,omitempty"`
+	CreatedAt  *time.Time  `json:"createdAt,omitempty"`
+}
+
+// EditItemRequest 编辑生成新 item，Format 为空时与来源相同
+type EditItemRequest struct {
+	ID         string      `json:"id"`
+	Operations []Operation `json:"operations"`
+	Name       string      `json:"name,omitempty"`
+	Format     string      `json:"format,omitempty"`
+	Quality    int         `json:"quality,omitempty"`
+}
+
+// ListItemsRequest 筛选 item，为零值的条件不限
+type ListItemsRequest struct {
+	Limit         *int        `json:"limit,omitempty"`
+	Offset        *int        `json:"offset,omitempty"`
+	OrderBy       *string     `json:"orderBy,omitempty"`
+	Exts          []string    `json:"exts,omitempty"`
+	Keyword       *string     `json:"keyword,omitempty"`
+	TagIDs        []uuid.UUID `json:"tagIds,omitempty"`
+	FolderIDs     []uuid.UUID `json:"folderIds,omitempty"`
+	IsDeleted     *bool       `json:"isDeleted,omitempty"` // 只列出回收站中的 item
+	Color         *string     `json:"color,omitempty"`     // #rrggbb
+	ColorDistance *float64    `json:"colorDistance,omitempty"`
+	BBox          *BBox       `json:"bbox,omitempty"`
+	Near          *Near       `json:"near,omitempty"`
+}
+
+// Rendition 预览图规格
+type Rendition struct {
+	Name      string `json:"name"`
+	MaxPixels int    `json:"maxPixels"`
+	Format    string `json:"format"`
+	Quality   int    `json:"quality,omitempty"`
+}
+
+// ImportLimits 导入时的解码限制，0 表示不限
+type ImportLimits struct {
+	MaxPixels   int64 `json:"maxPixels"`
+	MaxFileSize int64 `json:"maxFileSize"`
+}
+
+// BlobStore 文件存储配置
+type BlobStore struct {
+	Type          string `json:"type"` // local 或 s3
+	Redirect      bool   `json:"redirect"`
+	PresignExpiry int    `json:"presignExpiry"`
+	S3            S3     `json:"s3"`
+}
+
+// S3 S3 兼容存储的连接参数，读取时不返回 SecretKey
+type S3 struct {
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"`
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey,omitempty"`
+	PathStyle bool   `json:"pathStyle"`
+}
+
+// Job 后台任务某一时刻的状态，Status 为 running、done、failed 或 cancelled
+type Job struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Status     string          `json:"status"`
+	Total      int             `json:"total"`
+	Done       int             `json:"done"`
+	Message    string          `json:"message,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"` // 结构由任务类型决定
+	CreatedAt  time.Time       `json:"createdAt"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// StorageTotals 存储占用总计，单位为字节
+type StorageTotals struct {
+	Items          int64 `json:"items"`
+	TrashedItems   int64 `json:"trashedItems"`
+	RawBytes       int64 `json:"rawBytes"`
+	TrashedBytes   int64 `json:"trashedBytes"`
+	ThumbnailBytes int64 `json:"thumbnailBytes"`
+	PreviewBytes   int64 `json:"previewBytes"`
+	RenditionBytes int64 `json:"renditionBytes"`
+	Versions       int64 `json:"versions"`
+	VersionBytes   int64 `json:"versionBytes"`
+	TotalBytes     int64 `json:"totalBytes"`
+}
+
+// StorageGroup 按扩展名、文件夹、标签或导入时间分组的数量和原始文件大小
+type StorageGroup struct {
+	Key   string `json:"key"`
+	Name  string `json:"name,omitempty"`
+	Count int64  `json:"count"`
+	Size  int64  `json:"size"`
+}
+
+// StorageItem 按大小排序的 item
+type StorageItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Ext  string `json:"ext"`
+	Size int64  `json:"size"`
+}
+
+// StorageStats 资料库的存储统计
+type StorageStats struct {
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Interval    string         `json:"interval"`
+	Totals      StorageTotals  `json:"totals"`
+	ByExt       []StorageGroup `json:"byExt"`
+	ByFolder    []StorageGroup `json:"byFolder"`
+	ByTag       []StorageGroup `json:"byTag"`
+	Imports     []StorageGroup `json:"imports"`
+	Largest     []StorageItem  `json:"largest"`
+}
+
+// StorageStatsRequest 存储统计的选项，Interval 为 day、month（默认）或 year
+type StorageStatsRequest struct {
+	Interval string `json:"interval,omitempty"`
+	Limit    int    `json:"limit,omitempty"` // 返回的最大 item 数量，默认 20
+	Refresh  bool   `json:"refresh,omitempty"`
+}
+
+// Backup 备份归档
+type Backup struct {
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Base        string    `json:"base,omitempty"`
+	Renditions  bool      `json:"renditions"`
+	Blobs       int       `json:"blobs"`
+	Stored      int       `json:"stored"`
+	StoredBytes int64     `json:"storedBytes"`
+	Missing     int       `json:"missing"`
+	Size        int64     `json:"size"`
+	DownloadURL string    `json:"downloadUrl"`
+}
+
+// AuditQuery 审计日志的查询条件，零值字段不参与筛选
+type AuditQuery struct {
+	Actor     string     `json:"actor,omitempty"`     // 用户名或 token 指纹
+	Operation string     `json:"operation,omitempty"` // 完整的操作名，或以 . 结尾的前缀，如 item.
+	Target    string     `json:"target,omitempty"`    // 涉及的 item、文件夹或标签 ID
+	Since     *time.Time `json:"since,omitempty"`
+	Until     *time.Time `json:"until,omitempty"` // 不包含
+	Offset    int        `json:"offset,omitempty"`
+	Limit     int        `json:"limit,omitempty"` // 默认 100，最多 1000
+}
+
+// AuditEntry 审计日志的一条记录，Before 和 After 的结构由操作决定
+type AuditEntry struct {
+	ID        uint            `json:"id"`
+	Time      time.Time       `json:"time"`
+	Actor     string          `json:"actor"`
+	Token     string          `json:"token"`
+	Source    string          `json:"source"`
+	Operation string          `json:"operation"`
+	Targets   []string        `json:"targets"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+}
+
+// PackageUpload 上传的导出包
+type PackageUpload struct {
+	ID   uuid.UUID `json:"id"`
+	Size int64     `json:"size"`
+}
+
+// ImageOptions 获取指定尺寸图片的参数，为零值时使用服务器的默认值
+type ImageOptions struct {
+	Width   int
+	Height  int
+	Fit     string // contain、cover 或 crop
+	Format  string // webp、jpeg 或 png
+	Quality int
+}
+
+// Vector 计算得到的图像向量
+type Vector struct {
+	ItemID    string    `json:"item_id"`
+	ImageVec  []float32 `json:"image_vec"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"synapforest/backup"
+	"synapforest/database"
+	"synapforest/database/itemdb"
+)
+
+// 命令行子命令，执行后退出而不启动服务器
+var commands = map[string]func(args []string) error{
+	"backup":  backupCommand,
+	"restore": restoreCommand,
+	"rebuild": rebuildCommand,
+	"migrate": migrateCommand,
+}
+
+// synapforest backup [-library dir] [-o dir] [-renditions] [-incremental | -base archive]
+func backupCommand(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	library := fs.String("library", "test", "library directory")
+	out := fs.String("o", "", "backup directory, defaults to <library>/backups")
+	renditions := fs.Bool("renditions", false, "include thumbnails, previews and custom renditions")
+	incremental := fs.Bool("incremental", false, "only store files missing from the latest backup in the directory")
+	base := fs.String("base", "", "archive of the backup to use as the incremental base")
+	fs.Parse(args)
+
+	lib, err := database.Open(*library)
+	if err != nil {
+		return err
+	}
+	defer lib.Close()
+	dir := *out
+	if dir == "" {
+		dir = backup.Dir(*library)
+	}
+
+	basePath := *base
+	if basePath == "" && *incremental {
+		backups, err := backup.List(dir)
+		if err != nil {
+			return err
+		}
+		if len(backups) > 0 {
+			basePath = backup.ArchivePath(dir, backups[len(backups)-1].ID)
+		}
+	}
+
+	manifest, err := backup.Create(context.Background(), lib.DB, lib.VectorDB, lib.Blobs, dir, backup.Options{
+		Renditions: *renditions,
+		Base:       basePath,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	stored, size := manifest.Stored()
+	fmt.Printf("%s: %d files, %d stored (%d bytes)\n", backup.ArchivePath(dir, manifest.ID), len(manifest.Blobs), stored, size)
+	for _, key := range manifest.Missing {
+		fmt.Printf("missing: %s\n", key)
+	}
+	return nil
+}
+
+// synapforest restore -o dir archive.tar
+func restoreCommand(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	target := fs.String("o", "", "library directory to restore into, must not exist or be empty")
+	fs.Parse(args)
+
+	if *target == "" || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: synapforest restore -o dir archive.tar")
+		os.Exit(2)
+	}
+
+	result, err := backup.Restore(context.Background(), fs.Arg(0), *target, nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("restored %s to %s: %d files verified\n", result.ID, result.Target, result.Blobs)
+	for _, key := range result.Missing {
+		fmt.Printf("missing: %s\n", key)
+	}
+	if !result.Renditions {
+		fmt.Println("backup has no renditions, run the rendition_regenerate job after opening the library")
+	}
+	return nil
+}
+
+// synapforest rebuild [-library dir] [-o files.db] [-force] [-s3-secret-key key]
+func rebuildCommand(args []string) error {
+	fs := flag.NewFlagSet("rebuild", flag.ExitOnError)
+	library := fs.String("library", "test", "library directory, must not be opened by a running server")
+	out := fs.String("o", "", "database to write, defaults to <library>/files.db")
+	force := fs.Bool("force", false, "move an existing database aside instead of refusing")
+	secretKey := fs.String("s3-secret-key", "", "secret key of the s3 blob store, which is not saved in "+itemdb.LibraryManifestName)
+	fs.Parse(args)
+
+	output := *out
+	if output == "" {
+		output = filepath.Join(*library, "files.db")
+	}
+	if _, err := os.Stat(output); err == nil {
+		if !*force {
+			return fmt.Errorf("%s already exists, use -force to move it aside", output)
+		}
+		suffix := ".bak-" + time.Now().Format("20060102-150405")
+		for _, ext := range []string{"", "-wal", "-shm"} {
+			if _, err := os.Stat(output + ext); err != nil {
+				continue
+			}
+			if err := os.Rename(output+ext, output+ext+suffix); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("moved %s to %s\n", output, output+suffix)
+	}
+
+	result, err := itemdb.RebuildDatabase(*library, output, itemdb.RebuildOptions{S3SecretKey: *secretKey})
+	if err != nil {
+		return err
+	}
+
+	if !result.Manifest {
+		fmt.Printf("no %s, folders, tags and settings were not restored\n", itemdb.LibraryManifestName)
+	}
+	fmt.Printf("rebuilt %s: %d items, %d without metadata, %d folders, %d tags, %d recovered folders and tags\n",
+		output, result.Items+result.WithoutSidecar, result.WithoutSidecar, result.Folders, result.Tags, result.Recovered)
+	for _, failure := range result.Failed {
+		fmt.Printf("failed: %s\n", failure)
+	}
+	if result.WithoutSidecar > 0 {
+		fmt.Println("run the metadata_backfill and rendition_regenerate jobs after opening the library")
+	}
+	return nil
+}
+
+// synapforest migrate [-library dir] [-status | -dry-run]
+func migrateCommand(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	library := fs.String("library", "test", "library directory")
+	status := fs.Bool("status", false, "show the schema version and pending migrations without changing anything")
+	dryRun := fs.Bool("dry-run", false, "run pending migrations on a temporary copy of each database")
+	fs.Parse(args)
+
+	if *status {
+		statuses, err := database.MigrationStatus(*library)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			fmt.Printf("%s: version %d of %d\n", s.Database, s.Version, s.Latest)
+			for _, a := range s.Applied {
+				fmt.Printf("  applied %d %s at %s\n", a.Version, a.Name, a.AppliedAt.Format(time.RFC3339))
+			}
+			for _, m := range s.Pending {
+				fmt.Printf("  pending %d %s\n", m.Version, m.Name)
+			}
+		}
+		return nil
+	}
+
+	migrateFn := database.Migrate
+	if *dryRun {
+		migrateFn = database.DryRunMigrations
+	}
+	results, err := migrateFn(*library)
+	for _, r := range results {
+		if len(r.Applied) == 0 {
+			fmt.Printf("%s: up to date at version %d\n", r.Database, r.From)
+			continue
+		}
+		fmt.Printf("%s: migrated from version %d to %d\n", r.Database, r.From, r.To)
+		for _, m := range r.Applied {
+			fmt.Printf("  applied %d %s\n", m.Version, m.Name)
+		}
+		if r.Backup != "" {
+			fmt.Printf("  backup: %s\n", r.Backup)
+		}
+	}
+	if err == nil && *dryRun {
+		fmt.Println("dry run, no changes were made")
+	}
+	return err
+}
@@ -0,0 +1,307 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+// Package auditdb 记录资料库的每一次修改：谁、何时、做了什么、涉及哪些 item、文件夹或标签，
+// 以及修改前后的值。审计日志只追加，数据库触发器拒绝修改和删除已有的记录
+package auditdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"synapforest/database/dbcommon"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// 每次写入的目标数量，避免超出 SQLite 的参数数量限制
+const targetBatchSize = 500
+
+// 查询时默认和最多返回的记录数量
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// 记录的操作
+const (
+	FolderCreate      = "folder.create"
+	FolderUpdate      = "folder.update"
+	FolderMove        = "folder.move"
+	FolderDelete      = "folder.delete"
+	FolderAddItems    = "folder.add_items"
+	FolderRemoveItems = "folder.remove_items"
+
+	TagCreate      = "tag.create"
+	TagUpdate      = "tag.update"
+	TagMove        = "tag.move"
+	TagDelete      = "tag.delete"
+	TagAddItems    = "tag.add_items"
+	TagRemoveItems = "tag.remove_items"
+
+	ItemImport              = "item.import"
+	ItemUpdate              = "item.update"
+	ItemTrash               = "item.trash"
+	ItemDelete              = "item.delete"
+	ItemEdit                = "item.edit"
+	ItemReplaceFile         = "item.replace_file"
+	ItemRestoreVersion      = "item.restore_version"
+	ItemBackfillPalettes    = "item.backfill_palettes"
+	ItemBackfillPlaceholder = "item.backfill_placeholders"
+	ItemBackfillMetadata    = "item.backfill_metadata"
+	ItemRegenerate          = "item.regenerate_renditions"
+	ItemRepair              = "item.repair"
+
+	LibraryRebuild = "library.rebuild"
+)
+
+// Actor 发起修改的一方
+type Actor struct {
+	Name   string // 用户名，没有时为 token 指纹或调用方，如 sdk
+	Token  string // 请求使用的 token 指纹，不经过服务器时为空
+	Source string // 请求、任务或命令，如 POST /api/item/update
+}
+
+// System 没有指定发起方时记录的 Actor
+var System = Actor{Name: "system"}
+
+type actorKey struct{}
+
+// NewContext 返回关联了发起方的 context
+func NewContext(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom 返回 context 关联的发起方，没有时返回 System
+func ActorFrom(ctx context.Context) Actor {
+	if ctx != nil {
+		if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+			return actor
+		}
+	}
+	return System
+}
+
+// As 返回以 actor 名义修改的数据库连接，不继承 db 上的取消
+func As(db *gorm.DB, actor Actor) *gorm.DB {
+	ctx := context.Background()
+	if db.Statement != nil && db.Statement.Context != nil {
+		ctx = context.WithoutCancel(db.Statement.Context)
+	}
+	return db.WithContext(NewContext(ctx, actor))
+}
+
+// TokenFingerprint token 的指纹，用于在日志中区分 token 而不保存 token 本身
+func TokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:4])
+}
+
+// Change 一次修改
+type Change struct {
+	Operation string
+	Targets   []string    // 涉及的 item、文件夹或标签 ID
+	Before    interface{} // 修改前的值，nil 表示没有
+	After     interface{} // 修改后的值，nil 表示没有
+}
+
+// Record 把修改写入审计日志，发起方取自 db 的 context。
+// db 为事务时与修改一同提交或回滚
+func Record(db *gorm.DB, change Change) error {
+	before, err := marshal(change.Before)
+	if err != nil {
+		return err
+	}
+	after, err := marshal(change.After)
+	if err != nil {
+		return err
+	}
+
+	actor := ActorFrom(db.Statement.Context)
+	entry := dbcommon.AuditEntry{
+		CreatedAt: time.Now(),
+		Actor:     actor.Name,
+		Token:     actor.Token,
+		Source:    actor.Source,
+		Operation: change.Operation,
+		Before:    before,
+		After:     after,
+	}
+
+	return db.Session(&gorm.Session{NewDB: true}).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Targets").Create(&entry).Error; err != nil {
+			return fmt.Errorf("failed to write audit log: %v", err)
+		}
+
+		seen := make(map[string]bool, len(change.Targets))
+		var targets []dbcommon.AuditTarget
+		for _, target := range change.Targets {
+			if target == "" || seen[target] {
+				continue
+			}
+			seen[target] = true
+			targets = append(targets, dbcommon.AuditTarget{EntryID: entry.ID, Target: target})
+		}
+		if len(targets) > 0 {
+			if err := tx.CreateInBatches(targets, targetBatchSize).Error; err != nil {
+				return fmt.Errorf("failed to write audit log: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+func marshal(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit value: %v", err)
+	}
+	return string(data), nil
+}
+
+// UUIDs 把文件夹或标签 ID 转为审计目标
+func UUIDs(ids ...uuid.UUID) []string {
+	targets := make([]string, len(ids))
+	for i, id := range ids {
+		targets[i] = id.String()
+	}
+	return targets
+}
+
+// Node 审计日志中记录的文件夹或标签
+type Node struct {
+	ID          uuid.UUID `json:"id"`
+	Parent      uuid.UUID `json:"parent"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Icon        uint32    `json:"icon"`
+	IconColor   uint32    `json:"icon_color"`
+	IsExpand    bool      `json:"is_expand"`
+}
+
+// FolderNode 文件夹的审计值
+func FolderNode(f dbcommon.Folder) Node {
+	return Node{ID: f.ID, Parent: f.ParentID, Name: f.Name, Description: f.Description, Icon: f.Icon, IconColor: f.IconColor, IsExpand: f.IsExpand}
+}
+
+// TagNode 标签的审计值
+func TagNode(t dbcommon.Tag) Node {
+	return Node{ID: t.ID, Parent: t.ParentID, Name: t.Name, Description: t.Description, Icon: t.Icon, IconColor: t.IconColor, IsExpand: t.IsExpand}
+}
+
+// Filter 审计日志的查询条件，为空的条件不限制
+type Filter struct {
+	Actor     string
+	Operation string // 完整的操作名，或以 . 结尾的前缀，如 item.
+	Target    string // 涉及的 item、文件夹或标签 ID
+	Since     *time.Time
+	Until     *time.Time // 不包含
+	Offset    int
+	Limit     int // 默认 DefaultLimit，最多 MaxLimit
+}
+
+// Entry 查询得到的一条审计记录，Before 和 After 为 JSON，没有时为 null
+type Entry struct {
+	ID        uint            `json:"id"`
+	Time      time.Time       `json:"time"`
+	Actor     string          `json:"actor"`
+	Token     string          `json:"token"`
+	Source    string          `json:"source"`
+	Operation string          `json:"operation"`
+	Targets   []string        `json:"targets"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+}
+
+func toEntry(e dbcommon.AuditEntry) Entry {
+	entry := Entry{
+		ID:        e.ID,
+		Time:      e.CreatedAt,
+		Actor:     e.Actor,
+		Token:     e.Token,
+		Source:    e.Source,
+		Operation: e.Operation,
+		Targets:   make([]string, len(e.Targets)),
+		Before:    rawJSON(e.Before),
+		After:     rawJSON(e.After),
+	}
+	for i, t := range e.Targets {
+		entry.Targets[i] = t.Target
+	}
+	return entry
+}
+
+func rawJSON(s string) json.RawMessage {
+	if s == "" {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(s)
+}
+
+// List 按条件查询审计日志，最新的在前，同时返回符合条件的总数
+func List(db *gorm.DB, filter Filter) ([]Entry, int64, error) {
+	query := db.Model(&dbcommon.AuditEntry{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if strings.HasSuffix(filter.Operation, ".") {
+		query = query.Where("operation LIKE ? ESCAPE '\\'", escapeLike(filter.Operation)+"%")
+	} else if filter.Operation != "" {
+		query = query.Where("operation = ?", filter.Operation)
+	}
+	if filter.Target != "" {
+		query = query.Where("id IN (?)", db.Model(&dbcommon.AuditTarget{}).Select("entry_id").Where("target = ?", filter.Target))
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	offset := max(filter.Offset, 0)
+
+	var records []dbcommon.AuditEntry
+	err := query.Preload("Targets").Order("id DESC").Offset(offset).Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := make([]Entry, len(records))
+	for i, r := range records {
+		entries[i] = toEntry(r)
+	}
+	return entries, total, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -12,22 +12,14 @@
 package database
 
 import (
-	"log"
-	"os"
-	"path/filepath"
+	"synapforest/database/auditdb"
 	"synapforest/database/dbcommon"
 	"time"
 
 	"github.com/gofrs/uuid"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-var Token string = "TEST123123"
-var DB *gorm.DB
-var VectorDB *gorm.DB
-var DbBaseDir string
-
 func CreateRootFolder(db *gorm.DB) error {
 	rootFolder := dbcommon.Folder{
 		ID:         uuid.Nil, // Root 文件夹 ID 固定为 uuid.Nil
@@ -42,50 +34,6 @@ func CreateRootFolder(db *gorm.DB) error {
 	return result.Error
 }
 
-func Database_init(library_dir string) (*gorm.DB, error) {
-	var err error
-
-	DbBaseDir = library_dir
-
-	if err := os.MkdirAll(DbBaseDir, os.ModePerm); err != nil {
-		log.Fatalf("failed to create data directory: %v", err)
-	}
-
-	os.MkdirAll(filepath.Join(DbBaseDir, "raw_files"), os.ModePerm)
-	os.MkdirAll(filepath.Join(DbBaseDir, "thumbnails"), os.ModePerm)
-	os.MkdirAll(filepath.Join(DbBaseDir, "previews"), os.ModePerm)
-
-	DB, err = gorm.Open(sqlite.Open(filepath.Join(DbBaseDir, "files.db")), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
-	}
-
-	VectorDB, err = gorm.Open(sqlite.Open(filepath.Join(DbBaseDir, "vectors.db")), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
-	}
-
-	// 启用SQLite的WAL
-	DB.Exec("PRAGMA journal_mode=WAL;")
-
-	DB = DB.Debug()
-
-	VectorDB.Exec("PRAGMA journal_mode=WAL;")
-
-	VectorDB = VectorDB.Debug()
-
-	if err := DB.AutoMigrate(&dbcommon.Item{}, &dbcommon.Folder{}, &dbcommon.Tag{}); err != nil {
-		log.Fatalf("failed to migrate database: %v", err)
-	}
-
-	if err := VectorDB.AutoMigrate(&dbcommon.ItemVector{}); err != nil {
-		log.Fatalf("failed to migrate database: %v", err)
-	}
-
-	CreateRootFolder(DB)
-	return DB, err
-}
-
 // GetItemIDsByFolder 查询指定文件夹下所有图片的 ID
 func GetItemIDsByFolder(db *gorm.DB, folderID uuid.UUID) ([]string, error) {
 	var itemIDs []string
@@ -138,13 +86,28 @@ func RemoveFoldersForItems(db *gorm.DB, itemIDs []string, folderID uuid.UUID) er
 	}
 
 	return db.Transaction(func(tx *gorm.DB) error {
+		// 实际存在关联的图片
+		var removed []string
 		if err := tx.Table("item_folders").
 			Where("folder_id = ? AND item_id IN (?)", folderID, itemIDs).
+			Pluck("item_id", &removed).Error; err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+
+		if err := tx.Table("item_folders").
+			Where("folder_id = ? AND item_id IN (?)", folderID, removed).
 			Delete(nil).Error; err != nil {
 			return err
 		}
 
-		return nil
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.FolderRemoveItems,
+			Targets:   append(auditdb.UUIDs(folderID), removed...),
+			Before:    auditAssociations("folder_id", removed, []uuid.UUID{folderID}),
+		})
 	})
 }
 
@@ -175,11 +138,123 @@ func AddFolderForItems(db *gorm.DB, itemIDs []string, folderIDs []uuid.UUID) err
 				}
 			}
 		}
-		if len(records) > 0 {
-			if err := tx.Table("item_folders").Create(records).Error; err != nil {
-				return err
-			}
+		if len(records) == 0 {
+			return nil
+		}
+		if err := tx.Table("item_folders").Create(records).Error; err != nil {
+			return err
+		}
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.FolderAddItems,
+			Targets:   associationTargets(records, "folder_id"),
+			After:     associationPairs(records, "folder_id"),
+		})
+	})
+}
+
+// RemoveTagForItems 批量移除指定图片与标签的关联
+func RemoveTagForItems(db *gorm.DB, itemIDs []string, tagID uuid.UUID) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 实际存在关联的图片
+		var removed []string
+		if err := tx.Table("item_tags").
+			Where("tag_id = ? AND item_id IN (?)", tagID, itemIDs).
+			Pluck("item_id", &removed).Error; err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+
+		if err := tx.Table("item_tags").
+			Where("tag_id = ? AND item_id IN (?)", tagID, removed).
+			Delete(nil).Error; err != nil {
+			return err
 		}
+
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.TagRemoveItems,
+			Targets:   append(auditdb.UUIDs(tagID), removed...),
+			Before:    auditAssociations("tag_id", removed, []uuid.UUID{tagID}),
+		})
+	})
+}
+
+// AddTagsForItems 批量添加指定图片与多个标签的关联，已存在的关联不重复添加
+func AddTagsForItems(db *gorm.DB, itemIDs []string, tagIDs []uuid.UUID) error {
+	if len(itemIDs) == 0 || len(tagIDs) == 0 {
 		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing []struct {
+			ItemID string
+			TagID  uuid.UUID
+		}
+		if err := tx.Table("item_tags").Select("item_id, tag_id").
+			Where("item_id IN (?) AND tag_id IN (?)", itemIDs, tagIDs).
+			Scan(&existing).Error; err != nil {
+			return err
+		}
+		found := map[string]bool{}
+		for _, r := range existing {
+			found[r.ItemID+"/"+r.TagID.String()] = true
+		}
+
+		var records []map[string]interface{}
+		for _, itemID := range itemIDs {
+			for _, tagID := range tagIDs {
+				if !found[itemID+"/"+tagID.String()] {
+					records = append(records, map[string]interface{}{
+						"item_id": itemID,
+						"tag_id":  tagID,
+					})
+				}
+			}
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if err := tx.Table("item_tags").Create(records).Error; err != nil {
+			return err
+		}
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.TagAddItems,
+			Targets:   associationTargets(records, "tag_id"),
+			After:     associationPairs(records, "tag_id"),
+		})
 	})
 }
+
+// 审计日志中记录的关联，每个图片与每个文件夹或标签一条
+func auditAssociations(column string, itemIDs []string, ids []uuid.UUID) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, itemID := range itemIDs {
+		for _, id := range ids {
+			records = append(records, map[string]interface{}{"item_id": itemID, column: id})
+		}
+	}
+	return records
+}
+
+// 插入的关联记录，Create 会在 map 中写回 @id，只保留关联的两列
+func associationPairs(records []map[string]interface{}, column string) []map[string]interface{} {
+	pairs := make([]map[string]interface{}, len(records))
+	for i, r := range records {
+		pairs[i] = map[string]interface{}{"item_id": r["item_id"], column: r[column]}
+	}
+	return pairs
+}
+
+// 关联记录涉及的图片和文件夹或标签
+func associationTargets(records []map[string]interface{}, column string) []string {
+	var targets []string
+	for _, r := range records {
+		targets = append(targets, r["item_id"].(string), r[column].(uuid.UUID).String())
+	}
+	return targets
+}
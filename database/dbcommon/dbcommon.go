@@ -58,11 +58,14 @@ type Item struct {
 	Tags    []Tag    `gorm:"many2many:item_tags;"`    // Tags
 	Folders []Folder `gorm:"many2many:item_folders;"` // 文件夹ID列表
 
-	// Palettes []uint32 `json:"palettes"` // 色票（这是什么？）
-	Star uint8 `json:"star"` // 星级评分
-
-	HaveThumbnail bool `json:"have_thumbnail"` // 是否有缩略图
-	HavePreview   bool `json:"have_preview"`   // 是否有预览图
+	Palettes []ItemPalette `json:"palettes" gorm:"foreignKey:ItemID"` // 主色
+	Location *ItemLocation `json:"location" gorm:"foreignKey:ItemID"` // 拍摄位置
+	Star     uint8         `json:"star"`                              // 星级评分
+
+	HaveThumbnail bool   `json:"have_thumbnail"` // 是否有缩略图
+	HavePreview   bool   `json:"have_preview"`   // 是否有预览图
+	Placeholder   string `json:"placeholder"`    // 缩略图加载前显示的 BlurHash 占位图
+	ImportError   string `json:"import_error"`   // 导入时解码失败或超出限制的原因
 }
 
 type Folder struct {
@@ -92,3 +95,110 @@ type ItemVector struct {
 	ModifiedAt time.Time      `json:"modified_at"` // 修改时间
 	DeletedAt  gorm.DeletedAt `json:"deleted_at"`  // 删除时间
 }
+
+// ItemMetadata 以键值对形式保存导入时提取的附加信息
+type ItemMetadata struct {
+	ItemID string `json:"item_id" gorm:"primaryKey"`
+	Key    string `json:"key" gorm:"primaryKey"`
+	Value  string `json:"value"`
+}
+
+// ItemContent 保存从文件中提取的文本内容，用于全文搜索
+type ItemContent struct {
+	ItemID  string `json:"item_id" gorm:"primaryKey"`
+	Content string `json:"content"`
+}
+
+// ItemPalette item 的一个主色，同时保存 Lab 分量以便按感知距离查询
+type ItemPalette struct {
+	ID     uint    `json:"-" gorm:"primaryKey"`
+	ItemID string  `json:"item_id" gorm:"index"`
+	Color  uint32  `json:"color"`  // 0xRRGGBB
+	Weight float64 `json:"weight"` // 占比
+	L      float64 `json:"l"`
+	A      float64 `json:"a"`
+	B      float64 `json:"b"`
+}
+
+// Setting 资源库设置，值为 JSON
+type Setting struct {
+	Key   string `json:"key" gorm:"primaryKey"`
+	Value string `json:"value"`
+}
+
+// Rendition 一种预览图规格
+type Rendition struct {
+	Name      string `json:"name"`       // 规格名，thumbnail 和 preview 为内置规格
+	MaxPixels int    `json:"max_pixels"` // 总像素上限
+	Format    string `json:"format"`     // webp、jpeg 或 png
+	Quality   int    `json:"quality"`    // 有损编码质量 1-100
+}
+
+// ImportLimits 解码前检查的限制，防止解压炸弹耗尽内存，0 表示不限制
+type ImportLimits struct {
+	MaxPixels   int64 `json:"max_pixels"`    // 图片像素数上限
+	MaxFileSize int64 `json:"max_file_size"` // 需要解码的文件大小上限（字节）
+}
+
+// ItemLocation item 的拍摄位置。SQLite 没有三角函数，
+// 同时保存单位球面向量和 Web 墨卡托坐标，用于按距离筛选和按地图网格聚合
+type ItemLocation struct {
+	ItemID    string   `json:"item_id" gorm:"primaryKey"`
+	Latitude  float64  `json:"latitude" gorm:"index"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude"` // 海拔（米）
+
+	X         float64 `json:"-"`
+	Y         float64 `json:"-"`
+	Z         float64 `json:"-"`
+	MercatorX float64 `json:"-"`
+	MercatorY float64 `json:"-"`
+}
+
+// ItemDerivation 由编辑操作生成的 item 与来源 item 的关联
+type ItemDerivation struct {
+	ItemID     string    `json:"item_id" gorm:"primaryKey"`
+	SourceID   string    `json:"source_id" gorm:"index"`
+	Operations string    `json:"operations"` // 按顺序应用的操作，JSON 数组
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ItemVersion item 被替换前的文件，文件保存在 versions/<FileID>/ 中
+type ItemVersion struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ItemID     string    `json:"item_id" gorm:"index"` // 当前的 item，替换后随之更新
+	FileID     string    `json:"file_id" gorm:"index"` // 旧文件的哈希
+	Name       string    `json:"name"`
+	Ext        string    `json:"ext"`
+	Size       uint64    `json:"size"`
+	Width      uint32    `json:"width"`
+	Height     uint32    `json:"height"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// ItemRendition 已生成的预览图规格文件大小，用于统计存储占用而不必遍历文件。
+// Size 为 0 表示该规格没有文件
+type ItemRendition struct {
+	ItemID string `json:"item_id" gorm:"primaryKey"`
+	Name   string `json:"name" gorm:"primaryKey"`
+	Size   int64  `json:"size"`
+}
+
+// AuditEntry 审计日志中的一次修改，只追加，不能修改或删除
+type AuditEntry struct {
+	ID        uint          `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time     `json:"created_at" gorm:"index"`
+	Actor     string        `json:"actor" gorm:"index"`     // 发起修改的用户，没有时为 token 指纹或调用方
+	Token     string        `json:"token"`                  // 请求使用的 token 指纹，不经过服务器时为空
+	Source    string        `json:"source"`                 // 请求、任务或命令
+	Operation string        `json:"operation" gorm:"index"` // 如 item.update、folder.delete
+	Before    string        `json:"before"`                 // 修改前的值，JSON，没有时为空
+	After     string        `json:"after"`                  // 修改后的值，JSON，没有时为空
+	Targets   []AuditTarget `json:"targets" gorm:"foreignKey:EntryID"`
+}
+
+// AuditTarget 审计记录涉及的 item、文件夹或标签 ID
+type AuditTarget struct {
+	EntryID uint   `json:"entry_id" gorm:"primaryKey;autoIncrement:false"`
+	Target  string `json:"target" gorm:"primaryKey;index"`
+}
@@ -14,6 +14,7 @@ package folderdb
 import (
 	"fmt"
 	"log"
+	"synapforest/database/auditdb"
 	"synapforest/database/dbcommon"
 	"time"
 
@@ -42,7 +43,17 @@ func CreateFolder(db *gorm.DB, name *string, description string, icon uint32, ic
 			ModifiedAt:  time.Now(),
 			IsExpand:    is_expand,
 		}
-		if err := db.Create(&folder).Error; err != nil {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&folder).Error; err != nil {
+				return err
+			}
+			return auditdb.Record(tx, auditdb.Change{
+				Operation: auditdb.FolderCreate,
+				Targets:   auditdb.UUIDs(folder.ID),
+				After:     auditdb.FolderNode(folder),
+			})
+		})
+		if err != nil {
 			return nil, err
 		}
 		return &folder, nil
@@ -55,6 +66,7 @@ func UpdateFolder(db *gorm.DB, folderID uuid.UUID, name *string, description *st
 	if err := db.First(&folder, folderID).Error; err != nil {
 		return nil, err
 	}
+	before := auditdb.FolderNode(folder)
 
 	// 更新文件夹的字段（仅当参数不为 nil 时更新）
 	if name != nil {
@@ -75,7 +87,18 @@ func UpdateFolder(db *gorm.DB, folderID uuid.UUID, name *string, description *st
 	folder.ModifiedAt = time.Now() // 更新修改时间
 
 	// 保存更新后的文件夹
-	if err := db.Save(&folder).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&folder).Error; err != nil {
+			return err
+		}
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.FolderUpdate,
+			Targets:   auditdb.UUIDs(folder.ID),
+			Before:    before,
+			After:     auditdb.FolderNode(folder),
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -111,20 +134,37 @@ func UpdateFolderParents(db *gorm.DB, folderIDs []uuid.UUID, newParentID uuid.UU
 		return nil // 没有要更新的文件夹
 	}
 
-	// 批量更新
-	result := db.Model(&dbcommon.Folder{}).
-		Where("id IN ?", folderIDs).
-		Update("parent_id", newParentID)
+	return db.Transaction(func(tx *gorm.DB) error {
+		var folders []dbcommon.Folder
+		if err := tx.Where("id IN ?", folderIDs).Find(&folders).Error; err != nil {
+			return err
+		}
 
-	// 检查更新结果
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound // 没有匹配的记录
-	}
+		// 批量更新
+		result := tx.Model(&dbcommon.Folder{}).
+			Where("id IN ?", folderIDs).
+			Update("parent_id", newParentID)
+
+		// 检查更新结果
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound // 没有匹配的记录
+		}
 
-	return nil
+		// 修改前各文件夹的父文件夹
+		before := make(map[string]uuid.UUID, len(folders))
+		for _, f := range folders {
+			before[f.ID.String()] = f.ParentID
+		}
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.FolderMove,
+			Targets:   append(auditdb.UUIDs(folderIDs...), newParentID.String()),
+			Before:    before,
+			After:     map[string]uuid.UUID{"parent": newParentID},
+		})
+	})
 }
 
 // 递归获取所有子文件夹的ID
@@ -132,7 +172,8 @@ func getChildFolderIDs(db *gorm.DB, folderID uuid.UUID) ([]uuid.UUID, error) {
 	var folderIDs []uuid.UUID
 	var childFolders []dbcommon.Folder
 
-	if err := db.Where("parent_id = ?", folderID).Find(&childFolders).Error; err != nil {
+	// Root 的 ParentID 是它自己
+	if err := db.Where("parent_id = ? AND id != ?", folderID, folderID).Find(&childFolders).Error; err != nil {
 		return nil, err
 	}
 
@@ -149,43 +190,88 @@ func getChildFolderIDs(db *gorm.DB, folderID uuid.UUID) ([]uuid.UUID, error) {
 	return folderIDs, nil
 }
 
+// GetFolderTreeIDs 返回文件夹及其全部子孙文件夹的 ID
+func GetFolderTreeIDs(db *gorm.DB, folderID uuid.UUID) ([]uuid.UUID, error) {
+	return getChildFolderIDs(db, folderID)
+}
+
 // 删除文件夹及其子文件夹
 func DeleteFolder(db *gorm.DB, folderID uuid.UUID, hardDelete *bool, deleteAssociatedFiles *bool) error {
-	folderIDs, err := getChildFolderIDs(db, folderID)
-	if err != nil {
-		return err
-	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		folderIDs, err := getChildFolderIDs(tx, folderID)
+		if err != nil {
+			return err
+		}
 
-	if deleteAssociatedFiles != nil && *deleteAssociatedFiles {
+		var folders []dbcommon.Folder
+		if err := tx.Where("id IN ?", folderIDs).Find(&folders).Error; err != nil {
+			return err
+		}
 		// 获取所有关联的 item_id
 		var itemIDs []string
-		if err := db.Table("item_folders").
-			Select("item_id").
+		if err := tx.Table("item_folders").
+			Distinct("item_id").
 			Where("folder_id IN ?", folderIDs).
 			Pluck("item_id", &itemIDs).Error; err != nil {
 			return err
 		}
 
-		// 软删除关联的文件
-		if err := db.Delete(&dbcommon.Item{}, itemIDs).Error; err != nil {
-			return err
+		var after interface{}
+		if deleteAssociatedFiles != nil && *deleteAssociatedFiles {
+			// 软删除关联的文件
+			if len(itemIDs) > 0 {
+				if err := tx.Delete(&dbcommon.Item{}, itemIDs).Error; err != nil {
+					return err
+				}
+			}
+			after = map[string][]string{"trashed_items": itemIDs}
+		} else {
+			// 删除文件夹和文件的关联关系
+			if err := tx.Exec("DELETE FROM item_folders WHERE folder_id IN ?", folderIDs).Error; err != nil {
+				return err
+			}
 		}
-	} else {
-		// 删除文件夹和文件的关联关系
-		if err := db.Exec("DELETE FROM item_folders WHERE folder_id IN ?", folderIDs).Error; err != nil {
-			return err
+
+		if true { // 由于不知道软删除文件夹有什么意义，暂时忽略该项
+			if err := tx.Unscoped().Delete(&dbcommon.Folder{}, "id IN ?", folderIDs).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Delete(&dbcommon.Folder{}, "id IN ?", folderIDs).Error; err != nil {
+				return err
+			}
 		}
-	}
 
-	if true { // 由于不知道软删除文件夹有什么意义，暂时忽略该项
-		if err := db.Unscoped().Delete(&dbcommon.Folder{}, "id IN ?", folderIDs).Error; err != nil {
-			return err
+		nodes := make([]auditdb.Node, len(folders))
+		for i, f := range folders {
+			nodes[i] = auditdb.FolderNode(f)
 		}
-	} else {
-		if err := db.Delete(&dbcommon.Folder{}, "id IN ?", folderIDs).Error; err != nil {
-			return err
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.FolderDelete,
+			Targets:   append(auditdb.UUIDs(folderIDs...), itemIDs...),
+			Before:    map[string]interface{}{"folders": nodes, "items": itemIDs},
+			After:     after,
+		})
+	})
+}
+
+// GetFolderPath 返回从最顶层到指定文件夹的完整路径，不包含 Root
+func GetFolderPath(db *gorm.DB, folderID uuid.UUID) ([]dbcommon.Folder, error) {
+	var path []dbcommon.Folder
+	seen := make(map[uuid.UUID]bool)
+
+	id := folderID
+	for id != uuid.Nil && !seen[id] {
+		seen[id] = true
+
+		var folder dbcommon.Folder
+		if err := db.Unscoped().First(&folder, "id = ?", id).Error; err != nil {
+			return nil, err
 		}
+		path = append([]dbcommon.Folder{folder}, path...)
+
+		id = folder.ParentID
 	}
 
-	return nil
+	return path, nil
 }
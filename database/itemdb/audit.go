@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// itemState 审计日志中记录的 item，只包含用户可以修改的字段和组织信息
+type itemState struct {
+	Name       string      `json:"name"`
+	Ext        string      `json:"ext"`
+	Size       uint64      `json:"size"`
+	Url        string      `json:"url"`
+	Annotation string      `json:"annotation"`
+	Star       uint8       `json:"star"`
+	CreatedAt  time.Time   `json:"created_at"`
+	DeletedAt  *time.Time  `json:"deleted_at"`
+	Tags       []uuid.UUID `json:"tags"`
+	Folders    []uuid.UUID `json:"folders"`
+}
+
+// Tags 和 Folders 需要已经加载
+func stateOf(item dbcommon.Item) *itemState {
+	s := &itemState{
+		Name:       item.Name,
+		Ext:        item.Ext,
+		Size:       item.Size,
+		Url:        item.Url,
+		Annotation: item.Annotation,
+		Star:       item.Star,
+		CreatedAt:  item.CreatedAt,
+		DeletedAt:  deletedAt(item.DeletedAt),
+		Tags:       []uuid.UUID{},
+		Folders:    []uuid.UUID{},
+	}
+	for _, tag := range item.Tags {
+		s.Tags = append(s.Tags, tag.ID)
+	}
+	for _, folder := range item.Folders {
+		s.Folders = append(s.Folders, folder.ID)
+	}
+	sortUUIDs(s.Tags)
+	sortUUIDs(s.Folders)
+	return s
+}
+
+// 读取 item 当前的审计值，包括回收站中的 item，不存在的 item 不在结果中
+func loadStates(db *gorm.DB, ids []string) (map[string]*itemState, error) {
+	var items []dbcommon.Item
+	err := db.Unscoped().Preload("Tags").Preload("Folders").Where("id IN ?", ids).Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]*itemState, len(items))
+	for _, item := range items {
+		states[item.ID] = stateOf(item)
+	}
+	return states, nil
+}
+
+// 读取单个 item 的审计值，不存在时返回 nil
+func loadState(db *gorm.DB, id string) (*itemState, error) {
+	states, err := loadStates(db, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	return states[id], nil
+}
+
+// 记录对 item 的修改，应当在修改所在的事务中调用
+func recordChange(tx *gorm.DB, change auditdb.Change) error {
+	if err := auditdb.Record(tx, change); err != nil {
+		return fmt.Errorf("failed to record %s: %v", change.Operation, err)
+	}
+	return nil
+}
+
+// 记录修改后 item 的状态，before 为修改前的状态
+func recordItemChange(tx *gorm.DB, operation string, targets []string, before interface{}, id string, extra map[string]interface{}) error {
+	after, err := loadState(tx, id)
+	if err != nil {
+		return fmt.Errorf("failed to record %s: %v", operation, err)
+	}
+	var value interface{} = after
+	if extra != nil {
+		extra["item"] = after
+		value = extra
+	}
+	return recordChange(tx, auditdb.Change{Operation: operation, Targets: targets, Before: before, After: value})
+}
+
+// 批量维护任务结束时记录修改过的 item，中途取消或出错时记录已经完成的部分。
+// 各 item 的修改已经分别提交，记录失败时只写日志
+func recordBatch(db *gorm.DB, operation string, itemIDs []string) {
+	if len(itemIDs) == 0 {
+		return
+	}
+	err := recordChange(db, auditdb.Change{
+		Operation: operation,
+		Targets:   itemIDs,
+		After:     map[string]int{"count": len(itemIDs)},
+	})
+	if err != nil {
+		log.Print(err)
+	}
+}
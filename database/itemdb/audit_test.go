@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"errors"
+	"testing"
+
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+
+	"gorm.io/gorm"
+)
+
+func TestImportRecordsAudit(t *testing.T) {
+	lib := openLibrary(t)
+	id := importFile(t, lib, writePNG(t, t.TempDir(), "a.png", 4, 4, 1))
+
+	entries, _, err := auditdb.List(lib.DB, auditdb.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Operation == auditdb.ItemImport && len(e.Targets) == 1 && e.Targets[0] == id {
+			return
+		}
+	}
+	t.Errorf("no import entry for %s in %+v", id, entries)
+}
+
+// 审计日志写入失败时导入失败，并且不留下记录和文件
+func TestImportFailsWithoutAudit(t *testing.T) {
+	lib := openLibrary(t)
+	if err := lib.DB.Migrator().DropTable(&dbcommon.AuditEntry{}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := writePNG(t, t.TempDir(), "a.png", 4, 4, 1)
+	id, err := CalculateFileID(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AddItem(lib.DB, path, nil, nil, nil, nil, nil, nil, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := lib.DB.Unscoped().First(&dbcommon.Item{}, "id = ?", id).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("item kept: %v", err)
+	}
+	if kept, _ := blobs(lib.DB).List(RawDir(id)); len(kept) != 0 {
+		t.Errorf("files kept: %v", kept)
+	}
+}
+
+func TestReplaceFailsWithoutAudit(t *testing.T) {
+	lib := openLibrary(t)
+	dir := t.TempDir()
+	oldID := importFile(t, lib, writePNG(t, dir, "a.png", 4, 4, 1))
+	if err := lib.DB.Migrator().DropTable(&dbcommon.AuditEntry{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReplaceItemFile(lib.DB, oldID, writePNG(t, dir, "b.png", 4, 4, 2)); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := lib.DB.First(&dbcommon.Item{}, "id = ?", oldID).Error; err != nil {
+		t.Errorf("old item lost: %v", err)
+	}
+	if !blobExists(t, lib.DB, RawFileKey(oldID, "a", "png")) {
+		t.Error("old raw file not restored")
+	}
+}
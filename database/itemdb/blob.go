@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"fmt"
+	"image"
+	"path"
+
+	"synapforest/blobstore"
+	"synapforest/database"
+
+	"gorm.io/gorm"
+)
+
+// 数据库所属资料库的文件存储
+func blobs(db *gorm.DB) blobstore.Store {
+	return database.LibraryOf(db).Blobs
+}
+
+// RawFileKey item 原始文件在存储中的键
+func RawFileKey(itemID string, name string, ext string) string {
+	return path.Join(RawDir(itemID), name+"."+ext)
+}
+
+// RawDir item 原始文件所在的目录
+func RawDir(itemID string) string {
+	return path.Join("raw_files", itemID)
+}
+
+// 读取并解码存储中 item 的某个规格
+func decodeBlob(db *gorm.DB, rendition string, itemID string) (img image.Image, err error) {
+	defer recoverDecoder(&err)
+
+	key, err := RenditionFile(db, rendition, itemID)
+	if err != nil {
+		return nil, err
+	}
+	r, err := blobs(db).Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("open %s failed: %v", key, err)
+	}
+	defer r.Close()
+
+	img, _, err = image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("decode image failed: %v", err)
+	}
+	return img, nil
+}
+
+// 在本地路径上执行只能按路径读取文件的操作，非本地存储会先下载到临时文件
+func withRawFile(db *gorm.DB, itemID string, name string, ext string, fn func(path string) error) error {
+	filePath, cleanup, err := blobstore.Fetch(blobs(db), RawFileKey(itemID, name, ext))
+	if err != nil {
+		return fmt.Errorf("raw file of %s is not available: %v", itemID, err)
+	}
+	defer cleanup()
+	return fn(filePath)
+}
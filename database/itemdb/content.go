@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"errors"
+	"fmt"
+
+	"synapforest/database/dbcommon"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SetItemContent 保存 item 的文本内容，已存在时覆盖
+func SetItemContent(db *gorm.DB, itemID string, content string) error {
+	record := dbcommon.ItemContent{ItemID: itemID, Content: content}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "item_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content"}),
+	}).Create(&record).Error
+}
+
+// GetItemContent 查询 item 的文本内容，没有内容时返回空字符串
+func GetItemContent(db *gorm.DB, itemID string) (string, error) {
+	var record dbcommon.ItemContent
+	err := db.First(&record, "item_id = ?", itemID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query item content: %v", err)
+	}
+	return record.Content, nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 导入的文本文件内容保存到 item_contents，关键字搜索同时匹配名称和内容
+func TestKeywordSearchMatchesContent(t *testing.T) {
+	lib := openLibrary(t)
+	dir := t.TempDir()
+
+	textPath := filepath.Join(dir, "notes.go")
+	if err := os.WriteFile(textPath, []byte("package notes\r\n\r\n// zebra crossing\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	textID := importFile(t, lib, textPath)
+	importFile(t, lib, writePNG(t, dir, "zebra.png", 4, 4, 1))
+	importFile(t, lib, writePNG(t, dir, "other.png", 4, 4, 2))
+
+	content, err := GetItemContent(lib.DB, textID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "package notes\n\n// zebra crossing\n" {
+		t.Errorf("content = %q", content)
+	}
+
+	search := func(keyword string) []string {
+		t.Helper()
+		items, err := ItemList(lib.DB, nil, nil, nil, nil, nil, &keyword, nil, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name+"."+item.Ext)
+		}
+		return names
+	}
+
+	if names := search("crossing"); len(names) != 1 || names[0] != "notes.go" {
+		t.Errorf("crossing = %v", names)
+	}
+	if names := search("zebra"); len(names) != 2 {
+		t.Errorf("zebra = %v, want notes.go and zebra.png", names)
+	}
+	if names := search("giraffe"); len(names) != 0 {
+		t.Errorf("giraffe = %v", names)
+	}
+
+	// 覆盖后按新内容搜索
+	if err := SetItemContent(lib.DB, textID, "giraffe"); err != nil {
+		t.Fatal(err)
+	}
+	if names := search("giraffe"); len(names) != 1 || names[0] != "notes.go" {
+		t.Errorf("giraffe after update = %v", names)
+	}
+	if names := search("crossing"); len(names) != 0 {
+		t.Errorf("crossing after update = %v", names)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"synapforest/database"
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+	"synapforest/imageedit"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// 编辑结果默认的编码质量
+const DefaultEditQuality = 90
+
+// EditOptions 编辑结果的名称和编码方式，为空时使用默认值
+type EditOptions struct {
+	Name    string // 默认为来源名称加 "-edited"
+	Format  string // webp、jpeg 或 png，默认与来源相同，无法编码的格式使用 png
+	Quality int
+}
+
+// 与来源扩展名对应的编码格式
+func editFormat(ext string) string {
+	switch strings.ToLower(ext) {
+	case "jpg", "jpeg":
+		return "jpeg"
+	case "webp":
+		return "webp"
+	default:
+		return "png"
+	}
+}
+
+// EditItem 对 item 的原始文件依次应用编辑操作，结果作为新 item 导入，
+// 继承来源的标签和文件夹，并记录来源与操作。返回新 item 的 ID
+func EditItem(db *gorm.DB, sourceID string, ops []imageedit.Operation, opts EditOptions) (string, error) {
+	if len(ops) == 0 {
+		return "", fmt.Errorf("no operations given")
+	}
+	for i, op := range ops {
+		if err := op.Validate(); err != nil {
+			return "", fmt.Errorf("operation %d: %v", i, err)
+		}
+	}
+
+	format := opts.Format
+	if format != "" && format != "webp" && format != "jpeg" && format != "png" {
+		return "", fmt.Errorf("unsupported format %q", format)
+	}
+	quality := opts.Quality
+	if quality == 0 {
+		quality = DefaultEditQuality
+	}
+	if quality < 1 || quality > 100 {
+		return "", fmt.Errorf("quality must be between 1 and 100")
+	}
+
+	var source dbcommon.Item
+	err := db.Preload("Tags").Preload("Folders").First(&source, "id = ?", sourceID).Error
+	if err != nil {
+		return "", err
+	}
+	if !isImageExt("." + source.Ext) {
+		return "", fmt.Errorf("item %s is not an image", sourceID)
+	}
+
+	limits, err := settingdb.GetImportLimits(db)
+	if err != nil {
+		return "", err
+	}
+	// 按记录的尺寸先检查结果大小，避免解码和缩放时分配过多内存
+	if source.Width > 0 && source.Height > 0 {
+		if _, _, err := imageedit.Validate(int(source.Width), int(source.Height), ops, limits.MaxPixels); err != nil {
+			return "", err
+		}
+	}
+	var img image.Image
+	err = withRawFile(db, source.ID, source.Name, source.Ext, func(path string) error {
+		img, err = DecodeImage(path, limits)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	img, err = imageedit.Apply(img, ops, limits.MaxPixels)
+	if err != nil {
+		return "", err
+	}
+
+	if format == "" {
+		format = editFormat(source.Ext)
+	}
+	ext := format
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+
+	stagingDir := filepath.Join(database.LibraryOf(db).Dir, "staging")
+	if err := os.MkdirAll(stagingDir, os.ModePerm); err != nil {
+		return "", err
+	}
+	out, err := os.CreateTemp(stagingDir, "edit-*."+ext)
+	if err != nil {
+		return "", err
+	}
+	outPath := out.Name()
+	defer os.Remove(outPath)
+
+	err = EncodeImage(out, img, format, quality)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result: %v", err)
+	}
+
+	fileID, err := CalculateFileID(outPath)
+	if err != nil {
+		return "", err
+	}
+	// 导入相同内容会按重复文件合并，这里会导致来源被改名
+	if fileID == source.ID {
+		return "", fmt.Errorf("operations produce a file identical to the source")
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = source.Name + "-edited"
+	}
+	var tags []uuid.UUID
+	for _, tag := range source.Tags {
+		tags = append(tags, tag.ID)
+	}
+	var folders []uuid.UUID
+	for _, folder := range source.Folders {
+		folders = append(folders, folder.ID)
+	}
+
+	opsJSON, err := json.Marshal(ops)
+	if err != nil {
+		return "", err
+	}
+	_, _, err = addItem(db, ImportEntry{Path: outPath, Name: &name, Tags: tags, Folders: folders}, func(tx *gorm.DB, id string, before *itemState) error {
+		// 结果与已有的其他 item 内容相同时只合并，不为其记录来源
+		if before == nil {
+			derivation := dbcommon.ItemDerivation{
+				ItemID:     id,
+				SourceID:   source.ID,
+				Operations: string(opsJSON),
+				CreatedAt:  time.Now(),
+			}
+			if err := tx.Save(&derivation).Error; err != nil {
+				return fmt.Errorf("failed to save derivation: %v", err)
+			}
+		}
+		return recordItemChange(tx, auditdb.ItemEdit, []string{id, source.ID}, before, id, map[string]interface{}{
+			"source_id":  source.ID,
+			"operations": ops,
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	return fileID, nil
+}
+
+// GetDerivation 返回 item 的来源与编辑操作，不是编辑生成的 item 时返回 nil
+func GetDerivation(db *gorm.DB, itemID string) (*dbcommon.ItemDerivation, []imageedit.Operation, error) {
+	var derivation dbcommon.ItemDerivation
+	err := db.First(&derivation, "item_id = ?", itemID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	var ops []imageedit.Operation
+	if err := json.Unmarshal([]byte(derivation.Operations), &ops); err != nil {
+		return nil, nil, fmt.Errorf("invalid operations of %s: %v", itemID, err)
+	}
+	return &derivation, ops, nil
+}
+
+// GetDerivatives 返回由 item 编辑生成的 item ID
+func GetDerivatives(db *gorm.DB, sourceID string) ([]string, error) {
+	var ids []string
+	err := db.Model(&dbcommon.ItemDerivation{}).Where("source_id = ?", sourceID).Order("created_at").Pluck("item_id", &ids).Error
+	return ids, err
+}
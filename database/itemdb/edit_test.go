@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"testing"
+
+	"synapforest/database/dbcommon"
+	"synapforest/imageedit"
+)
+
+func TestEditItem(t *testing.T) {
+	lib := openLibrary(t)
+	source := importFile(t, lib, writePNG(t, t.TempDir(), "a.png", 4, 2, 1))
+
+	ops := []imageedit.Operation{{Type: imageedit.Rotate, Angle: 90}}
+	id, err := EditItem(lib.DB, source, ops, EditOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item dbcommon.Item
+	if err := lib.DB.First(&item, "id = ?", id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if item.Width != 2 || item.Height != 4 || item.Name != "a-edited" {
+		t.Errorf("got %dx%d %q", item.Width, item.Height, item.Name)
+	}
+
+	derivation, got, err := GetDerivation(lib.DB, id)
+	if err != nil || derivation == nil {
+		t.Fatalf("derivation: %v %v", derivation, err)
+	}
+	if derivation.SourceID != source || len(got) != 1 || got[0].Angle != 90 {
+		t.Errorf("got derivation %+v %+v", derivation, got)
+	}
+}
+
+// 编辑结果与已有的 item 相同时不为其记录来源
+func TestEditItemDuplicateKeepsDerivation(t *testing.T) {
+	lib := openLibrary(t)
+	source := importFile(t, lib, writePNG(t, t.TempDir(), "a.png", 4, 2, 1))
+
+	id, err := EditItem(lib.DB, source, []imageedit.Operation{{Type: imageedit.Rotate, Angle: 90}}, EditOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 让结果变成与来源无关的 item
+	if err := lib.DB.Where("item_id = ?", id).Delete(&dbcommon.ItemDerivation{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 180 + 270 度与 90 度的结果相同
+	ops := []imageedit.Operation{{Type: imageedit.Rotate, Angle: 180}, {Type: imageedit.Rotate, Angle: 270}}
+	dup, err := EditItem(lib.DB, source, ops, EditOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dup != id {
+		t.Fatalf("expected duplicate of %s, got %s", id, dup)
+	}
+	if derivation, _, err := GetDerivation(lib.DB, id); err != nil || derivation != nil {
+		t.Errorf("got derivation %+v %v", derivation, err)
+	}
+}
+
+func TestEditItemRejectsLargeResult(t *testing.T) {
+	lib := openLibrary(t)
+	source := importFile(t, lib, writePNG(t, t.TempDir(), "strip.png", 1, 16, 1))
+
+	// 按比例缩放后高度为 131072，超过最大边长
+	ops := []imageedit.Operation{{Type: imageedit.Resize, Width: 8192}}
+	if _, err := EditItem(lib.DB, source, ops, EditOptions{}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	ops = []imageedit.Operation{{Type: imageedit.Resize, Width: 16384, Height: 16384}}
+	if _, err := EditItem(lib.DB, source, ops, EditOptions{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
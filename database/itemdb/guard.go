@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"runtime/debug"
+
+	"synapforest/database/dbcommon"
+)
+
+// CheckImage 在完整解码之前读取图像头，检查文件大小和像素数是否超出限制
+func CheckImage(path string, limits dbcommon.ImportLimits) (cfg image.Config, err error) {
+	defer recoverDecoder(&err)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, fmt.Errorf("open image failed: %v", err)
+	}
+	defer file.Close()
+
+	if limits.MaxFileSize > 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return image.Config{}, err
+		}
+		if info.Size() > limits.MaxFileSize {
+			return image.Config{}, fmt.Errorf("file size %d bytes exceeds the limit of %d bytes", info.Size(), limits.MaxFileSize)
+		}
+	}
+
+	cfg, _, err = image.DecodeConfig(file)
+	if err != nil {
+		return image.Config{}, fmt.Errorf("read image header failed: %v", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return cfg, fmt.Errorf("invalid image size %dx%d", cfg.Width, cfg.Height)
+	}
+
+	pixels := int64(cfg.Width) * int64(cfg.Height)
+	if limits.MaxPixels > 0 && pixels > limits.MaxPixels {
+		return cfg, fmt.Errorf("image is %dx%d (%d pixels), exceeds the limit of %d pixels", cfg.Width, cfg.Height, pixels, limits.MaxPixels)
+	}
+	return cfg, nil
+}
+
+// DecodeImage 检查限制后再完整解码
+func DecodeImage(path string, limits dbcommon.ImportLimits) (image.Image, error) {
+	if _, err := CheckImage(path, limits); err != nil {
+		return nil, err
+	}
+	return decodeImageFile(path)
+}
+
+// 将解码器中的 panic 转为错误，恶意构造的文件不应让服务崩溃
+func recoverDecoder(err *error) {
+	if r := recover(); r != nil {
+		log.Printf("decoder panic: %v\n%s", r, debug.Stack())
+		*err = fmt.Errorf("decoder panic: %v", r)
+	}
+}
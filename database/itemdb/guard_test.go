@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"synapforest/database/dbcommon"
+)
+
+// 写出只有 IHDR 的 PNG，声明的尺寸可以任意大
+func writePNGHeader(t *testing.T, w, h uint32) string {
+	t.Helper()
+	ihdr := binary.BigEndian.AppendUint32(nil, w)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, h)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+
+	var b bytes.Buffer
+	b.WriteString("\x89PNG\r\n\x1a\n")
+	b.Write(binary.BigEndian.AppendUint32(nil, uint32(len(ihdr))))
+	chunk := append([]byte("IHDR"), ihdr...)
+	b.Write(chunk)
+	b.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(chunk)))
+
+	path := filepath.Join(t.TempDir(), "header.png")
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckImage(t *testing.T) {
+	path := writePNG(t, t.TempDir(), "a.png", 4, 3, 1)
+	cfg, err := CheckImage(path, dbcommon.ImportLimits{MaxPixels: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 4 || cfg.Height != 3 {
+		t.Errorf("size = %dx%d", cfg.Width, cfg.Height)
+	}
+
+	if _, err := CheckImage(path, dbcommon.ImportLimits{MaxPixels: 11}); err == nil || !strings.Contains(err.Error(), "pixels") {
+		t.Errorf("pixel limit: %v", err)
+	}
+	if _, err := CheckImage(path, dbcommon.ImportLimits{MaxFileSize: 10}); err == nil || !strings.Contains(err.Error(), "file size") {
+		t.Errorf("size limit: %v", err)
+	}
+}
+
+// 头部声明的尺寸超出限制时不解码
+func TestDecodeImageRejectsHugeHeader(t *testing.T) {
+	path := writePNGHeader(t, 100000, 100000)
+	if _, err := DecodeImage(path, dbcommon.ImportLimits{MaxPixels: 100_000_000}); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := CheckImage(writePNGHeader(t, 0, 10), dbcommon.ImportLimits{}); err == nil {
+		t.Error("expected error for empty image")
+	}
+}
+
+// 超出文件大小限制时仍然保留已经读取的技术信息
+func TestDeriveItemKeepsMetadataOverSizeLimit(t *testing.T) {
+	lib := openLibrary(t)
+	src, err := os.ReadFile(writePNG(t, t.TempDir(), "a.png", 4, 4, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 在 IHDR 之后插入 sRGB 块
+	const ihdrEnd = 8 + 25
+	srgb := []byte{0, 0, 0, 1, 's', 'R', 'G', 'B', 0}
+	srgb = binary.BigEndian.AppendUint32(srgb, crc32.ChecksumIEEE(srgb[4:]))
+	data := append(append(append([]byte{}, src[:ihdrEnd]...), srgb...), src[ihdrEnd:]...)
+	path := filepath.Join(t.TempDir(), "b.png")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	item := dbcommon.Item{ID: "x", Size: uint64(len(data))}
+	metadata, _, err := deriveItem(lib.DB, &item, path, ".png", dbcommon.ImportLimits{MaxFileSize: 10})
+	if err == nil {
+		t.Fatal("expected size error")
+	}
+	if metadata["image.color_space"] != "sRGB" {
+		t.Errorf("metadata = %v", metadata)
+	}
+	if item.HaveThumbnail {
+		t.Error("thumbnail generated for oversized file")
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"synapforest/database"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// 未设置 GOMEMLIMIT 时，批量导入同时占用的内存预算
+const defaultImportMemoryBudget = 1 << 30
+
+// 渲染模型、文本等生成的源图像大小
+const renderedSourceBytes = 768 * 768 * 4
+
+// ImportEntry 批量导入中的一个文件，字段含义同 AddItem 的参数
+type ImportEntry struct {
+	Path       string
+	Name       *string
+	Url        *string
+	Annotation *string
+	Tags       []uuid.UUID
+	Folders    []uuid.UUID
+	Star       *uint8
+	CreatedAt  *time.Time
+	KeepSource bool // 导入后保留 Path 处的文件，默认删除
+}
+
+// AddItems 并发导入一批文件，返回与 entries 一一对应的错误
+func AddItems(db *gorm.DB, entries []ImportEntry) []error {
+	_, errs := ImportItems(db, entries)
+	return errs
+}
+
+// ImportItems 并发导入一批文件，返回与 entries 一一对应的 item ID 和错误。
+// 同时处理的数量不超过 CPU 核数，且估算的解码内存总和不超过预算
+func ImportItems(db *gorm.DB, entries []ImportEntry) ([]string, []error) {
+	ids := make([]string, len(entries))
+	errs := make([]error, len(entries))
+	limiter := newMemoryLimiter(importMemoryBudget())
+	workers := make(chan struct{}, runtime.NumCPU())
+
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		cost := estimateImportMemory(entry.Path)
+		workers <- struct{}{}
+		limiter.acquire(cost)
+
+		wg.Add(1)
+		go func(i int, entry ImportEntry) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			defer limiter.release(cost)
+
+			ids[i], errs[i] = importItem(db, entry)
+		}(i, entry)
+	}
+	wg.Wait()
+
+	return ids, errs
+}
+
+// 设置了 GOMEMLIMIT 时使用其一半，否则使用默认预算
+func importMemoryBudget() int64 {
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		return limit / 2
+	}
+	return defaultImportMemoryBudget
+}
+
+// 估算导入一个文件时的峰值内存：图片按解码后的像素和缩放缓冲计算，其余按文件大小加渲染图计算
+func estimateImportMemory(path string) int64 {
+	if isImageExt(filepath.Ext(path)) {
+		if file, err := os.Open(path); err == nil {
+			cfg, _, err := image.DecodeConfig(file)
+			file.Close()
+			if err == nil {
+				return int64(cfg.Width) * int64(cfg.Height) * 4 * 2
+			}
+		}
+	}
+
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	return size + renderedSourceBytes
+}
+
+// memoryLimiter 按权重限制并发，单个超过预算的任务在没有其他任务时也允许执行
+type memoryLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	budget int64
+	used   int64
+}
+
+func newMemoryLimiter(budget int64) *memoryLimiter {
+	l := &memoryLimiter{budget: budget}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *memoryLimiter) acquire(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.used > 0 && l.used+n > l.budget {
+		l.cond.Wait()
+	}
+	l.used += n
+}
+
+func (l *memoryLimiter) release(n int64) {
+	l.mu.Lock()
+	l.used -= n
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// 将文件复制到资源库的暂存目录，同时计算 SHA256，返回暂存路径和文件 ID
+func stageFile(db *gorm.DB, path string) (string, string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer src.Close()
+
+	stagingDir := filepath.Join(database.LibraryOf(db).Dir, "staging")
+	if err := os.MkdirAll(stagingDir, os.ModePerm); err != nil {
+		return "", "", err
+	}
+
+	// 保留扩展名，导入时部分解码器按扩展名判断格式
+	dst, err := os.CreateTemp(stagingDir, "import-*"+filepath.Ext(path))
+	if err != nil {
+		return "", "", err
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(dst, hash), src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", "", err
+	}
+
+	return dst.Name(), hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+const benchmarkBatchSize = 16
+
+// 每次迭代使用新的资料库，导入 benchmarkBatchSize 个内容不同的 512x512 PNG
+func benchmarkImport(b *testing.B, importBatch func(db *gorm.DB, entries []ImportEntry) []error) {
+	dir := b.TempDir()
+	var entries []ImportEntry
+	for i := 0; i < benchmarkBatchSize; i++ {
+		path := writePNG(b, dir, fmt.Sprintf("%d.png", i), 512, 512, uint8(i))
+		entries = append(entries, ImportEntry{Path: path, KeepSource: true})
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		lib := openLibrary(b)
+		b.StartTimer()
+
+		for i, err := range importBatch(lib.DB, entries) {
+			if err != nil {
+				b.Fatalf("%s: %v", entries[i].Path, err)
+			}
+		}
+
+		b.StopTimer()
+		lib.Close()
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(b.Elapsed().Microseconds())/1000/float64(b.N*benchmarkBatchSize), "ms/file")
+}
+
+func BenchmarkAddItems(b *testing.B) {
+	benchmarkImport(b, AddItems)
+}
+
+// 逐个导入，作为 AddItems 的对照
+func BenchmarkAddItemsSequential(b *testing.B) {
+	benchmarkImport(b, func(db *gorm.DB, entries []ImportEntry) []error {
+		errs := make([]error, len(entries))
+		for i, entry := range entries {
+			_, errs[i] = importItem(db, entry)
+		}
+		return errs
+	})
+}
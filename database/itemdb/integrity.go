@@ -0,0 +1,381 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"synapforest/blobstore"
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+
+	"gorm.io/gorm"
+)
+
+// 完整性检查发现的问题类型
+const (
+	IssueMissingRawFile  = "missing_raw_file"  // item 的原始文件不存在
+	IssueMisnamedRawFile = "misnamed_raw_file" // 原始文件存在但文件名与 item 不一致
+	IssueHashMismatch    = "hash_mismatch"     // 原始文件的 SHA256 与 item ID 不一致
+	IssueOrphanRawFile   = "orphan_raw_file"   // 没有对应 item 的原始文件
+	IssueOrphanRendition = "orphan_rendition"  // 没有对应 item 的预览图
+	IssueOrphanVersion   = "orphan_version"    // 没有对应版本记录的历史版本文件
+	IssueRenditionFlag   = "rendition_flag"    // HaveThumbnail/HavePreview 与实际文件不一致
+)
+
+// QuarantineDir 修复时隔离的文件保存在该目录下，按检查时间分组并保留原来的键
+const QuarantineDir = "quarantine"
+
+// IntegrityIssue 一个不一致的问题
+type IntegrityIssue struct {
+	Kind     string `json:"kind"`
+	ItemID   string `json:"itemId,omitempty"`
+	Key      string `json:"key,omitempty"` // 相关文件在存储中的键
+	Detail   string `json:"detail"`
+	Action   string `json:"action,omitempty"` // 修复时执行的操作
+	Repaired bool   `json:"repaired"`
+	Error    string `json:"error,omitempty"` // 修复失败的原因
+}
+
+// IntegrityReport 完整性检查的结果
+type IntegrityReport struct {
+	Repair     bool             `json:"repair"`
+	VerifyHash bool             `json:"verifyHash"`
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	Items      int              `json:"items"`      // 检查的 item 数量
+	Files      int              `json:"files"`      // 检查的文件数量
+	Quarantine string           `json:"quarantine"` // 本次隔离文件的目录
+	Summary    map[string]int   `json:"summary"`    // 各类问题的数量
+	Repaired   int              `json:"repaired"`
+	Issues     []IntegrityIssue `json:"issues"`
+}
+
+// IntegrityOptions 完整性检查的选项
+type IntegrityOptions struct {
+	Repair     bool // 修复发现的问题
+	VerifyHash bool // 读取全部原始文件校验 SHA256
+}
+
+func (r *IntegrityReport) add(issue IntegrityIssue) *IntegrityIssue {
+	r.Summary[issue.Kind]++
+	r.Issues = append(r.Issues, issue)
+	return &r.Issues[len(r.Issues)-1]
+}
+
+// 记录修复结果
+func (r *IntegrityReport) resolve(issue *IntegrityIssue, action string, err error) {
+	issue.Action = action
+	if err != nil {
+		issue.Error = err.Error()
+		return
+	}
+	issue.Repaired = true
+	r.Repaired++
+}
+
+// CheckIntegrity 检查数据库与存储中的原始文件、预览图和历史版本是否一致。
+// 修复模式下重新生成预览图、把改名不同步的原始文件重新关联到 item，
+// 并把无法归属或内容不符的文件移入隔离目录；缺失的原始文件无法自动修复
+func CheckIntegrity(ctx context.Context, db *gorm.DB, opts IntegrityOptions, progress func(done int, total int)) (*IntegrityReport, error) {
+	report := &IntegrityReport{
+		Repair:     opts.Repair,
+		VerifyHash: opts.VerifyHash,
+		StartedAt:  time.Now(),
+		Summary:    map[string]int{},
+		Issues:     []IntegrityIssue{},
+	}
+	report.Quarantine = path.Join(QuarantineDir, report.StartedAt.Format("20060102-150405"))
+	if opts.Repair {
+		defer recordRepairs(db, report)
+	}
+
+	var items []dbcommon.Item
+	if err := db.Unscoped().Select("id", "name", "ext", "have_thumbnail", "have_preview").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to query items: %v", err)
+	}
+	report.Items = len(items)
+	known := make(map[string]bool, len(items))
+	for _, item := range items {
+		known[item.ID] = true
+	}
+
+	// 按 item 分组的原始文件
+	rawKeys, err := blobs(db).List("raw_files")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list raw files: %v", err)
+	}
+	rawFiles := map[string][]string{}
+	for _, key := range rawKeys {
+		id := path.Base(path.Dir(key))
+		// item 的元数据文件不是原始文件，没有对应 item 时随目录一起隔离
+		if key == SidecarKey(id) && known[id] {
+			continue
+		}
+		rawFiles[id] = append(rawFiles[id], key)
+		report.Files++
+	}
+
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress(i, len(items))
+
+		if err := checkItem(db, report, item, rawFiles[item.ID], opts); err != nil {
+			return nil, err
+		}
+	}
+	progress(len(items), len(items))
+
+	// 没有对应 item 的原始文件，按目录隔离
+	for id, keys := range rawFiles {
+		if known[id] {
+			continue
+		}
+		for _, key := range keys {
+			issue := report.add(IntegrityIssue{Kind: IssueOrphanRawFile, Key: key, Detail: "no item with id " + id})
+			if opts.Repair {
+				report.resolve(issue, "quarantine", quarantine(db, report, key))
+			}
+		}
+		if opts.Repair {
+			blobs(db).DeleteDir(RawDir(id))
+		}
+	}
+
+	if err := checkRenditionFiles(db, report, known, opts); err != nil {
+		return nil, err
+	}
+	if err := checkVersionFiles(db, report, opts); err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+func checkItem(db *gorm.DB, report *IntegrityReport, item dbcommon.Item, keys []string, opts IntegrityOptions) error {
+	expected := RawFileKey(item.ID, item.Name, item.Ext)
+
+	found := false
+	var others []string
+	for _, key := range keys {
+		if key == expected {
+			found = true
+		} else {
+			others = append(others, key)
+		}
+	}
+
+	switch {
+	case !found && len(others) == 1:
+		// 改名没有完成，目录中只剩一个文件时可以确定就是它
+		issue := report.add(IntegrityIssue{
+			Kind:   IssueMisnamedRawFile,
+			ItemID: item.ID,
+			Key:    others[0],
+			Detail: "expected " + expected,
+		})
+		if opts.Repair {
+			err := blobs(db).Move(others[0], expected)
+			report.resolve(issue, "relink", err)
+			found = err == nil
+		}
+		others = nil
+	case !found:
+		report.add(IntegrityIssue{
+			Kind:   IssueMissingRawFile,
+			ItemID: item.ID,
+			Key:    expected,
+			Detail: "raw file is missing and cannot be restored automatically",
+		})
+	}
+
+	// 目录中无法确定归属的其他文件
+	for _, key := range others {
+		issue := report.add(IntegrityIssue{Kind: IssueOrphanRawFile, ItemID: item.ID, Key: key, Detail: "unexpected file in item directory"})
+		if opts.Repair {
+			report.resolve(issue, "quarantine", quarantine(db, report, key))
+		}
+	}
+
+	if found && opts.VerifyHash {
+		sum, err := hashBlob(db, expected)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", expected, err)
+		}
+		if sum != item.ID {
+			issue := report.add(IntegrityIssue{
+				Kind:   IssueHashMismatch,
+				ItemID: item.ID,
+				Key:    expected,
+				Detail: "sha256 is " + sum,
+			})
+			if opts.Repair {
+				err := quarantine(db, report, expected)
+				report.resolve(issue, "quarantine", err)
+				found = err != nil
+			}
+		}
+	}
+
+	// 缩略图和预览图标记
+	var flagIssues []int
+	for _, name := range []string{settingdb.Thumbnail, settingdb.Preview} {
+		key, err := RenditionFile(db, name, item.ID)
+		if err != nil {
+			return err
+		}
+		flag := item.HaveThumbnail
+		if name == settingdb.Preview {
+			flag = item.HavePreview
+		}
+		if exists := blobstore.Exists(blobs(db), key); exists != flag {
+			report.add(IntegrityIssue{
+				Kind:   IssueRenditionFlag,
+				ItemID: item.ID,
+				Key:    key,
+				Detail: fmt.Sprintf("have_%s is %t but file exists is %t", name, flag, exists),
+			})
+			flagIssues = append(flagIssues, len(report.Issues)-1)
+		}
+	}
+	if len(flagIssues) > 0 && opts.Repair {
+		// 重新生成预览图，原始文件不可用时只按实际文件更新标记
+		err := regenerateItem(db, item.ID, nil)
+		action := "regenerate"
+		if !found {
+			action = "update_flag"
+		}
+		for _, i := range flagIssues {
+			report.resolve(&report.Issues[i], action, err)
+		}
+	}
+
+	return nil
+}
+
+// 没有对应 item 的缩略图、预览图和自定义规格文件，可以重新生成，修复时直接删除
+func checkRenditionFiles(db *gorm.DB, report *IntegrityReport, known map[string]bool, opts IntegrityOptions) error {
+	renditions, err := settingdb.GetRenditions(db)
+	if err != nil {
+		return err
+	}
+	dirs := []string{}
+	for _, r := range renditions {
+		dirs = append(dirs, RenditionDir(r.Name))
+	}
+
+	for _, dir := range dirs {
+		keys, err := blobs(db).List(dir)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %v", dir, err)
+		}
+		report.Files += len(keys)
+		for _, key := range keys {
+			base := path.Base(key)
+			id := strings.TrimSuffix(base, path.Ext(base))
+			if known[id] {
+				continue
+			}
+			issue := report.add(IntegrityIssue{Kind: IssueOrphanRendition, Key: key, Detail: "no item with id " + id})
+			if opts.Repair {
+				report.resolve(issue, "delete", blobs(db).Delete(key))
+			}
+		}
+	}
+	return nil
+}
+
+// 没有版本记录引用的历史版本文件
+func checkVersionFiles(db *gorm.DB, report *IntegrityReport, opts IntegrityOptions) error {
+	var fileIDs []string
+	if err := db.Model(&dbcommon.ItemVersion{}).Distinct().Pluck("file_id", &fileIDs).Error; err != nil {
+		return err
+	}
+	referenced := make(map[string]bool, len(fileIDs))
+	for _, id := range fileIDs {
+		referenced[id] = true
+	}
+
+	keys, err := blobs(db).List("versions")
+	if err != nil {
+		return fmt.Errorf("failed to list versions: %v", err)
+	}
+	report.Files += len(keys)
+	for _, key := range keys {
+		id := path.Base(path.Dir(key))
+		if referenced[id] {
+			continue
+		}
+		issue := report.add(IntegrityIssue{Kind: IssueOrphanVersion, Key: key, Detail: "no version record for file " + id})
+		if opts.Repair {
+			report.resolve(issue, "quarantine", quarantine(db, report, key))
+		}
+	}
+	return nil
+}
+
+// 记录修复成功的问题，中途取消或出错时记录已经完成的部分
+func recordRepairs(db *gorm.DB, report *IntegrityReport) {
+	var targets []string
+	var repaired []IntegrityIssue
+	for _, issue := range report.Issues {
+		if !issue.Repaired {
+			continue
+		}
+		repaired = append(repaired, issue)
+		if issue.ItemID != "" {
+			targets = append(targets, issue.ItemID)
+		}
+	}
+	if len(repaired) == 0 {
+		return
+	}
+	err := recordChange(db, auditdb.Change{
+		Operation: auditdb.ItemRepair,
+		Targets:   targets,
+		After:     map[string]interface{}{"quarantine": report.Quarantine, "issues": repaired},
+	})
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+// 把文件移入本次检查的隔离目录
+func quarantine(db *gorm.DB, report *IntegrityReport, key string) error {
+	return blobs(db).Move(key, path.Join(report.Quarantine, key))
+}
+
+func hashBlob(db *gorm.DB, key string) (string, error) {
+	r, err := blobs(db).Get(key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+)
+
+func issueKinds(report *IntegrityReport) []string {
+	var kinds []string
+	for _, issue := range report.Issues {
+		kinds = append(kinds, issue.Kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func findIssue(t *testing.T, report *IntegrityReport, kind, key string) IntegrityIssue {
+	t.Helper()
+	for _, issue := range report.Issues {
+		if issue.Kind == kind && issue.Key == key {
+			return issue
+		}
+	}
+	t.Fatalf("no %s issue for %s in %+v", kind, key, report.Issues)
+	return IntegrityIssue{}
+}
+
+// 损坏一个临时资料库，检查报告的问题和修复后的状态
+func TestCheckAndRepairIntegrity(t *testing.T) {
+	lib := openLibrary(t)
+	db := lib.DB
+	dir := t.TempDir()
+	store := blobs(db)
+
+	healthy := importFile(t, lib, writePNG(t, dir, "healthy.png", 8, 8, 1))
+	missing := importFile(t, lib, writePNG(t, dir, "missing.png", 8, 8, 2))
+	misnamed := importFile(t, lib, writePNG(t, dir, "misnamed.png", 8, 8, 3))
+	corrupt := importFile(t, lib, writePNG(t, dir, "corrupt.png", 8, 8, 4))
+	noThumb := importFile(t, lib, writePNG(t, dir, "nothumb.png", 8, 8, 5))
+	orphan := importFile(t, lib, writePNG(t, dir, "orphan.png", 8, 8, 6))
+	versioned := importFile(t, lib, writePNG(t, dir, "versioned.png", 8, 8, 7))
+	if _, err := ReplaceItemFile(db, versioned, writePNG(t, dir, "new.png", 8, 8, 8)); err != nil {
+		t.Fatal(err)
+	}
+
+	thumbKey := func(id string) string {
+		key, err := RenditionFile(db, settingdb.Thumbnail, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 原始文件丢失
+	missingKey := RawFileKey(missing, "missing", "png")
+	must(store.Delete(missingKey))
+	// 改名没有完成
+	misnamedKey := RawFileKey(misnamed, "misnamed", "png")
+	staleKey := path.Join(RawDir(misnamed), "old-name.png")
+	must(store.Move(misnamedKey, staleKey))
+	// 原始文件内容被改写
+	corruptKey := RawFileKey(corrupt, "corrupt", "png")
+	must(store.Put(corruptKey, strings.NewReader("garbage"), 7))
+	// 缩略图丢失，标记仍为 true
+	noThumbKey := thumbKey(noThumb)
+	must(store.Delete(noThumbKey))
+	// item 记录被删除，留下原始文件和预览图
+	orphanRaw := RawFileKey(orphan, "orphan", "png")
+	orphanThumb := thumbKey(orphan)
+	must(db.Exec("DELETE FROM items WHERE id = ?", orphan).Error)
+	// 版本记录被删除，留下历史版本文件
+	var version dbcommon.ItemVersion
+	must(db.First(&version).Error)
+	versionKey := VersionKey(version)
+	must(db.Delete(&version).Error)
+	// item 目录中多出的文件
+	strayKey := path.Join(RawDir(healthy), "stray.txt")
+	must(store.Put(strayKey, strings.NewReader("x"), 1))
+
+	report, err := CheckIntegrity(context.Background(), db, IntegrityOptions{VerifyHash: true}, func(int, int) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		IssueHashMismatch, IssueMisnamedRawFile, IssueMissingRawFile,
+		IssueOrphanRawFile, IssueOrphanRawFile, IssueOrphanRendition, IssueOrphanRendition,
+		IssueOrphanVersion, IssueRenditionFlag,
+	}
+	sort.Strings(want)
+	if got := issueKinds(report); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("issues = %v, want %v", got, want)
+	}
+	if report.Items != 6 || report.Repaired != 0 {
+		t.Errorf("items %d, repaired %d", report.Items, report.Repaired)
+	}
+	if issue := findIssue(t, report, IssueMissingRawFile, missingKey); issue.ItemID != missing {
+		t.Errorf("missing issue = %+v", issue)
+	}
+	if issue := findIssue(t, report, IssueOrphanRawFile, strayKey); issue.ItemID != healthy {
+		t.Errorf("stray issue = %+v", issue)
+	}
+	findIssue(t, report, IssueMisnamedRawFile, staleKey)
+	findIssue(t, report, IssueHashMismatch, corruptKey)
+	findIssue(t, report, IssueRenditionFlag, noThumbKey)
+	findIssue(t, report, IssueOrphanRawFile, orphanRaw)
+	findIssue(t, report, IssueOrphanRendition, orphanThumb)
+	findIssue(t, report, IssueOrphanVersion, versionKey)
+
+	// 只检查时不修改任何文件
+	for _, key := range []string{staleKey, corruptKey, orphanRaw, orphanThumb, versionKey, strayKey} {
+		if !blobExists(t, db, key) {
+			t.Errorf("%s changed by check", key)
+		}
+	}
+
+	report, err = CheckIntegrity(context.Background(), db, IntegrityOptions{Repair: true, VerifyHash: true}, func(int, int) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Repaired != len(report.Issues)-1 {
+		t.Errorf("repaired %d of %d: %+v", report.Repaired, len(report.Issues), report.Issues)
+	}
+	if issue := findIssue(t, report, IssueMissingRawFile, missingKey); issue.Repaired {
+		t.Errorf("missing raw file repaired: %+v", issue)
+	}
+
+	quarantined := func(key string) string { return path.Join(report.Quarantine, key) }
+	checks := []struct {
+		key    string
+		exists bool
+	}{
+		{RawFileKey(misnamed, "misnamed", "png"), true}, // 重新关联
+		{staleKey, false},
+		{corruptKey, false}, // 内容不符的文件被隔离
+		{quarantined(corruptKey), true},
+		{noThumbKey, true}, // 重新生成
+		{orphanRaw, false},
+		{quarantined(orphanRaw), true},
+		{orphanThumb, false}, // 预览图可以重新生成，直接删除
+		{quarantined(orphanThumb), false},
+		{versionKey, false},
+		{quarantined(versionKey), true},
+		{strayKey, false},
+		{quarantined(strayKey), true},
+		{RawFileKey(healthy, "healthy", "png"), true},
+	}
+	for _, c := range checks {
+		if got := blobExists(t, db, c.key); got != c.exists {
+			t.Errorf("%s exists = %v, want %v", c.key, got, c.exists)
+		}
+	}
+
+	var entry dbcommon.AuditEntry
+	must(db.Preload("Targets").Where("operation = ?", auditdb.ItemRepair).First(&entry).Error)
+	targets := map[string]bool{}
+	for _, target := range entry.Targets {
+		targets[target.Target] = true
+	}
+	for _, id := range []string{misnamed, corrupt, noThumb, healthy} {
+		if !targets[id] {
+			t.Errorf("repair of %s not audited: %+v", id, entry.Targets)
+		}
+	}
+
+	// 修复后只剩无法自动恢复的原始文件：丢失的和被隔离的
+	report, err = CheckIntegrity(context.Background(), db, IntegrityOptions{VerifyHash: true}, func(int, int) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := issueKinds(report); strings.Join(got, ",") != IssueMissingRawFile+","+IssueMissingRawFile {
+		t.Errorf("issues after repair = %+v", report.Issues)
+	}
+	findIssue(t, report, IssueMissingRawFile, corruptKey)
+}
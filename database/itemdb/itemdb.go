@@ -25,19 +25,25 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"synapforest/blobstore"
 	"synapforest/database"
+	"synapforest/database/auditdb"
 	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+	"synapforest/docextract"
+	"synapforest/imagemeta"
+	"synapforest/mesh"
+	"synapforest/textrender"
 
-	"github.com/chai2010/webp"
 	"github.com/gofrs/uuid"
-	"github.com/nfnt/resize"
 	"gorm.io/gorm"
 )
 
 // 查找符合条件的 items
-func ItemList(db *gorm.DB, isDeleted *bool, orderBy *string, page *int, pageSize *int, exts []string, keyword *string, tags []uuid.UUID, folders []uuid.UUID) ([]dbcommon.Item, error) {
+func ItemList(db *gorm.DB, isDeleted *bool, orderBy *string, page *int, pageSize *int, exts []string, keyword *string, tags []uuid.UUID, folders []uuid.UUID, color *string, colorDistance *float64, area *GeoFilter) ([]dbcommon.Item, error) {
 	var items []dbcommon.Item
 
 	query := db.Model(&dbcommon.Item{})
@@ -51,7 +57,25 @@ func ItemList(db *gorm.DB, isDeleted *bool, orderBy *string, page *int, pageSize
 	}
 
 	if keyword != nil && *keyword != "" {
-		query = query.Where("name LIKE ?", "%"+*keyword+"%")
+		// 同时匹配名称和提取出的文本内容
+		query = query.Where("name LIKE ? OR items.id IN (?)", "%"+*keyword+"%",
+			db.Model(&dbcommon.ItemContent{}).Select("item_id").Where("content LIKE ?", "%"+*keyword+"%"))
+	}
+
+	if color != nil && *color != "" {
+		colorQuery, err := PaletteQuery(db, *color, colorDistance)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("items.id IN (?)", colorQuery)
+	}
+
+	if area != nil && (area.BBox != nil || area.Radius != nil) {
+		geoQuery, err := GeoQuery(db, *area)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("items.id IN (?)", geoQuery)
 	}
 
 	if len(tags) > 0 {
@@ -93,7 +117,7 @@ func ItemList(db *gorm.DB, isDeleted *bool, orderBy *string, page *int, pageSize
 	}
 	query = query.Offset(page1 * pageSize1).Limit(pageSize1)
 
-	err := query.Preload("Folders").Preload("Tags").Find(&items).Error
+	err := query.Preload("Folders").Preload("Tags").Preload("Palettes", PaletteOrder).Preload("Location").Find(&items).Error
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -137,92 +161,78 @@ func calculateThumbnailSize(originalWidth, originalHeight, maxPixels int) (uint,
 	return newWidth, newHeight
 }
 
-// 生成缩略图，确保缩略图总像素数不超过 maxPixels
-func generateThumbnail(filePath string, thumbPath string, maxPixels int) error {
-	file, err := os.Open(filePath)
-
-	if err != nil {
-		return fmt.Errorf("open file failed: %v", err)
+// RenameFile 重命名 item 的原始文件
+func RenameFile(db *gorm.DB, itemID string, oldName string, oldExt string, newName string, newExt string) error {
+	if newName == "" {
+		return fmt.Errorf("new file name is empty")
 	}
 
-	defer file.Close()
-
-	img, _, err := image.Decode(file)
-	if err != nil {
-		return fmt.Errorf("decode image failed: %v", err)
+	oldKey := RawFileKey(itemID, oldName, oldExt)
+	newKey := RawFileKey(itemID, newName, newExt)
+	if oldKey == newKey {
+		return nil
 	}
 
-	originalWidth := img.Bounds().Dx()
-	originalHeight := img.Bounds().Dy()
-
-	newWidth, newHeight := calculateThumbnailSize(originalWidth, originalHeight, maxPixels)
-
-	thumb := resize.Resize(newWidth, newHeight, img, resize.Lanczos3)
-
-	out, err := os.Create(thumbPath)
-	if err != nil {
-		return fmt.Errorf("create thumb file failed: %v", err)
+	if !blobstore.Exists(blobs(db), oldKey) {
+		return fmt.Errorf("file not found: %s", oldKey)
 	}
-	defer out.Close()
-
-	err = webp.Encode(out, thumb, nil)
-	if err != nil {
-		return err
+	if blobstore.Exists(blobs(db), newKey) {
+		return fmt.Errorf("file with name %s already exists", newKey)
 	}
 
+	if err := blobs(db).Move(oldKey, newKey); err != nil {
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
 	return nil
 }
 
-func RenameFile(oldPath string, Name string, Ext *string) error {
-	if _, err := os.Stat(oldPath); os.IsNotExist(err) {
-		return fmt.Errorf("file not found: %s", oldPath)
-	}
-
-	dir := filepath.Dir(oldPath)
-
-	ext := filepath.Ext(oldPath)
-	if Ext != nil {
-		ext = *Ext
-		if ext != "" {
-			ext = "." + ext
-		}
-	}
+func AddItem(db *gorm.DB, path string, name *string, url *string, annotation *string, tags []uuid.UUID, folders []uuid.UUID, star *uint8, created_at *time.Time) error {
+	_, err := importItem(db, ImportEntry{Path: path, Name: name, Url: url, Annotation: annotation, Tags: tags, Folders: folders, Star: star, CreatedAt: created_at})
+	return err
+}
 
-	if Name == "" {
-		return fmt.Errorf("new file name is empty")
-	}
+// 导入一个文件并记录审计日志，返回 item ID
+func importItem(db *gorm.DB, entry ImportEntry) (string, error) {
+	id, _, err := addItem(db, entry, func(tx *gorm.DB, id string, before *itemState) error {
+		return recordItemChange(tx, auditdb.ItemImport, []string{id}, before, id, nil)
+	})
+	return id, err
+}
 
-	newPath := filepath.Join(dir, Name+ext)
+// 导入一个文件，返回 item ID。内容相同的 item 已存在时合并到已有的 item，
+// 同时返回合并前的审计值，新建时为 nil。解码和生成预览图在事务外完成，
+// item 的记录与 commit 在同一个事务中写入，失败时撤销本次导入
+func addItem(db *gorm.DB, entry ImportEntry, commit func(tx *gorm.DB, id string, before *itemState) error) (string, *itemState, error) {
+	path, name, url, annotation := entry.Path, entry.Name, entry.Url, entry.Annotation
+	tags, folders, star, created_at := entry.Tags, entry.Folders, entry.Star, entry.CreatedAt
 
-	if _, err := os.Stat(newPath); err == nil {
-		return fmt.Errorf("file with name %s already exists", newPath)
-	}
-
-	err := os.Rename(oldPath, newPath)
+	// 复制到资源库的同时计算哈希，之后只需要再读取一次用于解码
+	stagedPath, fileID, err := stageFile(db, path)
 	if err != nil {
-		return fmt.Errorf("failed to rename file: %w", err)
+		return "", nil, fmt.Errorf("failed to stage file: %v", err)
 	}
+	defer os.Remove(stagedPath)
 
-	return nil
-}
-
-func AddItem(db *gorm.DB, path string, name *string, url *string, annotation *string, tags []uuid.UUID, folders []uuid.UUID, star *uint8, created_at *time.Time) error {
-	fileID, err := CalculateFileID(path)
-	if err != nil {
-		return fmt.Errorf("failed to calculate file ID: %v", err)
-	}
+	// 同一批次中内容相同的文件依次处理，后处理的按重复文件合并
+	unlock := database.LibraryOf(db).ItemLocks.Lock(fileID)
+	defer unlock()
 
 	// 检查重复文件
 	var existingItem dbcommon.Item
 	err = db.Unscoped().First(&existingItem, "id = ?", fileID).Error
 	if err == nil {
+		before, err := loadState(db, fileID)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to query existing item: %v", err)
+		}
+
 		updates := map[string]interface{}{
 			"modified_at": time.Now(),
 		}
 		if name != nil {
-			err = RenameFile(filepath.Join(database.DbBaseDir, existingItem.ID, existingItem.Name+"."+existingItem.Ext), *name, nil)
+			err = RenameFile(db, existingItem.ID, existingItem.Name, existingItem.Ext, *name, existingItem.Ext)
 			if err != nil {
-				return fmt.Errorf("db_add_item rename exist file name failed %v", err)
+				return "", nil, fmt.Errorf("db_add_item rename exist file name failed %v", err)
 			}
 			updates["name"] = *name
 		}
@@ -238,80 +248,49 @@ func AddItem(db *gorm.DB, path string, name *string, url *string, annotation *st
 		if star != nil {
 			updates["star"] = *star
 		}
-		err = db.Model(&existingItem).Updates(updates).Error
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&existingItem).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update existing item: %v", err)
+			}
+			if err := appendAssociations(tx, &existingItem, tags, folders); err != nil {
+				return err
+			}
+			return commitItem(tx, commit, fileID, before)
+		})
 		if err != nil {
-			return fmt.Errorf("failed to update existing item: %v", err)
-		}
-
-		for _, tagID := range tags {
-			tag := dbcommon.Tag{ID: tagID}
-			err = db.Model(&existingItem).Association("Tags").Append(&tag)
-			if err != nil {
-				return fmt.Errorf("failed to append tag: %v", err)
+			if name != nil {
+				if undoErr := RenameFile(db, existingItem.ID, *name, existingItem.Ext, existingItem.Name, existingItem.Ext); undoErr != nil {
+					log.Printf("Failed to restore file name of %s: %v", existingItem.ID, undoErr)
+				}
 			}
+			return "", nil, err
 		}
 
-		for _, folderID := range folders {
-			folder := dbcommon.Folder{ID: folderID}
-			err = db.Model(&existingItem).Association("Folders").Append(&folder)
-			if err != nil {
-				return fmt.Errorf("failed to append folder: %v", err)
+		if !entry.KeepSource {
+			if err := os.Remove(path); err != nil {
+				log.Printf("Failed to delete original file: %v", err)
 			}
 		}
 
-		err = os.Remove(path)
-		if err != nil {
-			log.Printf("Failed to delete original file: %v", err)
-		}
-
-		return nil
+		return fileID, before, nil
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return fmt.Errorf("failed to query existing item: %v", err)
+		return "", nil, fmt.Errorf("failed to query existing item: %v", err)
 	}
 
-	fileInfo, err := os.Stat(path)
+	fileInfo, err := os.Stat(stagedPath)
 	if err != nil {
-		return fmt.Errorf("failed to get file info: %v", err)
+		return "", nil, fmt.Errorf("failed to get file info: %v", err)
 	}
 
-	ext := filepath.Ext(fileInfo.Name())
-
-	isImage := ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".gif"
+	baseName := filepath.Base(path)
+	ext := filepath.Ext(baseName)
 
-	var width, height uint32
 	var fileSize uint64 = uint64(fileInfo.Size())
 
-	if isImage {
-		file, err := os.Open(path)
-		if err != nil {
-			return fmt.Errorf("failed to open file: %v", err)
-		}
-		defer file.Close()
-
-		img, _, err := image.Decode(file)
-		if err == nil {
-			width = uint32(img.Bounds().Dx())
-			height = uint32(img.Bounds().Dy())
-		} else {
-			log.Printf("Failed to decode image: %v", err)
-		}
-	}
-
-	rawFileDir := filepath.Join(database.DbBaseDir, "raw_files", fileID)
-	err = os.MkdirAll(rawFileDir, 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create target directory: %v", err)
-	}
-
-	var name1 string = fileInfo.Name()[:len(fileInfo.Name())-len(ext)]
+	var name1 string = baseName[:len(baseName)-len(ext)]
 	if name != nil && *name != "" {
 		name1 = *name
 	}
-	destPath := filepath.Join(rawFileDir, name1+ext)
-	err = os.Rename(path, destPath)
-	if err != nil {
-		return fmt.Errorf("failed to move and rename file: %v", err)
-	}
 
 	item := dbcommon.Item{
 		ID:            fileID,
@@ -319,9 +298,7 @@ func AddItem(db *gorm.DB, path string, name *string, url *string, annotation *st
 		ImportedAt:    time.Now(),
 		ModifiedAt:    time.Now(),
 		Name:          name1,
-		Ext:           ext[1:],
-		Width:         width,
-		Height:        height,
+		Ext:           strings.TrimPrefix(ext, "."),
 		Size:          fileSize,
 		Tags:          []dbcommon.Tag{},    // 待处理
 		Folders:       []dbcommon.Folder{}, // 待处理
@@ -341,46 +318,183 @@ func AddItem(db *gorm.DB, path string, name *string, url *string, annotation *st
 		item.Annotation = *annotation
 	}
 
-	if isImage {
-		thumbPath := filepath.Join(database.DbBaseDir, "thumbnails", fileID+".webp")
-		err = generateThumbnail(destPath, thumbPath, 256*256)
-		if err != nil {
-			log.Printf("Failed to generate thumbnail: %v", err)
-		} else {
-			item.HaveThumbnail = true
+	limits, err := settingdb.GetImportLimits(db)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 解码失败或超出限制时仍然导入文件，只是没有预览图，并记录原因。
+	// 暂存文件在本地，处理完成后再移入存储
+	metadata, content, err := deriveItem(db, &item, stagedPath, ext, limits)
+	if err != nil {
+		log.Printf("Failed to process %s: %v", path, err)
+		item.ImportError = err.Error()
+	}
+
+	err = blobstore.MoveFile(blobs(db), RawFileKey(fileID, name1, item.Ext), stagedPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to store file: %v", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := appendAssociations(tx, &item, tags, folders); err != nil {
+			return err
 		}
+		if err := tx.Create(&item).Error; err != nil {
+			return fmt.Errorf("failed to create item in database: %v", err)
+		}
+		if len(metadata) > 0 {
+			if err := SetItemMetadata(tx, fileID, metadata); err != nil {
+				return fmt.Errorf("failed to save item metadata: %v", err)
+			}
+		}
+		if content != nil {
+			if err := SetItemContent(tx, fileID, *content); err != nil {
+				return fmt.Errorf("failed to save item content: %v", err)
+			}
+		}
+		return commitItem(tx, commit, fileID, nil)
+	})
+	if err != nil {
+		// 预览图的记录在事务外写入，与文件一起删除
+		noAudit := func(tx *gorm.DB) error { return nil }
+		if undoErr := hardDeleteItems(db, []string{fileID}, noAudit); undoErr != nil {
+			log.Printf("Failed to remove files of %s: %v", fileID, undoErr)
+		}
+		return "", nil, err
+	}
 
-		previewPath := filepath.Join(database.DbBaseDir, "previews", fileID+".webp")
-		err = generateThumbnail(destPath, previewPath, 768*768)
-		if err != nil {
-			log.Printf("Failed to generate preview: %v", err)
-		} else {
-			item.HavePreview = true
+	if !entry.KeepSource {
+		if err := os.Remove(path); err != nil {
+			log.Printf("Failed to delete original file: %v", err)
 		}
 	}
 
+	return fileID, nil, nil
+}
+
+// 把 item 加入标签和文件夹
+func appendAssociations(tx *gorm.DB, item *dbcommon.Item, tags []uuid.UUID, folders []uuid.UUID) error {
 	for _, tagID := range tags {
 		tag := dbcommon.Tag{ID: tagID}
-		err = db.Model(&item).Association("Tags").Append(&tag)
-		if err != nil {
+		if err := tx.Model(item).Association("Tags").Append(&tag); err != nil {
 			return fmt.Errorf("failed to append tag: %v", err)
 		}
 	}
-
 	for _, folderID := range folders {
 		folder := dbcommon.Folder{ID: folderID}
-		err = db.Model(&item).Association("Folders").Append(&folder)
-		if err != nil {
+		if err := tx.Model(item).Association("Folders").Append(&folder); err != nil {
 			return fmt.Errorf("failed to append folder: %v", err)
 		}
 	}
+	return nil
+}
 
-	err = db.Create(&item).Error
-	if err != nil {
-		return fmt.Errorf("failed to create item in database: %v", err)
+// commit 为 nil 时不做额外的写入
+func commitItem(tx *gorm.DB, commit func(tx *gorm.DB, id string, before *itemState) error, id string, before *itemState) error {
+	if commit == nil {
+		return nil
 	}
+	return commit(tx, id, before)
+}
 
-	return nil
+// 从原始文件提取尺寸、元数据和文本，并生成预览图。超出限制、解码失败或解码器 panic 时返回错误
+func deriveItem(db *gorm.DB, item *dbcommon.Item, destPath string, ext string, limits dbcommon.ImportLimits) (metadata map[string]string, content *string, err error) {
+	defer recoverDecoder(&err)
+
+	// 技术信息只读取元数据段，不受解码限制影响
+	if imagemeta.IsSupportedExt(ext) {
+		info, readErr := imagemeta.Read(destPath)
+		if readErr != nil {
+			log.Printf("Failed to read image metadata: %v", readErr)
+		} else {
+			metadata = info.Metadata()
+			if info.GPS != nil {
+				item.Location = newLocation(item.ID, info.GPS)
+			}
+		}
+	}
+
+	isImage := isImageExt(ext)
+	if (isImage || mesh.IsModelExt(ext) || docextract.IsDocumentExt(ext)) && limits.MaxFileSize > 0 && int64(item.Size) > limits.MaxFileSize {
+		return metadata, nil, fmt.Errorf("file size %d bytes exceeds the limit of %d bytes", item.Size, limits.MaxFileSize)
+	}
+
+	// 用于生成预览图的图像
+	var source image.Image
+
+	if mesh.IsModelExt(ext) {
+		img, meta, renderErr := renderModel(destPath, 768)
+		metadata = meta
+		if renderErr != nil {
+			err = fmt.Errorf("render model failed: %v", renderErr)
+		} else {
+			source = img
+		}
+	}
+
+	if !isImage && !mesh.IsModelExt(ext) && textrender.IsTextFile(destPath, ext) {
+		text, readErr := textrender.ReadText(destPath, textrender.MaxContentSize)
+		if readErr != nil {
+			err = fmt.Errorf("read text failed: %v", readErr)
+		} else {
+			content = &text
+			source = textrender.Render(text, ext, 768, 768)
+		}
+	}
+
+	if docextract.IsDocumentExt(ext) {
+		doc, extractErr := docextract.Extract(destPath, textrender.MaxContentSize)
+		if extractErr != nil {
+			err = fmt.Errorf("extract document failed: %v", extractErr)
+		} else {
+			metadata = doc.Metadata()
+			if doc.Text != "" {
+				content = &doc.Text
+			}
+		}
+	}
+
+	// 图片只解码一次，尺寸、各规格预览图都从同一份图像得到
+	if isImage {
+		cfg, checkErr := CheckImage(destPath, limits)
+		item.Width = uint32(cfg.Width)
+		item.Height = uint32(cfg.Height)
+		if checkErr != nil {
+			return metadata, content, checkErr
+		}
+
+		source, err = decodeImageFile(destPath)
+		if err != nil {
+			return metadata, content, err
+		}
+	}
+
+	var saved map[string]image.Image
+	if source != nil {
+		var saveErr error
+		saved, saveErr = saveRenditions(db, source, item.ID, nil)
+		if saveErr != nil {
+			log.Printf("Failed to generate renditions: %v", saveErr)
+		}
+		item.HaveThumbnail = saved[settingdb.Thumbnail] != nil
+		item.HavePreview = saved[settingdb.Preview] != nil
+	}
+
+	if thumb := saved[settingdb.Thumbnail]; thumb != nil {
+		// 主色从缩略图计算即可
+		if isImage {
+			item.Palettes = computePalette(item.ID, thumb)
+		}
+
+		placeholder, placeholderErr := computePlaceholder(thumb)
+		if placeholderErr != nil {
+			log.Printf("Failed to compute placeholder: %v", placeholderErr)
+		}
+		item.Placeholder = placeholder
+	}
+
+	return metadata, content, err
 }
 
 func UpdateItem(db *gorm.DB, fileID string, name *string, ext *string, url *string, annotation *string, tags []uuid.UUID, folders []uuid.UUID, star *uint8, created_at *time.Time) error {
@@ -392,6 +506,10 @@ func UpdateItem(db *gorm.DB, fileID string, name *string, ext *string, url *stri
 		}
 		return fmt.Errorf("failed to query existing item: %v", err)
 	}
+	before, err := loadState(db, fileID)
+	if err != nil {
+		return fmt.Errorf("failed to query existing item: %v", err)
+	}
 
 	updates := map[string]interface{}{
 		"modified_at": time.Now(),
@@ -408,9 +526,7 @@ func UpdateItem(db *gorm.DB, fileID string, name *string, ext *string, url *stri
 			newExt = *ext
 		}
 		if newName != existingItem.Name || newExt != existingItem.Ext {
-			err = RenameFile(filepath.Join(database.DbBaseDir, "raw_files", existingItem.ID, existingItem.Name+"."+existingItem.Ext), newName, ext)
-			fmt.Println(newName + "." + newExt)
-			fmt.Println(filepath.Join(database.DbBaseDir, "raw_files", existingItem.ID, existingItem.Name+"."+existingItem.Ext))
+			err = RenameFile(db, existingItem.ID, existingItem.Name, existingItem.Ext, newName, newExt)
 			if err != nil {
 				return fmt.Errorf("failed to rename file: %v", err)
 			}
@@ -435,89 +551,172 @@ func UpdateItem(db *gorm.DB, fileID string, name *string, ext *string, url *stri
 		updates["star"] = *star
 	}
 
-	err = db.Model(&existingItem).Updates(updates).Error
-	if err != nil {
-		return fmt.Errorf("failed to update existing item: %v", err)
-	}
-
-	if tags != nil {
-		err = db.Model(&existingItem).Association("Tags").Clear()
+	// 数据库中的修改与审计记录一同提交
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&existingItem).Updates(updates).Error
 		if err != nil {
-			return fmt.Errorf("failed to clear tags: %v", err)
+			return fmt.Errorf("failed to update existing item: %v", err)
 		}
 
-		for _, tagID := range tags {
-			tag := dbcommon.Tag{ID: tagID}
-			err = db.Model(&existingItem).Association("Tags").Append(&tag)
+		if tags != nil {
+			err = tx.Model(&existingItem).Association("Tags").Clear()
 			if err != nil {
-				return fmt.Errorf("failed to append tag: %v", err)
+				return fmt.Errorf("failed to clear tags: %v", err)
 			}
-		}
-	}
 
-	if folders != nil {
-		err = db.Model(&existingItem).Association("Folders").Clear()
-		if err != nil {
-			return fmt.Errorf("failed to clear folders: %v", err)
+			for _, tagID := range tags {
+				tag := dbcommon.Tag{ID: tagID}
+				err = tx.Model(&existingItem).Association("Tags").Append(&tag)
+				if err != nil {
+					return fmt.Errorf("failed to append tag: %v", err)
+				}
+			}
 		}
 
-		for _, folderID := range folders {
-			folder := dbcommon.Folder{ID: folderID}
-			err = db.Model(&existingItem).Association("Folders").Append(&folder)
+		if folders != nil {
+			err = tx.Model(&existingItem).Association("Folders").Clear()
 			if err != nil {
-				return fmt.Errorf("failed to append folder: %v", err)
+				return fmt.Errorf("failed to clear folders: %v", err)
+			}
+
+			for _, folderID := range folders {
+				folder := dbcommon.Folder{ID: folderID}
+				err = tx.Model(&existingItem).Association("Folders").Append(&folder)
+				if err != nil {
+					return fmt.Errorf("failed to append folder: %v", err)
+				}
 			}
 		}
-	}
 
-	return nil
+		after, err := loadState(tx, fileID)
+		if err != nil {
+			return err
+		}
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.ItemUpdate,
+			Targets:   []string{fileID},
+			Before:    before,
+			After:     after,
+		})
+	})
 }
 
 func ItemSoftDelete(db *gorm.DB, itemIDs []string) error {
 	err := db.Transaction(func(tx *gorm.DB) error {
+		// 只记录原本不在回收站中的 item
+		var ids []string
+		if err := tx.Model(&dbcommon.Item{}).Where("id IN ?", itemIDs).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		before, err := loadStates(tx, ids)
+		if err != nil {
+			return err
+		}
+
 		result := tx.Delete(&dbcommon.Item{}, itemIDs)
 		if result.Error != nil {
 			return fmt.Errorf("soft delete items failed: %v", result.Error)
 		}
+		if len(ids) == 0 {
+			return nil
+		}
 
-		return nil
+		after, err := loadStates(tx, ids)
+		if err != nil {
+			return err
+		}
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.ItemTrash,
+			Targets:   ids,
+			Before:    before,
+			After:     after,
+		})
 	})
 
 	return err
 }
 
 func ItemHardDelete(db *gorm.DB, itemIDs []string) error {
-	err := db.Unscoped().Transaction(func(tx *gorm.DB) error {
-		result := tx.Delete(&dbcommon.Item{}, itemIDs)
-		if result.Error != nil {
-			return fmt.Errorf("hard delete items failed: %v", result.Error)
-		}
+	before, err := loadStates(db, itemIDs)
+	if err != nil {
+		return fmt.Errorf("failed to query items: %v", err)
+	}
 
-		return nil
+	return hardDeleteItems(db, itemIDs, func(tx *gorm.DB) error {
+		if len(before) == 0 {
+			return nil
+		}
+		ids := make([]string, 0, len(before))
+		for _, id := range itemIDs {
+			if before[id] != nil {
+				ids = append(ids, id)
+			}
+		}
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.ItemDelete,
+			Targets:   ids,
+			Before:    before,
+		})
 	})
+}
 
+// 彻底删除 item 及其文件，audit 在删除数据库记录的事务中调用。文件在事务提交后删除
+func hardDeleteItems(db *gorm.DB, itemIDs []string, audit func(tx *gorm.DB) error) error {
+	var versions []dbcommon.ItemVersion
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		versions, err = deleteItemRows(tx, itemIDs)
+		if err != nil {
+			return err
+		}
+		return audit(tx)
+	})
 	if err != nil {
 		return err
 	}
+	return deleteItemFiles(db, itemIDs, versions)
+}
 
-	for _, itemID := range itemIDs {
-		itemDir := filepath.Join(database.DbBaseDir, "raw_files", itemID)
-		if err := os.RemoveAll(itemDir); err != nil {
-			return fmt.Errorf("failed to delete item directory '%s': %v", itemDir, err)
+// 删除 item 及其附属数据的记录，返回被删除的历史版本，用于之后删除文件
+func deleteItemRows(tx *gorm.DB, itemIDs []string) ([]dbcommon.ItemVersion, error) {
+	if err := tx.Unscoped().Delete(&dbcommon.Item{}, itemIDs).Error; err != nil {
+		return nil, fmt.Errorf("hard delete items failed: %v", err)
+	}
+
+	for _, model := range []interface{}{
+		&dbcommon.ItemMetadata{},
+		&dbcommon.ItemContent{},
+		&dbcommon.ItemPalette{},
+		&dbcommon.ItemLocation{},
+		&dbcommon.ItemDerivation{},
+		&dbcommon.ItemRendition{},
+	} {
+		if err := tx.Where("item_id IN ?", itemIDs).Delete(model).Error; err != nil {
+			return nil, fmt.Errorf("failed to delete %T: %v", model, err)
 		}
+	}
 
-		thumbnailFile := filepath.Join(database.DbBaseDir, "thumbnails", itemID+".webp")
-		if err := os.Remove(thumbnailFile); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("failed to delete thumbnail '%s': %v", thumbnailFile, err)
-		}
+	var versions []dbcommon.ItemVersion
+	if err := tx.Where("item_id IN ?", itemIDs).Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("item_id IN ?", itemIDs).Delete(&dbcommon.ItemVersion{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete item versions: %v", err)
+	}
+	return versions, nil
+}
 
-		previewFile := filepath.Join(database.DbBaseDir, "previews", itemID+".webp")
-		if err := os.Remove(previewFile); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("failed to delete thumbnail '%s': %v", previewFile, err)
+// 删除已删除记录的 item 的原始文件、预览图和不再被引用的历史版本文件
+func deleteItemFiles(db *gorm.DB, itemIDs []string, versions []dbcommon.ItemVersion) error {
+	for _, itemID := range itemIDs {
+		if err := blobs(db).DeleteDir(RawDir(itemID)); err != nil {
+			return fmt.Errorf("failed to delete raw files of '%s': %v", itemID, err)
+		}
+		if err := removeRenditionFilesOf(db, itemID); err != nil {
+			return fmt.Errorf("failed to delete renditions of '%s': %v", itemID, err)
 		}
 	}
-
-	return nil
+	return removeUnusedVersionFiles(db, versions)
 }
 
 // GetItemsByIDs 根据给定的 ID 数组获取对应的 Item 数组
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"fmt"
+	"math"
+
+	"synapforest/database/dbcommon"
+	"synapforest/geo"
+	"synapforest/imagemeta"
+
+	"gorm.io/gorm"
+)
+
+// 地图聚合支持的最大缩放级别
+const MaxClusterZoom = 22
+
+// 每个瓦片在每个方向上划分的聚合网格数
+const clusterCellsPerTile = 4
+
+// BBox 经纬度矩形范围，West 大于 East 时表示跨越 180° 经线
+type BBox struct {
+	South float64
+	West  float64
+	North float64
+	East  float64
+}
+
+// Radius 以某点为中心的圆形范围
+type Radius struct {
+	Latitude  float64
+	Longitude float64
+	Meters    float64
+}
+
+// GeoFilter 按拍摄位置筛选，两个条件同时给出时取交集
+type GeoFilter struct {
+	BBox   *BBox
+	Radius *Radius
+}
+
+// GeoCluster 地图上的一个聚合点
+type GeoCluster struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Count     int     `json:"count"`
+	ItemID    string  `json:"item_id"` // 只有一个 item 时为其 ID
+}
+
+func (b *BBox) validate() error {
+	if b.South < -90 || b.North > 90 || b.South > b.North {
+		return fmt.Errorf("invalid latitude range: %v to %v", b.South, b.North)
+	}
+	if b.West < -180 || b.West > 180 || b.East < -180 || b.East > 180 {
+		return fmt.Errorf("invalid longitude range: %v to %v", b.West, b.East)
+	}
+	return nil
+}
+
+// 在 item_locations 上应用矩形范围
+func (b *BBox) apply(query *gorm.DB) *gorm.DB {
+	query = query.Where("latitude BETWEEN ? AND ?", b.South, b.North)
+	if b.West <= b.East {
+		return query.Where("longitude BETWEEN ? AND ?", b.West, b.East)
+	}
+	return query.Where("(longitude >= ? OR longitude <= ?)", b.West, b.East)
+}
+
+func newLocation(itemID string, gps *imagemeta.GPS) *dbcommon.ItemLocation {
+	x, y, z := geo.UnitVector(gps.Latitude, gps.Longitude)
+	mx, my := geo.Mercator(gps.Latitude, gps.Longitude)
+	return &dbcommon.ItemLocation{
+		ItemID:    itemID,
+		Latitude:  gps.Latitude,
+		Longitude: gps.Longitude,
+		Altitude:  gps.Altitude,
+		X:         x,
+		Y:         y,
+		Z:         z,
+		MercatorX: mx,
+		MercatorY: my,
+	}
+}
+
+// SetItemLocation 替换 item 的拍摄位置，gps 为 nil 时删除
+func SetItemLocation(db *gorm.DB, itemID string, gps *imagemeta.GPS) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("item_id = ?", itemID).Delete(&dbcommon.ItemLocation{}).Error; err != nil {
+			return err
+		}
+		if gps == nil {
+			return nil
+		}
+		return tx.Create(newLocation(itemID, gps)).Error
+	})
+}
+
+// Validate 检查经纬度范围和半径是否有效
+func (filter GeoFilter) Validate() error {
+	if filter.BBox != nil {
+		if err := filter.BBox.validate(); err != nil {
+			return err
+		}
+	}
+	if r := filter.Radius; r != nil {
+		if r.Latitude < -90 || r.Latitude > 90 || r.Longitude < -180 || r.Longitude > 180 {
+			return fmt.Errorf("invalid center: %v, %v", r.Latitude, r.Longitude)
+		}
+		if r.Meters <= 0 {
+			return fmt.Errorf("radius must be positive")
+		}
+	}
+	return nil
+}
+
+// GeoQuery 构造子查询：拍摄位置满足条件的 item ID
+func GeoQuery(db *gorm.DB, filter GeoFilter) (*gorm.DB, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	query := db.Model(&dbcommon.ItemLocation{}).Select("item_id")
+
+	if filter.BBox != nil {
+		query = filter.BBox.apply(query)
+	}
+
+	if r := filter.Radius; r != nil {
+		// 先用纬度范围走索引，再用单位向量的点积精确判断球面距离
+		south, north := geo.RadiusBounds(r.Latitude, r.Meters)
+		x, y, z := geo.UnitVector(r.Latitude, r.Longitude)
+		query = query.Where("latitude BETWEEN ? AND ?", south, north).
+			Where("x * ? + y * ? + z * ? >= ?", x, y, z, geo.MinDot(r.Meters))
+	}
+
+	return query, nil
+}
+
+// GeoClusters 把范围内未删除 item 的拍摄位置按 Web 墨卡托网格聚合，网格大小随缩放级别变化
+func GeoClusters(db *gorm.DB, bbox BBox, zoom int) ([]GeoCluster, error) {
+	if err := bbox.validate(); err != nil {
+		return nil, err
+	}
+	if zoom < 0 || zoom > MaxClusterZoom {
+		return nil, fmt.Errorf("zoom must be between 0 and %d", MaxClusterZoom)
+	}
+	cells := math.Exp2(float64(zoom)) * clusterCellsPerTile
+
+	query := db.Table("item_locations").
+		Select("COUNT(*) AS count, AVG(item_locations.latitude) AS latitude, AVG(item_locations.longitude) AS longitude, MIN(item_locations.item_id) AS item_id").
+		Joins("JOIN items ON items.id = item_locations.item_id AND items.deleted_at IS NULL")
+	query = bbox.apply(query).
+		Group(fmt.Sprintf("CAST(mercator_x * %g AS INTEGER), CAST(mercator_y * %g AS INTEGER)", cells, cells))
+
+	var clusters []GeoCluster
+	if err := query.Scan(&clusters).Error; err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	for i := range clusters {
+		if clusters[i].Count > 1 {
+			clusters[i].ItemID = ""
+		}
+	}
+	return clusters, nil
+}
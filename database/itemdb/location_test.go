@@ -0,0 +1,292 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"synapforest/database"
+	"synapforest/database/dbcommon"
+	"synapforest/imagemeta"
+)
+
+// 生成带 GPS EXIF 的 JPEG，经纬度按度数写入
+func writeGeoJPEG(t *testing.T, dir, name string, lat, lon float64) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i) ^ uint8(lat)
+	}
+	img.Set(0, 0, color.RGBA{uint8(lon), 0, 0, 255})
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	le := binary.LittleEndian
+	latRef, lonRef := "N\x00", "E\x00"
+	if lat < 0 {
+		latRef = "S\x00"
+	}
+	if lon < 0 {
+		lonRef = "W\x00"
+	}
+	degrees := func(b []byte, v float64) []byte {
+		b = le.AppendUint32(b, uint32(math.Round(math.Abs(v)*1e6)))
+		b = le.AppendUint32(b, 1e6)
+		return append(b, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0)
+	}
+	entry := func(b []byte, tag, typ uint16, count uint32, value []byte) []byte {
+		b = le.AppendUint16(b, tag)
+		b = le.AppendUint16(b, typ)
+		b = le.AppendUint32(b, count)
+		return append(b, value...)
+	}
+
+	// 主 IFD 在 8 处只有 GPS 指针，GPS IFD 在 26 处，有理数值在 80 处
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = le.AppendUint16(tiff, 1)
+	tiff = entry(tiff, 0x8825, 4, 1, le.AppendUint32(nil, 26))
+	tiff = le.AppendUint32(tiff, 0)
+	tiff = le.AppendUint16(tiff, 4)
+	tiff = entry(tiff, 1, 2, 2, []byte(latRef+"\x00\x00"))
+	tiff = entry(tiff, 2, 5, 3, le.AppendUint32(nil, 80))
+	tiff = entry(tiff, 3, 2, 2, []byte(lonRef+"\x00\x00"))
+	tiff = entry(tiff, 4, 5, 3, le.AppendUint32(nil, 104))
+	tiff = le.AppendUint32(tiff, 0)
+	tiff = degrees(tiff, lat)
+	tiff = degrees(tiff, lon)
+
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(seg)+2))
+	data = append(data, seg...)
+	data = append(data, encoded.Bytes()[2:]...)
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 导入时从 EXIF 读取拍摄位置写入 item_locations，没有 GPS 的 item 没有位置
+func TestImportStoresLocation(t *testing.T) {
+	lib := openLibrary(t)
+	dir := t.TempDir()
+	photo := importFile(t, lib, writeGeoJPEG(t, dir, "tokyo.jpg", 35.6895, 139.6917))
+	plain := importFile(t, lib, writePNG(t, dir, "plain.png", 4, 4, 1))
+
+	var locations []dbcommon.ItemLocation
+	if err := lib.DB.Find(&locations).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 1 || locations[0].ItemID != photo {
+		t.Fatalf("locations = %+v", locations)
+	}
+	if l := locations[0]; math.Abs(l.Latitude-35.6895) > 1e-6 || math.Abs(l.Longitude-139.6917) > 1e-6 {
+		t.Errorf("location = %v, %v", l.Latitude, l.Longitude)
+	}
+
+	items, err := ItemList(lib.DB, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		switch item.ID {
+		case photo:
+			if item.Location == nil {
+				t.Error("photo has no location")
+			}
+		case plain:
+			if item.Location != nil {
+				t.Errorf("plain item location = %+v", item.Location)
+			}
+		}
+	}
+
+	// 删除位置后不再出现在 item_locations 中
+	if err := SetItemLocation(lib.DB, photo, nil); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	lib.DB.Model(&dbcommon.ItemLocation{}).Count(&count)
+	if count != 0 {
+		t.Errorf("locations after clearing = %d", count)
+	}
+}
+
+// 在不同位置放置 item，返回名称到 ID 的映射。nowhere 没有位置
+func placeItems(t *testing.T, lib *database.Library) map[string]string {
+	t.Helper()
+	places := []struct {
+		name     string
+		lat, lon float64
+	}{
+		{"tokyo", 35.6895, 139.6917},
+		{"london", 51.5074, -0.1278},
+		{"fiji", -17.7134, 178.0650},
+		{"samoa", -13.7590, -172.1046},
+		{"nowhere", 0, 0},
+	}
+	dir := t.TempDir()
+	ids := map[string]string{}
+	for i, p := range places {
+		id := importFile(t, lib, writePNG(t, dir, p.name+".png", 4, 4, uint8(i)))
+		if p.name != "nowhere" {
+			if err := SetItemLocation(lib.DB, id, &imagemeta.GPS{Latitude: p.lat, Longitude: p.lon}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ids[id] = p.name
+	}
+	return ids
+}
+
+func TestItemListGeoFilter(t *testing.T) {
+	lib := openLibrary(t)
+	names := placeItems(t, lib)
+
+	list := func(area *GeoFilter) ([]string, error) {
+		items, err := ItemList(lib.DB, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, area)
+		var got []string
+		for _, item := range items {
+			got = append(got, names[item.ID])
+		}
+		sort.Strings(got)
+		return got, err
+	}
+
+	tests := []struct {
+		name string
+		area GeoFilter
+		want []string
+	}{
+		{"asia", GeoFilter{BBox: &BBox{South: 20, West: 100, North: 50, East: 150}}, []string{"tokyo"}},
+		{"whole world", GeoFilter{BBox: &BBox{South: -90, West: -180, North: 90, East: 180}}, []string{"fiji", "london", "samoa", "tokyo"}},
+		// 跨越 180° 经线：西边界 170°E，东边界 170°W
+		{"antimeridian", GeoFilter{BBox: &BBox{South: -30, West: 170, North: 0, East: -170}}, []string{"fiji", "samoa"}},
+		{"antimeridian north", GeoFilter{BBox: &BBox{South: 0, West: 170, North: 60, East: -170}}, nil},
+		// 跨越经线的范围可以很宽，包含除 0° 附近以外的全部经度
+		{"wide antimeridian", GeoFilter{BBox: &BBox{South: -60, West: 10, North: 60, East: -10}}, []string{"fiji", "samoa", "tokyo"}},
+		{"radius", GeoFilter{Radius: &Radius{Latitude: 35.68, Longitude: 139.77, Meters: 20000}}, []string{"tokyo"}},
+		// 半径跨越经线
+		{"radius antimeridian", GeoFilter{Radius: &Radius{Latitude: -15, Longitude: 180, Meters: 1000000}}, []string{"fiji", "samoa"}},
+		{"bbox and radius", GeoFilter{BBox: &BBox{South: -30, West: 170, North: 0, East: -170}, Radius: &Radius{Latitude: -17, Longitude: 178, Meters: 200000}}, []string{"fiji"}},
+	}
+	for _, tt := range tests {
+		got, err := list(&tt.area)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !equalStrings(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// 没有位置的 item 只在不筛选位置时出现
+	if got, _ := list(nil); len(got) != 5 {
+		t.Errorf("without filter: %v", got)
+	}
+
+	for _, area := range []GeoFilter{
+		{BBox: &BBox{South: 10, West: 0, North: -10, East: 10}},
+		{BBox: &BBox{South: -10, West: -190, North: 10, East: 10}},
+		{Radius: &Radius{Latitude: 0, Longitude: 0, Meters: 0}},
+		{Radius: &Radius{Latitude: 91, Longitude: 0, Meters: 10}},
+	} {
+		if _, err := list(&area); err == nil {
+			t.Errorf("invalid area accepted: %+v %+v", area.BBox, area.Radius)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// 聚合只包含范围内未删除的 item，跨越经线的范围两侧分别聚合
+func TestGeoClustersAntimeridian(t *testing.T) {
+	lib := openLibrary(t)
+	names := placeItems(t, lib)
+
+	clusters, err := GeoClusters(lib.DB, BBox{South: -30, West: 170, North: 0, East: -170}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range clusters {
+		if c.Count != 1 {
+			t.Errorf("cluster = %+v", c)
+		}
+		got = append(got, names[c.ItemID])
+	}
+	sort.Strings(got)
+	if !equalStrings(got, []string{"fiji", "samoa"}) {
+		t.Errorf("clusters = %v", got)
+	}
+
+	// 缩放级别 0 时整个世界的位置聚合在少数网格中，数量之和为有位置的 item 数
+	clusters, err = GeoClusters(lib.DB, BBox{South: -90, West: -180, North: 90, East: 180}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, c := range clusters {
+		total += c.Count
+	}
+	if total != 4 {
+		t.Errorf("clustered %d items, want 4", total)
+	}
+
+	// 删除的 item 不参与聚合，但仍然可以按位置在回收站中找到
+	for id, name := range names {
+		if name == "fiji" {
+			if err := ItemSoftDelete(lib.DB, []string{id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	clusters, err = GeoClusters(lib.DB, BBox{South: -30, West: 170, North: 0, East: -170}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 1 || names[clusters[0].ItemID] != "samoa" {
+		t.Errorf("clusters after delete = %+v", clusters)
+	}
+	deleted := true
+	items, err := ItemList(lib.DB, &deleted, nil, nil, nil, nil, nil, nil, nil, nil, nil, &GeoFilter{BBox: &BBox{South: -30, West: 170, North: 0, East: -170}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || names[items[0].ID] != "fiji" {
+		t.Errorf("deleted items in box = %+v", items)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"strconv"
+
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+	"synapforest/imagemeta"
+	"synapforest/mesh"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SetItemMetadata 写入（或覆盖）指定 item 的元数据
+func SetItemMetadata(db *gorm.DB, itemID string, metadata map[string]string) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	var records []dbcommon.ItemMetadata
+	for key, value := range metadata {
+		records = append(records, dbcommon.ItemMetadata{ItemID: itemID, Key: key, Value: value})
+	}
+
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "item_id"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+	}).Create(&records).Error
+}
+
+// GetItemMetadata 查询指定 item 的全部元数据
+func GetItemMetadata(db *gorm.DB, itemID string) (map[string]string, error) {
+	var records []dbcommon.ItemMetadata
+	if err := db.Where("item_id = ?", itemID).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to query item metadata: %v", err)
+	}
+
+	metadata := make(map[string]string, len(records))
+	for _, record := range records {
+		metadata[record.Key] = record.Value
+	}
+	return metadata, nil
+}
+
+// BackfillImageMetadata 为已有的图片重新读取 EXIF 等技术信息
+func BackfillImageMetadata(ctx context.Context, db *gorm.DB, progress func(done int, total int)) error {
+	var items []dbcommon.Item
+	if err := db.Unscoped().Select("id", "name", "ext").Find(&items).Error; err != nil {
+		return fmt.Errorf("failed to query items: %v", err)
+	}
+
+	var targets []dbcommon.Item
+	for _, item := range items {
+		if imagemeta.IsSupportedExt(item.Ext) {
+			targets = append(targets, item)
+		}
+	}
+
+	var changed []string
+	defer func() { recordBatch(db, auditdb.ItemBackfillMetadata, changed) }()
+
+	for i, item := range targets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		progress(i, len(targets))
+
+		var info *imagemeta.Info
+		err := withRawFile(db, item.ID, item.Name, item.Ext, func(path string) (err error) {
+			info, err = imagemeta.Read(path)
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to read image metadata for %s: %v", item.ID, err)
+			continue
+		}
+		if err := SetItemMetadata(db, item.ID, info.Metadata()); err != nil {
+			return fmt.Errorf("failed to save metadata for %s: %v", item.ID, err)
+		}
+		if err := SetItemLocation(db, item.ID, info.GPS); err != nil {
+			return fmt.Errorf("failed to save location for %s: %v", item.ID, err)
+		}
+		changed = append(changed, item.ID)
+	}
+	progress(len(targets), len(targets))
+
+	return nil
+}
+
+// 加载 3D 模型并渲染等轴测预览图，同时返回三角形数量与包围盒尺寸
+func renderModel(path string, size int) (image.Image, map[string]string, error) {
+	m, err := mesh.Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	min, max := m.Bounds()
+	metadata := map[string]string{
+		"mesh.triangles": strconv.Itoa(len(m.Triangles)),
+		"mesh.size_x":    strconv.FormatFloat(max[0]-min[0], 'g', -1, 64),
+		"mesh.size_y":    strconv.FormatFloat(max[1]-min[1], 'g', -1, 64),
+		"mesh.size_z":    strconv.FormatFloat(max[2]-min[2], 'g', -1, 64),
+	}
+
+	img, err := mesh.Render(m, size, size)
+	if err != nil {
+		return nil, metadata, err
+	}
+	return img, metadata, nil
+}
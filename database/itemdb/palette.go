@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+	"synapforest/palette"
+
+	"gorm.io/gorm"
+)
+
+// 每个 item 保存的主色数量上限
+const paletteSize = 6
+
+// 颜色搜索默认的 ΔE 距离
+const DefaultColorDistance = 15.0
+
+// 从缩略图计算主色
+func computePalette(itemID string, thumb image.Image) []dbcommon.ItemPalette {
+	var palettes []dbcommon.ItemPalette
+	for _, c := range palette.Extract(thumb, paletteSize) {
+		palettes = append(palettes, dbcommon.ItemPalette{
+			ItemID: itemID,
+			Color:  c.RGB,
+			Weight: c.Weight,
+			L:      c.Lab.L,
+			A:      c.Lab.A,
+			B:      c.Lab.B,
+		})
+	}
+	return palettes
+}
+
+// PaletteOrder 预加载主色时使用，按占比从高到低排列，占比相同时按写入顺序
+func PaletteOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("weight DESC, id ASC")
+}
+
+// SetItemPalette 替换 item 的主色
+func SetItemPalette(db *gorm.DB, itemID string, palettes []dbcommon.ItemPalette) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("item_id = ?", itemID).Delete(&dbcommon.ItemPalette{}).Error; err != nil {
+			return err
+		}
+		if len(palettes) == 0 {
+			return nil
+		}
+		return tx.Create(&palettes).Error
+	})
+}
+
+// PaletteQuery 构造子查询：主色中任意一个与给定颜色的 ΔE 不超过 distance 的 item ID
+func PaletteQuery(db *gorm.DB, hex string, distance *float64) (*gorm.DB, error) {
+	rgb, err := palette.ParseHex(hex)
+	if err != nil {
+		return nil, err
+	}
+	lab := palette.RGBToLab(rgb)
+
+	d := DefaultColorDistance
+	if distance != nil && *distance > 0 {
+		d = *distance
+	}
+
+	return db.Model(&dbcommon.ItemPalette{}).
+		Select("item_id").
+		Where("(l - ?) * (l - ?) + (a - ?) * (a - ?) + (b - ?) * (b - ?) <= ?",
+			lab.L, lab.L, lab.A, lab.A, lab.B, lab.B, d*d), nil
+}
+
+// BackfillPalettes 为已有缩略图但没有主色的 item 计算主色，force 为 true 时重新计算全部
+func BackfillPalettes(ctx context.Context, db *gorm.DB, force bool, progress func(done int, total int)) error {
+	query := db.Unscoped().Model(&dbcommon.Item{}).Where("have_thumbnail = ?", true)
+	if !force {
+		query = query.Where("id NOT IN (?)", db.Model(&dbcommon.ItemPalette{}).Select("item_id"))
+	}
+
+	var itemIDs []string
+	if err := query.Pluck("id", &itemIDs).Error; err != nil {
+		return fmt.Errorf("failed to query items: %v", err)
+	}
+
+	var changed []string
+	defer func() { recordBatch(db, auditdb.ItemBackfillPalettes, changed) }()
+
+	for i, itemID := range itemIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		progress(i, len(itemIDs))
+
+		thumb, err := decodeBlob(db, settingdb.Thumbnail, itemID)
+		if err != nil {
+			log.Printf("Failed to compute palette for %s: %v", itemID, err)
+			continue
+		}
+		if err := SetItemPalette(db, itemID, computePalette(itemID, thumb)); err != nil {
+			return fmt.Errorf("failed to save palette for %s: %v", itemID, err)
+		}
+		changed = append(changed, itemID)
+	}
+	progress(len(itemIDs), len(itemIDs))
+
+	return nil
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"slices"
+	"testing"
+
+	"synapforest/database/dbcommon"
+)
+
+func weights(palettes []dbcommon.ItemPalette) []float64 {
+	var w []float64
+	for _, p := range palettes {
+		w = append(w, p.Weight)
+	}
+	return w
+}
+
+func TestPalettesOrderedByWeight(t *testing.T) {
+	lib := openLibrary(t)
+	id := importFile(t, lib, writePNG(t, t.TempDir(), "a.png", 4, 4, 1))
+	err := SetItemPalette(lib.DB, id, []dbcommon.ItemPalette{
+		{ItemID: id, Color: 0x111111, Weight: 0.2},
+		{ItemID: id, Color: 0x222222, Weight: 0.5},
+		{ItemID: id, Color: 0x333333, Weight: 0.3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0.5, 0.3, 0.2}
+
+	items, err := ItemList(lib.DB, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || !slices.Equal(weights(items[0].Palettes), want) {
+		t.Errorf("ItemList palettes = %v", weights(items[0].Palettes))
+	}
+
+	sidecars, err := loadSidecars(lib.DB, []string{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := weights(sidecars[id].Palettes); !slices.Equal(got, want) {
+		t.Errorf("sidecar palettes = %v", got)
+	}
+}
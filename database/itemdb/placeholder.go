@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"os"
+
+	"synapforest/blurhash"
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+
+	"gorm.io/gorm"
+)
+
+func decodeImageFile(path string) (img image.Image, err error) {
+	defer recoverDecoder(&err)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open image failed: %v", err)
+	}
+	defer file.Close()
+
+	img, _, err = image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("decode image failed: %v", err)
+	}
+	return img, nil
+}
+
+// 从缩略图计算 BlurHash 占位图
+func computePlaceholder(thumb image.Image) (string, error) {
+	return blurhash.Encode(thumb)
+}
+
+// BackfillPlaceholders 为已有缩略图但没有占位图的 item 计算占位图，force 为 true 时重新计算全部
+func BackfillPlaceholders(ctx context.Context, db *gorm.DB, force bool, progress func(done int, total int)) error {
+	query := db.Unscoped().Model(&dbcommon.Item{}).Where("have_thumbnail = ?", true)
+	if !force {
+		query = query.Where("placeholder = '' OR placeholder IS NULL")
+	}
+
+	var itemIDs []string
+	if err := query.Pluck("id", &itemIDs).Error; err != nil {
+		return fmt.Errorf("failed to query items: %v", err)
+	}
+
+	var changed []string
+	defer func() { recordBatch(db, auditdb.ItemBackfillPlaceholder, changed) }()
+
+	for i, itemID := range itemIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		progress(i, len(itemIDs))
+
+		thumb, err := decodeBlob(db, settingdb.Thumbnail, itemID)
+		if err != nil {
+			log.Printf("Failed to read thumbnail for %s: %v", itemID, err)
+			continue
+		}
+		placeholder, err := computePlaceholder(thumb)
+		if err != nil {
+			log.Printf("Failed to compute placeholder for %s: %v", itemID, err)
+			continue
+		}
+		err = db.Unscoped().Model(&dbcommon.Item{}).Where("id = ?", itemID).Update("placeholder", placeholder).Error
+		if err != nil {
+			return fmt.Errorf("failed to save placeholder for %s: %v", itemID, err)
+		}
+		changed = append(changed, itemID)
+	}
+	progress(len(itemIDs), len(itemIDs))
+
+	return nil
+}
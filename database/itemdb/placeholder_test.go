@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"synapforest/blurhash"
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+)
+
+// 导入时由缩略图计算占位图，补充任务只处理缺少占位图且有缩略图的 item
+func TestPlaceholderOnImportAndBackfill(t *testing.T) {
+	lib := openLibrary(t)
+	dir := t.TempDir()
+	wide := importFile(t, lib, writePNG(t, dir, "wide.png", 64, 32, 1))
+	tall := importFile(t, lib, writePNG(t, dir, "tall.png", 32, 64, 2))
+	zip := filepath.Join(dir, "a.zip")
+	if err := os.WriteFile(zip, []byte("PK\x05\x06"+string(make([]byte, 18))), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	archive := importFile(t, lib, zip)
+
+	read := func() map[string]string {
+		t.Helper()
+		var items []dbcommon.Item
+		if err := lib.DB.Find(&items).Error; err != nil {
+			t.Fatal(err)
+		}
+		m := map[string]string{}
+		for _, item := range items {
+			m[item.ID] = item.Placeholder
+		}
+		return m
+	}
+
+	// 横图 4x3 分量，竖图 3x4 分量
+	imported := read()
+	if len(imported[wide]) != 28 || imported[wide][:1] != "L" || len(imported[tall]) != 28 || imported[tall][:1] != "T" {
+		t.Errorf("placeholders = %v", imported)
+	}
+	if imported[archive] != "" {
+		t.Errorf("placeholder of archive = %q", imported[archive])
+	}
+
+	if err := lib.DB.Model(&dbcommon.Item{}).Where("id = ?", wide).Update("placeholder", "").Error; err != nil {
+		t.Fatal(err)
+	}
+	var total int
+	if err := BackfillPlaceholders(context.Background(), lib.DB, false, func(done, n int) { total = n }); err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 {
+		t.Errorf("backfilled %d items, want 1", total)
+	}
+	// 补充时从保存的缩略图计算，其余 item 不变
+	thumb, err := decodeBlob(lib.DB, settingdb.Thumbnail, wide)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := blurhash.Encode(thumb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after := read(); after[wide] != want || after[tall] != imported[tall] || after[archive] != "" {
+		t.Errorf("after backfill = %v, want %s for %s", after, want, wide)
+	}
+
+	var entry dbcommon.AuditEntry
+	if err := lib.DB.Preload("Targets").Where("operation = ?", auditdb.ItemBackfillPlaceholder).First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Targets) != 1 || entry.Targets[0].Target != wide {
+		t.Errorf("audited targets = %+v", entry.Targets)
+	}
+
+	// force 时重新计算全部有缩略图的 item
+	if err := BackfillPlaceholders(context.Background(), lib.DB, true, func(done, n int) { total = n }); err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Errorf("forced backfill of %d items, want 2", total)
+	}
+}
@@ -0,0 +1,410 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"synapforest/blobstore"
+	"synapforest/database"
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+	"synapforest/database/migrate"
+	"synapforest/database/settingdb"
+	"synapforest/imagemeta"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// RebuildResult 重建数据库的结果
+type RebuildResult struct {
+	Manifest       bool     `json:"manifest"`        // 是否读取到资料库清单
+	Items          int      `json:"items"`           // 从元数据文件恢复的 item
+	WithoutSidecar int      `json:"without_sidecar"` // 没有元数据文件，只按文件名恢复的 item
+	Folders        int      `json:"folders"`
+	Tags           int      `json:"tags"`
+	Recovered      int      `json:"recovered"` // 清单中没有、按 item 引用重新创建的文件夹和标签
+	Failed         []string `json:"failed"`    // 无法恢复的目录及原因
+}
+
+// RebuildOptions 重建时需要、但没有保存在清单中的信息
+type RebuildOptions struct {
+	S3SecretKey string // 清单中的存储设置为 s3 时使用的密钥
+}
+
+var fileIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// RebuildDatabase 根据资料库目录中的 library.json 和每个 item 目录中的 metadata.json
+// 重新生成 files.db，写入 output。output 必须不存在，资料库不能处于打开状态。
+// 没有元数据文件的 item 按文件名恢复，之后需要运行 metadata_backfill 和 rendition_regenerate
+func RebuildDatabase(dir string, output string, opts RebuildOptions) (*RebuildResult, error) {
+	if _, err := os.Stat(output); err == nil {
+		return nil, fmt.Errorf("%s already exists", output)
+	}
+	result := &RebuildResult{Failed: []string{}}
+
+	manifest := &LibraryManifest{}
+	data, err := os.ReadFile(LibraryManifestPath(dir))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, manifest); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", LibraryManifestName, err)
+		}
+		if manifest.Format != SidecarFormat {
+			return nil, fmt.Errorf("unsupported %s format %d", LibraryManifestName, manifest.Format)
+		}
+		result.Manifest = true
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	tmp := output + ".partial"
+	os.Remove(tmp)
+	db, err := gorm.Open(sqlite.Open(tmp), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		sqlDB.Close()
+		os.Remove(tmp)
+	}()
+
+	if _, err := migrate.Files.Migrate(context.Background(), db, ""); err != nil {
+		return nil, fmt.Errorf("failed to create tables: %v", err)
+	}
+	if err := restoreManifest(db, manifest, result); err != nil {
+		return nil, err
+	}
+
+	// 存储设置来自清单，清单中不保存对象存储的密钥
+	cfg, err := settingdb.GetBlobStore(db)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Type == "s3" && cfg.S3.SecretKey == "" {
+		if opts.S3SecretKey == "" {
+			return nil, fmt.Errorf("%s uses an s3 blob store, its secret key is required", LibraryManifestName)
+		}
+		cfg.S3.SecretKey = opts.S3SecretKey
+		if err := settingdb.SetBlobStore(db, cfg); err != nil {
+			return nil, err
+		}
+	}
+	store, err := blobstore.New(cfg, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob store: %v", err)
+	}
+
+	keys, err := store.List("raw_files")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list raw files: %v", err)
+	}
+	dirs := map[string][]string{}
+	for _, key := range keys {
+		id := path.Base(path.Dir(key))
+		dirs[id] = append(dirs[id], key)
+	}
+	ids := make([]string, 0, len(dirs))
+	for id := range dirs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	folders := map[uuid.UUID]bool{}
+	for _, f := range manifest.Folders {
+		folders[f.ID] = true
+	}
+	tags := map[uuid.UUID]bool{}
+	for _, t := range manifest.Tags {
+		tags[t.ID] = true
+	}
+
+	for start := 0; start < len(ids); start += sidecarBatchSize {
+		batch := ids[start:min(start+sidecarBatchSize, len(ids))]
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, id := range batch {
+				if !fileIDPattern.MatchString(id) {
+					result.Failed = append(result.Failed, RawDir(id)+": invalid item id")
+					continue
+				}
+
+				var s *Sidecar
+				var err error
+				if contains(dirs[id], SidecarKey(id)) {
+					s, err = readSidecar(store, id)
+				} else {
+					s, err = sidecarFromFiles(store, id, dirs[id])
+					if err == nil {
+						result.WithoutSidecar++
+					}
+				}
+				if err == nil {
+					// 每个 item 使用单独的保存点，失败时不留下部分写入的记录
+					var recovered recoveredNodes
+					err = tx.Transaction(func(tx *gorm.DB) error {
+						return insertSidecar(tx, s, folders, tags, &recovered)
+					})
+					recovered.apply(err, folders, tags, result)
+				}
+				if err != nil {
+					result.Failed = append(result.Failed, RawDir(id)+": "+err.Error())
+					continue
+				}
+				result.Items++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	result.Items -= result.WithoutSidecar
+
+	// 原有的审计日志无法从元数据文件恢复，新的日志从重建开始
+	if err := auditdb.Record(db, auditdb.Change{Operation: auditdb.LibraryRebuild, After: result}); err != nil {
+		return nil, err
+	}
+
+	if err := sqlDB.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, output); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func restoreManifest(db *gorm.DB, m *LibraryManifest, result *RebuildResult) error {
+	for _, setting := range m.Settings {
+		if err := db.Create(&setting).Error; err != nil {
+			return fmt.Errorf("failed to restore setting %s: %v", setting.Key, err)
+		}
+	}
+	for _, n := range m.Folders {
+		folder := dbcommon.Folder{
+			ID:          n.ID,
+			CreatedAt:   n.CreatedAt,
+			ModifiedAt:  n.ModifiedAt,
+			ParentID:    n.Parent,
+			Name:        n.Name,
+			Description: n.Description,
+			Icon:        n.Icon,
+			IconColor:   n.IconColor,
+			IsExpand:    n.IsExpand,
+			DeletedAt:   toDeletedAt(n.DeletedAt),
+		}
+		if err := db.Create(&folder).Error; err != nil {
+			return fmt.Errorf("failed to restore folder %s: %v", n.ID, err)
+		}
+		if n.ID != uuid.Nil {
+			result.Folders++
+		}
+	}
+	for _, n := range m.Tags {
+		tag := dbcommon.Tag{
+			ID:          n.ID,
+			CreatedAt:   n.CreatedAt,
+			ModifiedAt:  n.ModifiedAt,
+			ParentID:    n.Parent,
+			Name:        n.Name,
+			Description: n.Description,
+			Icon:        n.Icon,
+			IconColor:   n.IconColor,
+			IsExpand:    n.IsExpand,
+			DeletedAt:   toDeletedAt(n.DeletedAt),
+		}
+		if err := db.Create(&tag).Error; err != nil {
+			return fmt.Errorf("failed to restore tag %s: %v", n.ID, err)
+		}
+		result.Tags++
+	}
+	return database.CreateRootFolder(db)
+}
+
+func readSidecar(store blobstore.Store, id string) (*Sidecar, error) {
+	data, err := readBlob(store, SidecarKey(id))
+	if err != nil {
+		return nil, err
+	}
+	var s Sidecar
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", SidecarName, err)
+	}
+	if s.Format != SidecarFormat {
+		return nil, fmt.Errorf("unsupported %s format %d", SidecarName, s.Format)
+	}
+	if s.ID != id {
+		return nil, fmt.Errorf("%s belongs to item %s", SidecarName, s.ID)
+	}
+	return &s, nil
+}
+
+// 目录中只有一个原始文件时按文件名和文件信息恢复
+func sidecarFromFiles(store blobstore.Store, id string, keys []string) (*Sidecar, error) {
+	if len(keys) != 1 {
+		return nil, fmt.Errorf("no %s and %d files in directory", SidecarName, len(keys))
+	}
+	info, err := store.Stat(keys[0])
+	if err != nil {
+		return nil, err
+	}
+
+	base := path.Base(keys[0])
+	ext := path.Ext(base)
+	return &Sidecar{
+		ID:         id,
+		CreatedAt:  info.ModTime,
+		ImportedAt: info.ModTime,
+		ModifiedAt: info.ModTime,
+		Name:       strings.TrimSuffix(base, ext),
+		Ext:        strings.TrimPrefix(ext, "."),
+		Size:       uint64(info.Size),
+	}, nil
+}
+
+// recoveredNodes 一个 item 引用的、清单中没有而重新创建的文件夹和标签
+type recoveredNodes struct {
+	folders []uuid.UUID
+	tags    []uuid.UUID
+}
+
+// item 写入成功时计入结果，失败时保存点已回滚，从已知的文件夹和标签中移除
+func (r *recoveredNodes) apply(err error, folders map[uuid.UUID]bool, tags map[uuid.UUID]bool, result *RebuildResult) {
+	if err == nil {
+		result.Recovered += len(r.folders) + len(r.tags)
+		return
+	}
+	for _, id := range r.folders {
+		delete(folders, id)
+	}
+	for _, id := range r.tags {
+		delete(tags, id)
+	}
+}
+
+func insertSidecar(db *gorm.DB, s *Sidecar, folders map[uuid.UUID]bool, tags map[uuid.UUID]bool, recovered *recoveredNodes) error {
+	item := dbcommon.Item{
+		ID:            s.ID,
+		CreatedAt:     s.CreatedAt,
+		ImportedAt:    s.ImportedAt,
+		ModifiedAt:    s.ModifiedAt,
+		Name:          s.Name,
+		Ext:           s.Ext,
+		Width:         s.Width,
+		Height:        s.Height,
+		Size:          s.Size,
+		Url:           s.Url,
+		Annotation:    s.Annotation,
+		Star:          s.Star,
+		HaveThumbnail: s.HaveThumbnail,
+		HavePreview:   s.HavePreview,
+		Placeholder:   s.Placeholder,
+		ImportError:   s.ImportError,
+	}
+	item.DeletedAt = toDeletedAt(s.DeletedAt)
+	if err := db.Create(&item).Error; err != nil {
+		return err
+	}
+
+	// 清单中没有的文件夹和标签放在顶层，保留 item 的归属
+	for _, id := range s.Folders {
+		if !folders[id] {
+			name := "Recovered " + id.String()[:8]
+			if err := db.Create(&dbcommon.Folder{ID: id, Name: name, ParentID: uuid.Nil}).Error; err != nil {
+				return err
+			}
+			folders[id] = true
+			recovered.folders = append(recovered.folders, id)
+		}
+		if err := db.Table("item_folders").Create(map[string]interface{}{"item_id": s.ID, "folder_id": id}).Error; err != nil {
+			return err
+		}
+	}
+	for _, id := range s.Tags {
+		if !tags[id] {
+			name := "Recovered " + id.String()[:8]
+			if err := db.Create(&dbcommon.Tag{ID: id, Name: name, ParentID: uuid.Nil}).Error; err != nil {
+				return err
+			}
+			tags[id] = true
+			recovered.tags = append(recovered.tags, id)
+		}
+		if err := db.Table("item_tags").Create(map[string]interface{}{"item_id": s.ID, "tag_id": id}).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(s.Metadata) > 0 {
+		if err := SetItemMetadata(db, s.ID, s.Metadata); err != nil {
+			return err
+		}
+	}
+	if s.Content != nil {
+		if err := SetItemContent(db, s.ID, *s.Content); err != nil {
+			return err
+		}
+	}
+	for _, p := range s.Palettes {
+		p.ID = 0
+		p.ItemID = s.ID
+		if err := db.Create(&p).Error; err != nil {
+			return err
+		}
+	}
+	if l := s.Location; l != nil {
+		gps := &imagemeta.GPS{Latitude: l.Latitude, Longitude: l.Longitude, Altitude: l.Altitude}
+		if err := db.Create(newLocation(s.ID, gps)).Error; err != nil {
+			return err
+		}
+	}
+	if d := s.Derivation; d != nil {
+		d.ItemID = s.ID
+		if err := db.Create(d).Error; err != nil {
+			return err
+		}
+	}
+	for _, v := range s.Versions {
+		v.ItemID = s.ID
+		if err := db.Create(&v).Error; err != nil {
+			return err
+		}
+	}
+	for _, r := range s.Renditions {
+		r.ItemID = s.ID
+		if err := db.Create(&r).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toDeletedAt(t *time.Time) gorm.DeletedAt {
+	if t == nil {
+		return gorm.DeletedAt{}
+	}
+	return gorm.DeletedAt{Time: *t, Valid: true}
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"synapforest/blobstore"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func editSidecar(t *testing.T, dir string, id string, edit func(s *Sidecar)) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(SidecarKey(id)))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s Sidecar
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	edit(&s)
+	if data, err = json.Marshal(&s); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 写入途中失败的 item 整体回滚，并在结果中报告
+func TestRebuildDatabaseRollsBackFailedItem(t *testing.T) {
+	lib := openLibrary(t)
+	if err := StartServices(lib); err != nil {
+		t.Fatal(err)
+	}
+	files := t.TempDir()
+	good := importFile(t, lib, writePNG(t, files, "a.png", 4, 4, 1))
+	bad := importFile(t, lib, writePNG(t, files, "b.png", 4, 4, 2))
+	dir := lib.Dir
+	if err := lib.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := uuid.Must(uuid.NewV4())
+	editSidecar(t, dir, bad, func(s *Sidecar) {
+		// 清单中没有的文件夹会被重新创建，重复的规格记录在最后写入时失败
+		s.Folders = append(s.Folders, missing)
+		s.Renditions = append(s.Renditions, dbcommon.ItemRendition{Name: "thumbnail"}, dbcommon.ItemRendition{Name: "thumbnail"})
+	})
+
+	output := filepath.Join(t.TempDir(), "files.db")
+	result, err := RebuildDatabase(dir, output, RebuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Items != 1 || len(result.Failed) != 1 || result.Recovered != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	db, err := gorm.Open(sqlite.Open(output), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	var ids []string
+	if err := db.Unscoped().Model(&dbcommon.Item{}).Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != good {
+		t.Errorf("items = %v", ids)
+	}
+	for _, table := range []string{"item_metadata", "item_palettes", "item_renditions", "item_folders"} {
+		var n int64
+		if err := db.Table(table).Where("item_id = ?", bad).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("%s has %d rows of the failed item", table, n)
+		}
+	}
+	var n int64
+	if err := db.Model(&dbcommon.Folder{}).Where("id = ?", missing).Count(&n).Error; err != nil || n != 0 {
+		t.Errorf("recovered folder kept: %d %v", n, err)
+	}
+}
+
+// 清单中不保存对象存储的密钥，重建时由调用方提供
+func TestManifestOmitsS3SecretKey(t *testing.T) {
+	lib := openLibrary(t)
+	if err := StartServices(lib); err != nil {
+		t.Fatal(err)
+	}
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		fmt.Fprint(w, "<ListBucketResult><IsTruncated>false</IsTruncated></ListBucketResult>")
+	}))
+	defer srv.Close()
+	cfg := blobstore.Config{Type: "s3", S3: blobstore.S3Config{
+		Endpoint: srv.URL, Bucket: "library", AccessKey: "AKID", SecretKey: "top-secret", PathStyle: true,
+	}}
+	if err := settingdb.SetBlobStore(lib.DB, cfg); err != nil {
+		t.Fatal(err)
+	}
+	dir := lib.Dir
+	if err := lib.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(LibraryManifestPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "top-secret") || !strings.Contains(string(data), "AKID") {
+		t.Fatalf("manifest = %s", data)
+	}
+
+	output := filepath.Join(t.TempDir(), "files.db")
+	if _, err := RebuildDatabase(dir, output, RebuildOptions{}); err == nil || !strings.Contains(err.Error(), "secret key") {
+		t.Errorf("rebuild without secret: %v", err)
+	}
+	if _, err := RebuildDatabase(dir, output, RebuildOptions{S3SecretKey: "top-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(auth) == 0 || !strings.HasPrefix(auth[0], "AWS4-HMAC-SHA256 Credential=AKID/") {
+		t.Errorf("requests = %q", auth)
+	}
+
+	db, err := gorm.Open(sqlite.Open(output), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	restored, err := settingdb.GetBlobStore(db)
+	if err != nil || restored.S3.SecretKey != "top-secret" {
+		t.Errorf("restored = %+v, %v", restored, err)
+	}
+}
@@ -0,0 +1,414 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+
+	"synapforest/blobstore"
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+	"synapforest/mesh"
+	"synapforest/textrender"
+
+	"github.com/chai2010/webp"
+	"github.com/gofrs/uuid"
+	"github.com/nfnt/resize"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 可以直接解码生成预览图的扩展名
+func isImageExt(ext string) bool {
+	return ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".gif"
+}
+
+// RenditionDir 规格在存储中的目录，缩略图和预览图沿用原有目录
+func RenditionDir(name string) string {
+	switch name {
+	case settingdb.Thumbnail:
+		return "thumbnails"
+	case settingdb.Preview:
+		return "previews"
+	}
+	return path.Join("renditions", name)
+}
+
+// 规格格式对应的扩展名
+func renditionExt(format string) string {
+	if format == "jpeg" {
+		return "jpg"
+	}
+	return format
+}
+
+// RenditionKey 某个 item 在指定规格下的文件键
+func RenditionKey(r dbcommon.Rendition, itemID string) string {
+	return path.Join(RenditionDir(r.Name), itemID+"."+renditionExt(r.Format))
+}
+
+// RenditionFile 按当前设置查找 item 指定规格的文件键。修改规格的格式后、重新生成之前，
+// 返回旧格式的文件；都不存在时返回当前格式的键
+func RenditionFile(db *gorm.DB, name string, itemID string) (string, error) {
+	r, err := settingdb.GetRendition(db, name)
+	if err != nil {
+		return "", err
+	}
+	key, _ := renditionFile(db, r, itemID)
+	return key, nil
+}
+
+// 查找 item 在规格下实际存在的文件，优先使用当前格式
+func renditionFile(db *gorm.DB, r dbcommon.Rendition, itemID string) (string, bool) {
+	key := RenditionKey(r, itemID)
+	if blobstore.Exists(blobs(db), key) {
+		return key, true
+	}
+	for _, format := range settingdb.RenditionFormats {
+		if format == r.Format {
+			continue
+		}
+		old := path.Join(RenditionDir(r.Name), itemID+"."+renditionExt(format))
+		if blobstore.Exists(blobs(db), old) {
+			return old, true
+		}
+	}
+	return key, false
+}
+
+// AvailableRenditions 返回 item 已生成文件的预览图规格
+func AvailableRenditions(db *gorm.DB, itemID string) ([]dbcommon.Rendition, error) {
+	renditions, err := settingdb.GetRenditions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var available []dbcommon.Rendition
+	for _, r := range renditions {
+		if _, ok := renditionFile(db, r, itemID); ok {
+			available = append(available, r)
+		}
+	}
+	return available, nil
+}
+
+// EncodeImage 按格式编码图像，JPEG 会先合成到白色背景上
+func EncodeImage(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case "jpeg":
+		flat := image.NewRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+		return jpeg.Encode(w, flat, &jpeg.Options{Quality: quality})
+	case "png":
+		return png.Encode(w, img)
+	default:
+		return webp.Encode(w, img, &webp.Options{Quality: float32(quality)})
+	}
+}
+
+// 删除 item 在某个规格目录下的文件，包括格式变更前留下的旧文件，keep 除外
+func removeRenditionFiles(db *gorm.DB, dir string, itemID string, keep string) error {
+	for _, format := range settingdb.RenditionFormats {
+		key := path.Join(dir, itemID+"."+renditionExt(format))
+		if key == keep {
+			continue
+		}
+		if err := blobs(db).Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 删除 item 在当前设置的全部规格下的文件，不修改数据库
+func removeRenditionFilesOf(db *gorm.DB, itemID string) error {
+	renditions, err := settingdb.GetRenditions(db)
+	if err != nil {
+		return err
+	}
+	for _, r := range renditions {
+		if err := removeRenditionFiles(db, RenditionDir(r.Name), itemID, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 将图像缩放到规格的像素上限内并保存，返回缩放后的图像和文件大小
+func saveRendition(db *gorm.DB, img image.Image, r dbcommon.Rendition, itemID string) (image.Image, int64, error) {
+	key := RenditionKey(r, itemID)
+	if err := removeRenditionFiles(db, RenditionDir(r.Name), itemID, key); err != nil {
+		return nil, 0, fmt.Errorf("remove old rendition failed: %v", err)
+	}
+
+	newWidth, newHeight := calculateThumbnailSize(img.Bounds().Dx(), img.Bounds().Dy(), r.MaxPixels)
+	scaled := resize.Resize(newWidth, newHeight, prescale(img, int(newWidth), int(newHeight)), resize.Lanczos3)
+
+	var buf bytes.Buffer
+	if err := EncodeImage(&buf, scaled, r.Format, r.Quality); err != nil {
+		return nil, 0, err
+	}
+	size := int64(buf.Len())
+	if err := blobs(db).Put(key, &buf, size); err != nil {
+		return nil, 0, fmt.Errorf("save rendition failed: %v", err)
+	}
+	return scaled, size, nil
+}
+
+// Lanczos 的开销随源图像素数增长，源图大于目标两倍时先按 2x2 平均减半，直到小于目标尺寸的两倍
+func prescale(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	if b.Dx() < width*2 || b.Dy() < height*2 {
+		return img
+	}
+
+	src, ok := img.(*image.RGBA)
+	if !ok {
+		src = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	}
+
+	for src.Bounds().Dx() >= width*2 && src.Bounds().Dy() >= height*2 {
+		src = halve(src)
+	}
+	return src
+}
+
+func halve(src *image.RGBA) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()/2, b.Dy()/2))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		row0 := src.Pix[(2*y)*src.Stride:]
+		row1 := src.Pix[(2*y+1)*src.Stride:]
+		out := dst.Pix[y*dst.Stride:]
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			i := 8 * x
+			for c := 0; c < 4; c++ {
+				sum := uint32(row0[i+c]) + uint32(row0[i+4+c]) + uint32(row1[i+c]) + uint32(row1[i+4+c])
+				out[4*x+c] = uint8((sum + 2) / 4)
+			}
+		}
+	}
+	return dst
+}
+
+// 按设置生成 item 的预览图规格，names 为空时生成全部，返回成功生成的规格及其图像。
+// 从大到小依次生成，较小的规格从上一个结果缩放，避免每次都缩放原图
+func saveRenditions(db *gorm.DB, img image.Image, itemID string, names []string) (map[string]image.Image, error) {
+	renditions, err := settingdb.GetRenditions(db)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(renditions, func(i, j int) bool {
+		return renditions[i].MaxPixels > renditions[j].MaxPixels
+	})
+
+	saved := make(map[string]image.Image)
+	source := img
+	for _, r := range renditions {
+		if len(names) > 0 && !contains(names, r.Name) {
+			continue
+		}
+		scaled, size, err := saveRendition(db, source, r, itemID)
+		if err != nil {
+			log.Printf("Failed to generate rendition %s for %s: %v", r.Name, itemID, err)
+			continue
+		}
+		if err := setRenditionSize(db, itemID, r.Name, size); err != nil {
+			log.Printf("Failed to record rendition size for %s: %v", itemID, err)
+		}
+		saved[r.Name] = scaled
+		// 只有确实缩小过的结果才作为下一个规格的来源，否则保持原图
+		if scaled.Bounds().Dx() < source.Bounds().Dx() {
+			source = scaled
+		}
+	}
+	return saved, nil
+}
+
+func setRenditionSize(db *gorm.DB, itemID string, name string, size int64) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "item_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"size"}),
+	}).Create(&dbcommon.ItemRendition{ItemID: itemID, Name: name, Size: size}).Error
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// errNoRenderer 文件类型没有预览图，不是失败
+var errNoRenderer = errors.New("no renderer")
+
+// 从原始文件得到用于生成预览图的图像：图片直接解码，模型和文本渲染成图
+func renderSource(path string, ext string, limits dbcommon.ImportLimits) (img image.Image, err error) {
+	defer recoverDecoder(&err)
+
+	switch {
+	case isImageExt(ext):
+		return DecodeImage(path, limits)
+	case mesh.IsModelExt(ext):
+		if info, err := os.Stat(path); err == nil && limits.MaxFileSize > 0 && info.Size() > limits.MaxFileSize {
+			return nil, fmt.Errorf("file size %d bytes exceeds the limit of %d bytes", info.Size(), limits.MaxFileSize)
+		}
+		img, _, err := renderModel(path, 768)
+		return img, err
+	case textrender.IsTextFile(path, ext):
+		text, err := textrender.ReadText(path, textrender.MaxContentSize)
+		if err != nil {
+			return nil, err
+		}
+		return textrender.Render(text, ext, 768, 768), nil
+	}
+	return nil, fmt.Errorf("%w for %s files", errNoRenderer, ext)
+}
+
+// RenditionTargets 查找需要重新生成预览图的 item，条件为空时返回全部
+func RenditionTargets(db *gorm.DB, itemIDs []string, exts []string, tags []uuid.UUID, folders []uuid.UUID, missingOnly bool) ([]string, error) {
+	query := db.Unscoped().Model(&dbcommon.Item{})
+
+	if len(itemIDs) > 0 {
+		query = query.Where("items.id IN ?", itemIDs)
+	}
+	if len(exts) > 0 {
+		query = query.Where("ext IN ?", exts)
+	}
+	if len(tags) > 0 {
+		query = query.Where("items.id IN (?)", db.Table("item_tags").Select("item_id").Where("tag_id IN ?", tags))
+	}
+	if len(folders) > 0 {
+		query = query.Where("items.id IN (?)", db.Table("item_folders").Select("item_id").Where("folder_id IN ?", folders))
+	}
+	if missingOnly {
+		query = query.Where("have_thumbnail = ? OR have_preview = ?", false, false)
+	}
+
+	var ids []string
+	if err := query.Order("items.id ASC").Pluck("items.id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to query items: %v", err)
+	}
+	return ids, nil
+}
+
+// RegenerateRenditions 从原始文件重新生成指定 item 的预览图规格，names 为空时生成全部。
+// HaveThumbnail/HavePreview 按生成后文件是否存在更新
+func RegenerateRenditions(ctx context.Context, db *gorm.DB, itemIDs []string, names []string, progress func(done int, total int)) error {
+	var changed []string
+	defer func() { recordBatch(db, auditdb.ItemRegenerate, changed) }()
+
+	for i, itemID := range itemIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		progress(i, len(itemIDs))
+
+		if err := regenerateItem(db, itemID, names); errors.Is(err, errNoRenderer) {
+			continue
+		} else if err != nil {
+			log.Printf("Failed to regenerate renditions for %s: %v", itemID, err)
+			continue
+		}
+		changed = append(changed, itemID)
+	}
+	progress(len(itemIDs), len(itemIDs))
+
+	return nil
+}
+
+func regenerateItem(db *gorm.DB, itemID string, names []string) error {
+	var item dbcommon.Item
+	if err := db.Unscoped().First(&item, "id = ?", itemID).Error; err != nil {
+		return err
+	}
+
+	limits, err := settingdb.GetImportLimits(db)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{}
+
+	ext := "." + item.Ext
+	var saved map[string]image.Image
+	rawPath, cleanup, err := blobstore.Fetch(blobs(db), RawFileKey(item.ID, item.Name, item.Ext))
+	if err == nil {
+		var img image.Image
+		img, err = renderSource(rawPath, ext, limits)
+		cleanup()
+		// 与导入时一样跳过没有预览图的类型，保留原有的 import_error
+		if errors.Is(err, errNoRenderer) {
+			return err
+		}
+		if err == nil {
+			saved, err = saveRenditions(db, img, itemID, names)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to render %s: %v", itemID, err)
+		updates["import_error"] = err.Error()
+	} else {
+		updates["import_error"] = ""
+	}
+
+	for _, name := range []string{settingdb.Thumbnail, settingdb.Preview} {
+		if len(names) > 0 && !contains(names, name) {
+			continue
+		}
+		key, err := RenditionFile(db, name, itemID)
+		if err != nil {
+			return err
+		}
+		updates["have_"+name] = blobstore.Exists(blobs(db), key)
+	}
+
+	if have, ok := updates["have_"+settingdb.Thumbnail].(bool); ok {
+		placeholder := ""
+		if have {
+			thumb := saved[settingdb.Thumbnail]
+			if thumb == nil {
+				thumb, err = decodeBlob(db, settingdb.Thumbnail, itemID)
+			}
+			if err == nil {
+				placeholder, err = computePlaceholder(thumb)
+			}
+			if err != nil {
+				log.Printf("Failed to compute placeholder for %s: %v", itemID, err)
+			}
+		}
+		updates["placeholder"] = placeholder
+	}
+
+	return db.Unscoped().Model(&dbcommon.Item{}).Where("id = ?", itemID).Updates(updates).Error
+}
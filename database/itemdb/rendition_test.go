@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"synapforest/blobstore"
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+)
+
+// 修改格式后、重新生成之前继续使用旧格式的文件
+func TestRenditionFileAfterFormatChange(t *testing.T) {
+	lib := openLibrary(t)
+	id := importFile(t, lib, writePNG(t, t.TempDir(), "a.png", 64, 64, 1))
+
+	before, err := RenditionFile(lib.DB, settingdb.Thumbnail, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path.Ext(before) != ".webp" || !blobstore.Exists(lib.Blobs, before) {
+		t.Fatalf("thumbnail = %s", before)
+	}
+
+	renditions, err := settingdb.GetRenditions(lib.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range renditions {
+		renditions[i].Format = "png"
+	}
+	if err := settingdb.SetRenditions(lib.DB, renditions); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := RenditionFile(lib.DB, settingdb.Thumbnail, id); err != nil || got != before {
+		t.Errorf("before regenerate = %s, %v", got, err)
+	}
+	if available, err := AvailableRenditions(lib.DB, id); err != nil || len(available) != len(renditions) {
+		t.Errorf("available = %v, %v", available, err)
+	}
+
+	if err := RegenerateRenditions(context.Background(), lib.DB, []string{id}, nil, func(int, int) {}); err != nil {
+		t.Fatal(err)
+	}
+	after, err := RenditionFile(lib.DB, settingdb.Thumbnail, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path.Ext(after) != ".png" || !blobstore.Exists(lib.Blobs, after) {
+		t.Errorf("after regenerate = %s", after)
+	}
+	if blobstore.Exists(lib.Blobs, before) {
+		t.Error("old thumbnail not removed")
+	}
+}
+
+// 没有预览图的类型在重新生成时跳过，不覆盖导入时的错误
+func TestRegenerateSkipsItemsWithoutRenderer(t *testing.T) {
+	lib := openLibrary(t)
+	files := t.TempDir()
+	binary := []byte{0x00, 0x01, 0x02, 0xff, 0x00, 0x10}
+
+	archivePath := filepath.Join(files, "archive.zip")
+	pdfPath := filepath.Join(files, "broken.pdf")
+	for _, p := range []string{archivePath, pdfPath} {
+		if err := os.WriteFile(p, append(binary, p...), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive := importFile(t, lib, archivePath)
+	pdf := importFile(t, lib, pdfPath)
+	img := importFile(t, lib, writePNG(t, files, "a.png", 8, 8, 1))
+
+	importErrors := func() map[string]string {
+		var items []dbcommon.Item
+		if err := lib.DB.Find(&items).Error; err != nil {
+			t.Fatal(err)
+		}
+		errs := map[string]string{}
+		for _, item := range items {
+			errs[item.ID] = item.ImportError
+		}
+		return errs
+	}
+	before := importErrors()
+	if before[archive] != "" || before[pdf] == "" {
+		t.Fatalf("import errors = %v", before)
+	}
+
+	err := RegenerateRenditions(context.Background(), lib.DB, []string{archive, pdf, img}, nil, func(int, int) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := importErrors()
+	for id, want := range before {
+		if after[id] != want {
+			t.Errorf("import error of %s = %q, want %q", id, after[id], want)
+		}
+	}
+
+	var entry dbcommon.AuditEntry
+	if err := lib.DB.Preload("Targets").Where("operation = ?", auditdb.ItemRegenerate).First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Targets) != 1 || entry.Targets[0].Target != img {
+		t.Errorf("audited targets = %+v", entry.Targets)
+	}
+}
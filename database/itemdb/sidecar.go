@@ -0,0 +1,720 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"synapforest/blobstore"
+	"synapforest/database"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SidecarFormat item 元数据文件和资料库清单的格式版本
+const SidecarFormat = 1
+
+const (
+	// SidecarName item 目录中的元数据文件名
+	SidecarName = "metadata.json"
+	// LibraryManifestName 资料库目录中保存文件夹、标签和设置的清单文件名
+	LibraryManifestName = "library.json"
+)
+
+// 数据库写入后等待一段时间再写入元数据文件，合并连续的修改
+const sidecarDelay = time.Second
+
+// 每次从数据库读取的 item 数量
+const sidecarBatchSize = 200
+
+// Sidecar item 目录中的 metadata.json，包含原始文件之外重建数据库记录所需的全部信息
+type Sidecar struct {
+	Format     int        `json:"format"`
+	ID         string     `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ImportedAt time.Time  `json:"imported_at"`
+	ModifiedAt time.Time  `json:"modified_at"`
+	DeletedAt  *time.Time `json:"deleted_at"` // 在回收站中时不为空
+
+	Name          string `json:"name"`
+	Ext           string `json:"ext"`
+	Width         uint32 `json:"width"`
+	Height        uint32 `json:"height"`
+	Size          uint64 `json:"size"`
+	Url           string `json:"url"`
+	Annotation    string `json:"annotation"`
+	Star          uint8  `json:"star"`
+	HaveThumbnail bool   `json:"have_thumbnail"`
+	HavePreview   bool   `json:"have_preview"`
+	Placeholder   string `json:"placeholder"`
+	ImportError   string `json:"import_error"`
+
+	Tags       []uuid.UUID              `json:"tags"`
+	Folders    []uuid.UUID              `json:"folders"`
+	Metadata   map[string]string        `json:"metadata"`
+	Content    *string                  `json:"content"`
+	Palettes   []dbcommon.ItemPalette   `json:"palettes"`
+	Location   *dbcommon.ItemLocation   `json:"location"`
+	Derivation *dbcommon.ItemDerivation `json:"derivation"`
+	Versions   []dbcommon.ItemVersion   `json:"versions"`
+	Renditions []dbcommon.ItemRendition `json:"renditions"`
+}
+
+// LibraryManifest 资料库目录中的 library.json，保存文件夹、标签和设置
+type LibraryManifest struct {
+	Format   int                `json:"format"`
+	Folders  []ManifestNode     `json:"folders"`
+	Tags     []ManifestNode     `json:"tags"`
+	Settings []dbcommon.Setting `json:"settings"`
+}
+
+// ManifestNode 清单中的文件夹或标签
+type ManifestNode struct {
+	ID          uuid.UUID  `json:"id"`
+	Parent      uuid.UUID  `json:"parent"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModifiedAt  time.Time  `json:"modified_at"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Icon        uint32     `json:"icon"`
+	IconColor   uint32     `json:"icon_color"`
+	IsExpand    bool       `json:"is_expand"`
+}
+
+// SidecarKey item 元数据文件在存储中的键
+func SidecarKey(itemID string) string {
+	return path.Join(RawDir(itemID), SidecarName)
+}
+
+// LibraryManifestPath 资料库清单的路径。清单保存在本地，使用其他存储时也能从中读取存储设置
+func LibraryManifestPath(dir string) string {
+	return filepath.Join(dir, LibraryManifestName)
+}
+
+// 写入后需要更新元数据文件的表
+var sidecarTables = map[string]bool{
+	"items":            true,
+	"item_tags":        true,
+	"item_folders":     true,
+	"item_metadata":    true,
+	"item_contents":    true,
+	"item_palettes":    true,
+	"item_locations":   true,
+	"item_derivations": true,
+	"item_versions":    true,
+	"item_renditions":  true,
+}
+
+// 写入后需要更新资料库清单的表
+var manifestTables = map[string]bool{
+	"folders":  true,
+	"tags":     true,
+	"settings": true,
+}
+
+// 把数据库的修改写入元数据文件。回调只记录修改的 item，由后台合并写入
+type sidecarWriter struct {
+	lib *database.Library
+
+	mu       sync.Mutex
+	items    map[string]bool // 需要更新的 item
+	all      bool            // 无法确定修改的 item 时检查全部
+	manifest bool
+	wake     chan struct{}
+	done     chan struct{}
+
+	// 已写入内容的哈希，只在持有资料库时访问
+	written      map[string][sha256.Size]byte
+	manifestHash *[sha256.Size]byte
+}
+
+// WriteSidecars 在数据库写入后更新 item 目录中的 metadata.json 和资料库的 library.json。
+// 打开时检查全部 item，补写缺少或过期的文件；关闭资料库前写入尚未写入的修改
+func WriteSidecars(db *gorm.DB) error {
+	w := &sidecarWriter{
+		lib:      database.LibraryOf(db),
+		items:    map[string]bool{},
+		all:      true,
+		manifest: true,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		written:  map[string][sha256.Size]byte{},
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("sidecar:create", w.record); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("sidecar:update", w.record); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("sidecar:delete", w.record); err != nil {
+		return err
+	}
+	if err := cb.Raw().After("gorm:raw").Register("sidecar:raw", w.record); err != nil {
+		return err
+	}
+
+	w.lib.BeforeClose(w.close)
+	go w.run()
+	w.wake <- struct{}{}
+	return nil
+}
+
+func (w *sidecarWriter) record(tx *gorm.DB) {
+	if tx.Error != nil {
+		return
+	}
+	stmt := tx.Statement
+
+	w.mu.Lock()
+	switch {
+	case stmt.Table == "":
+		// 直接执行的 SQL
+		w.all = true
+		w.manifest = true
+	case manifestTables[stmt.Table]:
+		w.manifest = true
+	case sidecarTables[stmt.Table]:
+		ids := changedItemIDs(stmt)
+		if ids == nil {
+			w.all = true
+		}
+		for _, id := range ids {
+			w.items[id] = true
+		}
+	default:
+		w.mu.Unlock()
+		return
+	}
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *sidecarWriter) run() {
+	for {
+		select {
+		case <-w.wake:
+		case <-w.done:
+			return
+		}
+		select {
+		case <-time.After(sidecarDelay):
+		case <-w.done:
+			return
+		}
+
+		if !w.lib.Acquire() {
+			return
+		}
+		w.flush()
+		w.lib.Release()
+	}
+}
+
+// 在资料库关闭过程中调用，此时没有其他请求
+func (w *sidecarWriter) close() {
+	w.flush()
+	close(w.done)
+}
+
+func (w *sidecarWriter) flush() {
+	w.mu.Lock()
+	items, all, manifest := w.items, w.all, w.manifest
+	w.items, w.all, w.manifest = map[string]bool{}, false, false
+	w.mu.Unlock()
+
+	if manifest {
+		if err := w.writeManifest(); err != nil {
+			log.Printf("Failed to write %s: %v", LibraryManifestName, err)
+			w.mu.Lock()
+			w.manifest = true
+			w.mu.Unlock()
+		}
+	}
+
+	var err error
+	if all {
+		err = w.syncAll()
+	} else if len(items) > 0 {
+		ids := make([]string, 0, len(items))
+		for id := range items {
+			ids = append(ids, id)
+		}
+		err = w.syncItems(ids)
+	}
+	if err != nil {
+		log.Printf("Failed to write item metadata files: %v", err)
+		// 下次写入时重试
+		w.mu.Lock()
+		w.all = w.all || all
+		for id := range items {
+			w.items[id] = true
+		}
+		w.mu.Unlock()
+	}
+}
+
+func (w *sidecarWriter) syncItems(ids []string) error {
+	db := w.lib.DB
+	for start := 0; start < len(ids); start += sidecarBatchSize {
+		batch := ids[start:min(start+sidecarBatchSize, len(ids))]
+		sidecars, err := loadSidecars(db, batch)
+		if err != nil {
+			return err
+		}
+		for _, id := range batch {
+			s, ok := sidecars[id]
+			if !ok {
+				// item 已被彻底删除
+				delete(w.written, id)
+				if err := w.lib.Blobs.Delete(SidecarKey(id)); err != nil && !errors.Is(err, blobstore.ErrNotExist) {
+					return err
+				}
+				continue
+			}
+			if err := w.write(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *sidecarWriter) syncAll() error {
+	db := w.lib.DB
+	seen := map[string]bool{}
+	last := ""
+	for {
+		var ids []string
+		err := db.Unscoped().Model(&dbcommon.Item{}).Where("id > ?", last).
+			Order("id ASC").Limit(sidecarBatchSize).Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err := w.syncItems(ids); err != nil {
+			return err
+		}
+		for _, id := range ids {
+			seen[id] = true
+		}
+		last = ids[len(ids)-1]
+	}
+
+	for id := range w.written {
+		if !seen[id] {
+			delete(w.written, id)
+		}
+	}
+	return nil
+}
+
+// 内容没有变化时不写入。首次写入前与已有文件比较，避免每次启动重写全部文件
+func (w *sidecarWriter) write(s *Sidecar) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	prev, ok := w.written[s.ID]
+	if ok && prev == sum {
+		return nil
+	}
+
+	key := SidecarKey(s.ID)
+	if !ok {
+		if existing, err := readBlob(w.lib.Blobs, key); err == nil && bytes.Equal(existing, data) {
+			w.written[s.ID] = sum
+			return nil
+		}
+	}
+	if err := w.lib.Blobs.Put(key, bytes.NewReader(data), int64(len(data))); err != nil {
+		return err
+	}
+	w.written[s.ID] = sum
+	return nil
+}
+
+func (w *sidecarWriter) writeManifest() error {
+	m, err := buildLibraryManifest(w.lib.DB)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if w.manifestHash != nil && *w.manifestHash == sum {
+		return nil
+	}
+
+	p := LibraryManifestPath(w.lib.Dir)
+	if existing, err := os.ReadFile(p); err != nil || !bytes.Equal(existing, data) {
+		tmp := p + ".tmp"
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, p); err != nil {
+			return err
+		}
+	}
+	w.manifestHash = &sum
+	return nil
+}
+
+func buildLibraryManifest(db *gorm.DB) (*LibraryManifest, error) {
+	m := &LibraryManifest{Format: SidecarFormat, Folders: []ManifestNode{}, Tags: []ManifestNode{}, Settings: []dbcommon.Setting{}}
+
+	var folders []dbcommon.Folder
+	if err := db.Unscoped().Order("id ASC").Find(&folders).Error; err != nil {
+		return nil, err
+	}
+	for _, f := range folders {
+		m.Folders = append(m.Folders, ManifestNode{
+			ID:          f.ID,
+			Parent:      f.ParentID,
+			CreatedAt:   f.CreatedAt,
+			ModifiedAt:  f.ModifiedAt,
+			DeletedAt:   deletedAt(f.DeletedAt),
+			Name:        f.Name,
+			Description: f.Description,
+			Icon:        f.Icon,
+			IconColor:   f.IconColor,
+			IsExpand:    f.IsExpand,
+		})
+	}
+
+	var tags []dbcommon.Tag
+	if err := db.Unscoped().Order("id ASC").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		m.Tags = append(m.Tags, ManifestNode{
+			ID:          t.ID,
+			Parent:      t.ParentID,
+			CreatedAt:   t.CreatedAt,
+			ModifiedAt:  t.ModifiedAt,
+			DeletedAt:   deletedAt(t.DeletedAt),
+			Name:        t.Name,
+			Description: t.Description,
+			Icon:        t.Icon,
+			IconColor:   t.IconColor,
+			IsExpand:    t.IsExpand,
+		})
+	}
+
+	if err := db.Order("key ASC").Find(&m.Settings).Error; err != nil {
+		return nil, err
+	}
+	// 清单是明文文件，不保存对象存储的密钥
+	for i, setting := range m.Settings {
+		redacted, err := settingdb.Redact(setting)
+		if err != nil {
+			return nil, err
+		}
+		m.Settings[i] = redacted
+	}
+	return m, nil
+}
+
+// 读取一批 item 的元数据文件内容，不存在的 item 不在结果中
+func loadSidecars(db *gorm.DB, ids []string) (map[string]*Sidecar, error) {
+	var items []dbcommon.Item
+	err := db.Unscoped().Preload("Tags").Preload("Folders").Preload("Location").
+		Preload("Palettes", PaletteOrder).
+		Where("id IN ?", ids).Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sidecars := make(map[string]*Sidecar, len(items))
+	for _, item := range items {
+		s := &Sidecar{
+			Format:        SidecarFormat,
+			ID:            item.ID,
+			CreatedAt:     item.CreatedAt,
+			ImportedAt:    item.ImportedAt,
+			ModifiedAt:    item.ModifiedAt,
+			Name:          item.Name,
+			Ext:           item.Ext,
+			Width:         item.Width,
+			Height:        item.Height,
+			Size:          item.Size,
+			Url:           item.Url,
+			Annotation:    item.Annotation,
+			Star:          item.Star,
+			HaveThumbnail: item.HaveThumbnail,
+			HavePreview:   item.HavePreview,
+			Placeholder:   item.Placeholder,
+			ImportError:   item.ImportError,
+			Tags:          []uuid.UUID{},
+			DeletedAt:     deletedAt(item.DeletedAt),
+			Folders:       []uuid.UUID{},
+			Metadata:      map[string]string{},
+			Palettes:      item.Palettes,
+			Location:      item.Location,
+			Versions:      []dbcommon.ItemVersion{},
+			Renditions:    []dbcommon.ItemRendition{},
+		}
+		if s.Palettes == nil {
+			s.Palettes = []dbcommon.ItemPalette{}
+		}
+		for _, tag := range item.Tags {
+			s.Tags = append(s.Tags, tag.ID)
+		}
+		for _, folder := range item.Folders {
+			s.Folders = append(s.Folders, folder.ID)
+		}
+		sortUUIDs(s.Tags)
+		sortUUIDs(s.Folders)
+		sidecars[item.ID] = s
+	}
+
+	var metadata []dbcommon.ItemMetadata
+	if err := db.Where("item_id IN ?", ids).Find(&metadata).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range metadata {
+		if s, ok := sidecars[m.ItemID]; ok {
+			s.Metadata[m.Key] = m.Value
+		}
+	}
+
+	var contents []dbcommon.ItemContent
+	if err := db.Where("item_id IN ?", ids).Find(&contents).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range contents {
+		if s, ok := sidecars[c.ItemID]; ok {
+			s.Content = &c.Content
+		}
+	}
+
+	var derivations []dbcommon.ItemDerivation
+	if err := db.Where("item_id IN ?", ids).Find(&derivations).Error; err != nil {
+		return nil, err
+	}
+	for i, d := range derivations {
+		if s, ok := sidecars[d.ItemID]; ok {
+			s.Derivation = &derivations[i]
+		}
+	}
+
+	var versions []dbcommon.ItemVersion
+	if err := db.Where("item_id IN ?", ids).Order("id ASC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		if s, ok := sidecars[v.ItemID]; ok {
+			s.Versions = append(s.Versions, v)
+		}
+	}
+
+	var renditions []dbcommon.ItemRendition
+	if err := db.Where("item_id IN ?", ids).Order("name ASC").Find(&renditions).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range renditions {
+		if s, ok := sidecars[r.ItemID]; ok {
+			s.Renditions = append(s.Renditions, r)
+		}
+	}
+
+	return sidecars, nil
+}
+
+func sortUUIDs(ids []uuid.UUID) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+}
+
+func readBlob(store blobstore.Store, key string) ([]byte, error) {
+	r, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// 从写入的记录或 WHERE 条件中找出修改的 item，无法确定时返回 nil
+func changedItemIDs(stmt *gorm.Statement) []string {
+	if ids := itemIDsOfValue(stmt.ReflectValue, stmt.Table); len(ids) > 0 {
+		return ids
+	}
+	return itemIDsOfWhere(stmt)
+}
+
+func itemIDsOfValue(v reflect.Value, table string) []string {
+	if !v.IsValid() {
+		return nil
+	}
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		ids := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			id := itemIDOf(reflect.Indirect(v.Index(i)), table)
+			if id == "" {
+				return nil
+			}
+			ids = append(ids, id)
+		}
+		return ids
+	default:
+		if id := itemIDOf(v, table); id != "" {
+			return []string{id}
+		}
+	}
+	return nil
+}
+
+// items 表的主键或其他表的 item_id。关联表的记录可能是 map
+func itemIDOf(v reflect.Value, table string) string {
+	switch v.Kind() {
+	case reflect.Interface:
+		return itemIDOf(reflect.Indirect(v.Elem()), table)
+	case reflect.Struct:
+		name := "ItemID"
+		if table == "items" {
+			name = "ID"
+		}
+		if f := v.FieldByName(name); f.IsValid() && f.Kind() == reflect.String {
+			return f.String()
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			if f := v.MapIndex(reflect.ValueOf("item_id")); f.IsValid() {
+				if id, ok := f.Interface().(string); ok {
+					return id
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// 匹配 item_id IN ?、id = ? 等条件
+var itemIDCondition = regexp.MustCompile(`(?i)^\(?\s*(?:\w+\.)?(item_id|id)\s+(?:in|=)\s+\(?\?\)?\s*\)?$`)
+
+func itemIDsOfWhere(stmt *gorm.Statement) []string {
+	c, ok := stmt.Clauses["WHERE"]
+	if !ok {
+		return nil
+	}
+	where, ok := c.Expression.(clause.Where)
+	if !ok {
+		return nil
+	}
+
+	isItemColumn := func(column interface{}) bool {
+		var name string
+		switch col := column.(type) {
+		case string:
+			name = col
+		case clause.Column:
+			name = col.Name
+			if name == clause.PrimaryKey && stmt.Schema != nil && stmt.Schema.PrioritizedPrimaryField != nil {
+				name = stmt.Schema.PrioritizedPrimaryField.DBName
+			}
+		}
+		return name == "item_id" || (name == "id" && stmt.Table == "items")
+	}
+
+	for _, expr := range where.Exprs {
+		switch e := expr.(type) {
+		case clause.IN:
+			if isItemColumn(e.Column) {
+				if ids := idStrings(e.Values...); ids != nil {
+					return ids
+				}
+			}
+		case clause.Eq:
+			if isItemColumn(e.Column) {
+				if ids := idStrings(e.Value); ids != nil {
+					return ids
+				}
+			}
+		case clause.Expr:
+			// 按 AND 拆分，找到对应的参数
+			vars := e.Vars
+			for _, part := range strings.Split(e.SQL, " AND ") {
+				n := strings.Count(part, "?")
+				if m := itemIDCondition.FindStringSubmatch(part); m != nil && n == 1 && len(vars) > 0 &&
+					(strings.EqualFold(m[1], "item_id") || stmt.Table == "items") {
+					if ids := idStrings(vars[0]); ids != nil {
+						return ids
+					}
+				}
+				if n > len(vars) {
+					break
+				}
+				vars = vars[n:]
+			}
+		}
+	}
+	return nil
+}
+
+func idStrings(values ...interface{}) []string {
+	var ids []string
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			ids = append(ids, v)
+		case []string:
+			ids = append(ids, v...)
+		case []interface{}:
+			more := idStrings(v...)
+			if more == nil {
+				return nil
+			}
+			ids = append(ids, more...)
+		default:
+			return nil
+		}
+	}
+	return ids
+}
+
+func deletedAt(d gorm.DeletedAt) *time.Time {
+	if !d.Valid {
+		return nil
+	}
+	return &d.Time
+}
@@ -0,0 +1,345 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"synapforest/database"
+	"synapforest/database/dbcommon"
+	"synapforest/database/settingdb"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// StatsLargestLimit 统计中保留的最大 item 数量
+const StatsLargestLimit = 100
+
+// StorageTotals 存储占用总计，单位为字节
+type StorageTotals struct {
+	Items          int64 `json:"items"`
+	TrashedItems   int64 `json:"trashedItems"`
+	RawBytes       int64 `json:"rawBytes"`
+	TrashedBytes   int64 `json:"trashedBytes"` // 回收站中 item 的原始文件大小，包含在 RawBytes 中
+	ThumbnailBytes int64 `json:"thumbnailBytes"`
+	PreviewBytes   int64 `json:"previewBytes"`
+	RenditionBytes int64 `json:"renditionBytes"` // 自定义规格
+	Versions       int64 `json:"versions"`
+	VersionBytes   int64 `json:"versionBytes"`
+	TotalBytes     int64 `json:"totalBytes"`
+}
+
+// StorageGroup 按扩展名、文件夹、标签或导入时间分组的数量和原始文件大小
+type StorageGroup struct {
+	Key   string `json:"key"`            // 扩展名、ID 或时间段
+	Name  string `json:"name,omitempty"` // 文件夹或标签名称
+	Count int64  `json:"count"`
+	Size  int64  `json:"size"`
+}
+
+// StorageItem 按大小排序的 item
+type StorageItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Ext  string `json:"ext"`
+	Size int64  `json:"size"`
+}
+
+// StorageStats 资料库的存储统计
+type StorageStats struct {
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Interval    string         `json:"interval"`
+	Totals      StorageTotals  `json:"totals"`
+	ByExt       []StorageGroup `json:"byExt"`
+	ByFolder    []StorageGroup `json:"byFolder"` // 包含子文件夹中的 item
+	ByTag       []StorageGroup `json:"byTag"`
+	Imports     []StorageGroup `json:"imports"` // 按导入时间
+	Largest     []StorageItem  `json:"largest"`
+}
+
+// 导入时间的分组方式
+var statsIntervals = map[string]string{
+	"day":   "%Y-%m-%d",
+	"month": "%Y-%m",
+	"year":  "%Y",
+}
+
+// 每个资料库的统计缓存。数据库每次写入时更新被写入的表的时钟，
+// 统计结果按部分缓存，只有部分所依赖的表被写入后才重新计算该部分
+type libraryStats struct {
+	clock  atomic.Uint64
+	all    atomic.Uint64 // 无法确定表的写入（如原始 SQL），视为写入了所有表
+	tables sync.Map      // 表名 -> *atomic.Uint64，最后一次写入时的时钟
+
+	mu      sync.Mutex // 保护 entries，同一资料库同时只计算一次，不影响其他资料库
+	entries map[string]*statsEntry
+}
+
+// 某个导入时间分组方式下的统计结果，versions 记录各部分计算时依赖的表的时钟
+type statsEntry struct {
+	stats    *StorageStats
+	versions map[string]uint64
+}
+
+// statsSection 统计的一部分及其依赖的表
+type statsSection struct {
+	name    string
+	tables  []string
+	compute func(db *gorm.DB, format string, stats *StorageStats) error
+}
+
+var statsSections = []statsSection{
+	{"totals", []string{"items", "item_renditions", "item_versions"}, computeTotals},
+	{"byExt", []string{"items"}, computeByExt},
+	{"byFolder", []string{"items", "folders", "item_folders"}, computeByFolder},
+	{"byTag", []string{"items", "tags", "item_tags"}, computeByTag},
+	{"imports", []string{"items"}, computeImports},
+	{"largest", []string{"items"}, computeLargest},
+}
+
+// 统计缓存保存在资料库上的 key
+type statsKey struct{}
+
+func statsOf(lib *database.Library) *libraryStats {
+	return lib.Value(statsKey{}, func() interface{} {
+		return &libraryStats{entries: map[string]*statsEntry{}}
+	}).(*libraryStats)
+}
+
+// 记录一次写入，table 为空时视为写入了所有表
+func (s *libraryStats) touch(table string) {
+	now := s.clock.Add(1)
+	if table == "" {
+		s.all.Store(now)
+		return
+	}
+	v, _ := s.tables.LoadOrStore(table, new(atomic.Uint64))
+	v.(*atomic.Uint64).Store(now)
+}
+
+// 给定的表最后一次写入时的时钟
+func (s *libraryStats) version(tables []string) uint64 {
+	latest := s.all.Load()
+	for _, table := range tables {
+		if v, ok := s.tables.Load(table); ok {
+			latest = max(latest, v.(*atomic.Uint64).Load())
+		}
+	}
+	return latest
+}
+
+// TrackStorageChanges 在数据库写入后标记被写入的表的统计结果过期
+func TrackStorageChanges(db *gorm.DB) error {
+	s := statsOf(database.LibraryOf(db))
+	// 没有影响任何行的语句（如关联写入时对 item 本身的空更新）不使结果过期
+	touch := func(db *gorm.DB) {
+		if db.RowsAffected > 0 {
+			s.touch(db.Statement.Table)
+		}
+	}
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("stats:create", touch); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("stats:update", touch); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("stats:delete", touch); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("stats:raw", func(db *gorm.DB) {
+		if db.RowsAffected > 0 {
+			s.touch("")
+		}
+	})
+}
+
+// GetStorageStats 返回存储统计。结果按部分缓存，只重新计算依赖的表有写入的部分；
+// 预览图大小在生成时记录，只有尚未记录的 item 才需要读取文件信息
+func GetStorageStats(db *gorm.DB, interval string, refresh bool) (*StorageStats, error) {
+	format, ok := statsIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("invalid interval %q", interval)
+	}
+
+	s := statsOf(database.LibraryOf(db))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entries[interval]
+	if entry == nil || refresh {
+		entry = &statsEntry{stats: &StorageStats{Interval: interval}, versions: map[string]uint64{}}
+	}
+	var stale []statsSection
+	for _, section := range statsSections {
+		v, ok := entry.versions[section.name]
+		if !ok || v != s.version(section.tables) {
+			stale = append(stale, section)
+		}
+	}
+	if len(stale) == 0 {
+		return entry.stats, nil
+	}
+
+	if err := backfillRenditionSizes(db); err != nil {
+		return nil, err
+	}
+
+	// 缓存的结果可能正被其他请求使用，在副本上更新。
+	// 先读取时钟，计算期间发生的写入会使该部分在下次请求时重新计算
+	stats := *entry.stats
+	versions := make(map[string]uint64, len(statsSections))
+	for name, v := range entry.versions {
+		versions[name] = v
+	}
+	for _, section := range stale {
+		version := s.version(section.tables)
+		if err := section.compute(db, format, &stats); err != nil {
+			return nil, err
+		}
+		versions[section.name] = version
+	}
+	stats.GeneratedAt = time.Now()
+	s.entries[interval] = &statsEntry{stats: &stats, versions: versions}
+	return &stats, nil
+}
+
+// 为尚未记录大小的预览图读取文件信息，不存在的记为 0
+func backfillRenditionSizes(db *gorm.DB) error {
+	renditions, err := settingdb.GetRenditions(db)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range renditions {
+		var ids []string
+		err := db.Unscoped().Model(&dbcommon.Item{}).
+			Where("id NOT IN (?)", db.Model(&dbcommon.ItemRendition{}).Select("item_id").Where("name = ?", r.Name)).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return fmt.Errorf("failed to query items: %v", err)
+		}
+
+		for _, id := range ids {
+			var size int64
+			if info, err := blobs(db).Stat(RenditionKey(r, id)); err == nil {
+				size = info.Size
+			}
+			if err := setRenditionSize(db, id, r.Name, size); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func computeTotals(db *gorm.DB, format string, stats *StorageStats) error {
+	var t StorageTotals
+	row := db.Raw(`SELECT COUNT(*), COALESCE(SUM(size), 0),
+		COALESCE(SUM(CASE WHEN deleted_at IS NOT NULL THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN deleted_at IS NOT NULL THEN size ELSE 0 END), 0)
+		FROM items`).Row()
+	if err := row.Scan(&t.Items, &t.RawBytes, &t.TrashedItems, &t.TrashedBytes); err != nil {
+		return fmt.Errorf("failed to count items: %v", err)
+	}
+
+	var renditions []struct {
+		Name string
+		Size int64
+	}
+	if err := db.Model(&dbcommon.ItemRendition{}).Select("name, SUM(size) AS size").Group("name").Scan(&renditions).Error; err != nil {
+		return fmt.Errorf("failed to sum renditions: %v", err)
+	}
+	for _, r := range renditions {
+		switch r.Name {
+		case settingdb.Thumbnail:
+			t.ThumbnailBytes = r.Size
+		case settingdb.Preview:
+			t.PreviewBytes = r.Size
+		default:
+			t.RenditionBytes += r.Size
+		}
+	}
+
+	// 同一个旧文件可能被多条版本记录引用，只计算一次
+	row = db.Raw(`SELECT COUNT(*), COALESCE(SUM(size), 0) FROM
+		(SELECT file_id, MAX(size) AS size FROM item_versions GROUP BY file_id)`).Row()
+	if err := row.Scan(&t.Versions, &t.VersionBytes); err != nil {
+		return fmt.Errorf("failed to sum versions: %v", err)
+	}
+	t.TotalBytes = t.RawBytes + t.ThumbnailBytes + t.PreviewBytes + t.RenditionBytes + t.VersionBytes
+	stats.Totals = t
+	return nil
+}
+
+func computeByExt(db *gorm.DB, format string, stats *StorageStats) (err error) {
+	stats.ByExt, err = storageGroups(db, `SELECT ext AS key, '' AS name, COUNT(*) AS count, COALESCE(SUM(size), 0) AS size
+		FROM items GROUP BY ext ORDER BY size DESC, key`)
+	return err
+}
+
+// 递归展开每个文件夹的子文件夹，同一个 item 在多个子文件夹中只计算一次
+func computeByFolder(db *gorm.DB, format string, stats *StorageStats) (err error) {
+	stats.ByFolder, err = storageGroups(db, `WITH RECURSIVE tree(root, id) AS (
+			SELECT id, id FROM folders WHERE deleted_at IS NULL AND id != ?
+			UNION
+			SELECT tree.root, folders.id FROM folders JOIN tree ON folders.parent_id = tree.id
+			WHERE folders.deleted_at IS NULL AND folders.id != folders.parent_id
+		)
+		SELECT members.root AS key, folders.name AS name, COUNT(*) AS count, COALESCE(SUM(members.size), 0) AS size
+		FROM (SELECT DISTINCT tree.root AS root, items.id AS id, items.size AS size
+			FROM tree
+			JOIN item_folders ON item_folders.folder_id = tree.id
+			JOIN items ON items.id = item_folders.item_id) AS members
+		JOIN folders ON folders.id = members.root
+		GROUP BY members.root ORDER BY size DESC, name`, uuid.Nil)
+	return err
+}
+
+func computeByTag(db *gorm.DB, format string, stats *StorageStats) (err error) {
+	stats.ByTag, err = storageGroups(db, `SELECT tags.id AS key, tags.name AS name, COUNT(*) AS count, COALESCE(SUM(items.size), 0) AS size
+		FROM tags
+		JOIN item_tags ON item_tags.tag_id = tags.id
+		JOIN items ON items.id = item_tags.item_id
+		WHERE tags.deleted_at IS NULL
+		GROUP BY tags.id ORDER BY size DESC, name`)
+	return err
+}
+
+func computeImports(db *gorm.DB, format string, stats *StorageStats) (err error) {
+	stats.Imports, err = storageGroups(db, `SELECT strftime(?, imported_at) AS key, '' AS name, COUNT(*) AS count, COALESCE(SUM(size), 0) AS size
+		FROM items GROUP BY key ORDER BY key`, format)
+	return err
+}
+
+func computeLargest(db *gorm.DB, format string, stats *StorageStats) error {
+	largest := []StorageItem{}
+	err := db.Unscoped().Model(&dbcommon.Item{}).Select("id, name, ext, size").
+		Order("size DESC, id").Limit(StatsLargestLimit).Scan(&largest).Error
+	if err != nil {
+		return fmt.Errorf("failed to query largest items: %v", err)
+	}
+	stats.Largest = largest
+	return nil
+}
+
+func storageGroups(db *gorm.DB, query string, args ...interface{}) ([]StorageGroup, error) {
+	groups := []StorageGroup{}
+	if err := db.Raw(query, args...).Scan(&groups).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute storage stats: %v", err)
+	}
+	return groups, nil
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"synapforest/blobstore"
+	"synapforest/database/dbcommon"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestStorageStatsRecomputesChangedSections(t *testing.T) {
+	lib := openLibrary(t)
+	if err := TrackStorageChanges(lib.DB); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	id := importFile(t, lib, writePNG(t, dir, "a.png", 4, 4, 1))
+
+	first, err := GetStorageStats(lib.DB, "month", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Totals.Items != 1 || len(first.ByExt) != 1 || first.ByExt[0].Key != "png" || len(first.ByTag) != 0 {
+		t.Fatalf("stats = %+v", first)
+	}
+	if again, err := GetStorageStats(lib.DB, "month", false); err != nil || again != first {
+		t.Fatalf("cached stats not reused: %v", err)
+	}
+
+	// 只修改标签时只重新计算按标签的分组
+	tag := dbcommon.Tag{ID: uuid.Must(uuid.NewV4()), Name: "t"}
+	if err := lib.DB.Create(&tag).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := lib.DB.Model(&dbcommon.Item{ID: id}).Association("Tags").Append(&tag); err != nil {
+		t.Fatal(err)
+	}
+	second, err := GetStorageStats(lib.DB, "month", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.ByTag) != 1 || second.ByTag[0].Name != "t" || second.ByTag[0].Count != 1 {
+		t.Errorf("byTag = %+v", second.ByTag)
+	}
+	if &second.ByExt[0] != &first.ByExt[0] {
+		t.Error("byExt recomputed")
+	}
+	if len(second.Largest) == 0 || &second.Largest[0] != &first.Largest[0] {
+		t.Error("largest recomputed")
+	}
+
+	// 导入新 item 后所有依赖 items 的部分都更新
+	importFile(t, lib, writePNG(t, dir, "b.png", 4, 4, 2))
+	third, err := GetStorageStats(lib.DB, "month", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Totals.Items != 2 || third.ByExt[0].Count != 2 || len(third.Largest) != 2 {
+		t.Errorf("stats after import = %+v", third)
+	}
+
+	refreshed, err := GetStorageStats(lib.DB, "month", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed == third || refreshed.Totals != third.Totals {
+		t.Errorf("refresh = %+v", refreshed.Totals)
+	}
+}
+
+// Stat 阻塞到 release 关闭，模拟对象存储上较慢的请求
+type slowStore struct {
+	blobstore.Store
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowStore) Stat(key string) (blobstore.Info, error) {
+	s.once.Do(func() { close(s.started) })
+	<-s.release
+	return s.Store.Stat(key)
+}
+
+// 一个资料库计算统计时不阻塞其他资料库
+func TestStorageStatsDoesNotBlockOtherLibraries(t *testing.T) {
+	slow := openLibrary(t)
+	importFile(t, slow, writePNG(t, t.TempDir(), "a.png", 4, 4, 1))
+	if err := slow.DB.Where("1 = 1").Delete(&dbcommon.ItemRendition{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	store := &slowStore{Store: slow.Blobs, started: make(chan struct{}), release: make(chan struct{})}
+	slow.Blobs = store
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := GetStorageStats(slow.DB, "month", false)
+		done <- err
+	}()
+	<-store.started
+
+	other := openLibrary(t)
+	finished := make(chan error, 1)
+	go func() {
+		_, err := GetStorageStats(other.DB, "month", false)
+		finished <- err
+	}()
+	select {
+	case err := <-finished:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("stats of another library blocked")
+	}
+
+	close(store.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,272 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"synapforest/database"
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// VersionDir 旧文件在存储中的目录
+func VersionDir(fileID string) string {
+	return path.Join("versions", fileID)
+}
+
+// VersionKey 旧文件在存储中的键
+func VersionKey(v dbcommon.ItemVersion) string {
+	return path.Join(VersionDir(v.FileID), v.Name+"."+v.Ext)
+}
+
+// ReplaceItemFile 用新文件替换 item 的内容。新文件按正常流程导入，
+// 名称、注释、来源、星级、创建时间、标签、文件夹、向量和编辑关系都转移到新 item 上，
+// 旧文件保留为历史版本。filePath 指向的文件会被移动。返回新 item 的 ID
+func ReplaceItemFile(db *gorm.DB, itemID string, filePath string) (string, error) {
+	return replaceItemFile(db, itemID, filePath, func(tx *gorm.DB, newID string, before *itemState) error {
+		return recordItemChange(tx, auditdb.ItemReplaceFile, []string{itemID, newID}, before, newID, nil)
+	})
+}
+
+// 替换 item 的文件，返回新 item 的 ID。数据库修改在同一个事务中完成，
+// commit 在事务中最后调用，参数为新 item 的 ID 和替换前的审计值；事务失败时撤销已经移动的文件
+func replaceItemFile(db *gorm.DB, itemID string, filePath string, commit func(tx *gorm.DB, newID string, before *itemState) error) (_ string, err error) {
+	var old dbcommon.Item
+	if err := db.Preload("Tags").Preload("Folders").First(&old, "id = ?", itemID).Error; err != nil {
+		return "", err
+	}
+	before := stateOf(old)
+
+	newID, err := CalculateFileID(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash file: %v", err)
+	}
+	if newID == old.ID {
+		return "", fmt.Errorf("file is identical to the current one")
+	}
+	// 内容已存在时无法确定两个 item 的组织信息如何合并
+	var count int64
+	if err := db.Unscoped().Model(&dbcommon.Item{}).Where("id = ?", newID).Count(&count).Error; err != nil {
+		return "", err
+	}
+	if count > 0 {
+		return "", fmt.Errorf("file already exists in the library as item %s", newID)
+	}
+
+	var tags []uuid.UUID
+	for _, tag := range old.Tags {
+		tags = append(tags, tag.ID)
+	}
+	var folders []uuid.UUID
+	for _, folder := range old.Folders {
+		folders = append(folders, folder.ID)
+	}
+
+	version := dbcommon.ItemVersion{
+		FileID:     old.ID,
+		Name:       old.Name,
+		Ext:        old.Ext,
+		Size:       old.Size,
+		Width:      old.Width,
+		Height:     old.Height,
+		ReplacedAt: time.Now(),
+	}
+	// 相同内容可能已经作为其他 item 的历史版本保存过，此时沿用已保存的文件
+	kept, err := blobs(db).List(VersionDir(old.ID))
+	if err != nil {
+		return "", err
+	}
+	rawKey := RawFileKey(old.ID, old.Name, old.Ext)
+	moved := false
+	if len(kept) > 0 {
+		base := path.Base(kept[0])
+		ext := path.Ext(base)
+		version.Name, version.Ext = strings.TrimSuffix(base, ext), strings.TrimPrefix(ext, ".")
+	} else {
+		if err := blobs(db).Move(rawKey, VersionKey(version)); err != nil {
+			return "", fmt.Errorf("failed to keep previous file: %v", err)
+		}
+		moved = true
+	}
+
+	vectors := database.LibraryOf(db).VectorDB
+	vectorsMoved := false
+	defer func() {
+		if err == nil {
+			return
+		}
+		if vectorsMoved {
+			if undoErr := vectors.Unscoped().Model(&dbcommon.ItemVector{}).Where("item_id = ?", newID).Update("item_id", old.ID).Error; undoErr != nil {
+				log.Printf("Failed to restore vector of %s: %v", old.ID, undoErr)
+			}
+		}
+		if undoErr := deleteItemFiles(db, []string{newID}, nil); undoErr != nil {
+			log.Printf("Failed to remove files of %s: %v", newID, undoErr)
+		}
+		if moved {
+			if undoErr := blobs(db).Move(VersionKey(version), rawKey); undoErr != nil {
+				log.Printf("Failed to restore file of %s: %v", old.ID, undoErr)
+			}
+		}
+	}()
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, _, err := addItem(tx, ImportEntry{Path: filePath, Name: &old.Name, Url: &old.Url, Annotation: &old.Annotation,
+			Tags: tags, Folders: folders, Star: &old.Star, CreatedAt: &old.CreatedAt}, nil)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&dbcommon.ItemVersion{}).Where("item_id = ?", old.ID).Update("item_id", newID).Error; err != nil {
+			return fmt.Errorf("failed to move versions: %v", err)
+		}
+		version.ItemID = newID
+		if err := tx.Create(&version).Error; err != nil {
+			return fmt.Errorf("failed to save version: %v", err)
+		}
+		if err := tx.Model(&dbcommon.ItemDerivation{}).Where("source_id = ?", old.ID).Update("source_id", newID).Error; err != nil {
+			return fmt.Errorf("failed to move derivations: %v", err)
+		}
+		if err := tx.Model(&dbcommon.ItemDerivation{}).Where("item_id = ?", old.ID).Update("item_id", newID).Error; err != nil {
+			return fmt.Errorf("failed to move derivations: %v", err)
+		}
+		// 删除旧 item 是替换的一部分，不单独记录
+		if _, err := deleteItemRows(tx, []string{old.ID}); err != nil {
+			return err
+		}
+		if err := commit(tx, newID, before); err != nil {
+			return err
+		}
+		// 向量在另一个数据库中，放在最后以便失败时事务整体回滚
+		if err := vectors.Unscoped().Model(&dbcommon.ItemVector{}).Where("item_id = ?", old.ID).Update("item_id", newID).Error; err != nil {
+			return fmt.Errorf("failed to move vector: %v", err)
+		}
+		vectorsMoved = true
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// 旧 item 的历史版本已经转移，只删除原始文件和预览图
+	if err := deleteItemFiles(db, []string{old.ID}, nil); err != nil {
+		log.Printf("Failed to remove files of %s: %v", old.ID, err)
+	}
+	return newID, nil
+}
+
+// ListVersions 返回 item 的历史版本，最近替换的在前
+func ListVersions(db *gorm.DB, itemID string) ([]dbcommon.ItemVersion, error) {
+	var versions []dbcommon.ItemVersion
+	err := db.Where("item_id = ?", itemID).Order("replaced_at DESC, id DESC").Find(&versions).Error
+	return versions, err
+}
+
+// GetVersion 查询一个历史版本
+func GetVersion(db *gorm.DB, versionID uint) (*dbcommon.ItemVersion, error) {
+	var version dbcommon.ItemVersion
+	if err := db.First(&version, versionID).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// RestoreVersion 把历史版本恢复为 item 的当前文件，当前文件成为新的历史版本。返回新 item 的 ID
+func RestoreVersion(db *gorm.DB, itemID string, versionID uint) (string, error) {
+	version, err := GetVersion(db, versionID)
+	if err != nil {
+		return "", err
+	}
+	if version.ItemID != itemID {
+		return "", gorm.ErrRecordNotFound
+	}
+
+	// 替换时会移动源文件，因此先复制一份
+	stagingDir := filepath.Join(database.LibraryOf(db).Dir, "staging")
+	if err := os.MkdirAll(stagingDir, os.ModePerm); err != nil {
+		return "", err
+	}
+	tmpDir, err := os.MkdirTemp(stagingDir, "restore-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpPath := filepath.Join(tmpDir, version.Name+"."+version.Ext)
+	if err := copyBlob(db, VersionKey(*version), tmpPath); err != nil {
+		return "", fmt.Errorf("failed to read version: %v", err)
+	}
+
+	// 被恢复的版本记录在替换的同一事务中删除
+	newID, err := replaceItemFile(db, itemID, tmpPath, func(tx *gorm.DB, newID string, before *itemState) error {
+		if err := tx.Delete(&dbcommon.ItemVersion{}, version.ID).Error; err != nil {
+			return fmt.Errorf("failed to delete version: %v", err)
+		}
+		return recordItemChange(tx, auditdb.ItemRestoreVersion, []string{itemID, newID}, before, newID, map[string]interface{}{
+			"version_id": version.ID,
+			"file_id":    version.FileID,
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := removeUnusedVersionFiles(db, []dbcommon.ItemVersion{*version}); err != nil {
+		return "", err
+	}
+	return newID, nil
+}
+
+// 删除不再被任何历史版本引用的版本文件
+func removeUnusedVersionFiles(db *gorm.DB, versions []dbcommon.ItemVersion) error {
+	for _, v := range versions {
+		var refs int64
+		if err := db.Model(&dbcommon.ItemVersion{}).Where("file_id = ?", v.FileID).Count(&refs).Error; err != nil {
+			return err
+		}
+		if refs == 0 {
+			if err := blobs(db).DeleteDir(VersionDir(v.FileID)); err != nil {
+				return fmt.Errorf("failed to delete version files: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// 把存储中的文件复制到本地
+func copyBlob(db *gorm.DB, key string, dst string) error {
+	in, err := blobs(db).Get(key)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package itemdb
+
+import (
+	"errors"
+	"testing"
+
+	"synapforest/database/dbcommon"
+
+	"gorm.io/gorm"
+)
+
+func blobExists(t *testing.T, db *gorm.DB, key string) bool {
+	t.Helper()
+	_, err := blobs(db).Stat(key)
+	return err == nil
+}
+
+func TestReplaceItemFile(t *testing.T) {
+	lib := openLibrary(t)
+	dir := t.TempDir()
+	oldID := importFile(t, lib, writePNG(t, dir, "a.png", 4, 4, 1))
+
+	newID, err := ReplaceItemFile(lib.DB, oldID, writePNG(t, dir, "b.png", 4, 4, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item dbcommon.Item
+	if err := lib.DB.First(&item, "id = ?", newID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "a" {
+		t.Errorf("name = %q", item.Name)
+	}
+	if err := lib.DB.Unscoped().First(&dbcommon.Item{}, "id = ?", oldID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("old item still exists: %v", err)
+	}
+	if !blobExists(t, lib.DB, RawFileKey(newID, "a", "png")) {
+		t.Error("new raw file missing")
+	}
+	if blobExists(t, lib.DB, RawFileKey(oldID, "a", "png")) {
+		t.Error("old raw file not removed")
+	}
+
+	versions, err := ListVersions(lib.DB, newID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].FileID != oldID {
+		t.Fatalf("versions = %+v", versions)
+	}
+	if !blobExists(t, lib.DB, VersionKey(versions[0])) {
+		t.Error("version file missing")
+	}
+}
+
+// 旧文件已经作为历史版本保存过时，版本记录沿用已保存文件的名称
+func TestReplaceItemFileKeepsStoredVersionKey(t *testing.T) {
+	lib := openLibrary(t)
+	dir := t.TempDir()
+	first := writePNG(t, dir, "a.png", 4, 4, 1)
+	firstID, err := CalculateFileID(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemID := importFile(t, lib, first)
+	if _, err := ReplaceItemFile(lib.DB, itemID, writePNG(t, dir, "b.png", 4, 4, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 以另一个名称重新导入相同内容，再替换一次
+	if got := importFile(t, lib, writePNG(t, dir, "c.png", 4, 4, 1)); got != firstID {
+		t.Fatalf("reimported id = %s, want %s", got, firstID)
+	}
+	newID, err := ReplaceItemFile(lib.DB, firstID, writePNG(t, dir, "d.png", 4, 4, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := ListVersions(lib.DB, newID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("versions = %+v", versions)
+	}
+	if versions[0].Name != "a" || !blobExists(t, lib.DB, VersionKey(versions[0])) {
+		t.Errorf("version %s.%s does not match the stored file", versions[0].Name, versions[0].Ext)
+	}
+	if blobExists(t, lib.DB, RawFileKey(firstID, "c", "png")) {
+		t.Error("replaced raw file not removed")
+	}
+}
+
+func TestReplaceItemFileRollsBack(t *testing.T) {
+	lib := openLibrary(t)
+	dir := t.TempDir()
+	oldID := importFile(t, lib, writePNG(t, dir, "a.png", 4, 4, 1))
+	otherID := importFile(t, lib, writePNG(t, dir, "c.png", 4, 4, 3))
+
+	fail := func(tx *gorm.DB, newID string, before *itemState) error { return errors.New("boom") }
+	if _, err := replaceItemFile(lib.DB, oldID, writePNG(t, dir, "b.png", 4, 4, 2), fail); err == nil {
+		t.Fatal("expected error")
+	}
+	newID, err := CalculateFileID(writePNG(t, dir, "b.png", 4, 4, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lib.DB.First(&dbcommon.Item{}, "id = ?", oldID).Error; err != nil {
+		t.Errorf("old item lost: %v", err)
+	}
+	if err := lib.DB.Unscoped().First(&dbcommon.Item{}, "id = ?", newID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("new item kept: %v", err)
+	}
+	if !blobExists(t, lib.DB, RawFileKey(oldID, "a", "png")) {
+		t.Error("old raw file not restored")
+	}
+	if blobExists(t, lib.DB, RawFileKey(newID, "a", "png")) {
+		t.Error("new raw file not removed")
+	}
+	if kept, _ := blobs(lib.DB).List(VersionDir(oldID)); len(kept) != 0 {
+		t.Errorf("version files left: %v", kept)
+	}
+	if !blobExists(t, lib.DB, RawFileKey(otherID, "c", "png")) {
+		t.Error("unrelated raw file removed")
+	}
+}
+
+func TestRestoreVersion(t *testing.T) {
+	lib := openLibrary(t)
+	dir := t.TempDir()
+	oldID := importFile(t, lib, writePNG(t, dir, "a.png", 4, 4, 1))
+	newID, err := ReplaceItemFile(lib.DB, oldID, writePNG(t, dir, "b.png", 4, 4, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := ListVersions(lib.DB, newID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := RestoreVersion(lib.DB, newID, versions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != oldID {
+		t.Errorf("restored id = %s, want %s", restored, oldID)
+	}
+	versions, err = ListVersions(lib.DB, restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].FileID != newID {
+		t.Fatalf("versions = %+v", versions)
+	}
+	if kept, _ := blobs(lib.DB).List(VersionDir(oldID)); len(kept) != 0 {
+		t.Errorf("restored version files left: %v", kept)
+	}
+	if !blobExists(t, lib.DB, RawFileKey(oldID, "a", "png")) {
+		t.Error("restored raw file missing")
+	}
+}
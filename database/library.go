@@ -0,0 +1,244 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"synapforest/blobstore"
+	"synapforest/database/migrate"
+	"synapforest/database/settingdb"
+	"synapforest/imagecache"
+	"synapforest/job"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// ImageCacheSize 派生图片缓存的总大小上限
+const ImageCacheSize = 512 << 20
+
+// Library 一个打开的资料库，包含数据库连接、目录和文件存储
+type Library struct {
+	Dir        string
+	DB         *gorm.DB
+	VectorDB   *gorm.DB
+	Blobs      blobstore.Store  // 原始文件、预览图和历史版本的存储
+	BlobConfig blobstore.Config // 打开资料库时使用的存储设置
+	UploadDir  string           // 上传文件的暂存目录
+	ImageCache *imagecache.Cache
+	Jobs       *job.Manager // 资料库的后台任务
+	ItemLocks  *KeyLocks    // 按文件 ID 加锁，避免同一内容被并发导入两次
+
+	// 请求和任务使用期间持有读锁，Close 等待全部使用结束
+	mu     sync.RWMutex
+	closed bool
+
+	hooksMu     sync.Mutex
+	beforeClose []func()
+
+	valuesMu sync.Mutex
+	values   map[interface{}]interface{}
+}
+
+// Open 打开 dir 中的资料库，不存在时创建
+func Open(dir string) (*Library, error) {
+	lib := &Library{
+		Dir:       dir,
+		UploadDir: filepath.Join(dir, "uploads"),
+		Jobs:      job.NewManager(),
+		ItemLocks: NewKeyLocks(),
+		values:    map[interface{}]interface{}{},
+	}
+
+	for _, sub := range []string{"raw_files", "thumbnails", "previews", "uploads"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %v", err)
+		}
+	}
+
+	var err error
+	lib.DB, err = openDB(lib, filepath.Join(dir, "files.db"))
+	if err != nil {
+		return nil, err
+	}
+	lib.VectorDB, err = openDB(lib, filepath.Join(dir, "vectors.db"))
+	if err != nil {
+		lib.closeDBs()
+		return nil, err
+	}
+
+	if err := lib.init(); err != nil {
+		lib.closeDBs()
+		return nil, err
+	}
+	return lib, nil
+}
+
+func (lib *Library) init() error {
+	for _, m := range []struct {
+		schema *migrate.Schema
+		db     *gorm.DB
+	}{{migrate.Files, lib.DB}, {migrate.Vectors, lib.VectorDB}} {
+		result, err := m.schema.Migrate(context.Background(), m.db, MigrationBackupDir(lib.Dir))
+		if err != nil {
+			return fmt.Errorf("failed to migrate database: %v", err)
+		}
+		if len(result.Applied) > 0 {
+			log.Printf("%s: migrated %s from version %d to %d", lib.Dir, result.Database, result.From, result.To)
+		}
+	}
+
+	CreateRootFolder(lib.DB)
+
+	cfg, err := settingdb.GetBlobStore(lib.DB)
+	if err != nil {
+		return fmt.Errorf("failed to load blob store settings: %v", err)
+	}
+	lib.Blobs, err = blobstore.New(cfg, lib.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to open blob store: %v", err)
+	}
+	lib.BlobConfig = cfg
+
+	lib.ImageCache, err = imagecache.New(filepath.Join(lib.Dir, "cache"), ImageCacheSize)
+	if err != nil {
+		return fmt.Errorf("failed to init image cache: %v", err)
+	}
+	return nil
+}
+
+func openDB(lib *Library, path string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+	if err := db.Use(&libraryPlugin{lib: lib}); err != nil {
+		return nil, err
+	}
+
+	// 启用SQLite的WAL
+	db.Exec("PRAGMA journal_mode=WAL;")
+
+	return db.Debug(), nil
+}
+
+// Acquire 标记资料库正在使用，资料库已关闭时返回 false。使用结束后调用 Release
+func (lib *Library) Acquire() bool {
+	lib.mu.RLock()
+	if lib.closed {
+		lib.mu.RUnlock()
+		return false
+	}
+	return true
+}
+
+// Release 结束 Acquire 标记的使用
+func (lib *Library) Release() {
+	lib.mu.RUnlock()
+}
+
+// Value 返回资料库上 key 对应的状态，不存在时用 create 创建。
+// 供上层的包保存按资料库区分的缓存，随资料库一起释放
+func (lib *Library) Value(key interface{}, create func() interface{}) interface{} {
+	lib.valuesMu.Lock()
+	defer lib.valuesMu.Unlock()
+	v, ok := lib.values[key]
+	if !ok {
+		v = create()
+		lib.values[key] = v
+	}
+	return v
+}
+
+// BeforeClose 注册关闭时调用的函数。调用时请求和任务已经结束，数据库连接仍然可用
+func (lib *Library) BeforeClose(fn func()) {
+	lib.hooksMu.Lock()
+	defer lib.hooksMu.Unlock()
+	lib.beforeClose = append(lib.beforeClose, fn)
+}
+
+// Close 等待正在进行的请求和任务结束后关闭数据库连接
+func (lib *Library) Close() error {
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	if lib.closed {
+		return nil
+	}
+	lib.closed = true
+
+	lib.hooksMu.Lock()
+	hooks := lib.beforeClose
+	lib.hooksMu.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+	return lib.closeDBs()
+}
+
+func (lib *Library) closeDBs() error {
+	var firstErr error
+	for _, db := range []*gorm.DB{lib.DB, lib.VectorDB} {
+		if db == nil {
+			continue
+		}
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// 通过 gorm 插件把资料库关联到它的数据库连接，会话和事务共享插件
+const libraryPluginName = "synapforest:library"
+
+type libraryPlugin struct {
+	lib *Library
+}
+
+func (p *libraryPlugin) Name() string {
+	return libraryPluginName
+}
+
+func (p *libraryPlugin) Initialize(*gorm.DB) error {
+	return nil
+}
+
+// LibraryOf 返回数据库连接所属的资料库，不是由 Open 打开的连接返回 nil
+func LibraryOf(db *gorm.DB) *Library {
+	if p, ok := db.Config.Plugins[libraryPluginName].(*libraryPlugin); ok {
+		return p.lib
+	}
+	return nil
+}
+
+type libraryKey struct{}
+
+// WithLibrary 返回关联了资料库的 context
+func WithLibrary(ctx context.Context, lib *Library) context.Context {
+	return context.WithValue(ctx, libraryKey{}, lib)
+}
+
+// FromContext 返回请求所属的资料库
+func FromContext(ctx context.Context) *Library {
+	lib, _ := ctx.Value(libraryKey{}).(*Library)
+	return lib
+}
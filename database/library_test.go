@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+// 资料库状态按资料库区分，同一资料库中按 key 只创建一次
+func TestLibraryValue(t *testing.T) {
+	a, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	type key struct{}
+	created := 0
+	create := func() interface{} {
+		created++
+		return new(int)
+	}
+	va, vb := a.Value(key{}, create), b.Value(key{}, create)
+	if va == vb || a.Value(key{}, create) != va || created != 2 {
+		t.Errorf("values = %p %p, created %d", va, vb, created)
+	}
+}
+
+// 同一 key 互斥，不同 key 互不影响，解锁后删除没有使用者的锁
+func TestKeyLocks(t *testing.T) {
+	locks := NewKeyLocks()
+	unlock := locks.Lock("a")
+	locks.Lock("b")()
+
+	acquired := make(chan struct{})
+	go func() {
+		locks.Lock("a")()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second lock of a did not wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second lock of a not acquired after unlock")
+	}
+
+	locks.mu.Lock()
+	defer locks.mu.Unlock()
+	if len(locks.m) != 0 {
+		t.Errorf("locks left = %d", len(locks.m))
+	}
+}
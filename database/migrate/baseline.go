@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package migrate
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// table 基线中的一张表，列定义和约束与此前 AutoMigrate 创建的结构一致
+type table struct {
+	name        string
+	columns     []string // "`name` type"
+	constraints []string
+}
+
+// baseline 创建缺少的表，并为已有的表补上缺少的列和索引。
+// 此前的版本每次启动都用 AutoMigrate 更新结构，旧资料库可能缺少后来增加的表或列
+func baseline(tables []table, indexes []string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, t := range tables {
+			if !tx.Migrator().HasTable(t.name) {
+				defs := append(append([]string{}, t.columns...), t.constraints...)
+				if err := tx.Exec(fmt.Sprintf("CREATE TABLE `%s` (%s)", t.name, strings.Join(defs, ","))).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			for _, column := range t.columns {
+				name := strings.Trim(strings.Fields(column)[0], "`")
+				if tx.Migrator().HasColumn(t.name, name) {
+					continue
+				}
+				if err := tx.Exec(fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN %s", t.name, column)).Error; err != nil {
+					return err
+				}
+			}
+		}
+		for _, index := range indexes {
+			if err := tx.Exec(index).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
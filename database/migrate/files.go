@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package migrate
+
+// filesMigrations files.db 的迁移，按版本顺序追加
+var filesMigrations = []Migration{
+	{Version: 1, Name: "baseline", Up: baseline(filesTables, filesIndexes)},
+	{Version: 2, Name: "audit_log", Up: statements(auditLogSchema...)},
+}
+
+var filesTables = []table{
+	{
+		name: "items",
+		columns: []string{"`id` text", "`created_at` datetime", "`imported_at` datetime", "`modified_at` datetime", "`deleted_at` datetime",
+			"`name` text", "`ext` text", "`width` integer", "`height` integer", "`size` integer", "`url` text", "`annotation` text", "`star` integer",
+			"`have_thumbnail` numeric", "`have_preview` numeric", "`placeholder` text", "`import_error` text"},
+		constraints: []string{"PRIMARY KEY (`id`)"},
+	},
+	{
+		name: "folders",
+		columns: []string{"`id` text", "`created_at` datetime", "`modified_at` datetime", "`deleted_at` datetime", "`parent_id` text",
+			"`name` text", "`description` text", "`icon` integer", "`icon_color` integer", "`is_expand` numeric"},
+		constraints: []string{"PRIMARY KEY (`id`)", "CONSTRAINT `fk_folders_children` FOREIGN KEY (`parent_id`) REFERENCES `folders`(`id`)"},
+	},
+	{
+		name:    "item_folders",
+		columns: []string{"`folder_id` text", "`item_id` text"},
+		constraints: []string{"PRIMARY KEY (`folder_id`,`item_id`)",
+			"CONSTRAINT `fk_item_folders_folder` FOREIGN KEY (`folder_id`) REFERENCES `folders`(`id`)",
+			"CONSTRAINT `fk_item_folders_item` FOREIGN KEY (`item_id`) REFERENCES `items`(`id`)"},
+	},
+	{
+		name: "tags",
+		columns: []string{"`id` text", "`created_at` datetime", "`modified_at` datetime", "`deleted_at` datetime", "`parent_id` text",
+			"`name` text", "`description` text", "`icon` integer", "`icon_color` integer", "`is_expand` numeric"},
+		constraints: []string{"PRIMARY KEY (`id`)", "CONSTRAINT `fk_tags_children` FOREIGN KEY (`parent_id`) REFERENCES `tags`(`id`)"},
+	},
+	{
+		name:    "item_tags",
+		columns: []string{"`tag_id` text", "`item_id` text"},
+		constraints: []string{"PRIMARY KEY (`tag_id`,`item_id`)",
+			"CONSTRAINT `fk_item_tags_item` FOREIGN KEY (`item_id`) REFERENCES `items`(`id`)",
+			"CONSTRAINT `fk_item_tags_tag` FOREIGN KEY (`tag_id`) REFERENCES `tags`(`id`)"},
+	},
+	{
+		name:        "item_metadata",
+		columns:     []string{"`item_id` text", "`key` text", "`value` text"},
+		constraints: []string{"PRIMARY KEY (`item_id`,`key`)"},
+	},
+	{
+		name:        "item_contents",
+		columns:     []string{"`item_id` text", "`content` text"},
+		constraints: []string{"PRIMARY KEY (`item_id`)"},
+	},
+	{
+		name:        "item_palettes",
+		columns:     []string{"`id` integer PRIMARY KEY AUTOINCREMENT", "`item_id` text", "`color` integer", "`weight` real", "`l` real", "`a` real", "`b` real"},
+		constraints: []string{"CONSTRAINT `fk_items_palettes` FOREIGN KEY (`item_id`) REFERENCES `items`(`id`)"},
+	},
+	{
+		name:        "settings",
+		columns:     []string{"`key` text", "`value` text"},
+		constraints: []string{"PRIMARY KEY (`key`)"},
+	},
+	{
+		name: "item_locations",
+		columns: []string{"`item_id` text", "`latitude` real", "`longitude` real", "`altitude` real",
+			"`x` real", "`y` real", "`z` real", "`mercator_x` real", "`mercator_y` real"},
+		constraints: []string{"PRIMARY KEY (`item_id`)", "CONSTRAINT `fk_items_location` FOREIGN KEY (`item_id`) REFERENCES `items`(`id`)"},
+	},
+	{
+		name:        "item_derivations",
+		columns:     []string{"`item_id` text", "`source_id` text", "`operations` text", "`created_at` datetime"},
+		constraints: []string{"PRIMARY KEY (`item_id`)"},
+	},
+	{
+		name: "item_versions",
+		columns: []string{"`id` integer PRIMARY KEY AUTOINCREMENT", "`item_id` text", "`file_id` text", "`name` text", "`ext` text",
+			"`size` integer", "`width` integer", "`height` integer", "`replaced_at` datetime"},
+	},
+	{
+		name:        "item_renditions",
+		columns:     []string{"`item_id` text", "`name` text", "`size` integer"},
+		constraints: []string{"PRIMARY KEY (`item_id`,`name`)"},
+	},
+}
+
+var filesIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS `idx_items_deleted_at` ON `items`(`deleted_at`)",
+	"CREATE INDEX IF NOT EXISTS `idx_item_palettes_item_id` ON `item_palettes`(`item_id`)",
+	"CREATE INDEX IF NOT EXISTS `idx_item_locations_latitude` ON `item_locations`(`latitude`)",
+	"CREATE INDEX IF NOT EXISTS `idx_item_derivations_source_id` ON `item_derivations`(`source_id`)",
+	"CREATE INDEX IF NOT EXISTS `idx_item_versions_file_id` ON `item_versions`(`file_id`)",
+	"CREATE INDEX IF NOT EXISTS `idx_item_versions_item_id` ON `item_versions`(`item_id`)",
+}
+
+// 审计日志只追加，触发器拒绝修改和删除已有的记录
+var auditLogSchema = []string{
+	"CREATE TABLE `audit_entries` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`actor` text,`token` text," +
+		"`source` text,`operation` text,`before` text,`after` text)",
+	"CREATE INDEX `idx_audit_entries_created_at` ON `audit_entries`(`created_at`)",
+	"CREATE INDEX `idx_audit_entries_actor` ON `audit_entries`(`actor`)",
+	"CREATE INDEX `idx_audit_entries_operation` ON `audit_entries`(`operation`)",
+	"CREATE TABLE `audit_targets` (`entry_id` integer,`target` text,PRIMARY KEY (`entry_id`,`target`)," +
+		"CONSTRAINT `fk_audit_entries_targets` FOREIGN KEY (`entry_id`) REFERENCES `audit_entries`(`id`))",
+	"CREATE INDEX `idx_audit_targets_target` ON `audit_targets`(`target`)",
+	"CREATE TRIGGER `audit_entries_no_update` BEFORE UPDATE ON `audit_entries` BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END",
+	"CREATE TRIGGER `audit_entries_no_delete` BEFORE DELETE ON `audit_entries` BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END",
+	"CREATE TRIGGER `audit_targets_no_update` BEFORE UPDATE ON `audit_targets` BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END",
+	"CREATE TRIGGER `audit_targets_no_delete` BEFORE DELETE ON `audit_targets` BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END",
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"synapforest/database/dbcommon"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// versionTable 记录已执行迁移的表，结构固定不变
+const versionTable = "schema_version"
+
+// Migration 一次数据库结构或数据变更。Version 从 1 开始连续编号，
+// 发布后不能修改，新的变更只能追加新的迁移
+type Migration struct {
+	Version int                     `json:"version"`
+	Name    string                  `json:"name"`
+	Up      func(tx *gorm.DB) error `json:"-"`
+}
+
+// statements 依次执行 SQL 语句的迁移
+func statements(sqls ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, sql := range sqls {
+			if err := tx.Exec(sql).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Schema 一个数据库文件及其迁移列表
+type Schema struct {
+	Name       string // 数据库文件名
+	Migrations []Migration
+}
+
+// Files files.db 的迁移
+var Files = &Schema{Name: "files.db", Migrations: filesMigrations}
+
+// Vectors vectors.db 的迁移
+var Vectors = &Schema{Name: "vectors.db", Migrations: vectorsMigrations}
+
+// Schemas 资料库中的全部数据库
+var Schemas = []*Schema{Files, Vectors}
+
+// Status 数据库的迁移状态
+type Status struct {
+	Database string      `json:"database"`
+	Version  int         `json:"version"` // 当前版本，0 表示没有执行过迁移
+	Latest   int         `json:"latest"`
+	Empty    bool        `json:"empty"` // 没有任何数据表
+	Applied  []Applied   `json:"applied"`
+	Pending  []Migration `json:"pending"`
+}
+
+// Applied 已执行的迁移
+type Applied struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Result 一次迁移的结果
+type Result struct {
+	Database string      `json:"database"`
+	From     int         `json:"from"`
+	To       int         `json:"to"`
+	Applied  []Migration `json:"applied"`
+	Backup   string      `json:"backup"` // 迁移前的快照，数据库为空或未迁移时为空
+}
+
+// Latest 最新的版本号
+func (s *Schema) Latest() int {
+	return len(s.Migrations)
+}
+
+func (s *Schema) check() error {
+	for i, m := range s.Migrations {
+		if m.Version != i+1 || m.Up == nil {
+			return fmt.Errorf("%s: migration %d (%s) is out of order", s.Name, m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+// Status 读取 db 的迁移状态，不修改数据库
+func (s *Schema) Status(db *gorm.DB) (*Status, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	status := &Status{Database: s.Name, Latest: s.Latest(), Applied: []Applied{}, Pending: []Migration{}}
+
+	var tables int64
+	err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != ?", versionTable).
+		Scan(&tables).Error
+	if err != nil {
+		return nil, err
+	}
+	status.Empty = tables == 0
+
+	if db.Migrator().HasTable(versionTable) {
+		err := db.Raw("SELECT version, name, applied_at FROM " + versionTable + " ORDER BY version ASC").
+			Scan(&status.Applied).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range status.Applied {
+			status.Version = max(status.Version, a.Version)
+		}
+	}
+
+	if status.Version > status.Latest {
+		return nil, fmt.Errorf("%s has schema version %d, newer than the latest known version %d; open it with a newer synapforest",
+			s.Name, status.Version, status.Latest)
+	}
+	status.Pending = append(status.Pending, s.Migrations[status.Version:]...)
+	return status, nil
+}
+
+// Migrate 依次执行待执行的迁移，每个迁移在单独的事务中执行并记录版本，
+// 失败时回滚该迁移并返回错误，数据库停留在上一个版本。
+// backupDir 不为空且数据库中已有数据时，先把数据库快照保存到 backupDir
+func (s *Schema) Migrate(ctx context.Context, db *gorm.DB, backupDir string) (*Result, error) {
+	status, err := s.Status(db)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Database: s.Name, From: status.Version, To: status.Version, Applied: []Migration{}}
+	if len(status.Pending) == 0 {
+		return result, nil
+	}
+
+	if backupDir != "" && !status.Empty {
+		if err := os.MkdirAll(backupDir, os.ModePerm); err != nil {
+			return nil, err
+		}
+		path := filepath.Join(backupDir, fmt.Sprintf("%s.v%d-%s", s.Name, status.Version, time.Now().Format("20060102-150405")))
+		if err := dbcommon.Snapshot(ctx, db, path); err != nil {
+			os.Remove(path)
+			return nil, fmt.Errorf("failed to back up %s before migrating: %v", s.Name, err)
+		}
+		result.Backup = path
+	}
+
+	err = db.Exec("CREATE TABLE IF NOT EXISTS " + versionTable + " (version integer PRIMARY KEY, name text, applied_at datetime)").Error
+	if err != nil {
+		return result, err
+	}
+
+	for _, m := range status.Pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Exec("INSERT INTO "+versionTable+" (version, name, applied_at) VALUES (?, ?, ?)", m.Version, m.Name, time.Now()).Error
+		})
+		if err != nil {
+			err = fmt.Errorf("migration %d (%s) of %s failed: %v; the database was left at version %d", m.Version, m.Name, s.Name, err, result.To)
+			if result.Backup != "" {
+				err = fmt.Errorf("%v, the database before migrating was saved to %s", err, result.Backup)
+			}
+			return result, err
+		}
+		result.To = m.Version
+		result.Applied = append(result.Applied, m)
+	}
+	return result, nil
+}
+
+// DryRun 在 db 的临时副本上执行待执行的迁移，检查能否成功，不修改 db
+func (s *Schema) DryRun(ctx context.Context, db *gorm.DB, tmpDir string) (*Result, error) {
+	dir, err := os.MkdirTemp(tmpDir, ".migrate-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, s.Name)
+	if err := dbcommon.Snapshot(ctx, db, path); err != nil {
+		return nil, fmt.Errorf("failed to copy %s: %v", s.Name, err)
+	}
+	copyDB, err := OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if sqlDB, err := copyDB.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	return s.Migrate(ctx, copyDB, "")
+}
+
+// OpenFile 打开数据库文件用于迁移，不输出 SQL 日志
+func OpenFile(path string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package migrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"synapforest/database/dbcommon"
+
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T, dir string, name string) *gorm.DB {
+	t.Helper()
+	db, err := OpenFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func columnsOf(t *testing.T, db *gorm.DB, table string) map[string]bool {
+	t.Helper()
+	types, err := db.Migrator().ColumnTypes(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := map[string]bool{}
+	for _, c := range types {
+		columns[c.Name()] = true
+	}
+	return columns
+}
+
+// 基线创建的结构至少包含此前 AutoMigrate 按模型创建的全部表和列
+func TestBaselineMatchesModels(t *testing.T) {
+	for _, tc := range []struct {
+		schema *Schema
+		models []interface{}
+	}{
+		{Files, []interface{}{&dbcommon.Item{}, &dbcommon.Folder{}, &dbcommon.Tag{}, &dbcommon.ItemMetadata{}, &dbcommon.ItemContent{},
+			&dbcommon.ItemPalette{}, &dbcommon.Setting{}, &dbcommon.ItemLocation{}, &dbcommon.ItemDerivation{}, &dbcommon.ItemVersion{}, &dbcommon.ItemRendition{}}},
+		{Vectors, []interface{}{&dbcommon.ItemVector{}}},
+	} {
+		dir := t.TempDir()
+		migrated := openTestDB(t, dir, "migrated.db")
+		legacy := openTestDB(t, dir, "legacy.db")
+
+		result, err := tc.schema.Migrate(context.Background(), migrated, filepath.Join(dir, "backups"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.From != 0 || result.To != tc.schema.Latest() || result.Backup != "" {
+			t.Errorf("%s: result = %+v", tc.schema.Name, result)
+		}
+		if err := legacy.AutoMigrate(tc.models...); err != nil {
+			t.Fatal(err)
+		}
+
+		tables, err := legacy.Migrator().GetTables()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, table := range tables {
+			have := columnsOf(t, migrated, table)
+			for column := range columnsOf(t, legacy, table) {
+				if !have[column] {
+					t.Errorf("%s: %s.%s missing after migration", tc.schema.Name, table, column)
+				}
+			}
+		}
+	}
+}
+
+// 旧版本创建的资料库缺少后来增加的列，迁移前保存快照，迁移后保留原有数据
+func TestMigrateLegacyDatabase(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir, Files.Name)
+	err := db.Exec("CREATE TABLE `items` (`id` text, `name` text, `ext` text, PRIMARY KEY (`id`))").Error
+	if err == nil {
+		err = db.Exec("INSERT INTO `items` (`id`, `name`, `ext`) VALUES ('a', 'photo', 'jpg')").Error
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backupDir := filepath.Join(dir, "backups")
+	result, err := Files.Migrate(context.Background(), db, backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.From != 0 || result.To != Files.Latest() || len(result.Applied) != Files.Latest() {
+		t.Errorf("result = %+v", result)
+	}
+	if !strings.HasPrefix(result.Backup, backupDir) {
+		t.Errorf("backup = %q", result.Backup)
+	}
+	backup := openTestDB(t, filepath.Dir(result.Backup), filepath.Base(result.Backup))
+	if columnsOf(t, backup, "items")["import_error"] {
+		t.Error("backup was taken after migrating")
+	}
+
+	if !columnsOf(t, db, "items")["import_error"] {
+		t.Error("import_error not added")
+	}
+	var item dbcommon.Item
+	if err := db.First(&item, "id = ?", "a").Error; err != nil || item.Name != "photo" {
+		t.Errorf("item = %+v, %v", item, err)
+	}
+
+	// 已是最新版本时不再执行
+	result, err = Files.Migrate(context.Background(), db, backupDir)
+	if err != nil || len(result.Applied) != 0 || result.Backup != "" {
+		t.Errorf("second migrate = %+v, %v", result, err)
+	}
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir, "test.db")
+	s := &Schema{Name: "test.db", Migrations: []Migration{
+		{Version: 1, Name: "create_a", Up: statements("CREATE TABLE a (x integer)")},
+		{Version: 2, Name: "create_b", Up: statements("CREATE TABLE b (x integer)", "INSERT INTO missing VALUES (1)")},
+	}}
+
+	result, err := s.Migrate(context.Background(), db, "")
+	if err == nil || !strings.Contains(err.Error(), "left at version 1") {
+		t.Fatalf("err = %v", err)
+	}
+	if result.To != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if !db.Migrator().HasTable("a") || db.Migrator().HasTable("b") {
+		t.Error("failed migration was not rolled back")
+	}
+
+	status, err := s.Status(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != 1 || len(status.Pending) != 1 || status.Pending[0].Name != "create_b" {
+		t.Errorf("status = %+v", status)
+	}
+
+	// 有数据时失败的迁移在错误中给出快照位置
+	s.Migrations[1].Up = statements("INSERT INTO missing VALUES (1)")
+	s.Migrations = append(s.Migrations, Migration{Version: 3, Name: "noop", Up: statements()})
+	_, err = s.Migrate(context.Background(), db, filepath.Join(dir, "backups"))
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(dir, "backups")) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestStatusRejectsNewerDatabase(t *testing.T) {
+	db := openTestDB(t, t.TempDir(), Vectors.Name)
+	if _, err := Vectors.Migrate(context.Background(), db, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO "+versionTable+" (version, name) VALUES (?, 'future')", Vectors.Latest()+1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Vectors.Status(db); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := Vectors.Migrate(context.Background(), db, ""); err == nil {
+		t.Error("migrated a newer database")
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	s := &Schema{Name: "test.db", Migrations: []Migration{
+		{Version: 1, Name: "a", Up: statements()},
+		{Version: 3, Name: "b", Up: statements()},
+	}}
+	if _, err := s.Status(nil); err == nil {
+		t.Error("out of order migrations accepted")
+	}
+}
+
+func TestDryRunLeavesDatabase(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir, Files.Name)
+	if err := db.Exec("CREATE TABLE `items` (`id` text, PRIMARY KEY (`id`))").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Files.DryRun(context.Background(), db, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.To != Files.Latest() {
+		t.Errorf("result = %+v", result)
+	}
+	status, err := Files.Status(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != 0 || db.Migrator().HasTable(versionTable) || columnsOf(t, db, "items")["name"] {
+		t.Errorf("dry run modified the database: %+v", status)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary copy left behind: %v", entries)
+	}
+}
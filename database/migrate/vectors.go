@@ -0,0 +1,25 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package migrate
+
+// vectorsMigrations vectors.db 的迁移，按版本顺序追加
+var vectorsMigrations = []Migration{
+	{Version: 1, Name: "baseline", Up: baseline(vectorsTables, nil)},
+}
+
+var vectorsTables = []table{
+	{
+		name:        "item_vectors",
+		columns:     []string{"`item_id` text", "`image_vec` blob", "`created_at` datetime", "`modified_at` datetime", "`deleted_at` datetime"},
+		constraints: []string{"PRIMARY KEY (`item_id`)"},
+	},
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"synapforest/database/migrate"
+
+	"gorm.io/gorm"
+)
+
+// MigrationBackupDir 迁移前数据库快照的保存目录
+func MigrationBackupDir(dir string) string {
+	return filepath.Join(dir, "migrations")
+}
+
+// 不打开资料库，逐个打开 dir 中已存在的数据库文件
+func eachSchema(dir string, fn func(schema *migrate.Schema, db *gorm.DB) error) error {
+	for _, schema := range migrate.Schemas {
+		path := filepath.Join(dir, schema.Name)
+		if _, err := os.Stat(path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		db, err := migrate.OpenFile(path)
+		if err != nil {
+			return err
+		}
+		err = fn(schema, db)
+		if sqlDB, closeErr := db.DB(); closeErr == nil {
+			sqlDB.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrationStatus 查看 dir 中数据库的迁移状态，资料库可以正在使用
+func MigrationStatus(dir string) ([]*migrate.Status, error) {
+	statuses := []*migrate.Status{}
+	err := eachSchema(dir, func(schema *migrate.Schema, db *gorm.DB) error {
+		status, err := schema.Status(db)
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, status)
+		return nil
+	})
+	return statuses, err
+}
+
+// DryRunMigrations 在数据库副本上执行待执行的迁移，不修改资料库
+func DryRunMigrations(dir string) ([]*migrate.Result, error) {
+	results := []*migrate.Result{}
+	err := eachSchema(dir, func(schema *migrate.Schema, db *gorm.DB) error {
+		result, err := schema.DryRun(context.Background(), db, dir)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+// Migrate 执行 dir 中数据库待执行的迁移，迁移前保存快照。资料库不能正在被服务器使用
+func Migrate(dir string) ([]*migrate.Result, error) {
+	results := []*migrate.Result{}
+	err := eachSchema(dir, func(schema *migrate.Schema, db *gorm.DB) error {
+		result, err := schema.Migrate(context.Background(), db, MigrationBackupDir(dir))
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package database
+
+import (
+	"testing"
+
+	"synapforest/database/migrate"
+)
+
+// 打开资料库时执行全部迁移，之后的状态查询和试运行没有待执行的迁移
+func TestOpenMigratesLibrary(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib.Close()
+
+	statuses, err := MigrationStatus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(migrate.Schemas) {
+		t.Fatalf("statuses = %+v", statuses)
+	}
+	for _, s := range statuses {
+		if s.Version != s.Latest || len(s.Pending) != 0 || len(s.Applied) != s.Latest {
+			t.Errorf("%s: %+v", s.Database, s)
+		}
+	}
+
+	results, err := DryRunMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if len(r.Applied) != 0 {
+			t.Errorf("%s: dry run applied %+v", r.Database, r.Applied)
+		}
+	}
+}
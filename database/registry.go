@@ -0,0 +1,339 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+var (
+	// ErrLibraryNotFound 资料库未注册
+	ErrLibraryNotFound = errors.New("library not found")
+	// ErrLibraryClosed 资料库已注册但未打开
+	ErrLibraryClosed = errors.New("library is closed")
+)
+
+// 资料库名称用于路径前缀
+var libraryNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// LibraryInfo 注册的资料库
+type LibraryInfo struct {
+	Name    string `json:"name"`
+	Dir     string `json:"dir"`
+	Open    bool   `json:"open"`
+	Default bool   `json:"default"` // 未指定资料库的请求使用默认资料库
+}
+
+// 保存在注册表文件中的内容
+type registryFile struct {
+	Default   string         `json:"default"`
+	Libraries []registryItem `json:"libraries"`
+}
+
+type registryItem struct {
+	Name string `json:"name"`
+	Dir  string `json:"dir"`
+	Open bool   `json:"open"` // 启动时重新打开
+}
+
+// Registry 服务器管理的资料库。注册信息保存在文件中，启动时重新打开上次打开的资料库
+type Registry struct {
+	mu           sync.Mutex
+	path         string // 为空时只保存在内存中
+	librariesDir string // 新建资料库未指定目录时的上级目录
+	file         registryFile
+	open         map[string]*Library
+
+	// OnOpen 在资料库打开后、开始处理请求前调用
+	OnOpen func(lib *Library) error
+	// OnClose 在资料库关闭后调用
+	OnClose func(lib *Library)
+}
+
+// LoadRegistry 读取注册表文件，文件不存在时注册 defaultDir 作为默认资料库。
+// 新建的资料库默认放在注册表文件旁的 libraries 目录中
+func LoadRegistry(path string, defaultDir string) (*Registry, error) {
+	r := NewRegistry(defaultDir, filepath.Join(filepath.Dir(path), "libraries"))
+	r.path = path
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &r.file); err != nil {
+			return nil, fmt.Errorf("invalid library registry: %v", err)
+		}
+	}
+	return r, nil
+}
+
+// NewRegistry 创建只保存在内存中的注册表，默认资料库为 defaultDir，
+// 新建的资料库默认放在 librariesDir 中。用于嵌入和测试
+func NewRegistry(defaultDir string, librariesDir string) *Registry {
+	return &Registry{
+		librariesDir: librariesDir,
+		file: registryFile{
+			Default:   "default",
+			Libraries: []registryItem{{Name: "default", Dir: defaultDir, Open: true}},
+		},
+		open: map[string]*Library{},
+	}
+}
+
+// OpenAll 打开注册表中标记为打开的资料库，默认资料库总是打开
+func (r *Registry) OpenAll() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.file.Libraries {
+		item := &r.file.Libraries[i]
+		if !item.Open && item.Name != r.file.Default {
+			continue
+		}
+		if _, err := r.openLocked(item); err != nil {
+			return fmt.Errorf("failed to open library %s: %v", item.Name, err)
+		}
+	}
+	return r.save()
+}
+
+// List 列出注册的资料库
+func (r *Registry) List() []LibraryInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]LibraryInfo, 0, len(r.file.Libraries))
+	for _, item := range r.file.Libraries {
+		list = append(list, r.info(item))
+	}
+	return list
+}
+
+// Get 返回打开的资料库
+func (r *Registry) Get(name string) (*Library, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.find(name) == nil {
+		return nil, ErrLibraryNotFound
+	}
+	lib, ok := r.open[name]
+	if !ok {
+		return nil, ErrLibraryClosed
+	}
+	return lib, nil
+}
+
+// Default 返回默认资料库
+func (r *Registry) Default() (*Library, error) {
+	r.mu.Lock()
+	name := r.file.Default
+	r.mu.Unlock()
+	return r.Get(name)
+}
+
+// IsDefault 判断是否为默认资料库
+func (r *Registry) IsDefault(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return name == r.file.Default
+}
+
+// Create 注册并打开新的资料库，dir 为空时使用 <librariesDir>/<name>
+func (r *Registry) Create(name string, dir string) (LibraryInfo, error) {
+	if !libraryNamePattern.MatchString(name) {
+		return LibraryInfo{}, fmt.Errorf("invalid library name %q", name)
+	}
+	if dir == "" {
+		dir = filepath.Join(r.librariesDir, name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.find(name) != nil {
+		return LibraryInfo{}, fmt.Errorf("library %s already exists", name)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return LibraryInfo{}, err
+	}
+	for _, item := range r.file.Libraries {
+		if other, err := filepath.Abs(item.Dir); err == nil && other == abs {
+			return LibraryInfo{}, fmt.Errorf("directory %s is already used by library %s", dir, item.Name)
+		}
+	}
+
+	r.file.Libraries = append(r.file.Libraries, registryItem{Name: name, Dir: dir, Open: true})
+	item := &r.file.Libraries[len(r.file.Libraries)-1]
+	if _, err := r.openLocked(item); err != nil {
+		r.file.Libraries = r.file.Libraries[:len(r.file.Libraries)-1]
+		return LibraryInfo{}, err
+	}
+	return r.info(*item), r.save()
+}
+
+// Open 打开已注册的资料库，已经打开时直接返回
+func (r *Registry) Open(name string) (LibraryInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item := r.find(name)
+	if item == nil {
+		return LibraryInfo{}, ErrLibraryNotFound
+	}
+	if _, err := r.openLocked(item); err != nil {
+		return LibraryInfo{}, err
+	}
+	item.Open = true
+	return r.info(*item), r.save()
+}
+
+// Rename 修改资料库名称，目录不变
+func (r *Registry) Rename(name string, newName string) (LibraryInfo, error) {
+	if !libraryNamePattern.MatchString(newName) {
+		return LibraryInfo{}, fmt.Errorf("invalid library name %q", newName)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item := r.find(name)
+	if item == nil {
+		return LibraryInfo{}, ErrLibraryNotFound
+	}
+	if name == newName {
+		return r.info(*item), nil
+	}
+	if r.find(newName) != nil {
+		return LibraryInfo{}, fmt.Errorf("library %s already exists", newName)
+	}
+
+	item.Name = newName
+	if lib, ok := r.open[name]; ok {
+		delete(r.open, name)
+		r.open[newName] = lib
+	}
+	if r.file.Default == name {
+		r.file.Default = newName
+	}
+	return r.info(*item), r.save()
+}
+
+// Close 关闭资料库，等待正在进行的请求结束。默认资料库不能关闭
+func (r *Registry) Close(name string) (LibraryInfo, error) {
+	r.mu.Lock()
+	item := r.find(name)
+	if item == nil {
+		r.mu.Unlock()
+		return LibraryInfo{}, ErrLibraryNotFound
+	}
+	if name == r.file.Default {
+		r.mu.Unlock()
+		return LibraryInfo{}, fmt.Errorf("the default library cannot be closed")
+	}
+	lib := r.open[name]
+	delete(r.open, name)
+	item.Open = false
+	info := r.info(*item)
+	err := r.save()
+	r.mu.Unlock()
+
+	// 不再分配给新请求后再等待关闭
+	if lib != nil {
+		if closeErr := r.closeLibrary(lib); err == nil {
+			err = closeErr
+		}
+	}
+	return info, err
+}
+
+// CloseAll 关闭全部资料库
+func (r *Registry) CloseAll() {
+	r.mu.Lock()
+	libs := r.open
+	r.open = map[string]*Library{}
+	r.mu.Unlock()
+
+	for _, lib := range libs {
+		r.closeLibrary(lib)
+	}
+}
+
+func (r *Registry) closeLibrary(lib *Library) error {
+	err := lib.Close()
+	if r.OnClose != nil {
+		r.OnClose(lib)
+	}
+	return err
+}
+
+// 调用方需持有 mu
+func (r *Registry) openLocked(item *registryItem) (*Library, error) {
+	if lib, ok := r.open[item.Name]; ok {
+		return lib, nil
+	}
+	lib, err := Open(item.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if r.OnOpen != nil {
+		if err := r.OnOpen(lib); err != nil {
+			lib.Close()
+			return nil, err
+		}
+	}
+	r.open[item.Name] = lib
+	return lib, nil
+}
+
+func (r *Registry) find(name string) *registryItem {
+	for i := range r.file.Libraries {
+		if r.file.Libraries[i].Name == name {
+			return &r.file.Libraries[i]
+		}
+	}
+	return nil
+}
+
+func (r *Registry) info(item registryItem) LibraryInfo {
+	_, open := r.open[item.Name]
+	return LibraryInfo{
+		Name:    item.Name,
+		Dir:     item.Dir,
+		Open:    open,
+		Default: item.Name == r.file.Default,
+	}
+}
+
+func (r *Registry) save() error {
+	if r.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(r.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestRegistry(t *testing.T) (*Registry, string) {
+	t.Helper()
+	root := t.TempDir()
+	r, err := LoadRegistry(filepath.Join(root, "libraries.json"), filepath.Join(root, "default"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.OpenAll(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.CloseAll)
+	return r, root
+}
+
+func TestRegistryLifecycle(t *testing.T) {
+	r, root := newTestRegistry(t)
+	opened, closed := 0, 0
+	r.OnOpen = func(*Library) error { opened++; return nil }
+	r.OnClose = func(*Library) { closed++ }
+
+	info, err := r.Create("photos", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Dir != filepath.Join(root, "libraries", "photos") || !info.Open || info.Default || opened != 1 {
+		t.Errorf("created %+v, opened %d", info, opened)
+	}
+	photos, err := r.Get("photos")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, dir := range map[string]string{
+		"photos":    filepath.Join(root, "other"),               // 名称重复
+		"copy":      filepath.Join(root, "libraries", "photos"), // 目录已被使用
+		"bad/name":  "",
+		"":          "",
+		"with.dots": "",
+	} {
+		if _, err := r.Create(name, dir); err == nil {
+			t.Errorf("create %q in %q accepted", name, dir)
+		}
+	}
+
+	// 改名后以新名称访问同一个资料库，目录不变
+	info, err = r.Rename("photos", "pictures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Dir != filepath.Join(root, "libraries", "photos") {
+		t.Errorf("renamed dir = %s", info.Dir)
+	}
+	if lib, err := r.Get("pictures"); err != nil || lib != photos {
+		t.Errorf("get pictures = %p, %v", lib, err)
+	}
+	if _, err := r.Get("photos"); !errors.Is(err, ErrLibraryNotFound) {
+		t.Errorf("get old name: %v", err)
+	}
+	if _, err := r.Rename("pictures", "default"); err == nil {
+		t.Error("rename to an existing name accepted")
+	}
+
+	// 默认资料库改名后仍然是默认资料库
+	if _, err := r.Rename("default", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if lib, err := r.Default(); err != nil || lib.Dir != filepath.Join(root, "default") || !r.IsDefault("main") {
+		t.Errorf("default = %v, %v", lib, err)
+	}
+	if _, err := r.Close("main"); err == nil {
+		t.Error("closed the default library")
+	}
+
+	info, err = r.Close("pictures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Open || closed != 1 {
+		t.Errorf("closed %+v, OnClose called %d times", info, closed)
+	}
+	if _, err := r.Get("pictures"); !errors.Is(err, ErrLibraryClosed) {
+		t.Errorf("get closed library: %v", err)
+	}
+	if photos.Acquire() {
+		t.Error("closed library acquired")
+	}
+
+	if _, err := r.Open("pictures"); err != nil {
+		t.Fatal(err)
+	}
+	if lib, err := r.Get("pictures"); err != nil || lib == photos || opened != 2 {
+		t.Errorf("reopened %p, %v, opened %d", lib, err, opened)
+	}
+	if _, err := r.Open("missing"); !errors.Is(err, ErrLibraryNotFound) {
+		t.Errorf("open missing: %v", err)
+	}
+
+	// 重新读取注册表文件，只打开上次打开的资料库
+	if _, err := r.Create("archive", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Close("archive"); err != nil {
+		t.Fatal(err)
+	}
+	r.CloseAll()
+	loaded, err := LoadRegistry(filepath.Join(root, "libraries.json"), filepath.Join(root, "ignored"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.OpenAll(); err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.CloseAll()
+	got := map[string]LibraryInfo{}
+	for _, info := range loaded.List() {
+		got[info.Name] = info
+	}
+	if len(got) != 3 || !got["main"].Default || !got["main"].Open || got["main"].Dir != filepath.Join(root, "default") ||
+		!got["pictures"].Open || got["archive"].Open {
+		t.Errorf("loaded = %+v", got)
+	}
+}
+
+// 关闭资料库时等待持有 Acquire 的请求结束，期间不再分配给新请求
+func TestRegistryCloseWaitsForAcquire(t *testing.T) {
+	r, _ := newTestRegistry(t)
+	if _, err := r.Create("photos", ""); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := r.Get("photos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lib.Acquire() {
+		t.Fatal("acquire failed")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Close("photos")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("close returned while the library was in use: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := r.Get("photos"); !errors.Is(err, ErrLibraryClosed) {
+		t.Errorf("get while closing: %v", err)
+	}
+	// 正在进行的请求仍然可以使用数据库
+	if err := lib.DB.Exec("SELECT 1").Error; err != nil {
+		t.Errorf("query while closing: %v", err)
+	}
+
+	lib.Release()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("close did not return after release")
+	}
+	if lib.Acquire() {
+		t.Error("acquired after close")
+	}
+}
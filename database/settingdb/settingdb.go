@@ -0,0 +1,210 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package settingdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"synapforest/blobstore"
+	"synapforest/database/dbcommon"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	renditionsKey   = "renditions"
+	importLimitsKey = "import_limits"
+	blobStoreKey    = "blob_store"
+)
+
+// 内置规格名，分别对应 Item.HaveThumbnail 和 Item.HavePreview
+const (
+	Thumbnail = "thumbnail"
+	Preview   = "preview"
+)
+
+// RenditionFormats 预览图规格支持的格式
+var RenditionFormats = []string{"webp", "jpeg", "png"}
+
+func isRenditionFormat(format string) bool {
+	for _, f := range RenditionFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// 单个规格允许的最大像素数
+const maxRenditionPixels = 4096 * 4096
+
+var renditionNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// DefaultRenditions 未配置时使用的预览图规格
+var DefaultRenditions = []dbcommon.Rendition{
+	{Name: Thumbnail, MaxPixels: 256 * 256, Format: "webp", Quality: 90},
+	{Name: Preview, MaxPixels: 768 * 768, Format: "webp", Quality: 90},
+}
+
+// DefaultImportLimits 未配置时的解码限制，约 1 亿像素（解码后约 400MB）和 512MB 文件
+var DefaultImportLimits = dbcommon.ImportLimits{
+	MaxPixels:   100_000_000,
+	MaxFileSize: 512 << 20,
+}
+
+// Get 读取设置项到 value 中，设置项不存在时返回 false
+func Get(db *gorm.DB, key string, value interface{}) (bool, error) {
+	var setting dbcommon.Setting
+	err := db.First(&setting, "key = ?", key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to query setting %s: %v", key, err)
+	}
+
+	if err := json.Unmarshal([]byte(setting.Value), value); err != nil {
+		return false, fmt.Errorf("failed to decode setting %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// Set 保存设置项
+func Set(db *gorm.DB, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode setting %s: %v", key, err)
+	}
+
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+	}).Create(&dbcommon.Setting{Key: key, Value: string(data)}).Error
+}
+
+// GetRenditions 返回当前的预览图规格
+func GetRenditions(db *gorm.DB) ([]dbcommon.Rendition, error) {
+	var renditions []dbcommon.Rendition
+	ok, err := Get(db, renditionsKey, &renditions)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return append([]dbcommon.Rendition(nil), DefaultRenditions...), nil
+	}
+	return renditions, nil
+}
+
+// GetRendition 按名称查找预览图规格
+func GetRendition(db *gorm.DB, name string) (dbcommon.Rendition, error) {
+	renditions, err := GetRenditions(db)
+	if err != nil {
+		return dbcommon.Rendition{}, err
+	}
+	for _, r := range renditions {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return dbcommon.Rendition{}, fmt.Errorf("rendition %s not found", name)
+}
+
+// SetRenditions 校验并保存预览图规格，内置规格必须存在
+func SetRenditions(db *gorm.DB, renditions []dbcommon.Rendition) error {
+	if err := ValidateRenditions(renditions); err != nil {
+		return err
+	}
+	return Set(db, renditionsKey, renditions)
+}
+
+func ValidateRenditions(renditions []dbcommon.Rendition) error {
+	seen := make(map[string]bool)
+	for _, r := range renditions {
+		if !renditionNamePattern.MatchString(r.Name) {
+			return fmt.Errorf("invalid rendition name %q", r.Name)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("duplicate rendition %q", r.Name)
+		}
+		seen[r.Name] = true
+
+		if r.MaxPixels < 1 || r.MaxPixels > maxRenditionPixels {
+			return fmt.Errorf("rendition %s: maxPixels must be between 1 and %d", r.Name, maxRenditionPixels)
+		}
+		if !isRenditionFormat(r.Format) {
+			return fmt.Errorf("rendition %s: unsupported format %q", r.Name, r.Format)
+		}
+		if r.Quality < 1 || r.Quality > 100 {
+			return fmt.Errorf("rendition %s: quality must be between 1 and 100", r.Name)
+		}
+	}
+
+	if !seen[Thumbnail] || !seen[Preview] {
+		return fmt.Errorf("renditions %s and %s are required", Thumbnail, Preview)
+	}
+	return nil
+}
+
+// GetImportLimits 返回当前的解码限制
+func GetImportLimits(db *gorm.DB) (dbcommon.ImportLimits, error) {
+	limits := DefaultImportLimits
+	if _, err := Get(db, importLimitsKey, &limits); err != nil {
+		return dbcommon.ImportLimits{}, err
+	}
+	return limits, nil
+}
+
+// SetImportLimits 保存解码限制
+func SetImportLimits(db *gorm.DB, limits dbcommon.ImportLimits) error {
+	if limits.MaxPixels < 0 || limits.MaxFileSize < 0 {
+		return fmt.Errorf("import limits must not be negative")
+	}
+	return Set(db, importLimitsKey, limits)
+}
+
+// GetBlobStore 返回文件存储设置，未设置时为本地存储
+func GetBlobStore(db *gorm.DB) (blobstore.Config, error) {
+	cfg := blobstore.Config{Type: "local"}
+	if _, err := Get(db, blobStoreKey, &cfg); err != nil {
+		return blobstore.Config{}, err
+	}
+	return cfg, nil
+}
+
+// Redact 去掉设置项中的凭据，用于写入资料库目录下的明文文件
+func Redact(setting dbcommon.Setting) (dbcommon.Setting, error) {
+	if setting.Key != blobStoreKey {
+		return setting, nil
+	}
+	var cfg blobstore.Config
+	if err := json.Unmarshal([]byte(setting.Value), &cfg); err != nil {
+		return setting, fmt.Errorf("failed to decode setting %s: %v", setting.Key, err)
+	}
+	cfg.S3.SecretKey = ""
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return setting, err
+	}
+	setting.Value = string(data)
+	return setting, nil
+}
+
+// SetBlobStore 保存文件存储设置，重新打开资料库后生效
+func SetBlobStore(db *gorm.DB, cfg blobstore.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	return Set(db, blobStoreKey, cfg)
+}
@@ -14,6 +14,7 @@ package tagdb
 import (
 	"fmt"
 	"log"
+	"synapforest/database/auditdb"
 	"synapforest/database/dbcommon"
 	"time"
 
@@ -42,7 +43,17 @@ func CreateTag(db *gorm.DB, name *string, description string, icon uint32, iconC
 			ModifiedAt:  time.Now(),
 			IsExpand:    is_expand,
 		}
-		if err := db.Create(&tag).Error; err != nil {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&tag).Error; err != nil {
+				return err
+			}
+			return auditdb.Record(tx, auditdb.Change{
+				Operation: auditdb.TagCreate,
+				Targets:   auditdb.UUIDs(tag.ID),
+				After:     auditdb.TagNode(tag),
+			})
+		})
+		if err != nil {
 			return nil, err
 		}
 		return &tag, nil
@@ -55,6 +66,7 @@ func UpdateTag(db *gorm.DB, tagID uuid.UUID, name *string, description *string,
 	if err := db.First(&tag, tagID).Error; err != nil {
 		return nil, err
 	}
+	before := auditdb.TagNode(tag)
 
 	// 更新标签的字段（仅当参数不为 nil 时更新）
 	if name != nil {
@@ -75,7 +87,18 @@ func UpdateTag(db *gorm.DB, tagID uuid.UUID, name *string, description *string,
 	tag.ModifiedAt = time.Now() // 更新修改时间
 
 	// 保存更新后的标签
-	if err := db.Save(&tag).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&tag).Error; err != nil {
+			return err
+		}
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.TagUpdate,
+			Targets:   auditdb.UUIDs(tag.ID),
+			Before:    before,
+			After:     auditdb.TagNode(tag),
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -111,20 +134,37 @@ func UpdateTagParents(db *gorm.DB, tagIDs []uuid.UUID, newParentID uuid.UUID) er
 		return nil // 没有要更新的标签
 	}
 
-	// 批量更新
-	result := db.Model(&dbcommon.Tag{}).
-		Where("id IN ?", tagIDs).
-		Update("parent_id", newParentID)
+	return db.Transaction(func(tx *gorm.DB) error {
+		var tags []dbcommon.Tag
+		if err := tx.Where("id IN ?", tagIDs).Find(&tags).Error; err != nil {
+			return err
+		}
 
-	// 检查更新结果
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound // 没有匹配的记录
-	}
+		// 批量更新
+		result := tx.Model(&dbcommon.Tag{}).
+			Where("id IN ?", tagIDs).
+			Update("parent_id", newParentID)
 
-	return nil
+		// 检查更新结果
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound // 没有匹配的记录
+		}
+
+		// 修改前各标签的父标签
+		before := make(map[string]uuid.UUID, len(tags))
+		for _, t := range tags {
+			before[t.ID.String()] = t.ParentID
+		}
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.TagMove,
+			Targets:   append(auditdb.UUIDs(tagIDs...), newParentID.String()),
+			Before:    before,
+			After:     map[string]uuid.UUID{"parent": newParentID},
+		})
+	})
 }
 
 // 递归获取所有子标签的ID
@@ -151,41 +191,81 @@ func getChildTagIDs(db *gorm.DB, tagID uuid.UUID) ([]uuid.UUID, error) {
 
 // 删除标签及其子标签
 func DeleteTag(db *gorm.DB, tagID uuid.UUID, hardDelete *bool, deleteAssociatedFiles *bool) error {
-	tagIDs, err := getChildTagIDs(db, tagID)
-	if err != nil {
-		return err
-	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		tagIDs, err := getChildTagIDs(tx, tagID)
+		if err != nil {
+			return err
+		}
 
-	if deleteAssociatedFiles != nil && *deleteAssociatedFiles {
+		var tags []dbcommon.Tag
+		if err := tx.Where("id IN ?", tagIDs).Find(&tags).Error; err != nil {
+			return err
+		}
 		// 获取所有关联的 item_id
 		var itemIDs []string
-		if err := db.Table("item_tags").
-			Select("item_id").
+		if err := tx.Table("item_tags").
+			Distinct("item_id").
 			Where("tag_id IN ?", tagIDs).
 			Pluck("item_id", &itemIDs).Error; err != nil {
 			return err
 		}
 
-		// 软删除关联的文件
-		if err := db.Delete(&dbcommon.Item{}, itemIDs).Error; err != nil {
-			return err
+		var after interface{}
+		if deleteAssociatedFiles != nil && *deleteAssociatedFiles {
+			// 软删除关联的文件
+			if len(itemIDs) > 0 {
+				if err := tx.Delete(&dbcommon.Item{}, itemIDs).Error; err != nil {
+					return err
+				}
+			}
+			after = map[string][]string{"trashed_items": itemIDs}
+		} else {
+			// 删除标签和文件的关联关系
+			if err := tx.Exec("DELETE FROM item_tags WHERE tag_id IN ?", tagIDs).Error; err != nil {
+				return err
+			}
 		}
-	} else {
-		// 删除标签和文件的关联关系
-		if err := db.Exec("DELETE FROM item_tags WHERE tag_id IN ?", tagIDs).Error; err != nil {
-			return err
+
+		if true { // 由于不知道软删除标签有什么意义，暂时忽略该项
+			if err := tx.Unscoped().Delete(&dbcommon.Tag{}, "id IN ?", tagIDs).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Delete(&dbcommon.Tag{}, "id IN ?", tagIDs).Error; err != nil {
+				return err
+			}
 		}
-	}
 
-	if true { // 由于不知道软删除标签有什么意义，暂时忽略该项
-		if err := db.Unscoped().Delete(&dbcommon.Tag{}, "id IN ?", tagIDs).Error; err != nil {
-			return err
+		nodes := make([]auditdb.Node, len(tags))
+		for i, t := range tags {
+			nodes[i] = auditdb.TagNode(t)
 		}
-	} else {
-		if err := db.Delete(&dbcommon.Tag{}, "id IN ?", tagIDs).Error; err != nil {
-			return err
+		return auditdb.Record(tx, auditdb.Change{
+			Operation: auditdb.TagDelete,
+			Targets:   append(auditdb.UUIDs(tagIDs...), itemIDs...),
+			Before:    map[string]interface{}{"tags": nodes, "items": itemIDs},
+			After:     after,
+		})
+	})
+}
+
+// GetTagPath 返回从最顶层到指定标签的完整路径
+func GetTagPath(db *gorm.DB, tagID uuid.UUID) ([]dbcommon.Tag, error) {
+	var path []dbcommon.Tag
+	seen := make(map[uuid.UUID]bool)
+
+	id := tagID
+	for id != uuid.Nil && !seen[id] {
+		seen[id] = true
+
+		var tag dbcommon.Tag
+		if err := db.Unscoped().First(&tag, "id = ?", id).Error; err != nil {
+			return nil, err
 		}
+		path = append([]dbcommon.Tag{tag}, path...)
+
+		id = tag.ParentID
 	}
 
-	return nil
+	return path, nil
 }
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package docextract
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document 文档中提取出的信息
+type Document struct {
+	Title  string
+	Author string
+	Pages  int
+	Text   string
+}
+
+// IsDocumentExt 判断扩展名是否为支持提取的文档格式
+func IsDocumentExt(ext string) bool {
+	switch strings.ToLower(strings.TrimPrefix(ext, ".")) {
+	case "pdf", "docx", "xlsx", "pptx":
+		return true
+	}
+	return false
+}
+
+// Extract 根据扩展名提取文档信息，正文最多保留 maxText 字节
+func Extract(path string, maxText int) (*Document, error) {
+	var doc *Document
+	var err error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		doc, err = extractPDF(path, maxText)
+	case ".docx", ".xlsx", ".pptx":
+		doc, err = extractOOXML(path)
+	default:
+		return nil, fmt.Errorf("unsupported document format: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Text = truncateUTF8(strings.TrimSpace(doc.Text), maxText)
+	return doc, nil
+}
+
+// Metadata 将文档信息转换为 item 元数据
+func (d *Document) Metadata() map[string]string {
+	metadata := map[string]string{}
+	if d.Title != "" {
+		metadata["doc.title"] = d.Title
+	}
+	if d.Author != "" {
+		metadata["doc.author"] = d.Author
+	}
+	if d.Pages > 0 {
+		metadata["doc.pages"] = strconv.Itoa(d.Pages)
+	}
+	return metadata
+}
+
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	s = s[:maxBytes]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package docextract
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 单个 XML 部件解压后的大小上限，防止压缩炸弹
+const maxPartSize = 64 << 20
+
+// 提取 docx/xlsx/pptx（Office Open XML）中的属性与正文
+func extractOOXML(filePath string) (*Document, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open office document failed: %v", err)
+	}
+	defer zr.Close()
+
+	parts := map[string]*zip.File{}
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+
+	doc := &Document{}
+
+	if f, ok := parts["docProps/core.xml"]; ok {
+		var core struct {
+			Title   string `xml:"title"`
+			Creator string `xml:"creator"`
+		}
+		if err := decodePart(f, &core); err == nil {
+			doc.Title = strings.TrimSpace(core.Title)
+			doc.Author = strings.TrimSpace(core.Creator)
+		}
+	}
+
+	if f, ok := parts["docProps/app.xml"]; ok {
+		var app struct {
+			Pages  string `xml:"Pages"`
+			Slides string `xml:"Slides"`
+		}
+		if err := decodePart(f, &app); err == nil {
+			if n, err := strconv.Atoi(app.Pages); err == nil {
+				doc.Pages = n
+			} else if n, err := strconv.Atoi(app.Slides); err == nil {
+				doc.Pages = n
+			}
+		}
+	}
+
+	var textParts []string
+	switch {
+	case parts["word/document.xml"] != nil:
+		textParts = []string{"word/document.xml"}
+	case parts["ppt/presentation.xml"] != nil:
+		textParts = numberedParts(parts, "ppt/slides/slide")
+		if doc.Pages == 0 {
+			doc.Pages = len(textParts)
+		}
+	case parts["xl/workbook.xml"] != nil:
+		if _, ok := parts["xl/sharedStrings.xml"]; ok {
+			textParts = append(textParts, "xl/sharedStrings.xml")
+		}
+		sheets := numberedParts(parts, "xl/worksheets/sheet")
+		textParts = append(textParts, sheets...)
+		// 表格没有页的概念，以工作表数量作为页数
+		if doc.Pages == 0 {
+			doc.Pages = len(sheets)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized office document")
+	}
+
+	var sb strings.Builder
+	for _, name := range textParts {
+		if err := collectText(parts[name], &sb); err != nil {
+			return nil, fmt.Errorf("read %s failed: %v", name, err)
+		}
+	}
+	doc.Text = sb.String()
+
+	return doc, nil
+}
+
+// 按编号顺序返回 prefix1.xml、prefix2.xml ... 等部件名
+func numberedParts(parts map[string]*zip.File, prefix string) []string {
+	type numbered struct {
+		name string
+		n    int
+	}
+	var found []numbered
+	for name := range parts {
+		if !strings.HasPrefix(name, prefix) || path.Ext(name) != ".xml" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".xml"))
+		if err != nil {
+			continue
+		}
+		found = append(found, numbered{name, n})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].n < found[j].n })
+
+	names := make([]string, len(found))
+	for i, f := range found {
+		names[i] = f.name
+	}
+	return names
+}
+
+func openPart(f *zip.File) (io.ReadCloser, error) {
+	if f.UncompressedSize64 > maxPartSize {
+		return nil, fmt.Errorf("part %s too large", f.Name)
+	}
+	return f.Open()
+}
+
+func decodePart(f *zip.File, v interface{}) error {
+	rc, err := openPart(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(io.LimitReader(rc, maxPartSize)).Decode(v)
+}
+
+// 收集 <w:t>、<a:t>、<t> 以及单元格 <v> 中的文本，段落、行结束时换行
+func collectText(f *zip.File, sb *strings.Builder) error {
+	rc, err := openPart(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	decoder := xml.NewDecoder(io.LimitReader(rc, maxPartSize))
+	inText := false
+	sharedCell := false
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "c":
+				// t="s" 的单元格值只是共享字符串的索引，正文已从 sharedStrings.xml 读取
+				sharedCell = false
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "t" && attr.Value == "s" {
+						sharedCell = true
+					}
+				}
+			case "t":
+				inText = true
+			case "v":
+				inText = !sharedCell
+			case "tab":
+				sb.WriteByte('\t')
+			case "br":
+				sb.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "v":
+				if inText {
+					sb.WriteByte('\t')
+				}
+				inText = false
+			case "p", "si", "row":
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+}
@@ -0,0 +1,908 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package docextract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// 超过该大小的 PDF 不做解析
+const maxPDFSize = 256 << 20
+
+// 单个流解压后的大小上限
+const maxStreamSize = 64 << 20
+
+// 字典和数组的最大嵌套层数，防止恶意文件耗尽栈空间
+const maxNesting = 256
+
+var errTooDeep = errors.New("pdf objects nested too deeply")
+
+type pdfName string
+
+type pdfKeyword string
+
+type pdfRef struct {
+	num int
+	gen int
+}
+
+type pdfDict map[string]interface{}
+
+type pdfArray []interface{}
+
+type pdfObject struct {
+	value  interface{}
+	stream []byte // 未解码的流数据，非流对象为 nil
+}
+
+type pdfFile struct {
+	objects map[int]*pdfObject
+	trailer pdfDict
+	cmaps   map[int]*cmap
+}
+
+var objHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// 不依赖交叉引用表，直接扫描文件中的全部对象（包括对象流中的对象）并按页提取文本
+func extractPDF(filePath string, maxText int) (*Document, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("stat pdf failed: %v", err)
+	}
+	if info.Size() > maxPDFSize {
+		return nil, fmt.Errorf("pdf too large: %d bytes", info.Size())
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("read pdf failed: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, fmt.Errorf("invalid pdf header")
+	}
+
+	f := parsePDF(data)
+	if _, ok := f.trailer["Encrypt"]; ok {
+		return nil, fmt.Errorf("encrypted pdf is not supported")
+	}
+
+	doc := &Document{}
+	if infoDict, ok := f.resolve(f.trailer["Info"]).(pdfDict); ok {
+		if s, ok := f.resolve(infoDict["Title"]).([]byte); ok {
+			doc.Title = strings.TrimSpace(decodeTextString(s))
+		}
+		if s, ok := f.resolve(infoDict["Author"]).([]byte); ok {
+			doc.Author = strings.TrimSpace(decodeTextString(s))
+		}
+	}
+
+	pages := f.pages()
+	doc.Pages = len(pages)
+
+	var sb strings.Builder
+	for _, page := range pages {
+		if sb.Len() >= maxText {
+			break
+		}
+		f.pageText(page, &sb)
+		sb.WriteString("\n")
+	}
+	doc.Text = sb.String()
+
+	return doc, nil
+}
+
+func parsePDF(data []byte) *pdfFile {
+	f := &pdfFile{
+		objects: map[int]*pdfObject{},
+		trailer: pdfDict{},
+		cmaps:   map[int]*cmap{},
+	}
+
+	var objStreams []*pdfObject
+	end := 0
+	for _, m := range objHeader.FindAllSubmatchIndex(data, -1) {
+		// 跳过出现在前一个对象（通常是压缩流）内部的匹配
+		if m[0] < end {
+			continue
+		}
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+
+		l := &lexer{data: data, pos: m[1]}
+		value, err := l.parseValue()
+		if err != nil {
+			continue
+		}
+		obj := &pdfObject{value: value}
+
+		l.skipSpace()
+		if bytes.HasPrefix(data[l.pos:], []byte("stream")) {
+			obj.stream = readStream(data, l.pos+len("stream"), value)
+			l.pos += len("stream") + len(obj.stream)
+		}
+		end = l.pos
+
+		// 增量更新时后出现的对象覆盖先前的定义
+		f.objects[num] = obj
+
+		if dict, ok := value.(pdfDict); ok {
+			switch dict["Type"] {
+			case pdfName("ObjStm"):
+				objStreams = append(objStreams, obj)
+			case pdfName("XRef"):
+				f.mergeTrailer(dict)
+			}
+		}
+	}
+
+	for _, idx := range bytesIndexAll(data, []byte("trailer")) {
+		l := &lexer{data: data, pos: idx + len("trailer")}
+		if value, err := l.parseValue(); err == nil {
+			if dict, ok := value.(pdfDict); ok {
+				f.mergeTrailer(dict)
+			}
+		}
+	}
+
+	for _, obj := range objStreams {
+		f.expandObjectStream(obj)
+	}
+
+	return f
+}
+
+func (f *pdfFile) mergeTrailer(dict pdfDict) {
+	for _, key := range []string{"Root", "Info", "Encrypt"} {
+		if v, ok := dict[key]; ok {
+			f.trailer[key] = v
+		}
+	}
+}
+
+// 读取 stream 关键字之后的流数据，优先使用 /Length，不可用时查找 endstream
+func readStream(data []byte, pos int, value interface{}) []byte {
+	if bytes.HasPrefix(data[pos:], []byte("\r\n")) {
+		pos += 2
+	} else if pos < len(data) && (data[pos] == '\n' || data[pos] == '\r') {
+		pos++
+	}
+
+	if dict, ok := value.(pdfDict); ok {
+		// 在转换为 int 之前检查范围，1e300 之类的值会溢出
+		if length, ok := dict["Length"].(float64); ok && length >= 0 && length <= float64(len(data)-pos) {
+			end := pos + int(length)
+			if bytes.Contains(data[end:min(end+32, len(data))], []byte("endstream")) {
+				return data[pos:end]
+			}
+		}
+	}
+
+	end := bytes.Index(data[pos:], []byte("endstream"))
+	if end < 0 {
+		return data[pos:]
+	}
+	return bytes.TrimRight(data[pos:pos+end], "\r\n")
+}
+
+// 展开对象流（PDF 1.5+）中压缩存放的对象
+func (f *pdfFile) expandObjectStream(obj *pdfObject) {
+	dict := obj.value.(pdfDict)
+	data, err := f.decodeStream(obj)
+	if err != nil {
+		return
+	}
+	n, _ := dict["N"].(float64)
+	first, _ := dict["First"].(float64)
+
+	l := &lexer{data: data}
+	type entry struct{ num, offset int }
+	var entries []entry
+	for i := 0; i < int(n); i++ {
+		num, err1 := l.next()
+		offset, err2 := l.next()
+		numF, ok1 := num.(float64)
+		offsetF, ok2 := offset.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return
+		}
+		entries = append(entries, entry{int(numF), int(offsetF)})
+	}
+
+	for _, e := range entries {
+		if _, exists := f.objects[e.num]; exists {
+			continue
+		}
+		pos := int(first) + e.offset
+		if pos < 0 || pos >= len(data) {
+			continue
+		}
+		l := &lexer{data: data, pos: pos}
+		if value, err := l.parseValue(); err == nil {
+			f.objects[e.num] = &pdfObject{value: value}
+		}
+	}
+}
+
+// resolve 解析间接引用，返回实际的值
+func (f *pdfFile) resolve(v interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		obj, ok := f.objects[ref.num]
+		if !ok {
+			return nil
+		}
+		v = obj.value
+	}
+	return nil
+}
+
+// streamData 返回引用所指向的流对象解码后的数据
+func (f *pdfFile) streamData(v interface{}) ([]byte, error) {
+	ref, ok := v.(pdfRef)
+	if !ok {
+		return nil, fmt.Errorf("expected stream reference")
+	}
+	obj, ok := f.objects[ref.num]
+	if !ok || obj.stream == nil {
+		return nil, fmt.Errorf("stream object %d not found", ref.num)
+	}
+	return f.decodeStream(obj)
+}
+
+func (f *pdfFile) decodeStream(obj *pdfObject) ([]byte, error) {
+	dict, _ := obj.value.(pdfDict)
+
+	var filters []interface{}
+	switch filter := f.resolve(dict["Filter"]).(type) {
+	case pdfName:
+		filters = []interface{}{filter}
+	case pdfArray:
+		filters = filter
+	}
+
+	data := obj.stream
+	for _, filter := range filters {
+		switch f.resolve(filter) {
+		case pdfName("FlateDecode"):
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			decoded, err := io.ReadAll(io.LimitReader(zr, maxStreamSize))
+			zr.Close()
+			// 许多 PDF 的压缩流末尾不完整，保留已解压的部分
+			if err != nil && len(decoded) == 0 {
+				return nil, err
+			}
+			data = decoded
+		case pdfName("ASCII85Decode"):
+			src := bytes.TrimSpace(data)
+			src = bytes.TrimSuffix(src, []byte("~>"))
+			// 每个 z 解码为 4 字节，输出大小无法按输入长度估计，按流读取并限制大小
+			decoded, err := io.ReadAll(io.LimitReader(ascii85.NewDecoder(bytes.NewReader(src)), maxStreamSize))
+			if err != nil {
+				return nil, err
+			}
+			data = decoded
+		case pdfName("ASCIIHexDecode"):
+			src := bytes.TrimSuffix(bytes.Join(bytes.Fields(data), nil), []byte(">"))
+			if len(src)%2 == 1 {
+				src = append(src, '0')
+			}
+			decoded := make([]byte, len(src)/2)
+			if _, err := hex.Decode(decoded, src); err != nil {
+				return nil, err
+			}
+			data = decoded
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %v", filter)
+		}
+	}
+	return data, nil
+}
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages 按顺序遍历页树，资源字典可从父节点继承
+func (f *pdfFile) pages() []pdfPage {
+	root, ok := f.resolve(f.trailer["Root"]).(pdfDict)
+	if !ok {
+		return nil
+	}
+
+	var pages []pdfPage
+	visited := map[int]bool{}
+	var walk func(node interface{}, resources pdfDict, depth int)
+	walk = func(node interface{}, resources pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref.num] {
+				return
+			}
+			visited[ref.num] = true
+		}
+		dict, ok := f.resolve(node).(pdfDict)
+		if !ok || depth > 64 {
+			return
+		}
+		if res, ok := f.resolve(dict["Resources"]).(pdfDict); ok {
+			resources = res
+		}
+		if kids, ok := f.resolve(dict["Kids"]).(pdfArray); ok {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+		pages = append(pages, pdfPage{dict: dict, resources: resources})
+	}
+	walk(root["Pages"], nil, 0)
+
+	return pages
+}
+
+// pageText 解释页面内容流中的文本操作符
+func (f *pdfFile) pageText(page pdfPage, sb *strings.Builder) {
+	var content []byte
+	switch contents := page.dict["Contents"].(type) {
+	case pdfRef:
+		if arr, ok := f.resolve(contents).(pdfArray); ok {
+			content = f.concatStreams(arr)
+		} else if data, err := f.streamData(contents); err == nil {
+			content = data
+		}
+	case pdfArray:
+		content = f.concatStreams(contents)
+	}
+	if len(content) == 0 {
+		return
+	}
+
+	fonts, _ := f.resolve(page.resources["Font"]).(pdfDict)
+	var current *cmap
+
+	newline := func() {
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteByte('\n')
+		}
+	}
+	space := func() {
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), " ") && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteByte(' ')
+		}
+	}
+	show := func(v interface{}) {
+		if s, ok := v.([]byte); ok {
+			sb.WriteString(current.decode(s))
+		}
+	}
+
+	l := &lexer{data: content}
+	var operands []interface{}
+	lastY := 0.0
+	for {
+		tok, err := l.parseValue()
+		if err != nil {
+			return
+		}
+		op, ok := tok.(pdfKeyword)
+		if !ok {
+			operands = append(operands, tok)
+			continue
+		}
+
+		switch op {
+		case "Tf":
+			if len(operands) >= 1 {
+				if name, ok := operands[0].(pdfName); ok {
+					current = f.fontCMap(fonts[string(name)])
+				}
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				show(operands[0])
+			}
+		case "'":
+			newline()
+			if len(operands) >= 1 {
+				show(operands[0])
+			}
+		case "\"":
+			newline()
+			if len(operands) >= 3 {
+				show(operands[2])
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				if arr, ok := operands[0].(pdfArray); ok {
+					for _, part := range arr {
+						// 较大的负向位移通常表示单词间距
+						if n, ok := part.(float64); ok && n < -150 {
+							space()
+						}
+						show(part)
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				tx, _ := operands[0].(float64)
+				ty, _ := operands[1].(float64)
+				if ty != 0 {
+					newline()
+				} else if tx > 0 {
+					space()
+				}
+			}
+		case "T*":
+			newline()
+		case "Tm":
+			if len(operands) >= 6 {
+				if y, ok := operands[5].(float64); ok && y != lastY {
+					newline()
+					lastY = y
+				}
+			}
+		case "ET":
+			space()
+		case "ID":
+			// 跳过内联图像的二进制数据
+			idx := bytes.Index(l.data[l.pos:], []byte("EI"))
+			if idx < 0 {
+				return
+			}
+			l.pos += idx + 2
+		}
+		operands = operands[:0]
+	}
+}
+
+func (f *pdfFile) concatStreams(refs pdfArray) []byte {
+	var buf bytes.Buffer
+	for _, ref := range refs {
+		if data, err := f.streamData(ref); err == nil {
+			buf.Write(data)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+// fontCMap 返回字体的 ToUnicode 映射，没有时返回 nil（按单字节 Latin-1 解码）
+func (f *pdfFile) fontCMap(fontRef interface{}) *cmap {
+	ref, isRef := fontRef.(pdfRef)
+	if isRef {
+		if cm, ok := f.cmaps[ref.num]; ok {
+			return cm
+		}
+	}
+
+	var cm *cmap
+	if font, ok := f.resolve(fontRef).(pdfDict); ok {
+		if data, err := f.streamData(font["ToUnicode"]); err == nil {
+			cm = parseCMap(data)
+		}
+	}
+
+	if isRef {
+		f.cmaps[ref.num] = cm
+	}
+	return cm
+}
+
+type cmap struct {
+	codeLen int
+	mapping map[uint32]string
+}
+
+// 单个 bfrange 展开的最大条目数
+const maxCMapRange = 1 << 16
+
+func parseCMap(data []byte) *cmap {
+	cm := &cmap{codeLen: 1, mapping: map[uint32]string{}}
+	l := &lexer{data: data}
+
+	readHexes := func(end pdfKeyword, n int, handle func(vals []interface{})) {
+		for {
+			var vals []interface{}
+			for i := 0; i < n; i++ {
+				v, err := l.parseValue()
+				if err != nil || v == end {
+					return
+				}
+				vals = append(vals, v)
+			}
+			handle(vals)
+		}
+	}
+
+	for {
+		tok, err := l.parseValue()
+		if err != nil {
+			return cm
+		}
+		switch tok {
+		case pdfKeyword("begincodespacerange"):
+			readHexes("endcodespacerange", 2, func(vals []interface{}) {
+				if lo, ok := vals[0].([]byte); ok && len(lo) > 0 {
+					cm.codeLen = len(lo)
+				}
+			})
+		case pdfKeyword("beginbfchar"):
+			readHexes("endbfchar", 2, func(vals []interface{}) {
+				src, ok1 := vals[0].([]byte)
+				dst, ok2 := vals[1].([]byte)
+				if ok1 && ok2 {
+					cm.mapping[codeOf(src)] = decodeUTF16BE(dst)
+				}
+			})
+		case pdfKeyword("beginbfrange"):
+			readHexes("endbfrange", 3, func(vals []interface{}) {
+				lo, ok1 := vals[0].([]byte)
+				hi, ok2 := vals[1].([]byte)
+				if !ok1 || !ok2 {
+					return
+				}
+				start, stop := codeOf(lo), codeOf(hi)
+				if stop < start || stop-start > maxCMapRange {
+					return
+				}
+				switch dst := vals[2].(type) {
+				case []byte:
+					base := []rune(decodeUTF16BE(dst))
+					if len(base) == 0 {
+						return
+					}
+					for code := start; code <= stop; code++ {
+						r := append([]rune{}, base...)
+						r[len(r)-1] += rune(code - start)
+						cm.mapping[code] = string(r)
+					}
+				case pdfArray:
+					for i, d := range dst {
+						if s, ok := d.([]byte); ok && start+uint32(i) <= stop {
+							cm.mapping[start+uint32(i)] = decodeUTF16BE(s)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func (cm *cmap) decode(s []byte) string {
+	if cm == nil {
+		return decodeLatin1(s)
+	}
+	var sb strings.Builder
+	for i := 0; i+cm.codeLen <= len(s); i += cm.codeLen {
+		code := codeOf(s[i : i+cm.codeLen])
+		if text, ok := cm.mapping[code]; ok {
+			sb.WriteString(text)
+		} else if cm.codeLen == 1 {
+			sb.WriteString(decodeLatin1(s[i : i+1]))
+		}
+	}
+	return sb.String()
+}
+
+func codeOf(b []byte) uint32 {
+	var code uint32
+	for _, c := range b {
+		code = code<<8 | uint32(c)
+	}
+	return code
+}
+
+// 文档信息中的字符串可能是带 BOM 的 UTF-16BE、UTF-8 或 PDFDocEncoding
+func decodeTextString(s []byte) string {
+	switch {
+	case bytes.HasPrefix(s, []byte{0xfe, 0xff}):
+		return decodeUTF16BE(s[2:])
+	case bytes.HasPrefix(s, []byte{0xef, 0xbb, 0xbf}):
+		return string(s[3:])
+	}
+	return decodeLatin1(s)
+}
+
+func decodeUTF16BE(s []byte) string {
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		units[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+func decodeLatin1(s []byte) string {
+	runes := make([]rune, len(s))
+	for i, c := range s {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func bytesIndexAll(data []byte, sep []byte) []int {
+	var idx []int
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], sep)
+		if i < 0 {
+			return idx
+		}
+		idx = append(idx, pos+i)
+		pos += i + len(sep)
+	}
+}
+
+// lexer PDF 对象与内容流的词法分析器
+type lexer struct {
+	data  []byte
+	pos   int
+	depth int // parseValue 当前的嵌套层数
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+		} else if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+// next 返回下一个词法单元：float64、pdfName、[]byte（字符串）或 pdfKeyword
+func (l *lexer) next() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfName(decodeNameEscapes(l.data[start:l.pos])), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<"), nil
+		}
+		return l.hexString(), nil
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), nil
+		}
+		l.pos++
+		return pdfKeyword(">"), nil
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		// 无法识别的单个分隔符，跳过
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil {
+		return n, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+// parseValue 解析一个完整的值，字典、数组和间接引用会被组装起来
+func (l *lexer) parseValue() (interface{}, error) {
+	l.depth++
+	defer func() { l.depth-- }()
+	if l.depth > maxNesting {
+		return nil, errTooDeep
+	}
+
+	tok, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case pdfKeyword("<<"):
+		dict := pdfDict{}
+		for {
+			key, err := l.next()
+			if err != nil {
+				return nil, err
+			}
+			if key == pdfKeyword(">>") {
+				return dict, nil
+			}
+			value, err := l.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			if name, ok := key.(pdfName); ok {
+				dict[string(name)] = value
+			}
+		}
+	case pdfKeyword("["):
+		arr := pdfArray{}
+		for {
+			save := l.pos
+			tok, err := l.next()
+			if err != nil {
+				return nil, err
+			}
+			if tok == pdfKeyword("]") {
+				return arr, nil
+			}
+			l.pos = save
+			value, err := l.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+	case pdfKeyword("true"):
+		return true, nil
+	case pdfKeyword("false"):
+		return false, nil
+	case pdfKeyword("null"):
+		return nil, nil
+	}
+
+	// 形如 "12 0 R" 的间接引用
+	if num, ok := tok.(float64); ok {
+		save := l.pos
+		gen, err1 := l.next()
+		r, err2 := l.next()
+		if genF, ok := gen.(float64); ok && err1 == nil && err2 == nil && r == pdfKeyword("R") {
+			return pdfRef{num: int(num), gen: int(genF)}, nil
+		}
+		l.pos = save
+	}
+
+	return tok, nil
+}
+
+func (l *lexer) literalString() []byte {
+	l.pos++ // 跳过 '('
+	var buf []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+			buf = append(buf, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return buf
+			}
+			buf = append(buf, c)
+		case '\\':
+			if l.pos >= len(l.data) {
+				return buf
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b':
+				buf = append(buf, '\b')
+			case 'f':
+				buf = append(buf, '\f')
+			case '\r':
+				// 行尾续行
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					buf = append(buf, byte(v))
+				} else {
+					buf = append(buf, e)
+				}
+			}
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+func (l *lexer) hexString() []byte {
+	l.pos++ // 跳过 '<'
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // 跳过 '>'
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	decoded := make([]byte, len(digits)/2)
+	n, _ := hex.Decode(decoded, digits)
+	return decoded[:n]
+}
+
+func decodeNameEscapes(b []byte) string {
+	if bytes.IndexByte(b, '#') < 0 {
+		return string(b)
+	}
+	var out []byte
+	for i := 0; i < len(b); i++ {
+		if b[i] == '#' && i+2 <= len(b)-1 {
+			if v, err := strconv.ParseUint(string(b[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
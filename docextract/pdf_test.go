@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package docextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 构造只有一页的最小 PDF，content 为页面内容流
+func minimalPDF(content, extra string) string {
+	return "%PDF-1.4\n" +
+		"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+		"2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n" +
+		"3 0 obj << /Type /Page /Parent 2 0 R /Contents 4 0 R >> endobj\n" +
+		"4 0 obj << /Length " + strconv.Itoa(len(content)) + " >>\nstream\n" + content + "\nendstream\nendobj\n" +
+		"5 0 obj << /Title (Hello PDF) /Author (Alice) >> endobj\n" +
+		extra +
+		"trailer << /Root 1 0 R /Info 5 0 R >>\n%%EOF\n"
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractPDF(t *testing.T) {
+	path := writeFile(t, "a.pdf", []byte(minimalPDF("BT /F1 12 Tf (Hello) Tj 0 -14 Td (World) Tj ET", "")))
+
+	doc, err := Extract(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Hello PDF" || doc.Author != "Alice" || doc.Pages != 1 {
+		t.Errorf("got title %q author %q pages %d", doc.Title, doc.Author, doc.Pages)
+	}
+	if doc.Text != "Hello\nWorld" {
+		t.Errorf("got text %q", doc.Text)
+	}
+}
+
+func TestExtractPDFTruncatesText(t *testing.T) {
+	path := writeFile(t, "a.pdf", []byte(minimalPDF("BT (Hello World) Tj ET", "")))
+
+	doc, err := Extract(path, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Text != "Hello" {
+		t.Errorf("got text %q", doc.Text)
+	}
+
+	// 不在 UTF-8 字符中间截断
+	if got := truncateUTF8("你好世界", 4); got != "你" {
+		t.Errorf("truncateUTF8: got %q", got)
+	}
+}
+
+func TestExtractPDFRejectsInvalid(t *testing.T) {
+	if _, err := Extract(writeFile(t, "a.pdf", []byte("not a pdf")), 1024); err == nil {
+		t.Error("expected error for missing header")
+	}
+
+	encrypted := minimalPDF("", "") + "trailer << /Encrypt 6 0 R >>\n"
+	if _, err := Extract(writeFile(t, "b.pdf", []byte(encrypted)), 1024); err == nil {
+		t.Error("expected error for encrypted pdf")
+	}
+}
+
+// 深度嵌套的数组不能耗尽栈空间，对象被跳过，其余内容正常提取
+func TestExtractPDFDeepNesting(t *testing.T) {
+	const n = 1 << 20
+	nested := "6 0 obj " + strings.Repeat("[", n) + strings.Repeat("]", n) + " endobj\n"
+	content := "BT " + strings.Repeat("[", n) + " (Hello) Tj ET"
+	path := writeFile(t, "a.pdf", []byte(minimalPDF(content, nested)))
+
+	doc, err := Extract(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Pages != 1 {
+		t.Errorf("got %d pages", doc.Pages)
+	}
+}
+
+func TestParseValueNesting(t *testing.T) {
+	l := &lexer{data: []byte(strings.Repeat("[", maxNesting) + strings.Repeat("]", maxNesting))}
+	if _, err := l.parseValue(); err != nil {
+		t.Errorf("nesting %d: %v", maxNesting, err)
+	}
+
+	l = &lexer{data: []byte(strings.Repeat("<< /A ", maxNesting+1))}
+	if _, err := l.parseValue(); err != errTooDeep {
+		t.Errorf("nesting %d: got %v, want errTooDeep", maxNesting+1, err)
+	}
+}
+
+func TestReadStreamLength(t *testing.T) {
+	data := []byte("stream\nabcdef\nendstream")
+	pos := len("stream")
+
+	tests := []struct {
+		length interface{}
+		want   string
+	}{
+		{6.0, "abcdef"},
+		{1e300, "abcdef"},
+		{-1e300, "abcdef"},
+		{100.0, "abcdef"},
+		{nil, "abcdef"},
+	}
+	for _, tt := range tests {
+		got := readStream(data, pos, pdfDict{"Length": tt.length})
+		if string(got) != tt.want {
+			t.Errorf("Length %v: got %q, want %q", tt.length, got, tt.want)
+		}
+	}
+}
+
+// z 表示 4 个零字节，连续的 z 解码后比输入长得多
+func TestDecodeASCII85ZeroRuns(t *testing.T) {
+	f := &pdfFile{}
+	tests := []struct {
+		src  string
+		want []byte
+	}{
+		{"zzzzzzzzzz~>", make([]byte, 40)},
+		{"87cURz~>", append([]byte("Hell"), 0, 0, 0, 0)},
+		{" zz\nz z ~>", make([]byte, 16)},
+	}
+	for _, tt := range tests {
+		obj := &pdfObject{value: pdfDict{"Filter": pdfName("ASCII85Decode")}, stream: []byte(tt.src)}
+		got, err := f.decodeStream(obj)
+		if err != nil {
+			t.Errorf("%q: %v", tt.src, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%q: got %d bytes %q, want %d bytes", tt.src, len(got), got, len(tt.want))
+		}
+	}
+}
+
+func TestDecodeNameEscapes(t *testing.T) {
+	tests := map[string]string{
+		"Name":      "Name",
+		"A#20B":     "A B",
+		"End#41":    "EndA",
+		"#41":       "A",
+		"Short#4":   "Short#4",
+		"Bad#zz":    "Bad#zz",
+		"Trailing#": "Trailing#",
+	}
+	for in, want := range tests {
+		if got := decodeNameEscapes([]byte(in)); got != want {
+			t.Errorf("%q: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExtractDOCX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.docx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	parts := map[string]string{
+		"docProps/core.xml": `<cp:coreProperties xmlns:cp="c" xmlns:dc="d"><dc:title>Report</dc:title><dc:creator>Bob</dc:creator></cp:coreProperties>`,
+		"docProps/app.xml":  `<Properties><Pages>3</Pages></Properties>`,
+		"word/document.xml": `<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>First</w:t></w:r></w:p><w:p><w:r><w:t>Second</w:t></w:r></w:p></w:body></w:document>`,
+	}
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	doc, err := Extract(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Report" || doc.Author != "Bob" || doc.Pages != 3 {
+		t.Errorf("got title %q author %q pages %d", doc.Title, doc.Author, doc.Pages)
+	}
+	if !strings.Contains(doc.Text, "First") || !strings.Contains(doc.Text, "Second") {
+		t.Errorf("got text %q", doc.Text)
+	}
+}
+
+func TestExtractUnsupported(t *testing.T) {
+	if _, err := Extract(writeFile(t, "a.txt", []byte("x")), 1024); err == nil {
+		t.Error("expected error for unsupported extension")
+	}
+	if _, err := Extract(writeFile(t, "a.docx", []byte("not a zip")), 1024); err == nil {
+		t.Error("expected error for invalid zip")
+	}
+}
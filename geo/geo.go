@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+
+// Package geo 提供经纬度与单位球面向量、Web 墨卡托坐标之间的换算。
+// SQLite 没有三角函数，按距离筛选和按地图网格聚合都依赖导入时预先算好的坐标
+package geo
+
+import "math"
+
+// EarthRadius 地球平均半径（米）
+const EarthRadius = 6371008.8
+
+// Web 墨卡托投影能表示的最大纬度
+const MaxMercatorLatitude = 85.05112878
+
+// UnitVector 经纬度对应的单位球面向量
+func UnitVector(lat, lon float64) (x, y, z float64) {
+	phi := lat * math.Pi / 180
+	lambda := lon * math.Pi / 180
+	return math.Cos(phi) * math.Cos(lambda), math.Cos(phi) * math.Sin(lambda), math.Sin(phi)
+}
+
+// Mercator 经纬度对应的 Web 墨卡托坐标，取值范围 [0, 1]，y 向南增大
+func Mercator(lat, lon float64) (x, y float64) {
+	lat = math.Max(-MaxMercatorLatitude, math.Min(MaxMercatorLatitude, lat))
+	phi := lat * math.Pi / 180
+	x = (lon + 180) / 360
+	y = (1 - math.Log(math.Tan(phi)+1/math.Cos(phi))/math.Pi) / 2
+	return x, y
+}
+
+// MinDot 距离不超过 meters 的两点，其单位向量点积的下限
+func MinDot(meters float64) float64 {
+	angle := meters / EarthRadius
+	if angle >= math.Pi {
+		return -1
+	}
+	return math.Cos(angle)
+}
+
+// RadiusBounds 以某点为中心、半径为 meters 的圆的纬度范围，用于在精确计算前缩小查询范围
+func RadiusBounds(lat, meters float64) (south, north float64) {
+	delta := meters / EarthRadius * 180 / math.Pi
+	return math.Max(-90, lat-delta), math.Min(90, lat+delta)
+}
@@ -15,8 +15,14 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"synapforest/api"
 	"synapforest/database"
 	"synapforest/database/dbcommon"
+	"synapforest/database/folderdb"
+	"synapforest/database/itemdb"
+	"synapforest/database/tagdb"
+	"synapforest/imagemeta"
+	"synapforest/palette"
 
 	"github.com/gofrs/uuid"
 	"github.com/graphql-go/graphql"
@@ -41,10 +47,165 @@ var itemType = graphql.NewObject(graphql.ObjectConfig{
 		"star":           &graphql.Field{Type: graphql.Int},
 		"have_thumbnail": &graphql.Field{Type: graphql.Boolean},
 		"have_preview":   &graphql.Field{Type: graphql.Boolean},
+		"placeholder":    &graphql.Field{Type: graphql.String},
+		"import_error":   &graphql.Field{Type: graphql.String},
 		// 注意：tags 和 folders 关系字段后面添加
 	},
 })
 
+var paletteType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Palette",
+	Fields: graphql.Fields{
+		"color": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				entry, ok := p.Source.(dbcommon.ItemPalette)
+				if !ok {
+					return nil, fmt.Errorf("expected ItemPalette type, got %T", p.Source)
+				}
+				return palette.Hex(entry.Color), nil
+			},
+		},
+		"weight": &graphql.Field{Type: graphql.Float},
+	},
+})
+
+var renditionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Rendition",
+	Fields: graphql.Fields{
+		"name":       &graphql.Field{Type: graphql.String},
+		"format":     &graphql.Field{Type: graphql.String},
+		"max_pixels": &graphql.Field{Type: graphql.Int},
+		"url":        &graphql.Field{Type: graphql.String},
+	},
+})
+
+var technicalType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Technical",
+	Fields: graphql.Fields{
+		"make":               &graphql.Field{Type: graphql.String},
+		"model":              &graphql.Field{Type: graphql.String},
+		"lens_make":          &graphql.Field{Type: graphql.String},
+		"lens_model":         &graphql.Field{Type: graphql.String},
+		"exposure_time":      &graphql.Field{Type: graphql.String},
+		"f_number":           &graphql.Field{Type: graphql.Float},
+		"focal_length":       &graphql.Field{Type: graphql.Float},
+		"iso":                &graphql.Field{Type: graphql.Int},
+		"color_space":        &graphql.Field{Type: graphql.String},
+		"color_profile":      &graphql.Field{Type: graphql.String},
+		"dpi_x":              &graphql.Field{Type: graphql.Float},
+		"dpi_y":              &graphql.Field{Type: graphql.Float},
+		"software":           &graphql.Field{Type: graphql.String},
+		"date_time_original": &graphql.Field{Type: graphql.String},
+		"orientation":        &graphql.Field{Type: graphql.Int},
+	},
+})
+
+var locationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Location",
+	Fields: graphql.Fields{
+		"latitude":  &graphql.Field{Type: graphql.Float},
+		"longitude": &graphql.Field{Type: graphql.Float},
+		"altitude":  &graphql.Field{Type: graphql.Float},
+	},
+})
+
+var editOperationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "EditOperation",
+	Fields: graphql.Fields{
+		"type":      &graphql.Field{Type: graphql.String},
+		"x":         &graphql.Field{Type: graphql.Int},
+		"y":         &graphql.Field{Type: graphql.Int},
+		"width":     &graphql.Field{Type: graphql.Int},
+		"height":    &graphql.Field{Type: graphql.Int},
+		"angle":     &graphql.Field{Type: graphql.Int},
+		"direction": &graphql.Field{Type: graphql.String},
+	},
+})
+
+var derivationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Derivation",
+	Fields: graphql.Fields{
+		"source_id":  &graphql.Field{Type: graphql.String},
+		"operations": &graphql.Field{Type: graphql.NewList(editOperationType)},
+		"created_at": &graphql.Field{Type: graphql.DateTime},
+	},
+})
+
+var versionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Version",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.Int},
+		"file_id":     &graphql.Field{Type: graphql.String},
+		"name":        &graphql.Field{Type: graphql.String},
+		"ext":         &graphql.Field{Type: graphql.String},
+		"size":        &graphql.Field{Type: graphql.Int},
+		"width":       &graphql.Field{Type: graphql.Int},
+		"height":      &graphql.Field{Type: graphql.Int},
+		"replaced_at": &graphql.Field{Type: graphql.DateTime},
+		"url": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				v, ok := p.Source.(dbcommon.ItemVersion)
+				if !ok {
+					return nil, fmt.Errorf("expected ItemVersion type, got %T", p.Source)
+				}
+				return api.VersionURL(p.Context, v.ID), nil
+			},
+		},
+	},
+})
+
+var geoClusterType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "GeoCluster",
+	Fields: graphql.Fields{
+		"latitude":  &graphql.Field{Type: graphql.Float},
+		"longitude": &graphql.Field{Type: graphql.Float},
+		"count":     &graphql.Field{Type: graphql.Int},
+		"item_id":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+var bboxInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "BBoxInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"south": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		"west":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		"north": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		"east":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+var nearInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "NearInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"latitude":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		"longitude": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		"radius":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float), Description: "Radius in meters"},
+	},
+})
+
+// 从 bbox 输入对象得到经纬度范围
+func parseBBox(arg interface{}) *itemdb.BBox {
+	m, ok := arg.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	south, _ := m["south"].(float64)
+	west, _ := m["west"].(float64)
+	north, _ := m["north"].(float64)
+	east, _ := m["east"].(float64)
+	return &itemdb.BBox{South: south, West: west, North: north, East: east}
+}
+
+var metadataType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Metadata",
+	Fields: graphql.Fields{
+		"key":   &graphql.Field{Type: graphql.String},
+		"value": &graphql.Field{Type: graphql.String},
+	},
+})
+
 var tagType = graphql.NewObject(graphql.ObjectConfig{
 	Name: "Tag",
 	Fields: graphql.Fields{
@@ -106,10 +267,210 @@ func init() {
 		},
 	})
 
+	itemType.AddFieldConfig("location", &graphql.Field{Type: locationType})
+
+	itemType.AddFieldConfig("derived_from", &graphql.Field{
+		Type:        derivationType,
+		Description: "由编辑生成时的来源与操作",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
+			item, ok := p.Source.(dbcommon.Item)
+			if !ok {
+				return nil, fmt.Errorf("expected Item type, got %T", p.Source)
+			}
+
+			derivation, ops, err := itemdb.GetDerivation(lib.DB, item.ID)
+			if err != nil || derivation == nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"source_id":  derivation.SourceID,
+				"operations": ops,
+				"created_at": derivation.CreatedAt,
+			}, nil
+		},
+	})
+
+	itemType.AddFieldConfig("versions", &graphql.Field{
+		Type:        graphql.NewList(versionType),
+		Description: "被替换前的历史版本，最近替换的在前",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
+			item, ok := p.Source.(dbcommon.Item)
+			if !ok {
+				return nil, fmt.Errorf("expected Item type, got %T", p.Source)
+			}
+			return itemdb.ListVersions(lib.DB, item.ID)
+		},
+	})
+
+	itemType.AddFieldConfig("derivatives", &graphql.Field{
+		Type:        graphql.NewList(graphql.String),
+		Description: "由该 item 编辑生成的 item ID",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
+			item, ok := p.Source.(dbcommon.Item)
+			if !ok {
+				return nil, fmt.Errorf("expected Item type, got %T", p.Source)
+			}
+			return itemdb.GetDerivatives(lib.DB, item.ID)
+		},
+	})
+
+	itemType.AddFieldConfig("palettes", &graphql.Field{
+		Type: graphql.NewList(paletteType),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			item, ok := p.Source.(dbcommon.Item)
+			if !ok {
+				return nil, fmt.Errorf("expected Item type, got %T", p.Source)
+			}
+
+			return item.Palettes, nil
+		},
+	})
+
+	itemType.AddFieldConfig("folder_paths", &graphql.Field{
+		Type:        graphql.NewList(graphql.NewList(folderType)),
+		Description: "每个所属文件夹从最顶层开始的完整路径",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
+			item, ok := p.Source.(dbcommon.Item)
+			if !ok {
+				return nil, fmt.Errorf("expected Item type, got %T", p.Source)
+			}
+
+			var paths [][]dbcommon.Folder
+			for _, folder := range item.Folders {
+				path, err := folderdb.GetFolderPath(lib.DB, folder.ID)
+				if err != nil {
+					return nil, err
+				}
+				paths = append(paths, path)
+			}
+			return paths, nil
+		},
+	})
+
+	itemType.AddFieldConfig("tag_paths", &graphql.Field{
+		Type:        graphql.NewList(graphql.NewList(tagType)),
+		Description: "每个标签从最顶层开始的完整路径",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
+			item, ok := p.Source.(dbcommon.Item)
+			if !ok {
+				return nil, fmt.Errorf("expected Item type, got %T", p.Source)
+			}
+
+			var paths [][]dbcommon.Tag
+			for _, tag := range item.Tags {
+				path, err := tagdb.GetTagPath(lib.DB, tag.ID)
+				if err != nil {
+					return nil, err
+				}
+				paths = append(paths, path)
+			}
+			return paths, nil
+		},
+	})
+
+	itemType.AddFieldConfig("renditions", &graphql.Field{
+		Type: graphql.NewList(renditionType),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
+			item, ok := p.Source.(dbcommon.Item)
+			if !ok {
+				return nil, fmt.Errorf("expected Item type, got %T", p.Source)
+			}
+
+			renditions, err := itemdb.AvailableRenditions(lib.DB, item.ID)
+			if err != nil {
+				return nil, err
+			}
+			var result []map[string]interface{}
+			for _, r := range renditions {
+				result = append(result, map[string]interface{}{
+					"name":       r.Name,
+					"format":     r.Format,
+					"max_pixels": r.MaxPixels,
+					"url":        api.RenditionURL(p.Context, r.Name, item.ID),
+				})
+			}
+			return result, nil
+		},
+	})
+
+	itemType.AddFieldConfig("vectorized", &graphql.Field{
+		Type: graphql.Boolean,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
+			item, ok := p.Source.(dbcommon.Item)
+			if !ok {
+				return nil, fmt.Errorf("expected Item type, got %T", p.Source)
+			}
+
+			var count int64
+			err := lib.VectorDB.Model(&dbcommon.ItemVector{}).Where("item_id = ?", item.ID).Count(&count).Error
+			return count > 0, err
+		},
+	})
+
+	itemType.AddFieldConfig("technical", &graphql.Field{
+		Type: technicalType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
+			item, ok := p.Source.(dbcommon.Item)
+			if !ok {
+				return nil, fmt.Errorf("expected Item type, got %T", p.Source)
+			}
+
+			metadata, err := itemdb.GetItemMetadata(lib.DB, item.ID)
+			if err != nil {
+				return nil, err
+			}
+			info := imagemeta.FromMetadata(metadata)
+			if info == nil {
+				return nil, nil
+			}
+			return map[string]interface{}{
+				"make":               info.Make,
+				"model":              info.Model,
+				"lens_make":          info.LensMake,
+				"lens_model":         info.LensModel,
+				"exposure_time":      info.ExposureTime,
+				"f_number":           info.FNumber,
+				"focal_length":       info.FocalLength,
+				"iso":                info.ISO,
+				"color_space":        info.ColorSpace,
+				"color_profile":      info.ColorProfile,
+				"dpi_x":              info.DPIX,
+				"dpi_y":              info.DPIY,
+				"software":           info.Software,
+				"date_time_original": info.DateTimeOriginal,
+				"orientation":        info.Orientation,
+			}, nil
+		},
+	})
+
+	itemType.AddFieldConfig("metadata", &graphql.Field{
+		Type: graphql.NewList(metadataType),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
+			item, ok := p.Source.(dbcommon.Item)
+			if !ok {
+				return nil, fmt.Errorf("expected Item type, got %T", p.Source)
+			}
+
+			var records []dbcommon.ItemMetadata
+			err := lib.DB.Where("item_id = ?", item.ID).Order("key").Find(&records).Error
+			return records, err
+		},
+	})
+
 	// 添加 Folder 的关系字段
 	folderType.AddFieldConfig("parent", &graphql.Field{
 		Type: folderType,
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
 			// 从父对象获取文件夹ID
 			folder, ok := p.Source.(dbcommon.Folder)
 			if !ok {
@@ -119,7 +480,7 @@ func init() {
 			// 如果 Parent 是 nil，尝试从数据库加载
 			if folder.Parent == nil {
 				var parent dbcommon.Folder
-				err := database.DB.
+				err := lib.DB.
 					Where("id = ?", folder.ParentID).
 					Find(&parent).Error
 				if err != nil {
@@ -138,6 +499,7 @@ func init() {
 	folderType.AddFieldConfig("children", &graphql.Field{
 		Type: graphql.NewList(folderType),
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
 			// 从父对象获取文件夹ID
 			folder, ok := p.Source.(dbcommon.Folder)
 			if !ok {
@@ -147,13 +509,13 @@ func init() {
 			var err error
 			if folder.ID == uuid.Nil {
 				// 查询根目录的子目录时，确保parent_id是nil且不是根目录本身
-				err = database.DB.
+				err = lib.DB.
 					Where("parent_id = ? AND id != ?", uuid.Nil, uuid.Nil).
 					Preload("Parent").
 					Find(&childFolders).Error
 			} else {
 				// 普通目录的查询
-				err = database.DB.
+				err = lib.DB.
 					Where("parent_id = ?", folder.ID).
 					Preload("Parent").
 					Find(&childFolders).Error
@@ -170,16 +532,17 @@ func init() {
 	folderType.AddFieldConfig("items", &graphql.Field{
 		Type: graphql.NewList(itemType),
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			lib := database.FromContext(p.Context)
 			// 从父对象获取文件夹ID
 			folder, ok := p.Source.(dbcommon.Folder)
 			if !ok {
 				return nil, fmt.Errorf("expected Folder type, got %T", p.Source)
 			}
 			var items []dbcommon.Item
-			err := database.DB.
+			err := lib.DB.
 				Joins("JOIN item_folders ON item_folders.item_id = items.id").
-				Where("item_folders.folder_id = ?", folder.ID). // 使用folder.ID
-				Preload("Folders").Preload("Tags").             // 如果需要预加载关联
+				Where("item_folders.folder_id = ?", folder.ID).                              // 使用folder.ID
+				Preload("Folders").Preload("Tags").Preload("Palettes", itemdb.PaletteOrder). // 如果需要预加载关联
 				Find(&items).Error
 
 			if err != nil {
@@ -236,6 +599,7 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 				},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				lib := database.FromContext(p.Context)
 				ids, _ := p.Args["ids"].([]interface{})
 				itemIds, _ := p.Args["itemIds"].([]interface{})
 				includeItems, _ := p.Args["includeItems"].(bool)
@@ -257,13 +621,13 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 				// 根据参数决定查询方式
 				if len(ids) > 0 {
 					var folderList []dbcommon.Folder
-					if err = database.DB.Where("id IN (?)", ids).Find(&folderList).Error; err != nil {
+					if err = lib.DB.Where("id IN (?)", ids).Find(&folderList).Error; err != nil {
 						return nil, err
 					}
 					folders = append(folders, folderList...)
 				} else if len(itemIDStrings) > 0 {
 					// 通过itemIds查询关联的文件夹
-					if err = database.DB.
+					if err = lib.DB.
 						Select("folders.*").
 						Joins("JOIN item_folders ON item_folders.folder_id = folders.id").
 						Where("item_folders.item_id IN (?)", itemIDStrings).
@@ -282,7 +646,7 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 
 					if includeItems {
 						// 根据 itemFields 选择性加载字段
-						query := database.DB.Model(&dbcommon.Item{})
+						query := lib.DB.Model(&dbcommon.Item{})
 						if len(itemFields) > 0 {
 							var selectedFields []string
 							for _, field := range itemFields {
@@ -300,7 +664,7 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 
 					if includeFolders {
 						// 根据 folderFields 选择性加载字段
-						query := database.DB.Model(&dbcommon.Folder{})
+						query := lib.DB.Model(&dbcommon.Folder{})
 						if len(folderFields) > 0 {
 							var selectedFields []string
 							for _, field := range folderFields {
@@ -345,8 +709,25 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 					DefaultValue: "AND",
 					Description:  "Logic to combine folder and tag filters (AND/OR)",
 				},
+				"color": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Hex color, matches items with any palette entry close to it",
+				},
+				"colorDistance": &graphql.ArgumentConfig{
+					Type:        graphql.Float,
+					Description: "Maximum perceptual distance (CIE76 ΔE) for the color filter",
+				},
+				"bbox": &graphql.ArgumentConfig{
+					Type:        bboxInput,
+					Description: "Matches items taken inside the box, west > east crosses the antimeridian",
+				},
+				"near": &graphql.ArgumentConfig{
+					Type:        nearInput,
+					Description: "Matches items taken within radius meters of the point",
+				},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				lib := database.FromContext(p.Context)
 				folderIds, _ := p.Args["folderIds"].([]interface{})
 				tagIds, _ := p.Args["tagIds"].([]interface{})
 				folderLogic, _ := p.Args["folderLogic"].(string)
@@ -370,12 +751,12 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 					}
 				}
 
-				query := database.DB.Model(&dbcommon.Item{}).Distinct("items.*")
+				query := lib.DB.Model(&dbcommon.Item{}).Distinct("items.*")
 
 				var folderQuery, tagQuery *gorm.DB
 				// 构建文件夹条件
 				if len(folderIDStrs) > 0 {
-					folderQuery = database.DB.
+					folderQuery = lib.DB.
 						Select("item_id").
 						Table("item_folders").
 						Where("folder_id IN (?)", folderIDStrs)
@@ -404,7 +785,7 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 
 				// 构建标签条件
 				if len(tagIDStrs) > 0 {
-					tagQuery = database.DB.
+					tagQuery = lib.DB.
 						Select("item_id").
 						Table("item_tags").
 						Where("tag_id IN (?)", tagIDStrs)
@@ -435,7 +816,7 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 				if len(folderIDStrs) > 0 && len(tagIDStrs) > 0 {
 					if combinedLogic == "OR" {
 						// OR 逻辑：使用 UNION 组合 folderQuery 和 tagQuery
-						combinedQuery := database.DB.
+						combinedQuery := lib.DB.
 							Table("(?) UNION (?) AS combined_items",
 								folderQuery.Select("item_id"),
 								tagQuery.Select("item_id")).
@@ -455,8 +836,38 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 					query = query.Joins("JOIN (?) AS tag_items ON tag_items.item_id = items.id", tagQuery)
 				}
 
+				// 主色筛选
+				if color, _ := p.Args["color"].(string); color != "" {
+					var distance *float64
+					if d, ok := p.Args["colorDistance"].(float64); ok {
+						distance = &d
+					}
+					colorQuery, err := itemdb.PaletteQuery(lib.DB, color, distance)
+					if err != nil {
+						return nil, err
+					}
+					query = query.Where("items.id IN (?)", colorQuery)
+				}
+
+				// 拍摄位置筛选
+				var area itemdb.GeoFilter
+				area.BBox = parseBBox(p.Args["bbox"])
+				if near, ok := p.Args["near"].(map[string]interface{}); ok {
+					lat, _ := near["latitude"].(float64)
+					lon, _ := near["longitude"].(float64)
+					radius, _ := near["radius"].(float64)
+					area.Radius = &itemdb.Radius{Latitude: lat, Longitude: lon, Meters: radius}
+				}
+				if area.BBox != nil || area.Radius != nil {
+					geoQuery, err := itemdb.GeoQuery(lib.DB, area)
+					if err != nil {
+						return nil, err
+					}
+					query = query.Where("items.id IN (?)", geoQuery)
+				}
+
 				// 预加载关联数据
-				query = query.Preload("Folders").Preload("Tags")
+				query = query.Preload("Folders").Preload("Tags").Preload("Palettes", itemdb.PaletteOrder).Preload("Location")
 
 				var items []dbcommon.Item
 				if err := query.Find(&items).Error; err != nil {
@@ -466,5 +877,44 @@ var RootQuery = graphql.NewObject(graphql.ObjectConfig{
 				return items, nil
 			},
 		},
+		"item": &graphql.Field{
+			Type: itemType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				lib := database.FromContext(p.Context)
+				id, _ := p.Args["id"].(string)
+
+				var item dbcommon.Item
+				err := lib.DB.Unscoped().Preload("Folders").Preload("Tags").Preload("Palettes", itemdb.PaletteOrder).Preload("Location").First(&item, "id = ?", id).Error
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, nil
+				} else if err != nil {
+					return nil, fmt.Errorf("failed to query item: %w", err)
+				}
+
+				return item, nil
+			},
+		},
+		"geo_clusters": &graphql.Field{
+			Type:        graphql.NewList(geoClusterType),
+			Description: "Photo locations inside the viewport, clustered for the zoom level",
+			Args: graphql.FieldConfigArgument{
+				"bbox": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(bboxInput),
+				},
+				"zoom": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				lib := database.FromContext(p.Context)
+				zoom, _ := p.Args["zoom"].(int)
+				return itemdb.GeoClusters(lib.DB, *parseBBox(p.Args["bbox"]), zoom)
+			},
+		},
 	},
 })
@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"synapforest/database"
+	"synapforest/database/itemdb"
+	"synapforest/imagemeta"
+
+	"github.com/graphql-go/graphql"
+)
+
+// 打开临时资料库并导入 item，locations 中没有的名称不设置位置
+func openLibrary(t *testing.T, names []string, locations map[string]imagemeta.GPS) (*database.Library, map[string]string) {
+	t.Helper()
+	lib, err := database.Open(filepath.Join(t.TempDir(), "lib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lib.Close() })
+
+	dir := t.TempDir()
+	ids := map[string]string{}
+	for i, name := range names {
+		img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+		img.Set(0, 0, color.NRGBA{uint8(i), 0, 0, 255})
+		path := filepath.Join(dir, name+".png")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = png.Encode(f, img)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := itemdb.CalculateFileID(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := itemdb.AddItem(lib.DB, path, nil, nil, nil, nil, nil, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if gps, ok := locations[name]; ok {
+			if err := itemdb.SetItemLocation(lib.DB, id, &gps); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ids[id] = name
+	}
+	return lib, ids
+}
+
+func query(t *testing.T, lib *database.Library, request string, out interface{}) []string {
+	t.Helper()
+	result := graphql.Do(graphql.Params{
+		Schema:        Schema,
+		RequestString: request,
+		Context:       database.WithLibrary(context.Background(), lib),
+	})
+	var errs []string
+	for _, err := range result.Errors {
+		errs = append(errs, err.Message)
+	}
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+	return errs
+}
+
+func TestItemsBBox(t *testing.T) {
+	lib, names := openLibrary(t, []string{"tokyo", "fiji", "samoa", "nowhere"}, map[string]imagemeta.GPS{
+		"tokyo": {Latitude: 35.6895, Longitude: 139.6917},
+		"fiji":  {Latitude: -17.7134, Longitude: 178.0650},
+		"samoa": {Latitude: -13.7590, Longitude: -172.1046},
+	})
+
+	var result struct {
+		Items []struct {
+			ID       string `json:"id"`
+			Location *struct {
+				Latitude  float64 `json:"latitude"`
+				Longitude float64 `json:"longitude"`
+			} `json:"location"`
+		} `json:"items"`
+	}
+	list := func(args string) []string {
+		t.Helper()
+		if errs := query(t, lib, "{ items"+args+" { id location { latitude longitude } } }", &result); len(errs) > 0 {
+			t.Fatalf("%s: %v", args, errs)
+		}
+		var got []string
+		for _, item := range result.Items {
+			got = append(got, names[item.ID])
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", "fiji nowhere samoa tokyo"},
+		{"(bbox: {south: 20, west: 100, north: 50, east: 150})", "tokyo"},
+		// 西边界大于东边界时跨越 180° 经线
+		{"(bbox: {south: -30, west: 170, north: 0, east: -170})", "fiji samoa"},
+		{"(bbox: {south: -30, west: -170, north: 0, east: 170})", ""},
+		{"(near: {latitude: -15, longitude: 180, radius: 1000000})", "fiji samoa"},
+		{"(bbox: {south: -30, west: 170, north: 0, east: -170}, near: {latitude: -17, longitude: 178, radius: 200000})", "fiji"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(list(tt.args), " "); got != tt.want {
+			t.Errorf("items%s = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	// 没有 GPS 的 item 的 location 为 null
+	list("")
+	for _, item := range result.Items {
+		if (names[item.ID] == "nowhere") != (item.Location == nil) {
+			t.Errorf("location of %s = %+v", names[item.ID], item.Location)
+		}
+	}
+
+	if errs := query(t, lib, "{ items(bbox: {south: 10, west: 0, north: -10, east: 10}) { id } }", &result); len(errs) == 0 {
+		t.Error("invalid bbox accepted")
+	}
+}
+
+func TestGeoClustersBBox(t *testing.T) {
+	lib, names := openLibrary(t, []string{"fiji", "samoa", "nowhere"}, map[string]imagemeta.GPS{
+		"fiji":  {Latitude: -17.7134, Longitude: 178.0650},
+		"samoa": {Latitude: -13.7590, Longitude: -172.1046},
+	})
+
+	var result struct {
+		Clusters []struct {
+			Count  int    `json:"count"`
+			ItemID string `json:"item_id"`
+		} `json:"geo_clusters"`
+	}
+	if errs := query(t, lib, "{ geo_clusters(bbox: {south: -30, west: 170, north: 0, east: -170}, zoom: 4) { count item_id } }", &result); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	var got []string
+	for _, c := range result.Clusters {
+		got = append(got, names[c.ItemID])
+	}
+	sort.Strings(got)
+	if strings.Join(got, " ") != "fiji samoa" {
+		t.Errorf("clusters = %+v", result.Clusters)
+	}
+
+	if errs := query(t, lib, "{ geo_clusters(bbox: {south: -30, west: 170, north: 0, east: -170}, zoom: 99) { count } }", &result); len(errs) == 0 {
+		t.Error("invalid zoom accepted")
+	}
+}
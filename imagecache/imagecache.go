@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package imagecache
+
+import (
+	"container/list"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache 按总大小限制的磁盘缓存，超出上限时淘汰最久未访问的文件
+//
+// 缓存文件按 item 分目录存放：<dir>/<itemID>/<name>，便于整体失效
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List // 队首为最近访问
+	entries map[string]*list.Element
+}
+
+type entry struct {
+	key  string
+	size int64
+}
+
+// New 创建缓存，并从磁盘恢复已有的缓存文件（以修改时间近似访问顺序）
+func New(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	c := &Cache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+	}
+
+	type found struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []found
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, found{filepath.ToSlash(rel), info.Size(), info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan cache directory: %v", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	for _, f := range files {
+		c.entries[f.key] = c.lru.PushBack(&entry{key: f.key, size: f.size})
+		c.size += f.size
+	}
+
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+
+	return c, nil
+}
+
+// Key 生成缓存键
+func Key(itemID string, name string) string {
+	return itemID + "/" + name
+}
+
+// Get 查询缓存，命中时返回已打开的文件并更新访问顺序，由调用方关闭。
+// 文件在持有锁时打开，之后被淘汰也不影响读取
+func (c *Cache) Get(key string) (*os.File, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	path := c.path(key)
+	f, err := os.Open(path)
+	if err != nil {
+		// 文件被外部删除
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return f, true
+}
+
+// Put 写入缓存（先写临时文件再重命名），返回文件路径
+func (c *Cache) Put(key string, data []byte) (string, error) {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cache file: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write cache file: %v", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to rename cache file: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.size -= elem.Value.(*entry).size
+		elem.Value.(*entry).size = int64(len(data))
+		c.lru.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.lru.PushFront(&entry{key: key, size: int64(len(data))})
+	}
+	c.size += int64(len(data))
+	c.evict()
+
+	return path, nil
+}
+
+// Invalidate 删除指定 item 的全部派生文件
+func (c *Cache) Invalidate(itemIDs ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, itemID := range itemIDs {
+		prefix := itemID + "/"
+		for key, elem := range c.entries {
+			if strings.HasPrefix(key, prefix) {
+				c.lru.Remove(elem)
+				delete(c.entries, key)
+				c.size -= elem.Value.(*entry).size
+			}
+		}
+		if err := os.RemoveAll(filepath.Join(c.dir, itemID)); err != nil {
+			return fmt.Errorf("failed to remove cache of item %s: %v", itemID, err)
+		}
+	}
+	return nil
+}
+
+// Size 返回当前缓存的总字节数
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(key))
+}
+
+// 调用方需持有锁
+func (c *Cache) evict() {
+	for c.size > c.maxBytes && c.lru.Len() > 0 {
+		c.remove(c.lru.Back())
+	}
+}
+
+// 调用方需持有锁
+func (c *Cache) remove(elem *list.Element) {
+	e := elem.Value.(*entry)
+	c.lru.Remove(elem)
+	delete(c.entries, e.key)
+	c.size -= e.size
+	os.Remove(c.path(e.key))
+}
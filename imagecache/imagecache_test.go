@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package imagecache
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPutGet(t *testing.T) {
+	c, err := New(t.TempDir(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(Key("a", "x.webp")); ok {
+		t.Fatal("unexpected hit")
+	}
+	if _, err := c.Put(Key("a", "x.webp"), []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	f, ok := c.Get(Key("a", "x.webp"))
+	if !ok {
+		t.Fatal("miss after put")
+	}
+	defer f.Close()
+	if data, _ := io.ReadAll(f); string(data) != "hello" {
+		t.Errorf("data = %q", data)
+	}
+	if c.Size() != 5 {
+		t.Errorf("size = %d", c.Size())
+	}
+}
+
+// 已经取出的文件在被淘汰后仍然可以完整读取
+func TestGetSurvivesEviction(t *testing.T) {
+	c, err := New(t.TempDir(), 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := bytes.Repeat([]byte("a"), 10)
+	if _, err := c.Put(Key("a", "1"), first); err != nil {
+		t.Fatal(err)
+	}
+	f, ok := c.Get(Key("a", "1"))
+	if !ok {
+		t.Fatal("miss")
+	}
+	defer f.Close()
+
+	if _, err := c.Put(Key("b", "1"), bytes.Repeat([]byte("b"), 10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(Key("a", "1")); ok {
+		t.Fatal("entry not evicted")
+	}
+	if data, err := io.ReadAll(f); err != nil || !bytes.Equal(data, first) {
+		t.Errorf("read after eviction = %q, %v", data, err)
+	}
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	c, err := New(t.TempDir(), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if _, err := c.Put(Key(id, "1"), make([]byte, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, ok := c.Get(Key("a", "1"))
+	if !ok {
+		t.Fatal("miss")
+	}
+	f.Close()
+	if _, err := c.Put(Key("c", "1"), make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		f, ok := c.Get(Key(id, "1"))
+		if ok {
+			f.Close()
+		}
+		if ok != want {
+			t.Errorf("%s cached = %t", id, ok)
+		}
+	}
+}
+
+func TestInvalidateAndReload(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{Key("a", "1"), Key("a", "2"), Key("b", "1")} {
+		if _, err := c.Put(key, []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Invalidate("a"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Size() != 4 {
+		t.Errorf("size after invalidate = %d", c.Size())
+	}
+
+	// 重新打开时从磁盘恢复
+	c, err = New(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Size() != 4 {
+		t.Errorf("size after reload = %d", c.Size())
+	}
+	f, ok := c.Get(Key("b", "1"))
+	if !ok {
+		t.Fatal("miss after reload")
+	}
+	f.Close()
+
+	// 外部删除的文件按未命中处理
+	if err := os.RemoveAll(filepath.Join(dir, "b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(Key("b", "1")); ok {
+		t.Error("hit on removed file")
+	}
+	if c.Size() != 0 {
+		t.Errorf("size = %d", c.Size())
+	}
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+
+// Package imageedit 按顺序对图像应用裁剪、旋转、翻转和缩放
+package imageedit
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/nfnt/resize"
+)
+
+// 操作类型
+const (
+	Crop   = "crop"
+	Rotate = "rotate"
+	Flip   = "flip"
+	Resize = "resize"
+)
+
+// 缩放后允许的最大边长
+const MaxDimension = 16384
+
+// Operation 一个编辑操作，坐标和尺寸都相对于上一步的结果
+type Operation struct {
+	Type string `json:"type"`
+
+	// crop：左上角和尺寸；resize：目标尺寸，其中一个为 0 时按比例计算
+	X      int `json:"x,omitempty"`
+	Y      int `json:"y,omitempty"`
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+
+	// rotate：顺时针角度，必须是 90 的倍数
+	Angle int `json:"angle,omitempty"`
+
+	// flip：horizontal 或 vertical
+	Direction string `json:"direction,omitempty"`
+}
+
+// Validate 检查操作参数，不依赖图像尺寸的部分
+func (op Operation) Validate() error {
+	switch op.Type {
+	case Crop:
+		if op.X < 0 || op.Y < 0 || op.Width <= 0 || op.Height <= 0 {
+			return fmt.Errorf("crop needs a non-negative origin and a positive size")
+		}
+	case Rotate:
+		if op.Angle%90 != 0 {
+			return fmt.Errorf("rotate angle must be a multiple of 90, got %d", op.Angle)
+		}
+	case Flip:
+		if op.Direction != "horizontal" && op.Direction != "vertical" {
+			return fmt.Errorf("flip direction must be horizontal or vertical, got %q", op.Direction)
+		}
+	case Resize:
+		if op.Width < 0 || op.Height < 0 || (op.Width == 0 && op.Height == 0) {
+			return fmt.Errorf("resize needs a positive width or height")
+		}
+		if op.Width > MaxDimension || op.Height > MaxDimension {
+			return fmt.Errorf("resize exceeds the maximum dimension of %d", MaxDimension)
+		}
+	default:
+		return fmt.Errorf("unknown operation %q", op.Type)
+	}
+	return nil
+}
+
+// Validate 检查 width x height 的图像依次应用全部操作是否可行，返回结果尺寸。
+// 任一步的结果超过 MaxDimension 或 maxPixels（大于 0 时）都返回错误，用于在解码和分配内存之前拒绝
+func Validate(width, height int, ops []Operation, maxPixels int64) (int, int, error) {
+	for i, op := range ops {
+		if err := op.Validate(); err != nil {
+			return 0, 0, fmt.Errorf("operation %d: %v", i, err)
+		}
+
+		switch op.Type {
+		case Crop:
+			if op.X+op.Width > width || op.Y+op.Height > height {
+				return 0, 0, fmt.Errorf("operation %d: crop %dx%d+%d+%d is outside the %dx%d image", i, op.Width, op.Height, op.X, op.Y, width, height)
+			}
+			width, height = op.Width, op.Height
+		case Rotate:
+			if (op.Angle/90)%2 != 0 {
+				width, height = height, width
+			}
+		case Resize:
+			width, height = resizedSize(width, height, op.Width, op.Height)
+		}
+
+		if width > MaxDimension || height > MaxDimension {
+			return 0, 0, fmt.Errorf("operation %d: result %dx%d exceeds the maximum dimension of %d", i, width, height, MaxDimension)
+		}
+		if maxPixels > 0 && int64(width)*int64(height) > maxPixels {
+			return 0, 0, fmt.Errorf("operation %d: result %dx%d exceeds the limit of %d pixels", i, width, height, maxPixels)
+		}
+	}
+	return width, height, nil
+}
+
+// 缩放的目标尺寸，其中一边为 0 时按比例计算，与 resize.Resize 一致
+func resizedSize(width, height, targetW, targetH int) (int, int) {
+	if width <= 0 || height <= 0 {
+		return targetW, targetH
+	}
+	if targetW == 0 {
+		targetW = int(0.7 + float64(width)*float64(targetH)/float64(height))
+	}
+	if targetH == 0 {
+		targetH = int(0.7 + float64(height)*float64(targetW)/float64(width))
+	}
+	return targetW, targetH
+}
+
+// Apply 依次应用全部操作，应用前按 Validate 检查结果尺寸
+func Apply(img image.Image, ops []Operation, maxPixels int64) (image.Image, error) {
+	b := img.Bounds()
+	if _, _, err := Validate(b.Dx(), b.Dy(), ops, maxPixels); err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		var err error
+		img, err = apply(img, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %v", i, err)
+		}
+	}
+	return img, nil
+}
+
+func apply(img image.Image, op Operation) (image.Image, error) {
+	switch op.Type {
+	case Crop:
+		return crop(img, op.X, op.Y, op.Width, op.Height)
+	case Rotate:
+		return rotate(img, op.Angle), nil
+	case Flip:
+		return flip(img, op.Direction == "horizontal"), nil
+	default:
+		return resize.Resize(uint(op.Width), uint(op.Height), img, resize.Lanczos3), nil
+	}
+}
+
+func crop(img image.Image, x, y, w, h int) (image.Image, error) {
+	b := img.Bounds()
+	rect := image.Rect(x, y, x+w, y+h).Add(b.Min)
+	if !rect.In(b) {
+		return nil, fmt.Errorf("crop %dx%d+%d+%d is outside the %dx%d image", w, h, x, y, b.Dx(), b.Dy())
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+	return dst, nil
+}
+
+// 顺时针旋转 90 度的倍数
+func rotate(img image.Image, angle int) image.Image {
+	turns := ((angle/90)%4 + 4) % 4
+	if turns == 0 {
+		return img
+	}
+
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	var dst *image.NRGBA
+	if turns == 2 {
+		dst = image.NewNRGBA(image.Rect(0, 0, w, h))
+	} else {
+		dst = image.NewNRGBA(image.Rect(0, 0, h, w))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch turns {
+			case 1:
+				dx, dy = h-1-y, x
+			case 2:
+				dx, dy = w-1-x, h-1-y
+			case 3:
+				dx, dy = y, w-1-x
+			}
+			copyPixel(dst, dx, dy, src, x, y)
+		}
+	}
+	return dst
+}
+
+func flip(img image.Image, horizontal bool) image.Image {
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if horizontal {
+				copyPixel(dst, w-1-x, y, src, x, y)
+			} else {
+				copyPixel(dst, x, h-1-y, src, x, y)
+			}
+		}
+	}
+	return dst
+}
+
+// 转换为原点在 (0, 0) 的 NRGBA，便于直接按字节复制像素
+func toNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok && n.Rect.Min == (image.Point{}) {
+		return n
+	}
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+func copyPixel(dst *image.NRGBA, dx, dy int, src *image.NRGBA, sx, sy int) {
+	d := dst.PixOffset(dx, dy)
+	s := src.PixOffset(sx, sy)
+	copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+}
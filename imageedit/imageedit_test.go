@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package imageedit
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// 2x1 的图像，左红右蓝
+func redBlue() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.NRGBA{0, 0, 255, 255})
+	return img
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0xf000 && g < 0x1000 && b < 0x1000
+}
+
+func TestApply(t *testing.T) {
+	img, err := Apply(redBlue(), []Operation{{Type: Rotate, Angle: 90}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 1 || img.Bounds().Dy() != 2 || !isRed(img.At(0, 0)) {
+		t.Errorf("rotate 90: got %v, top is red: %v", img.Bounds(), isRed(img.At(0, 0)))
+	}
+
+	img, err = Apply(redBlue(), []Operation{{Type: Rotate, Angle: -90}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isRed(img.At(0, 1)) {
+		t.Error("rotate -90: red should be at the bottom")
+	}
+
+	img, err = Apply(redBlue(), []Operation{{Type: Flip, Direction: "horizontal"}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isRed(img.At(1, 0)) {
+		t.Error("flip: red should be on the right")
+	}
+
+	img, err = Apply(redBlue(), []Operation{{Type: Crop, X: 1, Width: 1, Height: 1}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 1 || isRed(img.At(0, 0)) {
+		t.Error("crop: expected the blue pixel")
+	}
+
+	img, err = Apply(redBlue(), []Operation{{Type: Resize, Width: 8}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 8 || img.Bounds().Dy() != 4 {
+		t.Errorf("resize: got %v", img.Bounds())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		ops           []Operation
+		maxPixels     int64
+		wantW, wantH  int
+		ok            bool
+	}{
+		{"rotate swaps", 40, 30, []Operation{{Type: Rotate, Angle: 270}}, 0, 30, 40, true},
+		{"rotate 180", 40, 30, []Operation{{Type: Rotate, Angle: 180}}, 0, 40, 30, true},
+		{"crop then resize", 100, 50, []Operation{{Type: Crop, Width: 10, Height: 5}, {Type: Resize, Width: 20}}, 0, 20, 10, true},
+		{"proportional height", 200, 100, []Operation{{Type: Resize, Height: 50}}, 0, 100, 50, true},
+		{"crop outside", 10, 10, []Operation{{Type: Crop, X: 5, Width: 6, Height: 1}}, 0, 0, 0, false},
+		{"explicit too many pixels", 10, 10, []Operation{{Type: Resize, Width: 16384, Height: 16384}}, 100_000_000, 0, 0, false},
+		// 细长图像按比例缩放后另一边超过上限
+		{"thin strip", 1, 10000, []Operation{{Type: Resize, Width: 16384}}, 0, 0, 0, false},
+		{"thin strip pixels", 10, 1000, []Operation{{Type: Resize, Width: 1000}}, 1_000_000, 0, 0, false},
+		{"within limit", 10, 10, []Operation{{Type: Resize, Width: 1000, Height: 1000}}, 1_000_000, 1000, 1000, true},
+		{"bad op", 10, 10, []Operation{{Type: "blur"}}, 0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		w, h, err := Validate(tt.width, tt.height, tt.ops, tt.maxPixels)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: got error %v", tt.name, err)
+			continue
+		}
+		if tt.ok && (w != tt.wantW || h != tt.wantH) {
+			t.Errorf("%s: got %dx%d, want %dx%d", tt.name, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+// 超过像素上限时在分配结果之前拒绝
+func TestApplyRejectsLargeResult(t *testing.T) {
+	if _, err := Apply(redBlue(), []Operation{{Type: Resize, Width: 16384, Height: 16384}}, 1_000_000); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestOperationValidate(t *testing.T) {
+	invalid := []Operation{
+		{Type: Crop, X: -1, Width: 1, Height: 1},
+		{Type: Crop, Width: 0, Height: 1},
+		{Type: Rotate, Angle: 45},
+		{Type: Flip, Direction: "diagonal"},
+		{Type: Resize},
+		{Type: Resize, Width: MaxDimension + 1},
+	}
+	for _, op := range invalid {
+		if err := op.Validate(); err == nil {
+			t.Errorf("%+v: expected error", op)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package imagemeta
+
+import (
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+)
+
+// 读取 ICC 配置文件中的描述（desc 标签），v2 为 ASCII，v4 为 mluc 多语言文本
+func iccDescription(profile []byte) string {
+	if len(profile) < 132 {
+		return ""
+	}
+
+	count := int(binary.BigEndian.Uint32(profile[128:]))
+	for i := 0; i < count; i++ {
+		entry := 132 + 12*i
+		if entry+12 > len(profile) {
+			break
+		}
+		if string(profile[entry:entry+4]) != "desc" {
+			continue
+		}
+
+		offset := int(binary.BigEndian.Uint32(profile[entry+4:]))
+		size := int(binary.BigEndian.Uint32(profile[entry+8:]))
+		if offset < 0 || size < 12 || offset+size > len(profile) || offset+size < offset {
+			return ""
+		}
+		return decodeTextTag(profile[offset : offset+size])
+	}
+	return ""
+}
+
+func decodeTextTag(tag []byte) string {
+	switch string(tag[:4]) {
+	case "desc":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		if n <= 0 || 12+n > len(tag) {
+			return ""
+		}
+		return strings.TrimRight(string(tag[12:12+n]), "\x00 ")
+	case "mluc":
+		if len(tag) < 28 {
+			return ""
+		}
+		records := int(binary.BigEndian.Uint32(tag[8:]))
+		if records < 1 {
+			return ""
+		}
+		// 取第一条记录
+		length := int(binary.BigEndian.Uint32(tag[20:]))
+		offset := int(binary.BigEndian.Uint32(tag[24:]))
+		if length < 0 || offset < 0 || offset+length > len(tag) || offset+length < offset {
+			return ""
+		}
+		units := make([]uint16, length/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(tag[offset+2*i:])
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00 ")
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package imagemeta
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// 生成只有一个标签的 ICC 配置文件，标签数据紧跟在标签表之后
+func buildICC(sig string, tag []byte) []byte {
+	be := binary.BigEndian
+	b := make([]byte, 128)
+	b = be.AppendUint32(b, 1)
+	b = append(b, sig...)
+	b = be.AppendUint32(b, 144)
+	b = be.AppendUint32(b, uint32(len(tag)))
+	return append(b, tag...)
+}
+
+func descTag(s string) []byte {
+	b := []byte("desc\x00\x00\x00\x00")
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)+1))
+	b = append(b, s...)
+	return append(b, 0, 0, 0, 0)
+}
+
+func mlucTag(s string) []byte {
+	be := binary.BigEndian
+	units := utf16.Encode([]rune(s))
+	b := []byte("mluc\x00\x00\x00\x00")
+	b = be.AppendUint32(b, 1)
+	b = be.AppendUint32(b, 12)
+	b = append(b, "enUS"...)
+	b = be.AppendUint32(b, uint32(2*len(units)))
+	b = be.AppendUint32(b, 28)
+	for _, u := range units {
+		b = be.AppendUint16(b, u)
+	}
+	return b
+}
+
+func TestICCDescription(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile []byte
+		want    string
+	}{
+		{"v2", buildICC("desc", descTag("sRGB IEC61966-2.1")), "sRGB IEC61966-2.1"},
+		{"v4", buildICC("desc", mlucTag("Display P3")), "Display P3"},
+		{"no desc", buildICC("cprt", descTag("Copyright")), ""},
+		{"unknown type", buildICC("desc", append([]byte("text"), descTag("x")[4:]...)), ""},
+		{"short header", make([]byte, 131), ""},
+	}
+	for _, tt := range tests {
+		if got := iccDescription(tt.profile); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 标签表、标签和文本的长度与数据不符时返回空，不能越界
+func TestICCDescriptionMalformed(t *testing.T) {
+	be := binary.BigEndian
+	v2 := buildICC("desc", descTag("sRGB"))
+	v4 := buildICC("desc", mlucTag("Display P3"))
+
+	// 标签数很大，标签表被截断
+	huge := append([]byte{}, v2...)
+	be.PutUint32(huge[128:], 0x7FFFFFFF)
+	if got := iccDescription(huge); got != "sRGB" {
+		t.Errorf("huge tag count: got %q", got)
+	}
+
+	// 标签大小超出文件
+	tooBig := append([]byte{}, v2...)
+	be.PutUint32(tooBig[140:], 1<<31)
+	// 文本长度超出标签
+	longText := append([]byte{}, v2...)
+	be.PutUint32(longText[144+8:], 1000)
+	// mluc 记录的偏移超出标签
+	badRecord := append([]byte{}, v4...)
+	be.PutUint32(badRecord[144+24:], 0xFFFFFFF0)
+	// 标签偏移加大小溢出
+	overflow := append([]byte{}, v2...)
+	be.PutUint32(overflow[136:], 0xFFFFFFFF)
+	for name, profile := range map[string][]byte{"too big": tooBig, "long text": longText, "bad record": badRecord, "overflow": overflow} {
+		if got := iccDescription(profile); got != "" {
+			t.Errorf("%s: got %q", name, got)
+		}
+	}
+
+	for _, profile := range [][]byte{v2, v4} {
+		for n := 0; n < len(profile); n++ {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Fatalf("panic on %d bytes: %v", n, r)
+					}
+				}()
+				iccDescription(profile[:n])
+			}()
+		}
+	}
+}
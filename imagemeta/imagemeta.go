@@ -0,0 +1,545 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+
+// Package imagemeta 读取图片中的 EXIF、ICC 配置文件和分辨率等技术信息，
+// 支持 JPEG、PNG、WebP 和 TIFF 结构的文件，只读取元数据段，不解码像素
+package imagemeta
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 单个元数据段的大小上限
+const maxSegmentSize = 16 << 20
+
+// Info 图片的技术信息，缺失的字段为零值
+type Info struct {
+	Make             string
+	Model            string
+	LensMake         string
+	LensModel        string
+	Software         string
+	DateTimeOriginal string
+	ExposureTime     string  // 如 1/125
+	FNumber          float64 // 光圈
+	FocalLength      float64 // 焦距（毫米）
+	ISO              int
+	Orientation      int
+	ColorSpace       string // sRGB 或 Uncalibrated
+	ColorProfile     string // ICC 配置文件描述
+	DPIX             float64
+	DPIY             float64
+	GPS              *GPS // 没有 GPS 信息时为 nil
+}
+
+// GPS 拍摄位置，经纬度为十进制度数，南纬和西经为负
+type GPS struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  *float64 // 海拔（米），低于海平面为负
+}
+
+// IsSupportedExt 判断扩展名是否可能包含可读取的技术信息
+func IsSupportedExt(ext string) bool {
+	switch strings.ToLower(strings.TrimPrefix(ext, ".")) {
+	case "jpg", "jpeg", "png", "webp", "tif", "tiff", "dng":
+		return true
+	}
+	return false
+}
+
+// Read 读取文件中的技术信息
+func Read(path string) (info *Info, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed metadata: %v", r)
+		}
+	}()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	head, err := r.Peek(12)
+	if err != nil && len(head) < 4 {
+		return nil, errors.New("file too short")
+	}
+
+	info = &Info{}
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8}):
+		err = readJPEG(r, info)
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		err = readPNG(r, info)
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		err = readWebP(r, info)
+	case string(head[:4]) == "II*\x00" || string(head[:4]) == "MM\x00*":
+		// TIFF 的 IFD 可能位于文件任意位置，只处理元数据在前 16MB 内的文件
+		data, readErr := io.ReadAll(io.LimitReader(r, maxSegmentSize))
+		if readErr != nil {
+			return nil, readErr
+		}
+		err = info.applyExif(data)
+	default:
+		return nil, errors.New("unsupported image format")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func readJPEG(r *bufio.Reader, info *Info) error {
+	if _, err := r.Discard(2); err != nil {
+		return err
+	}
+
+	var icc []byte
+segments:
+	for {
+		marker, err := r.ReadByte()
+		if err != nil {
+			break segments
+		}
+		if marker != 0xFF {
+			continue
+		}
+		kind, err := r.ReadByte()
+		if err != nil {
+			break segments
+		}
+		// 填充字节、无长度的标记
+		if kind == 0xFF || kind == 0x00 || kind == 0x01 || (kind >= 0xD0 && kind <= 0xD7) {
+			continue
+		}
+		// 图像数据开始或结束，之后不再有元数据
+		if kind == 0xDA || kind == 0xD9 {
+			break segments
+		}
+
+		var lenBuf [2]byte
+		if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+			break segments
+		}
+		length := int(binary.BigEndian.Uint16(lenBuf[:])) - 2
+		if length < 0 {
+			break segments
+		}
+
+		switch kind {
+		case 0xE0, 0xE1, 0xE2:
+			seg := make([]byte, length)
+			if _, err := io.ReadFull(r, seg); err != nil {
+				return err
+			}
+			switch {
+			case kind == 0xE0 && bytes.HasPrefix(seg, []byte("JFIF\x00")) && len(seg) >= 12:
+				// 只在没有 EXIF 分辨率时使用 JFIF 的密度
+				if info.DPIX == 0 {
+					info.DPIX, info.DPIY = densityToDPI(seg[7], binary.BigEndian.Uint16(seg[8:]), binary.BigEndian.Uint16(seg[10:]))
+				}
+			case kind == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")):
+				if err := info.applyExif(seg[6:]); err != nil {
+					return err
+				}
+			case kind == 0xE2 && bytes.HasPrefix(seg, []byte("ICC_PROFILE\x00")) && len(seg) > 14:
+				// ICC 配置文件可能被拆分到多个 APP2 段
+				icc = append(icc, seg[14:]...)
+			}
+		default:
+			if _, err := r.Discard(length); err != nil {
+				break segments
+			}
+		}
+	}
+
+	if len(icc) > 0 {
+		info.ColorProfile = iccDescription(icc)
+	}
+	return nil
+}
+
+func readPNG(r *bufio.Reader, info *Info) error {
+	if _, err := r.Discard(8); err != nil {
+		return err
+	}
+
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		kind := string(header[4:])
+
+		// 技术信息相关的块都位于图像数据之前
+		if kind == "IDAT" || kind == "IEND" {
+			return nil
+		}
+
+		switch kind {
+		case "pHYs", "iCCP", "eXIf", "sRGB":
+			if length > maxSegmentSize {
+				return errors.New("png chunk too large")
+			}
+			data := make([]byte, length)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return err
+			}
+			applyPNGChunk(kind, data, info)
+			if _, err := r.Discard(4); err != nil {
+				return nil
+			}
+		default:
+			if _, err := r.Discard(int(length) + 4); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func applyPNGChunk(kind string, data []byte, info *Info) {
+	switch kind {
+	case "pHYs":
+		if len(data) >= 9 && data[8] == 1 {
+			// 单位为像素每米
+			info.DPIX = round2(float64(binary.BigEndian.Uint32(data)) * 0.0254)
+			info.DPIY = round2(float64(binary.BigEndian.Uint32(data[4:])) * 0.0254)
+		}
+	case "iCCP":
+		i := bytes.IndexByte(data, 0)
+		if i < 0 || i+2 > len(data) {
+			return
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(data[i+2:]))
+		if err != nil {
+			return
+		}
+		profile, err := io.ReadAll(io.LimitReader(zr, maxSegmentSize))
+		if err == nil {
+			info.ColorProfile = iccDescription(profile)
+		}
+		if info.ColorProfile == "" {
+			info.ColorProfile = string(data[:i])
+		}
+	case "sRGB":
+		info.ColorSpace = "sRGB"
+	case "eXIf":
+		info.applyExif(data)
+	}
+}
+
+func readWebP(r *bufio.Reader, info *Info) error {
+	if _, err := r.Discard(12); err != nil {
+		return err
+	}
+
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil
+		}
+		kind := string(header[:4])
+		length := int64(binary.LittleEndian.Uint32(header[4:]))
+		padded := length + length%2
+
+		if kind != "EXIF" && kind != "ICCP" {
+			if _, err := r.Discard(int(padded)); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		if length > maxSegmentSize {
+			return errors.New("webp chunk too large")
+		}
+		data := make([]byte, padded)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+		data = data[:length]
+		if kind == "ICCP" {
+			info.ColorProfile = iccDescription(data)
+		} else {
+			// 部分文件在 TIFF 头前保留了 JPEG 的 Exif 前缀
+			info.applyExif(bytes.TrimPrefix(data, []byte("Exif\x00\x00")))
+		}
+	}
+}
+
+// EXIF 标签
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagXResolution      = 0x011A
+	tagYResolution      = 0x011B
+	tagResolutionUnit   = 0x0128
+	tagSoftware         = 0x0131
+	tagExposureTime     = 0x829A
+	tagFNumber          = 0x829D
+	tagISO              = 0x8827
+	tagDateTimeOriginal = 0x9003
+	tagFocalLength      = 0x920A
+	tagColorSpace       = 0xA001
+	tagLensMake         = 0xA433
+	tagLensModel        = 0xA434
+)
+
+func (info *Info) applyExif(b []byte) error {
+	data, err := parseTIFF(b)
+	if err != nil {
+		return err
+	}
+
+	if f, ok := data.main[tagMake]; ok {
+		info.Make = f.string()
+	}
+	if f, ok := data.main[tagModel]; ok {
+		info.Model = f.string()
+	}
+	if f, ok := data.main[tagSoftware]; ok {
+		info.Software = f.string()
+	}
+	if f, ok := data.main[tagOrientation]; ok {
+		if v, ok := f.uint(0); ok {
+			info.Orientation = int(v)
+		}
+	}
+
+	unit := uint64(2)
+	if f, ok := data.main[tagResolutionUnit]; ok {
+		unit, _ = f.uint(0)
+	}
+	if x, ok := data.main[tagXResolution]; ok && unit != 1 {
+		if v, ok := x.float(0); ok {
+			info.DPIX = resolutionToDPI(v, unit)
+			info.DPIY = info.DPIX
+		}
+		if y, ok := data.main[tagYResolution]; ok {
+			if v, ok := y.float(0); ok {
+				info.DPIY = resolutionToDPI(v, unit)
+			}
+		}
+	}
+
+	exif := data.exif
+	if f, ok := exif[tagExposureTime]; ok {
+		if num, den, ok := f.rational(0); ok && num > 0 && den > 0 {
+			info.ExposureTime = formatExposure(num, den)
+		}
+	}
+	if f, ok := exif[tagFNumber]; ok {
+		if v, ok := f.float(0); ok {
+			info.FNumber = round2(v)
+		}
+	}
+	if f, ok := exif[tagFocalLength]; ok {
+		if v, ok := f.float(0); ok {
+			info.FocalLength = round2(v)
+		}
+	}
+	if f, ok := exif[tagISO]; ok {
+		if v, ok := f.uint(0); ok {
+			info.ISO = int(v)
+		}
+	}
+	if f, ok := exif[tagDateTimeOriginal]; ok {
+		info.DateTimeOriginal = f.string()
+	}
+	if f, ok := exif[tagLensMake]; ok {
+		info.LensMake = f.string()
+	}
+	if f, ok := exif[tagLensModel]; ok {
+		info.LensModel = f.string()
+	}
+	if gps := parseGPS(data.gps); gps != nil {
+		info.GPS = gps
+	}
+	if f, ok := exif[tagColorSpace]; ok {
+		if v, ok := f.uint(0); ok {
+			switch v {
+			case 1:
+				info.ColorSpace = "sRGB"
+			case 2:
+				info.ColorSpace = "Adobe RGB"
+			case 0xFFFF:
+				info.ColorSpace = "Uncalibrated"
+			}
+		}
+	}
+	return nil
+}
+
+// GPS 标签
+const (
+	tagGPSLatitudeRef  = 0x01
+	tagGPSLatitude     = 0x02
+	tagGPSLongitudeRef = 0x03
+	tagGPSLongitude    = 0x04
+	tagGPSAltitudeRef  = 0x05
+	tagGPSAltitude     = 0x06
+)
+
+func parseGPS(gps ifd) *GPS {
+	lat, ok := degrees(gps[tagGPSLatitude])
+	if !ok {
+		return nil
+	}
+	lon, ok := degrees(gps[tagGPSLongitude])
+	if !ok {
+		return nil
+	}
+	if gps[tagGPSLatitudeRef].string() == "S" {
+		lat = -lat
+	}
+	if gps[tagGPSLongitudeRef].string() == "W" {
+		lon = -lon
+	}
+	// 没有定位成功的设备常写入 0,0
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 || (lat == 0 && lon == 0) {
+		return nil
+	}
+
+	result := &GPS{Latitude: lat, Longitude: lon}
+	if f, ok := gps[tagGPSAltitude]; ok {
+		if alt, ok := f.float(0); ok {
+			if ref, ok := gps[tagGPSAltitudeRef].uint(0); ok && ref == 1 {
+				alt = -alt
+			}
+			alt = round2(alt)
+			result.Altitude = &alt
+		}
+	}
+	return result
+}
+
+// 度、分、秒三个有理数转为十进制度数
+func degrees(f field) (float64, bool) {
+	if f.count < 3 {
+		return 0, false
+	}
+	var parts [3]float64
+	for i := range parts {
+		v, ok := f.float(i)
+		if !ok {
+			return 0, false
+		}
+		parts[i] = v
+	}
+	return parts[0] + parts[1]/60 + parts[2]/3600, true
+}
+
+// unit 为 2 表示英寸，3 表示厘米
+func resolutionToDPI(v float64, unit uint64) float64 {
+	if unit == 3 {
+		v *= 2.54
+	}
+	return round2(v)
+}
+
+// JFIF 密度单位：1 为英寸，2 为厘米，0 表示只有长宽比
+func densityToDPI(unit byte, x, y uint16) (float64, float64) {
+	switch unit {
+	case 1:
+		return float64(x), float64(y)
+	case 2:
+		return round2(float64(x) * 2.54), round2(float64(y) * 2.54)
+	}
+	return 0, 0
+}
+
+func formatExposure(num, den int64) string {
+	if num >= den {
+		return strconv.FormatFloat(float64(num)/float64(den), 'f', -1, 64)
+	}
+	return fmt.Sprintf("1/%d", int64(math.Round(float64(den)/float64(num))))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Metadata 转为保存到 item 元数据表的键值对
+func (info *Info) Metadata() map[string]string {
+	m := map[string]string{}
+	set := func(key, value string) {
+		if value != "" {
+			m[key] = value
+		}
+	}
+	setFloat := func(key string, value float64) {
+		if value != 0 {
+			m[key] = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	}
+
+	set("exif.make", info.Make)
+	set("exif.model", info.Model)
+	set("exif.lens_make", info.LensMake)
+	set("exif.lens_model", info.LensModel)
+	set("exif.software", info.Software)
+	set("exif.date_time_original", info.DateTimeOriginal)
+	set("exif.exposure_time", info.ExposureTime)
+	setFloat("exif.f_number", info.FNumber)
+	setFloat("exif.focal_length", info.FocalLength)
+	if info.ISO != 0 {
+		m["exif.iso"] = strconv.Itoa(info.ISO)
+	}
+	if info.Orientation != 0 {
+		m["exif.orientation"] = strconv.Itoa(info.Orientation)
+	}
+	set("image.color_space", info.ColorSpace)
+	set("image.color_profile", info.ColorProfile)
+	setFloat("image.dpi_x", info.DPIX)
+	setFloat("image.dpi_y", info.DPIY)
+	return m
+}
+
+// FromMetadata 从元数据表的键值对还原技术信息，没有任何相关键时返回 nil
+func FromMetadata(m map[string]string) *Info {
+	info := &Info{
+		Make:             m["exif.make"],
+		Model:            m["exif.model"],
+		LensMake:         m["exif.lens_make"],
+		LensModel:        m["exif.lens_model"],
+		Software:         m["exif.software"],
+		DateTimeOriginal: m["exif.date_time_original"],
+		ExposureTime:     m["exif.exposure_time"],
+		ColorSpace:       m["image.color_space"],
+		ColorProfile:     m["image.color_profile"],
+	}
+	info.FNumber, _ = strconv.ParseFloat(m["exif.f_number"], 64)
+	info.FocalLength, _ = strconv.ParseFloat(m["exif.focal_length"], 64)
+	info.ISO, _ = strconv.Atoi(m["exif.iso"])
+	info.Orientation, _ = strconv.Atoi(m["exif.orientation"])
+	info.DPIX, _ = strconv.ParseFloat(m["image.dpi_x"], 64)
+	info.DPIY, _ = strconv.ParseFloat(m["image.dpi_y"], 64)
+
+	if *info == (Info{}) {
+		return nil
+	}
+	return info
+}
@@ -0,0 +1,256 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func ascii(tag uint16, s string) tiffEntry {
+	return tiffEntry{tag, typeASCII, uint32(len(s) + 1), append([]byte(s), 0)}
+}
+
+func short(tag uint16, v uint16) tiffEntry {
+	return tiffEntry{tag, typeShort, 1, binary.LittleEndian.AppendUint16(nil, v)}
+}
+
+func long(tag uint16, v uint32) tiffEntry {
+	return tiffEntry{tag, typeLong, 1, binary.LittleEndian.AppendUint32(nil, v)}
+}
+
+func rationals(tag uint16, values ...uint32) tiffEntry {
+	var b []byte
+	for _, v := range values {
+		b = binary.LittleEndian.AppendUint32(b, v)
+	}
+	return tiffEntry{tag, typeRational, uint32(len(values) / 2), b}
+}
+
+// 生成位于 base 处的 IFD，超过 4 字节的值放在 IFD 之后
+func buildIFD(entries []tiffEntry, base int) []byte {
+	le := binary.LittleEndian
+	head := le.AppendUint16(nil, uint16(len(entries)))
+	dataOff := base + 2 + 12*len(entries) + 4
+	var data []byte
+	for _, e := range entries {
+		head = le.AppendUint16(head, e.tag)
+		head = le.AppendUint16(head, e.typ)
+		head = le.AppendUint32(head, e.count)
+		if len(e.data) <= 4 {
+			head = append(head, append(e.data, make([]byte, 4-len(e.data))...)...)
+		} else {
+			head = le.AppendUint32(head, uint32(dataOff+len(data)))
+			data = append(data, e.data...)
+		}
+	}
+	head = le.AppendUint32(head, 0)
+	return append(head, data...)
+}
+
+// 生成小端序 TIFF，exif 和 gps 为空时不写入对应的子 IFD
+func buildTIFF(main, exif, gps []tiffEntry) []byte {
+	withPointers := func(exifOff, gpsOff uint32) []tiffEntry {
+		entries := append([]tiffEntry{}, main...)
+		if exif != nil {
+			entries = append(entries, long(tagExifIFD, exifOff))
+		}
+		if gps != nil {
+			entries = append(entries, long(tagGPSIFD, gpsOff))
+		}
+		return entries
+	}
+	exifOff := 8 + len(buildIFD(withPointers(0, 0), 8))
+	exifBlock := buildIFD(exif, exifOff)
+	gpsOff := exifOff + len(exifBlock)
+	if exif == nil {
+		exifBlock, gpsOff = nil, exifOff
+	}
+
+	b := []byte("II*\x00\x08\x00\x00\x00")
+	b = append(b, buildIFD(withPointers(uint32(exifOff), uint32(gpsOff)), 8)...)
+	b = append(b, exifBlock...)
+	if gps != nil {
+		b = append(b, buildIFD(gps, gpsOff)...)
+	}
+	return b
+}
+
+func sampleTIFF() []byte {
+	return buildTIFF(
+		[]tiffEntry{ascii(tagMake, "Canon"), ascii(tagModel, "EOS R5"), short(tagOrientation, 6)},
+		[]tiffEntry{rationals(tagExposureTime, 1, 125), rationals(tagFNumber, 28, 10), short(tagISO, 200), short(tagColorSpace, 1)},
+		[]tiffEntry{
+			ascii(tagGPSLatitudeRef, "N"), rationals(tagGPSLatitude, 35, 1, 41, 1, 222, 10),
+			ascii(tagGPSLongitudeRef, "W"), rationals(tagGPSLongitude, 139, 1, 30, 1, 0, 1),
+			{tagGPSAltitudeRef, typeByte, 1, []byte{1}}, rationals(tagGPSAltitude, 105, 10),
+		},
+	)
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func jpegWithExif(tiff []byte) []byte {
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	b := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	b = binary.BigEndian.AppendUint16(b, uint16(len(seg)+2))
+	b = append(b, seg...)
+	return append(b, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9)
+}
+
+func TestReadJPEG(t *testing.T) {
+	info, err := Read(writeFile(t, "a.jpg", jpegWithExif(sampleTIFF())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Make != "Canon" || info.Model != "EOS R5" || info.Orientation != 6 {
+		t.Errorf("camera = %q %q %d", info.Make, info.Model, info.Orientation)
+	}
+	if info.ExposureTime != "1/125" || info.FNumber != 2.8 || info.ISO != 200 || info.ColorSpace != "sRGB" {
+		t.Errorf("exposure = %q f/%v ISO %d %s", info.ExposureTime, info.FNumber, info.ISO, info.ColorSpace)
+	}
+	if info.GPS == nil {
+		t.Fatal("missing GPS")
+	}
+	if lat := info.GPS.Latitude; lat < 35.6894 || lat > 35.6895 {
+		t.Errorf("latitude = %v", lat)
+	}
+	if info.GPS.Longitude != -139.5 {
+		t.Errorf("longitude = %v", info.GPS.Longitude)
+	}
+	if info.GPS.Altitude == nil || *info.GPS.Altitude != -10.5 {
+		t.Errorf("altitude = %v", info.GPS.Altitude)
+	}
+
+	m := info.Metadata()
+	if m["exif.make"] != "Canon" || m["exif.f_number"] != "2.8" || m["exif.iso"] != "200" {
+		t.Errorf("metadata = %v", m)
+	}
+	if got := FromMetadata(m); got == nil || got.Model != "EOS R5" || got.ExposureTime != "1/125" {
+		t.Errorf("FromMetadata = %+v", got)
+	}
+}
+
+func pngChunk(kind string, data []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	b = append(b, kind...)
+	b = append(b, data...)
+	return append(b, 0, 0, 0, 0)
+}
+
+func TestReadPNG(t *testing.T) {
+	phys := binary.BigEndian.AppendUint32(nil, 11811)
+	phys = binary.BigEndian.AppendUint32(phys, 11811)
+	phys = append(phys, 1)
+
+	var b bytes.Buffer
+	b.WriteString("\x89PNG\r\n\x1a\n")
+	b.Write(pngChunk("IHDR", make([]byte, 13)))
+	b.Write(pngChunk("pHYs", phys))
+	b.Write(pngChunk("sRGB", []byte{0}))
+	b.Write(pngChunk("eXIf", buildTIFF([]tiffEntry{ascii(tagSoftware, "GIMP")}, nil, nil)))
+	b.Write(pngChunk("IEND", nil))
+
+	info, err := Read(writeFile(t, "a.png", b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DPIX != 300 || info.DPIY != 300 || info.ColorSpace != "sRGB" || info.Software != "GIMP" {
+		t.Errorf("info = %+v", info)
+	}
+}
+
+func TestReadRejectsOversizedPNGChunk(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("\x89PNG\r\n\x1a\n")
+	b.Write(binary.BigEndian.AppendUint32(nil, maxSegmentSize+1))
+	b.WriteString("iCCP")
+	if _, err := Read(writeFile(t, "a.png", b.Bytes())); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestReadUnsupported(t *testing.T) {
+	if _, err := Read(writeFile(t, "a.gif", []byte("GIF89a......"))); err == nil {
+		t.Error("expected error for gif")
+	}
+	if _, err := Read(writeFile(t, "a.jpg", []byte{0xFF})); err == nil {
+		t.Error("expected error for short file")
+	}
+}
+
+// 截断或篡改的 EXIF 只能返回错误或缺少字段，不能越界
+func TestParseTIFFMalformed(t *testing.T) {
+	tiff := sampleTIFF()
+	parse := func(b []byte) {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("panic on %d bytes: %v", len(b), r)
+			}
+		}()
+		var info Info
+		info.applyExif(b)
+	}
+	for n := 0; n <= len(tiff); n++ {
+		parse(tiff[:n])
+	}
+	for i := 4; i < len(tiff); i++ {
+		b := append([]byte{}, tiff...)
+		b[i] ^= 0xFF
+		parse(b)
+	}
+}
+
+func TestReadIFDLimits(t *testing.T) {
+	b := []byte("II*\x00\x08\x00\x00\x00")
+	b = binary.LittleEndian.AppendUint16(b, maxIFDEntries+1)
+	if _, err := parseTIFF(b); err == nil {
+		t.Error("expected error for too many entries")
+	}
+	if _, err := parseTIFF([]byte("II*\x00\xff\xff\x00\x00")); err == nil {
+		t.Error("expected error for offset out of range")
+	}
+	if _, err := parseTIFF([]byte("XX*\x00\x08\x00\x00\x00")); err == nil {
+		t.Error("expected error for byte order")
+	}
+}
+
+func TestParseGPSIgnoresNullIsland(t *testing.T) {
+	tiff := buildTIFF(nil, nil, []tiffEntry{
+		rationals(tagGPSLatitude, 0, 1, 0, 1, 0, 1),
+		rationals(tagGPSLongitude, 0, 1, 0, 1, 0, 1),
+	})
+	var info Info
+	if err := info.applyExif(tiff); err != nil {
+		t.Fatal(err)
+	}
+	if info.GPS != nil {
+		t.Errorf("GPS = %+v", info.GPS)
+	}
+}
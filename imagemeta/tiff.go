@@ -0,0 +1,198 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package imagemeta
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// TIFF 字段类型
+const (
+	typeByte      = 1
+	typeASCII     = 2
+	typeShort     = 3
+	typeLong      = 4
+	typeRational  = 5
+	typeUndefined = 7
+	typeSLong     = 9
+	typeSRational = 10
+)
+
+var typeSizes = map[uint16]int{
+	typeByte: 1, typeASCII: 1, typeShort: 2, typeLong: 4, typeRational: 8,
+	typeUndefined: 1, typeSLong: 4, typeSRational: 8,
+}
+
+// 指向子 IFD 的标签
+const (
+	tagExifIFD = 0x8769
+	tagGPSIFD  = 0x8825
+)
+
+// 单个 IFD 最多读取的条目数，防止构造的文件让解析无限循环
+const maxIFDEntries = 1000
+
+// field 一个 TIFF 字段的原始数据
+type field struct {
+	typ   uint16
+	count uint32
+	data  []byte
+	order binary.ByteOrder
+}
+
+// ifd 标签到字段的映射
+type ifd map[uint16]field
+
+// exifData 解析后的 EXIF，分为主 IFD、Exif 子 IFD 和 GPS 子 IFD
+type exifData struct {
+	main ifd
+	exif ifd
+	gps  ifd
+}
+
+// 解析以 TIFF 头开始的 EXIF 数据
+func parseTIFF(b []byte) (*exifData, error) {
+	if len(b) < 8 {
+		return nil, errors.New("tiff header too short")
+	}
+
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid tiff byte order")
+	}
+	if order.Uint16(b[2:]) != 42 {
+		return nil, errors.New("invalid tiff magic")
+	}
+
+	data := &exifData{}
+	var err error
+	data.main, err = readIFD(b, order, order.Uint32(b[4:]))
+	if err != nil {
+		return nil, err
+	}
+
+	if f, ok := data.main[tagExifIFD]; ok {
+		if off, ok := f.uint(0); ok {
+			data.exif, _ = readIFD(b, order, uint32(off))
+		}
+	}
+	if f, ok := data.main[tagGPSIFD]; ok {
+		if off, ok := f.uint(0); ok {
+			data.gps, _ = readIFD(b, order, uint32(off))
+		}
+	}
+	return data, nil
+}
+
+func readIFD(b []byte, order binary.ByteOrder, offset uint32) (ifd, error) {
+	if int64(offset)+2 > int64(len(b)) {
+		return nil, errors.New("ifd offset out of range")
+	}
+	n := int(order.Uint16(b[offset:]))
+	if n > maxIFDEntries {
+		return nil, errors.New("too many ifd entries")
+	}
+
+	entries := make(ifd, n)
+	pos := int(offset) + 2
+	for i := 0; i < n; i++ {
+		if pos+12 > len(b) {
+			break
+		}
+		entry := b[pos : pos+12]
+		pos += 12
+
+		tag := order.Uint16(entry)
+		typ := order.Uint16(entry[2:])
+		count := order.Uint32(entry[4:])
+		size, ok := typeSizes[typ]
+		if !ok {
+			continue
+		}
+
+		total := int64(size) * int64(count)
+		var value []byte
+		if total <= 4 {
+			value = entry[8 : 8+total]
+		} else {
+			off := int64(order.Uint32(entry[8:]))
+			if off+total > int64(len(b)) {
+				continue
+			}
+			value = b[off : off+total]
+		}
+		entries[tag] = field{typ: typ, count: count, data: value, order: order}
+	}
+	return entries, nil
+}
+
+func (f field) string() string {
+	s := string(f.data)
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// 第 i 个整数值
+func (f field) uint(i int) (uint64, bool) {
+	switch f.typ {
+	case typeByte, typeUndefined:
+		if i < len(f.data) {
+			return uint64(f.data[i]), true
+		}
+	case typeShort:
+		if 2*i+2 <= len(f.data) {
+			return uint64(f.order.Uint16(f.data[2*i:])), true
+		}
+	case typeLong, typeSLong:
+		if 4*i+4 <= len(f.data) {
+			return uint64(f.order.Uint32(f.data[4*i:])), true
+		}
+	}
+	return 0, false
+}
+
+// 第 i 个有理数，返回分子和分母
+func (f field) rational(i int) (int64, int64, bool) {
+	if f.typ != typeRational && f.typ != typeSRational {
+		if v, ok := f.uint(i); ok {
+			return int64(v), 1, true
+		}
+		return 0, 0, false
+	}
+	if 8*i+8 > len(f.data) {
+		return 0, 0, false
+	}
+	num := f.order.Uint32(f.data[8*i:])
+	den := f.order.Uint32(f.data[8*i+4:])
+	if f.typ == typeSRational {
+		return int64(int32(num)), int64(int32(den)), true
+	}
+	return int64(num), int64(den), true
+}
+
+// 第 i 个值转为浮点数
+func (f field) float(i int) (float64, bool) {
+	num, den, ok := f.rational(i)
+	if !ok || den == 0 {
+		return 0, false
+	}
+	return float64(num) / float64(den), true
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package imagemeta
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// 大端序的 TIFF 按字段类型读取整数、有理数和字符串
+func TestParseTIFFBigEndian(t *testing.T) {
+	be := binary.BigEndian
+	b := []byte("MM\x00*\x00\x00\x00\x08")
+	b = be.AppendUint16(b, 3)
+	// Make：超过 4 字节，值在 IFD 之后的偏移 50 处
+	b = be.AppendUint16(b, tagMake)
+	b = be.AppendUint16(b, typeASCII)
+	b = be.AppendUint32(b, 6)
+	b = be.AppendUint32(b, 50)
+	// Orientation：SHORT 值左对齐在值字段中
+	b = be.AppendUint16(b, tagOrientation)
+	b = be.AppendUint16(b, typeShort)
+	b = be.AppendUint32(b, 1)
+	b = append(b, 0, 8, 0, 0)
+	// ExposureBiasValue：有符号有理数，偏移 56 处
+	b = be.AppendUint16(b, 0x9204)
+	b = be.AppendUint16(b, typeSRational)
+	b = be.AppendUint32(b, 1)
+	b = be.AppendUint32(b, 56)
+	b = be.AppendUint32(b, 0)
+	b = append(b, "Nikon\x00"...)
+	b = be.AppendUint32(b, uint32(0xFFFFFFFF)) // -1
+	b = be.AppendUint32(b, 3)
+
+	data, err := parseTIFF(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := data.main[tagMake].string(); s != "Nikon" {
+		t.Errorf("make = %q", s)
+	}
+	if v, ok := data.main[tagOrientation].uint(0); !ok || v != 8 {
+		t.Errorf("orientation = %d, %v", v, ok)
+	}
+	if num, den, ok := data.main[0x9204].rational(0); !ok || num != -1 || den != 3 {
+		t.Errorf("bias = %d/%d, %v", num, den, ok)
+	}
+	if data.exif != nil || data.gps != nil {
+		t.Errorf("sub ifds = %v %v", data.exif, data.gps)
+	}
+}
+
+// IFD 声明的条目数多于实际数据时只读取完整的条目
+func TestReadIFDTruncatedEntries(t *testing.T) {
+	b := buildTIFF([]tiffEntry{short(tagOrientation, 3), short(tagColorSpace, 1)}, nil, nil)
+	// 第二个条目只剩一半
+	b = b[:8+2+12+6]
+	data, err := parseTIFF(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.main) != 1 {
+		t.Fatalf("entries = %v", data.main)
+	}
+	if v, _ := data.main[tagOrientation].uint(0); v != 3 {
+		t.Errorf("orientation = %d", v)
+	}
+
+	// IFD 只剩条目数
+	if data, err := parseTIFF(b[:10]); err != nil || len(data.main) != 0 {
+		t.Errorf("got %v, %v", data, err)
+	}
+	// 条目数也不完整
+	if _, err := parseTIFF(b[:9]); err == nil {
+		t.Error("expected error for truncated entry count")
+	}
+}
+
+// 未知类型和值超出数据范围的条目被跳过，不影响其他条目
+func TestReadIFDSkipsMalformedEntries(t *testing.T) {
+	b := buildTIFF([]tiffEntry{
+		{tagMake, 99, 4, []byte("abcd")},
+		{tagModel, typeASCII, 100, []byte("too long")},
+		{tagSoftware, typeRational, 0x20000000, []byte("12345678")},
+		short(tagOrientation, 6),
+	}, nil, nil)
+	data, err := parseTIFF(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.main) != 1 {
+		t.Errorf("entries = %v", data.main)
+	}
+	if v, _ := data.main[tagOrientation].uint(0); v != 6 {
+		t.Errorf("orientation = %d", v)
+	}
+}
+
+// 子 IFD 的偏移无效时忽略子 IFD，主 IFD 仍然可用
+func TestParseTIFFBadSubIFD(t *testing.T) {
+	b := buildTIFF([]tiffEntry{ascii(tagMake, "Canon"), long(tagExifIFD, 0xFFFFFFF0), long(tagGPSIFD, 1<<20)}, nil, nil)
+	data, err := parseTIFF(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.main[tagMake].string() != "Canon" || data.exif != nil || data.gps != nil {
+		t.Errorf("data = %+v", data)
+	}
+
+	// 子 IFD 指向自己时只读取一层
+	b = buildTIFF([]tiffEntry{long(tagExifIFD, 8)}, nil, nil)
+	data, err = parseTIFF(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := data.exif[tagExifIFD]; !ok {
+		t.Errorf("exif = %v", data.exif)
+	}
+}
+
+func TestFieldAccessors(t *testing.T) {
+	le := binary.LittleEndian
+	shorts := field{typ: typeShort, count: 2, data: []byte{1, 0, 2}, order: le}
+	if v, ok := shorts.uint(0); !ok || v != 1 {
+		t.Errorf("uint(0) = %d, %v", v, ok)
+	}
+	if _, ok := shorts.uint(1); ok {
+		t.Error("uint(1) read past the data")
+	}
+
+	// 非有理数类型按整数读取
+	if num, den, ok := shorts.rational(0); !ok || num != 1 || den != 1 {
+		t.Errorf("rational(0) = %d/%d, %v", num, den, ok)
+	}
+
+	zero := field{typ: typeRational, count: 1, data: make([]byte, 8), order: le}
+	if _, ok := zero.float(0); ok {
+		t.Error("float with zero denominator")
+	}
+	if _, _, ok := zero.rational(1); ok {
+		t.Error("rational(1) read past the data")
+	}
+
+	ascii := field{typ: typeASCII, data: []byte(" EOS \x00garbage")}
+	if s := ascii.string(); s != "EOS" {
+		t.Errorf("string = %q", s)
+	}
+	if _, ok := ascii.uint(0); ok {
+		t.Error("uint of ascii field")
+	}
+}
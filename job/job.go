@@ -0,0 +1,249 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusDone      Status = "done"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// 内存中保留的已结束任务数量
+const maxFinishedJobs = 100
+
+// Job 后台任务，进度信息可被并发读取
+type Job struct {
+	mu sync.Mutex
+
+	id         string
+	kind       string
+	status     Status
+	total      int
+	done       int
+	message    string
+	err        string
+	result     interface{}
+	createdAt  time.Time
+	finishedAt time.Time
+
+	cancel context.CancelFunc
+}
+
+// Snapshot 任务某一时刻的状态
+type Snapshot struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Status     Status      `json:"status"`
+	Total      int         `json:"total"`
+	Done       int         `json:"done"`
+	Message    string      `json:"message,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+}
+
+// Manager 管理一个资料库的后台任务
+type Manager struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func NewManager() *Manager {
+	return &Manager{jobs: map[string]*Job{}}
+}
+
+// Start 在后台运行任务并立即返回
+func (m *Manager) Start(kind string, run func(ctx context.Context, j *Job) error) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &Job{
+		id:        uuid.Must(uuid.NewV4()).String(),
+		kind:      kind,
+		status:    StatusRunning,
+		createdAt: time.Now(),
+		cancel:    cancel,
+	}
+
+	m.mu.Lock()
+	m.jobs[j.id] = j
+	m.prune()
+	m.mu.Unlock()
+
+	go func() {
+		defer cancel()
+
+		var err error
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("job panicked: %v", r)
+				}
+			}()
+			err = run(ctx, j)
+		}()
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.finishedAt = time.Now()
+		switch {
+		case errors.Is(err, context.Canceled):
+			j.status = StatusCancelled
+		case err != nil:
+			j.status = StatusFailed
+			j.err = err.Error()
+		default:
+			j.status = StatusDone
+		}
+	}()
+
+	return j
+}
+
+// Get 根据 ID 查找任务
+func (m *Manager) Get(id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	return j, ok
+}
+
+// List 返回全部任务，按创建时间倒序
+func (m *Manager) List() []Snapshot {
+	m.mu.Lock()
+	list := make([]*Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		list = append(list, j)
+	}
+	m.mu.Unlock()
+
+	snapshots := make([]Snapshot, len(list))
+	for i, j := range list {
+		snapshots[i] = j.Snapshot()
+	}
+	sort.Slice(snapshots, func(i, k int) bool { return snapshots[i].CreatedAt.After(snapshots[k].CreatedAt) })
+	return snapshots
+}
+
+// Running 返回正在运行的任务数量
+func (m *Manager) Running() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, j := range m.jobs {
+		j.mu.Lock()
+		if j.status == StatusRunning {
+			n++
+		}
+		j.mu.Unlock()
+	}
+	return n
+}
+
+// 清理最早结束的任务，调用方需持有 m.mu
+func (m *Manager) prune() {
+	var finished []*Job
+	for _, j := range m.jobs {
+		j.mu.Lock()
+		if j.status != StatusRunning {
+			finished = append(finished, j)
+		}
+		j.mu.Unlock()
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, k int) bool { return finished[i].finishedAt.Before(finished[k].finishedAt) })
+	for _, j := range finished[:len(finished)-maxFinishedJobs] {
+		delete(m.jobs, j.id)
+	}
+}
+
+func (j *Job) ID() string {
+	return j.id
+}
+
+// Cancel 请求取消任务，任务函数需要检查 ctx
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// SetTotal 设置需要处理的总数
+func (j *Job) SetTotal(total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.total = total
+}
+
+// Progress 更新已处理的数量
+func (j *Job) Progress(done int, total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.done = done
+	j.total = total
+}
+
+// Step 已处理数量加一
+func (j *Job) Step() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.done++
+}
+
+// SetMessage 设置当前状态说明
+func (j *Job) SetMessage(message string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.message = message
+}
+
+// SetResult 设置任务结果，任务结束后可通过 Snapshot 读取
+func (j *Job) SetResult(result interface{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.result = result
+}
+
+func (j *Job) Snapshot() Snapshot {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	s := Snapshot{
+		ID:        j.id,
+		Type:      j.kind,
+		Status:    j.status,
+		Total:     j.total,
+		Done:      j.done,
+		Message:   j.message,
+		Error:     j.err,
+		Result:    j.result,
+		CreatedAt: j.createdAt,
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		s.FinishedAt = &finishedAt
+	}
+	return s
+}
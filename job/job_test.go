@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func wait(t *testing.T, j *Job) Snapshot {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		s := j.Snapshot()
+		if s.Status != StatusRunning {
+			return s
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job still running: %+v", s)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 每个 Manager 只能看到自己启动的任务
+func TestManagersAreIndependent(t *testing.T) {
+	a, b := NewManager(), NewManager()
+
+	started := make(chan struct{})
+	j := a.Start("test", func(ctx context.Context, j *Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+
+	if a.Running() != 1 || b.Running() != 0 {
+		t.Errorf("running = %d, %d", a.Running(), b.Running())
+	}
+	if _, ok := b.Get(j.ID()); ok {
+		t.Error("job found in another manager")
+	}
+	if list := b.List(); len(list) != 0 {
+		t.Errorf("list of another manager = %+v", list)
+	}
+
+	j.Cancel()
+	if s := wait(t, j); s.Status != StatusCancelled {
+		t.Errorf("status = %s", s.Status)
+	}
+	if a.Running() != 0 {
+		t.Errorf("running after cancel = %d", a.Running())
+	}
+	if list := a.List(); len(list) != 1 || list[0].ID != j.ID() {
+		t.Errorf("list = %+v", list)
+	}
+}
@@ -13,80 +13,36 @@ package main
 
 import (
 	"log"
+	"os"
 
-	"synapforest/api"
-	"synapforest/api/folderapi"
-	"synapforest/api/graphql"
-	"synapforest/api/itemapi"
-	"synapforest/api/tagapi"
-	"synapforest/api/vectorapi"
 	"synapforest/database"
-
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
+	"synapforest/server"
 )
 
+// 私有接口的默认 token
+const token = "TEST123123"
+
 func main() {
-	_, err := database.Database_init("test")
-	if err != nil {
-		log.Fatalf("failed init database: %v", err)
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			log.Fatalf("unknown command: %s", os.Args[1])
+		}
+		if err := command(os.Args[2:]); err != nil {
+			log.Fatalf("%s failed: %v", os.Args[1], err)
+		}
+		return
 	}
 
-	err = api.ApiInit("uploads")
+	libraries, err := database.LoadRegistry("libraries.json", "test")
 	if err != nil {
-		log.Fatalf("failed init Api: %v", err)
-	}
-
-	r := gin.Default()
-
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"}, // 允许所有域名
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
-
-	publicRoutes := r.Group("/public")
-	{
-		publicRoutes.GET("/thumbnails/:id", api.ServeThumbnails)
-		publicRoutes.GET("/raw_files/:id", api.ServeRawFile)
-		publicRoutes.GET("/previews/:id", api.ServePreviews)
-
-		publicRoutes.POST("/vectorize/:id", vectorapi.HandleVectorize)
+		log.Fatalf("failed load library registry: %v", err)
 	}
-
-	privateRoutes := r.Group("/api")
-	privateRoutes.Use(api.AuthMiddleware())
-	{
-		privateRoutes.POST("/uploadfiles", api.Uploadfiles)
-
-		privateRoutes.POST("/folder/create", folderapi.CreateFolder)
-		privateRoutes.POST("/folder/list", folderapi.ListFolder)
-		privateRoutes.POST("/folder/update", folderapi.UpdateFolder)
-		privateRoutes.POST("/folder/updateParent", folderapi.UpdateFoldersParent)
-		privateRoutes.POST("/folder/delete", folderapi.DeleteFolder)
-
-		privateRoutes.POST("/tag/create", tagapi.CreateTag)
-		privateRoutes.POST("/tag/list", tagapi.ListTag)
-		privateRoutes.POST("/tag/update", tagapi.UpdateTag)
-		privateRoutes.POST("/tag/updateParent", tagapi.UpdateTagsParent)
-		privateRoutes.POST("/tag/delete", tagapi.DeleteTag)
-
-		privateRoutes.POST("/item/addFromUrls", itemapi.AddFromUrls)
-		privateRoutes.POST("/item/addFromPaths", itemapi.AddFromPaths)
-		privateRoutes.POST("/item/info", itemapi.Info)
-		privateRoutes.POST("/item/moveToTrash", itemapi.MoveToTrash)
-		privateRoutes.POST("/item/update", itemapi.Update)
-		privateRoutes.POST("/item/list", itemapi.List)
-
-		privateRoutes.POST("/item/remove-folder", api.RemoveFolderForItems)
-		privateRoutes.POST("/item/add-folder", api.AddFolderForItems)
-
-		gqlHandler := graphql.NewHandler()
-		r.GET("/graphql", gin.WrapH(gqlHandler))
-		r.POST("/graphql", gin.WrapH(gqlHandler))
+	s, err := server.New(libraries, server.Options{Token: token})
+	if err != nil {
+		log.Fatalf("failed init database: %v", err)
 	}
+	defer s.Close()
 
-	r.Run(":41595")
+	s.Run(":41595")
 }
@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Vec3 [3]float64
+
+type Triangle [3]Vec3
+
+type Mesh struct {
+	Triangles []Triangle
+}
+
+// IsModelExt 判断扩展名是否为支持的 3D 模型格式
+func IsModelExt(ext string) bool {
+	switch strings.ToLower(strings.TrimPrefix(ext, ".")) {
+	case "stl", "obj":
+		return true
+	}
+	return false
+}
+
+// Load 根据扩展名加载 STL 或 OBJ 模型
+func Load(path string) (*Mesh, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open model failed: %v", err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".stl":
+		return LoadSTL(file)
+	case ".obj":
+		return LoadOBJ(file)
+	}
+	return nil, fmt.Errorf("unsupported model format: %s", filepath.Ext(path))
+}
+
+// Bounds 返回模型的包围盒
+func (m *Mesh) Bounds() (min Vec3, max Vec3) {
+	if len(m.Triangles) == 0 {
+		return
+	}
+	min = m.Triangles[0][0]
+	max = m.Triangles[0][0]
+	for _, tri := range m.Triangles {
+		for _, v := range tri {
+			for i := 0; i < 3; i++ {
+				min[i] = math.Min(min[i], v[i])
+				max[i] = math.Max(max[i], v[i])
+			}
+		}
+	}
+	return
+}
+
+// LoadSTL 解析 ASCII 或二进制 STL
+func LoadSTL(r io.Reader) (*Mesh, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read stl failed: %v", err)
+	}
+
+	// 二进制 STL 的长度严格等于 84 + 50 * 三角形数，部分导出工具的二进制文件头也以 "solid" 开头，所以先按长度判断
+	if len(data) >= 84 {
+		count := binary.LittleEndian.Uint32(data[80:84])
+		if uint64(len(data)) == 84+50*uint64(count) {
+			return parseBinarySTL(data[84:], int(count))
+		}
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid")) {
+		return parseASCIISTL(data)
+	}
+
+	return nil, fmt.Errorf("invalid stl file")
+}
+
+func parseBinarySTL(data []byte, count int) (*Mesh, error) {
+	m := &Mesh{Triangles: make([]Triangle, 0, count)}
+	for i := 0; i < count; i++ {
+		// 每个三角形：法线 12 字节 + 3 个顶点 36 字节 + 属性 2 字节
+		rec := data[i*50 : i*50+50]
+		var tri Triangle
+		for v := 0; v < 3; v++ {
+			for c := 0; c < 3; c++ {
+				off := 12 + v*12 + c*4
+				f := float64(math.Float32frombits(binary.LittleEndian.Uint32(rec[off : off+4])))
+				if math.IsNaN(f) || math.IsInf(f, 0) {
+					return nil, fmt.Errorf("invalid stl vertex in triangle %d: %v", i, f)
+				}
+				tri[v][c] = f
+			}
+		}
+		m.Triangles = append(m.Triangles, tri)
+	}
+	return m, nil
+}
+
+func parseASCIISTL(data []byte) (*Mesh, error) {
+	m := &Mesh{}
+	var verts []Vec3
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "vertex":
+			v, err := parseVec3(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid stl vertex: %v", err)
+			}
+			verts = append(verts, v)
+		case "endloop":
+			// 多于三个顶点的环按扇形拆分
+			for i := 1; i+1 < len(verts); i++ {
+				m.Triangles = append(m.Triangles, Triangle{verts[0], verts[i], verts[i+1]})
+			}
+			verts = verts[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read stl failed: %v", err)
+	}
+	return m, nil
+}
+
+// LoadOBJ 解析 OBJ 中的顶点和面，忽略材质与纹理坐标
+func LoadOBJ(r io.Reader) (*Mesh, error) {
+	m := &Mesh{}
+	var verts []Vec3
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			v, err := parseVec3(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid obj vertex: %v", err)
+			}
+			verts = append(verts, v)
+		case "f":
+			var face []Vec3
+			for _, ref := range fields[1:] {
+				// 面的顶点格式可能是 v、v/vt、v//vn 或 v/vt/vn
+				idx, err := strconv.Atoi(strings.SplitN(ref, "/", 2)[0])
+				if err != nil {
+					return nil, fmt.Errorf("invalid obj face: %v", err)
+				}
+				// 负数索引表示相对当前已读取顶点的位置
+				if idx < 0 {
+					idx = len(verts) + idx + 1
+				}
+				if idx < 1 || idx > len(verts) {
+					return nil, fmt.Errorf("obj face index %d out of range", idx)
+				}
+				face = append(face, verts[idx-1])
+			}
+			for i := 1; i+1 < len(face); i++ {
+				m.Triangles = append(m.Triangles, Triangle{face[0], face[i], face[i+1]})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read obj failed: %v", err)
+	}
+	return m, nil
+}
+
+func parseVec3(fields []string) (Vec3, error) {
+	var v Vec3
+	if len(fields) < 3 {
+		return v, fmt.Errorf("expected 3 components, got %d", len(fields))
+	}
+	for i := 0; i < 3; i++ {
+		f, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return v, err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return v, fmt.Errorf("non-finite component %q", fields[i])
+		}
+		v[i] = f
+	}
+	return v, nil
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package mesh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+const asciiSTL = `solid test
+facet normal 0 0 1
+  outer loop
+    vertex 0 0 0
+    vertex 1 0 0
+    vertex 0 1 0
+  endloop
+endfacet
+endsolid test
+`
+
+func binarySTL(tris ...[9]float32) []byte {
+	var buf bytes.Buffer
+	header := make([]byte, 80)
+	copy(header, "solid but actually binary")
+	buf.Write(header)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(tris)))
+	for _, t := range tris {
+		binary.Write(&buf, binary.LittleEndian, [3]float32{}) // 法线
+		binary.Write(&buf, binary.LittleEndian, t)
+		binary.Write(&buf, binary.LittleEndian, uint16(0))
+	}
+	return buf.Bytes()
+}
+
+func TestLoadSTL(t *testing.T) {
+	m, err := LoadSTL(strings.NewReader(asciiSTL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Triangle{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}
+	if len(m.Triangles) != 1 || m.Triangles[0] != want {
+		t.Errorf("ascii: got %v", m.Triangles)
+	}
+
+	// 文件头以 solid 开头的二进制 STL 按长度识别
+	m, err = LoadSTL(bytes.NewReader(binarySTL([9]float32{0, 0, 0, 1, 0, 0, 0, 1, 0})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Triangles) != 1 || m.Triangles[0] != want {
+		t.Errorf("binary: got %v", m.Triangles)
+	}
+
+	if _, err := LoadSTL(strings.NewReader("garbage")); err == nil {
+		t.Error("expected error for invalid stl")
+	}
+}
+
+func TestLoadSTLRejectsNonFinite(t *testing.T) {
+	nan := float32(math.NaN())
+	inf := float32(math.Inf(1))
+	for _, v := range []float32{nan, inf, -inf} {
+		if _, err := LoadSTL(bytes.NewReader(binarySTL([9]float32{0, 0, 0, v, 0, 0, 0, 1, 0}))); err == nil {
+			t.Errorf("binary %v: expected error", v)
+		}
+	}
+
+	ascii := strings.Replace(asciiSTL, "vertex 1 0 0", "vertex NaN 0 0", 1)
+	if _, err := LoadSTL(strings.NewReader(ascii)); err == nil {
+		t.Error("ascii NaN: expected error")
+	}
+}
+
+func TestLoadOBJ(t *testing.T) {
+	obj := `# quad
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+f 1/1 2//1 3/1/1 4
+f -4 -3 -2
+`
+	m, err := LoadOBJ(strings.NewReader(obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 四边形按扇形拆分为两个三角形，加上负数索引的一个
+	if len(m.Triangles) != 3 {
+		t.Fatalf("got %d triangles", len(m.Triangles))
+	}
+	if m.Triangles[2] != (Triangle{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}}) {
+		t.Errorf("negative indices: got %v", m.Triangles[2])
+	}
+
+	min, max := m.Bounds()
+	if min != (Vec3{0, 0, 0}) || max != (Vec3{1, 1, 0}) {
+		t.Errorf("bounds: got %v %v", min, max)
+	}
+}
+
+func TestLoadOBJRejectsInvalid(t *testing.T) {
+	tests := []string{
+		"v 0 0 0\nv 1 0 0\nv NaN 0 0\nf 1 2 3\n",
+		"v 0 0 0\nv 1 0 0\nv Inf 0 0\nf 1 2 3\n",
+		"v 0 0\n",
+		"v 0 0 0\nf 1 2 5\n",
+		"v 0 0 0\nf x y z\n",
+	}
+	for _, obj := range tests {
+		if _, err := LoadOBJ(strings.NewReader(obj)); err == nil {
+			t.Errorf("%q: expected error", obj)
+		}
+	}
+}
+
+func TestIsModelExt(t *testing.T) {
+	for ext, want := range map[string]bool{"stl": true, ".OBJ": true, "png": false} {
+		if got := IsModelExt(ext); got != want {
+			t.Errorf("%q: got %v", ext, got)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package mesh
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+var (
+	baseColor = [3]float64{110, 150, 200}
+	lightDir  = normalize(Vec3{-0.4, 0.6, 0.7})
+)
+
+const ambient = 0.25
+
+// Render 以等轴测视角软件光栅化模型，背景透明
+func Render(m *Mesh, width, height int) (image.Image, error) {
+	if len(m.Triangles) == 0 {
+		return nil, fmt.Errorf("mesh has no triangles")
+	}
+
+	min, max := m.Bounds()
+	center := Vec3{(min[0] + max[0]) / 2, (min[1] + max[1]) / 2, (min[2] + max[2]) / 2}
+
+	// 先绕 Z 轴旋转 45°，再绕 X 轴倾斜 arctan(1/√2)，得到标准等轴测视角（模型 Z 轴朝上）
+	rotZ := math.Pi / 4
+	tilt := math.Atan(1 / math.Sqrt2)
+	project := func(v Vec3) Vec3 {
+		x, y, z := v[0]-center[0], v[1]-center[1], v[2]-center[2]
+		x, y = x*math.Cos(rotZ)-y*math.Sin(rotZ), x*math.Sin(rotZ)+y*math.Cos(rotZ)
+		y, z = y*math.Cos(tilt)-z*math.Sin(tilt), y*math.Sin(tilt)+z*math.Cos(tilt)
+		// 屏幕坐标：x 向右，z 向上，y 为深度（越大越远）
+		return Vec3{x, -z, y}
+	}
+
+	projected := make([]Triangle, len(m.Triangles))
+	pmin := Vec3{math.Inf(1), math.Inf(1), 0}
+	pmax := Vec3{math.Inf(-1), math.Inf(-1), 0}
+	for i, tri := range m.Triangles {
+		for j, v := range tri {
+			p := project(v)
+			projected[i][j] = p
+			pmin[0], pmin[1] = math.Min(pmin[0], p[0]), math.Min(pmin[1], p[1])
+			pmax[0], pmax[1] = math.Max(pmax[0], p[0]), math.Max(pmax[1], p[1])
+		}
+	}
+
+	// 保留 5% 边距，等比缩放到画布中央
+	spanX, spanY := pmax[0]-pmin[0], pmax[1]-pmin[1]
+	if spanX <= 0 && spanY <= 0 {
+		return nil, fmt.Errorf("mesh is degenerate")
+	}
+	scale := 0.9 * math.Min(float64(width)/math.Max(spanX, 1e-9), float64(height)/math.Max(spanY, 1e-9))
+	offX := float64(width)/2 - (pmin[0]+pmax[0])/2*scale
+	offY := float64(height)/2 - (pmin[1]+pmax[1])/2*scale
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	zbuf := make([]float64, width*height)
+	for i := range zbuf {
+		zbuf[i] = math.Inf(1)
+	}
+
+	for i, tri := range projected {
+		// 光照在模型空间中计算，法线取绝对值，兼容顶点顺序不一致的模型
+		orig := m.Triangles[i]
+		n := normalize(cross(sub(orig[1], orig[0]), sub(orig[2], orig[0])))
+		if math.IsNaN(n[0]) {
+			continue
+		}
+		shade := ambient + (1-ambient)*math.Abs(dot(n, lightDir))
+		c := color.RGBA{
+			R: uint8(math.Min(255, baseColor[0]*shade+30)),
+			G: uint8(math.Min(255, baseColor[1]*shade+30)),
+			B: uint8(math.Min(255, baseColor[2]*shade+30)),
+			A: 255,
+		}
+
+		var s [3]Vec3
+		for j := range tri {
+			s[j] = Vec3{tri[j][0]*scale + offX, tri[j][1]*scale + offY, tri[j][2]}
+		}
+		rasterize(img, zbuf, s, c)
+	}
+
+	return img, nil
+}
+
+// 使用重心坐标填充三角形，并进行深度测试
+func rasterize(img *image.RGBA, zbuf []float64, t [3]Vec3, c color.RGBA) {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+
+	minX, maxX, ok := clampSpan(math.Min(t[0][0], math.Min(t[1][0], t[2][0])), math.Max(t[0][0], math.Max(t[1][0], t[2][0])), width)
+	if !ok {
+		return
+	}
+	minY, maxY, ok := clampSpan(math.Min(t[0][1], math.Min(t[1][1], t[2][1])), math.Max(t[0][1], math.Max(t[1][1], t[2][1])), height)
+	if !ok {
+		return
+	}
+
+	area := edge(t[0], t[1], t[2][0], t[2][1])
+	if area == 0 {
+		return
+	}
+
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			w0 := edge(t[1], t[2], px, py) / area
+			w1 := edge(t[2], t[0], px, py) / area
+			w2 := edge(t[0], t[1], px, py) / area
+			if w0 < 0 || w1 < 0 || w2 < 0 {
+				continue
+			}
+			z := w0*t[0][2] + w1*t[1][2] + w2*t[2][2]
+			idx := y*width + x
+			if z >= zbuf[idx] {
+				continue
+			}
+			zbuf[idx] = z
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// 把投影后的坐标范围限制在 [0, size-1] 内，坐标溢出为 NaN 或完全在画布外时返回 false
+func clampSpan(lo, hi float64, size int) (int, int, bool) {
+	if math.IsNaN(lo) || math.IsNaN(hi) {
+		return 0, 0, false
+	}
+	lo = math.Max(0, math.Floor(lo))
+	hi = math.Min(float64(size-1), math.Ceil(hi))
+	if lo > hi {
+		return 0, 0, false
+	}
+	return int(lo), int(hi), true
+}
+
+func edge(a, b Vec3, x, y float64) float64 {
+	return (b[0]-a[0])*(y-a[1]) - (b[1]-a[1])*(x-a[0])
+}
+
+func sub(a, b Vec3) Vec3 {
+	return Vec3{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func cross(a, b Vec3) Vec3 {
+	return Vec3{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+func dot(a, b Vec3) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func normalize(v Vec3) Vec3 {
+	l := math.Sqrt(dot(v, v))
+	return Vec3{v[0] / l, v[1] / l, v[2] / l}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package mesh
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	m := &Mesh{Triangles: []Triangle{
+		{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+		{{0, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+	}}
+	img, err := Render(m, 64, 48)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 64, 48) {
+		t.Errorf("got bounds %v", img.Bounds())
+	}
+
+	opaque := 0
+	rgba := img.(*image.RGBA)
+	for i := 3; i < len(rgba.Pix); i += 4 {
+		if rgba.Pix[i] == 255 {
+			opaque++
+		}
+	}
+	if opaque == 0 {
+		t.Error("nothing was drawn")
+	}
+	// 背景透明
+	if rgba.Pix[3] != 0 {
+		t.Error("corner pixel is not transparent")
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if _, err := Render(&Mesh{}, 10, 10); err == nil {
+		t.Error("expected error for empty mesh")
+	}
+	point := Triangle{{1, 1, 1}, {1, 1, 1}, {1, 1, 1}}
+	if _, err := Render(&Mesh{Triangles: []Triangle{point}}, 10, 10); err == nil {
+		t.Error("expected error for degenerate mesh")
+	}
+}
+
+// 坐标相差过大导致投影溢出时不能越界
+func TestRenderHugeCoordinates(t *testing.T) {
+	m := &Mesh{Triangles: []Triangle{
+		{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+		{{-math.MaxFloat64, 0, 0}, {math.MaxFloat64, 0, 0}, {0, math.MaxFloat64, 0}},
+	}}
+	if _, err := Render(m, 16, 16); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClampSpan(t *testing.T) {
+	tests := []struct {
+		lo, hi   float64
+		min, max int
+		ok       bool
+	}{
+		{1.2, 3.7, 1, 4, true},
+		{-5, 100, 0, 9, true},
+		{math.NaN(), 3, 0, 0, false},
+		{math.Inf(-1), math.Inf(1), 0, 9, true},
+		{20, 30, 0, 0, false},
+		{-30, -20, 0, 0, false},
+	}
+	for _, tt := range tests {
+		min, max, ok := clampSpan(tt.lo, tt.hi, 10)
+		if ok != tt.ok || (ok && (min != tt.min || max != tt.max)) {
+			t.Errorf("clampSpan(%v, %v): got %d %d %v", tt.lo, tt.hi, min, max, ok)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package palette
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nfnt/resize"
+)
+
+// 提取前缩小到的最大边长，足以反映主色且计算量小
+const sampleSize = 64
+
+// 感知距离小于该值的色块合并为一个
+const mergeDistance = 8
+
+type Lab struct {
+	L, A, B float64
+}
+
+type Color struct {
+	RGB    uint32  // 0xRRGGBB
+	Weight float64 // 占全部有效像素的比例
+	Lab    Lab
+}
+
+// Extract 使用中位切分提取最多 k 个主色，按权重从大到小排列
+func Extract(img image.Image, k int) []Color {
+	small := resize.Thumbnail(sampleSize, sampleSize, img, resize.Bilinear)
+	bounds := small.Bounds()
+
+	var pixels [][3]uint8
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := small.At(x, y).RGBA()
+			// 忽略大部分透明的像素
+			if a < 0x8000 {
+				continue
+			}
+			// 反预乘 alpha
+			pixels = append(pixels, [3]uint8{uint8(r * 0xff / a), uint8(g * 0xff / a), uint8(b * 0xff / a)})
+		}
+	}
+	if len(pixels) == 0 {
+		return nil
+	}
+
+	boxes := [][][3]uint8{pixels}
+	for len(boxes) < k {
+		// 选择通道跨度最大的色块进行切分
+		best, bestChannel, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			channel, rng := widestChannel(box)
+			if rng > bestRange {
+				best, bestChannel, bestRange = i, channel, rng
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool { return box[i][bestChannel] < box[j][bestChannel] })
+		mid := len(box) / 2
+		boxes[best] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	var colors []Color
+	for _, box := range boxes {
+		var sum [3]int
+		for _, p := range box {
+			sum[0] += int(p[0])
+			sum[1] += int(p[1])
+			sum[2] += int(p[2])
+		}
+		n := len(box)
+		rgb := uint32(sum[0]/n)<<16 | uint32(sum[1]/n)<<8 | uint32(sum[2]/n)
+		colors = append(colors, Color{RGB: rgb, Weight: float64(n) / float64(len(pixels)), Lab: RGBToLab(rgb)})
+	}
+
+	return merge(colors)
+}
+
+func widestChannel(box [][3]uint8) (int, int) {
+	lo := [3]uint8{255, 255, 255}
+	var hi [3]uint8
+	for _, p := range box {
+		for c := 0; c < 3; c++ {
+			lo[c] = min(lo[c], p[c])
+			hi[c] = max(hi[c], p[c])
+		}
+	}
+	channel := 0
+	for c := 1; c < 3; c++ {
+		if int(hi[c])-int(lo[c]) > int(hi[channel])-int(lo[channel]) {
+			channel = c
+		}
+	}
+	return channel, int(hi[channel]) - int(lo[channel])
+}
+
+// 合并感知上几乎相同的颜色，颜色按权重加权平均
+func merge(colors []Color) []Color {
+	sort.Slice(colors, func(i, j int) bool { return colors[i].Weight > colors[j].Weight })
+
+	var merged []Color
+	for _, c := range colors {
+		found := false
+		for i := range merged {
+			if DeltaE(merged[i].Lab, c.Lab) < mergeDistance {
+				w := merged[i].Weight + c.Weight
+				merged[i].Lab = Lab{
+					L: (merged[i].Lab.L*merged[i].Weight + c.Lab.L*c.Weight) / w,
+					A: (merged[i].Lab.A*merged[i].Weight + c.Lab.A*c.Weight) / w,
+					B: (merged[i].Lab.B*merged[i].Weight + c.Lab.B*c.Weight) / w,
+				}
+				merged[i].Weight = w
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, c)
+		}
+	}
+
+	for i := range merged {
+		merged[i].RGB = LabToRGB(merged[i].Lab)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Weight > merged[j].Weight })
+	return merged
+}
+
+// ParseHex 解析 #RRGGBB、RRGGBB 或 #RGB 形式的颜色
+func ParseHex(s string) (uint32, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return 0, fmt.Errorf("invalid hex color %q", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hex color %q", s)
+	}
+	return uint32(v), nil
+}
+
+// Hex 将颜色格式化为 #rrggbb
+func Hex(rgb uint32) string {
+	return fmt.Sprintf("#%06x", rgb&0xffffff)
+}
+
+// DeltaE 计算 CIE76 色差（Lab 空间的欧氏距离）
+func DeltaE(a, b Lab) float64 {
+	return math.Sqrt((a.L-b.L)*(a.L-b.L) + (a.A-b.A)*(a.A-b.A) + (a.B-b.B)*(a.B-b.B))
+}
+
+// D65 白点
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// RGBToLab 将 sRGB 颜色转换到 CIELAB
+func RGBToLab(rgb uint32) Lab {
+	r := srgbToLinear(float64(rgb>>16&0xff) / 255)
+	g := srgbToLinear(float64(rgb>>8&0xff) / 255)
+	b := srgbToLinear(float64(rgb&0xff) / 255)
+
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / whiteX
+	y := (0.2126729*r + 0.7151522*g + 0.0721750*b) / whiteY
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / whiteZ
+
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return Lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+// LabToRGB 将 CIELAB 颜色转换回 sRGB，超出色域的分量被截断
+func LabToRGB(lab Lab) uint32 {
+	fy := (lab.L + 16) / 116
+	fx := fy + lab.A/500
+	fz := fy - lab.B/200
+
+	x := labFInv(fx) * whiteX
+	y := labFInv(fy) * whiteY
+	z := labFInv(fz) * whiteZ
+
+	r := linearToSRGB(3.2404542*x - 1.5371385*y - 0.4985314*z)
+	g := linearToSRGB(-0.9692660*x + 1.8760108*y + 0.0415560*z)
+	b := linearToSRGB(0.0556434*x - 0.2040259*y + 1.0572252*z)
+
+	return uint32(r)<<16 | uint32(g)<<8 | uint32(b)
+}
+
+func srgbToLinear(c float64) float64 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(c float64) uint8 {
+	if c <= 0.0031308 {
+		c *= 12.92
+	} else {
+		c = 1.055*math.Pow(c, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(math.Max(0, math.Min(1, c)) * 255))
+}
+
+func labF(t float64) float64 {
+	if t > 216.0/24389 {
+		return math.Cbrt(t)
+	}
+	return (24389.0/27*t + 16) / 116
+}
+
+func labFInv(t float64) float64 {
+	if t3 := t * t * t; t3 > 216.0/24389 {
+		return t3
+	}
+	return (116*t - 16) / (24389.0 / 27)
+}
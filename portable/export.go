@@ -0,0 +1,331 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package portable
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"synapforest/blobstore"
+	"synapforest/database"
+	"synapforest/database/dbcommon"
+	"synapforest/database/folderdb"
+	"synapforest/database/itemdb"
+	"synapforest/database/tagdb"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// ItemList 单页的数量上限
+const listPageSize = 1000
+
+// Filter 选择导出的 item，条件同 item 列表。Subtree 不为空时导出该文件夹及其子文件夹中的 item，
+// 包中的文件夹只包含这棵子树，子树的根位于顶层
+type Filter struct {
+	Exts          []string
+	Keyword       *string
+	Tags          []uuid.UUID
+	Folders       []uuid.UUID
+	Color         *string
+	ColorDistance *float64
+	Area          *itemdb.GeoFilter
+	Subtree       *uuid.UUID
+}
+
+// Options 导出选项
+type Options struct {
+	Vectors bool // 同时导出向量
+}
+
+// Export 把符合条件的 item 导出到 dst。先写入临时文件，完成后再重命名
+func Export(ctx context.Context, lib *database.Library, filter Filter, opts Options, dst string, progress func(done int, total int)) (*Manifest, error) {
+	if progress == nil {
+		progress = func(int, int) {}
+	}
+
+	// 子树中的文件夹，为 nil 时不限制
+	var tree map[uuid.UUID]bool
+	var treeIDs []uuid.UUID
+	top := uuid.Nil
+	folders := filter.Folders
+	if filter.Subtree != nil && *filter.Subtree != uuid.Nil {
+		top = *filter.Subtree
+		ids, err := folderdb.GetFolderTreeIDs(lib.DB, top)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query folder tree: %v", err)
+		}
+		tree = map[uuid.UUID]bool{}
+		for _, id := range ids {
+			tree[id] = true
+		}
+		treeIDs = ids
+		folders = append(append([]uuid.UUID{}, folders...), ids...)
+	}
+
+	items, err := listItems(lib.DB, filter, folders)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Format:    FormatVersion,
+		CreatedAt: time.Now(),
+		Vectors:   opts.Vectors,
+		Folders:   []Node{},
+		Tags:      []Node{},
+		Items:     []Item{},
+		Missing:   []string{},
+	}
+
+	folderNodes := newNodeSet()
+	tagNodes := newNodeSet()
+	// 空文件夹也属于导出的子树
+	for _, id := range treeIDs {
+		if err := folderNodes.addFolder(lib.DB, id, top); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.Create(dst + ".partial")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		file.Close()
+		os.Remove(dst + ".partial")
+	}()
+	zw := zip.NewWriter(file)
+
+	for i, it := range items {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		entry := Item{
+			ID:         it.ID,
+			Name:       it.Name,
+			Ext:        it.Ext,
+			Size:       it.Size,
+			Url:        it.Url,
+			Annotation: it.Annotation,
+			Star:       it.Star,
+			CreatedAt:  it.CreatedAt,
+			ImportedAt: it.ImportedAt,
+			Tags:       []uuid.UUID{},
+			Folders:    []uuid.UUID{},
+		}
+		entry.File = filePath(entry)
+
+		err := writeRawFile(zw, lib.Blobs, it, entry.File)
+		if errors.Is(err, blobstore.ErrNotExist) {
+			manifest.Missing = append(manifest.Missing, it.ID)
+			progress(i+1, len(items))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %v", it.ID, err)
+		}
+
+		for _, folder := range it.Folders {
+			if folder.ID == uuid.Nil || (tree != nil && !tree[folder.ID]) {
+				continue
+			}
+			if err := folderNodes.addFolder(lib.DB, folder.ID, top); err != nil {
+				return nil, err
+			}
+			entry.Folders = append(entry.Folders, folder.ID)
+		}
+		for _, tag := range it.Tags {
+			if err := tagNodes.addTag(lib.DB, tag.ID); err != nil {
+				return nil, err
+			}
+			entry.Tags = append(entry.Tags, tag.ID)
+		}
+
+		if opts.Vectors {
+			ok, err := writeVector(zw, lib.VectorDB, it.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to export vector of %s: %v", it.ID, err)
+			}
+			if ok {
+				entry.Vector = vectorPath(it.ID)
+			}
+		}
+
+		manifest.Items = append(manifest.Items, entry)
+		progress(i+1, len(items))
+	}
+
+	manifest.Folders = folderNodes.nodes
+	manifest.Tags = tagNodes.nodes
+
+	w, err := zw.Create(manifestName)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(dst+".partial", dst); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// 按页读取全部符合条件的 item，同时属于多个筛选文件夹或标签的 item 只保留一次
+func listItems(db *gorm.DB, filter Filter, folders []uuid.UUID) ([]dbcommon.Item, error) {
+	pageSize := listPageSize
+	seen := map[string]bool{}
+	var items []dbcommon.Item
+	for page := 0; ; page++ {
+		batch, err := itemdb.ItemList(db, nil, nil, &page, &pageSize, filter.Exts, filter.Keyword, filter.Tags, folders, filter.Color, filter.ColorDistance, filter.Area)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range batch {
+			if !seen[item.ID] {
+				seen[item.ID] = true
+				items = append(items, item)
+			}
+		}
+		if len(batch) < pageSize {
+			return items, nil
+		}
+	}
+}
+
+func writeRawFile(zw *zip.Writer, store blobstore.Store, item dbcommon.Item, name string) error {
+	r, err := store.Get(itemdb.RawFileKey(item.ID, item.Name, item.Ext))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// 图片等原始文件大多已经压缩过，直接存储
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: item.ModifiedAt})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func writeVector(zw *zip.Writer, vectorDB *gorm.DB, itemID string) (bool, error) {
+	var vec dbcommon.ItemVector
+	err := vectorDB.First(&vec, "item_id = ?", itemID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	w, err := zw.Create(vectorPath(itemID))
+	if err != nil {
+		return false, err
+	}
+	_, err = w.Write(vec.ImageVec)
+	return err == nil, err
+}
+
+// 按加入顺序保存的文件夹或标签，先加入祖先，保证父级在子级之前
+type nodeSet struct {
+	nodes []Node
+	seen  map[uuid.UUID]bool
+}
+
+func newNodeSet() *nodeSet {
+	return &nodeSet{nodes: []Node{}, seen: map[uuid.UUID]bool{}}
+}
+
+// 加入文件夹及其祖先，top 及以上的祖先不导出，top 成为顶层文件夹
+func (s *nodeSet) addFolder(db *gorm.DB, id uuid.UUID, top uuid.UUID) error {
+	if s.seen[id] {
+		return nil
+	}
+	path, err := folderdb.GetFolderPath(db, id)
+	if err != nil {
+		return fmt.Errorf("failed to query folder %s: %v", id, err)
+	}
+	if top != uuid.Nil {
+		for i, folder := range path {
+			if folder.ID == top {
+				path = path[i:]
+				break
+			}
+		}
+	}
+	for i, folder := range path {
+		parent := folder.ParentID
+		if i == 0 {
+			parent = uuid.Nil
+		}
+		s.add(Node{
+			ID:          folder.ID,
+			Parent:      parent,
+			Name:        folder.Name,
+			Description: folder.Description,
+			Icon:        folder.Icon,
+			IconColor:   folder.IconColor,
+			IsExpand:    folder.IsExpand,
+		})
+	}
+	return nil
+}
+
+func (s *nodeSet) addTag(db *gorm.DB, id uuid.UUID) error {
+	if s.seen[id] {
+		return nil
+	}
+	path, err := tagdb.GetTagPath(db, id)
+	if err != nil {
+		return fmt.Errorf("failed to query tag %s: %v", id, err)
+	}
+	for i, tag := range path {
+		parent := tag.ParentID
+		if i == 0 {
+			parent = uuid.Nil
+		}
+		s.add(Node{
+			ID:          tag.ID,
+			Parent:      parent,
+			Name:        tag.Name,
+			Description: tag.Description,
+			Icon:        tag.Icon,
+			IconColor:   tag.IconColor,
+			IsExpand:    tag.IsExpand,
+		})
+	}
+	return nil
+}
+
+func (s *nodeSet) add(node Node) {
+	if !s.seen[node.ID] {
+		s.seen[node.ID] = true
+		s.nodes = append(s.nodes, node)
+	}
+}
@@ -0,0 +1,431 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package portable
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"synapforest/database"
+	"synapforest/database/auditdb"
+	"synapforest/database/dbcommon"
+	"synapforest/database/itemdb"
+	"synapforest/vector"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// 每批解压并导入的 item 数量
+const importBatchSize = 32
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Folder uuid.UUID // 包中的顶层文件夹放在该文件夹下，默认为 Root
+	Tag    uuid.UUID // 包中的顶层标签放在该标签下，默认为顶层
+}
+
+// ImportResult 导入的结果
+type ImportResult struct {
+	Items    int             `json:"items"`
+	Imported int             `json:"imported"` // 新导入的 item
+	Merged   int             `json:"merged"`   // 资料库中已有的 item，只合并文件夹和标签
+	Failed   []ImportFailure `json:"failed"`
+	Folders  MergeCount      `json:"folders"`
+	Tags     MergeCount      `json:"tags"`
+	Vectors  int             `json:"vectors"` // 导入的向量，已有向量的 item 保留原向量
+}
+
+// MergeCount 新建和合并到已有记录的文件夹或标签数量
+type MergeCount struct {
+	Created int `json:"created"`
+	Reused  int `json:"reused"`
+}
+
+// ImportFailure 导入失败的 item
+type ImportFailure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// Import 把导出包合并到资料库。文件夹和标签按 UUID 匹配，其次按同一父级下的名称匹配，
+// 都没有时保留原 UUID 新建。资料库中已有的 item 只添加包中的文件夹和标签
+func Import(ctx context.Context, lib *database.Library, packagePath string, opts ImportOptions, progress func(done int, total int)) (*ImportResult, error) {
+	if progress == nil {
+		progress = func(int, int) {}
+	}
+
+	zr, err := zip.OpenReader(packagePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid package: %v", err)
+	}
+	defer zr.Close()
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	manifest, err := readManifest(files[manifestName])
+	if err != nil {
+		return nil, err
+	}
+
+	// 以 ctx 中的发起方记录审计日志
+	db := auditdb.As(lib.DB, auditdb.ActorFrom(ctx))
+
+	if !exists(db, &dbcommon.Folder{}, opts.Folder) {
+		return nil, fmt.Errorf("folder %s not found", opts.Folder)
+	}
+	if opts.Tag != uuid.Nil && !exists(db, &dbcommon.Tag{}, opts.Tag) {
+		return nil, fmt.Errorf("tag %s not found", opts.Tag)
+	}
+
+	result := &ImportResult{Items: len(manifest.Items), Failed: []ImportFailure{}}
+
+	var folders, tags map[uuid.UUID]uuid.UUID
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		folders, err = mergeNodes(tx, &dbcommon.Folder{}, manifest.Folders, opts.Folder, createFolder, &result.Folders)
+		if err != nil {
+			return fmt.Errorf("failed to merge folders: %v", err)
+		}
+		tags, err = mergeNodes(tx, &dbcommon.Tag{}, manifest.Tags, opts.Tag, createTag, &result.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to merge tags: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stagingDir := filepath.Join(lib.Dir, "staging")
+	if err := os.MkdirAll(stagingDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp(stagingDir, "package-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for start := 0; start < len(manifest.Items); start += importBatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(start+importBatchSize, len(manifest.Items))
+
+		var entries []itemdb.ImportEntry
+		var pending []Item
+		for i, item := range manifest.Items[start:end] {
+			entry := itemdb.ImportEntry{
+				Tags:    remap(item.Tags, tags),
+				Folders: remap(item.Folders, folders),
+			}
+
+			err := validateItem(item)
+			merged := false
+			if err == nil {
+				merged, err = mergeItem(db, item, entry.Tags, entry.Folders)
+			}
+			if err == nil && !merged {
+				entry.Path, err = extractFile(files[item.File], item, filepath.Join(tmpDir, strconv.Itoa(start+i)))
+			}
+			switch {
+			case err != nil:
+				result.Failed = append(result.Failed, ImportFailure{ID: item.ID, Error: err.Error()})
+				continue
+			case merged:
+				result.Merged++
+				result.Vectors += importVector(lib.VectorDB, files, item)
+				continue
+			}
+
+			entry.Name = &item.Name
+			entry.Url = &item.Url
+			entry.Annotation = &item.Annotation
+			entry.Star = &item.Star
+			entry.CreatedAt = &item.CreatedAt
+			entries = append(entries, entry)
+			pending = append(pending, item)
+		}
+
+		for i, err := range itemdb.AddItems(db, entries) {
+			if err != nil {
+				result.Failed = append(result.Failed, ImportFailure{ID: pending[i].ID, Error: err.Error()})
+				continue
+			}
+			result.Imported++
+			result.Vectors += importVector(lib.VectorDB, files, pending[i])
+		}
+		progress(end, len(manifest.Items))
+	}
+
+	return result, nil
+}
+
+func readManifest(f *zip.File) (*Manifest, error) {
+	if f == nil {
+		return nil, fmt.Errorf("package has no manifest")
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if manifest.Format != FormatVersion {
+		return nil, fmt.Errorf("unsupported package format %d", manifest.Format)
+	}
+	return &manifest, nil
+}
+
+func exists(db *gorm.DB, model interface{}, id uuid.UUID) bool {
+	var count int64
+	return db.Model(model).Where("id = ?", id).Count(&count).Error == nil && count > 0
+}
+
+// 按包中的顺序合并文件夹或标签，返回包中 UUID 到资料库中 UUID 的映射
+func mergeNodes(db *gorm.DB, model interface{}, nodes []Node, top uuid.UUID, create func(db *gorm.DB, node Node, parent uuid.UUID) error, count *MergeCount) (map[uuid.UUID]uuid.UUID, error) {
+	mapped := map[uuid.UUID]uuid.UUID{}
+	for _, node := range nodes {
+		if node.ID == uuid.Nil {
+			continue
+		}
+		// 父级不在包中时放在顶层
+		parent := top
+		if p, ok := mapped[node.Parent]; ok {
+			parent = p
+		}
+
+		// 只合并到未删除的文件夹或标签
+		var ids []uuid.UUID
+		if err := db.Model(model).Where("id = ?", node.ID).Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			err := db.Model(model).Where("parent_id = ? AND name = ? AND id != ?", parent, node.Name, uuid.Nil).
+				Order("created_at ASC").Limit(1).Pluck("id", &ids).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(ids) > 0 {
+			mapped[node.ID] = ids[0]
+			count.Reused++
+			continue
+		}
+
+		// ID 仍被已删除的记录占用时使用新的 ID
+		id := node.ID
+		var deleted int64
+		if err := db.Unscoped().Model(model).Where("id = ?", id).Count(&deleted).Error; err != nil {
+			return nil, err
+		}
+		if deleted > 0 {
+			newID, err := uuid.NewV4()
+			if err != nil {
+				return nil, err
+			}
+			node.ID = newID
+		}
+
+		if err := create(db, node, parent); err != nil {
+			return nil, err
+		}
+		mapped[id] = node.ID
+		count.Created++
+	}
+	return mapped, nil
+}
+
+func createFolder(db *gorm.DB, node Node, parent uuid.UUID) error {
+	now := time.Now()
+	folder := dbcommon.Folder{
+		ID:          node.ID,
+		CreatedAt:   now,
+		ModifiedAt:  now,
+		ParentID:    parent,
+		Name:        node.Name,
+		Description: node.Description,
+		Icon:        node.Icon,
+		IconColor:   node.IconColor,
+		IsExpand:    node.IsExpand,
+	}
+	if err := db.Create(&folder).Error; err != nil {
+		return err
+	}
+	return auditdb.Record(db, auditdb.Change{
+		Operation: auditdb.FolderCreate,
+		Targets:   auditdb.UUIDs(folder.ID),
+		After:     auditdb.FolderNode(folder),
+	})
+}
+
+func createTag(db *gorm.DB, node Node, parent uuid.UUID) error {
+	now := time.Now()
+	tag := dbcommon.Tag{
+		ID:          node.ID,
+		CreatedAt:   now,
+		ModifiedAt:  now,
+		ParentID:    parent,
+		Name:        node.Name,
+		Description: node.Description,
+		Icon:        node.Icon,
+		IconColor:   node.IconColor,
+		IsExpand:    node.IsExpand,
+	}
+	if err := db.Create(&tag).Error; err != nil {
+		return err
+	}
+	return auditdb.Record(db, auditdb.Change{
+		Operation: auditdb.TagCreate,
+		Targets:   auditdb.UUIDs(tag.ID),
+		After:     auditdb.TagNode(tag),
+	})
+}
+
+// 包中不存在的文件夹和标签被忽略
+func remap(ids []uuid.UUID, mapped map[uuid.UUID]uuid.UUID) []uuid.UUID {
+	var result []uuid.UUID
+	for _, id := range ids {
+		if m, ok := mapped[id]; ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// 检查清单中的 item，名称和扩展名会用作文件路径，不能为空或包含路径分隔符和 ..
+func validateItem(item Item) error {
+	if !itemIDPattern.MatchString(item.ID) {
+		return fmt.Errorf("invalid item id")
+	}
+	if !validPathPart(item.Name) {
+		return fmt.Errorf("invalid item name %q", item.Name)
+	}
+	if !validPathPart(item.Ext) {
+		return fmt.Errorf("invalid item ext %q", item.Ext)
+	}
+	return nil
+}
+
+func validPathPart(s string) bool {
+	return s != "" && !strings.ContainsAny(s, `/\`) && !strings.Contains(s, "..")
+}
+
+// 资料库中已有该 item 时添加文件夹和标签，返回是否已有
+func mergeItem(db *gorm.DB, item Item, tags []uuid.UUID, folders []uuid.UUID) (bool, error) {
+	var existing dbcommon.Item
+	err := db.Unscoped().Select("id").First(&existing, "id = ?", item.ID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := database.AddTagsForItems(db, []string{existing.ID}, tags); err != nil {
+		return true, fmt.Errorf("failed to append tag: %v", err)
+	}
+	if err := database.AddFolderForItems(db, []string{existing.ID}, folders); err != nil {
+		return true, fmt.Errorf("failed to append folder: %v", err)
+	}
+	return true, nil
+}
+
+// 解压原始文件，大小和 SHA256 必须与 item 一致
+func extractFile(f *zip.File, item Item, name string) (string, error) {
+	if f == nil {
+		return "", fmt.Errorf("%s not found in package", item.File)
+	}
+	if f.UncompressedSize64 != item.Size {
+		return "", fmt.Errorf("size mismatch")
+	}
+	r, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	// 保留扩展名，导入时部分解码器按扩展名判断格式
+	if item.Ext != "" {
+		name += "." + item.Ext
+	}
+	out, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(out, hash), io.LimitReader(r, int64(item.Size)+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && uint64(n) != item.Size {
+		err = fmt.Errorf("size mismatch")
+	}
+	if err == nil && hex.EncodeToString(hash.Sum(nil)) != item.ID {
+		err = fmt.Errorf("checksum mismatch")
+	}
+	if err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	return name, nil
+}
+
+// 导入 item 的向量，item 已有向量或包中的向量无效时跳过，返回导入的数量
+func importVector(vectorDB *gorm.DB, files map[string]*zip.File, item Item) int {
+	f := files[item.Vector]
+	if item.Vector == "" || f == nil {
+		return 0
+	}
+
+	var count int64
+	if err := vectorDB.Model(&dbcommon.ItemVector{}).Where("item_id = ?", item.ID).Count(&count).Error; err != nil || count > 0 {
+		return 0
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return 0
+	}
+	data, err := io.ReadAll(io.LimitReader(r, 1<<20))
+	r.Close()
+	if err != nil {
+		return 0
+	}
+	if _, err := vector.DeserializeVector(data); err != nil {
+		return 0
+	}
+
+	now := time.Now()
+	if err := vectorDB.Create(&dbcommon.ItemVector{ItemID: item.ID, ImageVec: data, CreatedAt: now, ModifiedAt: now}).Error; err != nil {
+		return 0
+	}
+	return 1
+}
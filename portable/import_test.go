@@ -0,0 +1,220 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package portable
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"synapforest/database"
+	"synapforest/database/dbcommon"
+
+	"github.com/gofrs/uuid"
+)
+
+func openLibrary(t *testing.T) *database.Library {
+	t.Helper()
+	lib, err := database.Open(filepath.Join(t.TempDir(), "lib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lib.Close() })
+	return lib
+}
+
+func pngBytes(t *testing.T, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := 0; i < 64; i++ {
+		img.Set(i%8, i/8, c)
+	}
+	path := filepath.Join(t.TempDir(), "a.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	data, _ := os.ReadFile(path)
+	return data
+}
+
+// 按清单和文件内容写出导出包，item 的 ID、大小和 File 由内容计算
+func writePackage(t *testing.T, manifest Manifest, contents [][]byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pkg.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+
+	manifest.Format = FormatVersion
+	for i := range manifest.Items {
+		sum := sha256.Sum256(contents[i])
+		item := &manifest.Items[i]
+		item.ID = hex.EncodeToString(sum[:])
+		item.Size = uint64(len(contents[i]))
+		item.File = fileDir + "/" + item.ID
+		w, err := zw.Create(item.File)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(contents[i])
+	}
+	w, err := zw.Create(manifestName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewEncoder(w).Encode(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return path
+}
+
+func TestImportRejectsUnsafeNames(t *testing.T) {
+	lib := openLibrary(t)
+
+	names := []struct{ name, ext string }{
+		{"../../../escaped", "png"},
+		{`..\escaped`, "png"},
+		{"a/b", "png"},
+		{"", "png"},
+		{"ok", "../png"},
+		{"ok", ""},
+	}
+	manifest := Manifest{}
+	var contents [][]byte
+	for i, n := range names {
+		manifest.Items = append(manifest.Items, Item{Name: n.name, Ext: n.ext, CreatedAt: time.Now()})
+		contents = append(contents, pngBytes(t, color.RGBA{uint8(i * 20), 0, 0, 255}))
+	}
+
+	result, err := Import(context.Background(), lib, writePackage(t, manifest, contents), ImportOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 0 || len(result.Failed) != len(names) {
+		t.Errorf("got %d imported, %d failed: %+v", result.Imported, len(result.Failed), result.Failed)
+	}
+
+	var count int64
+	lib.DB.Model(&dbcommon.Item{}).Count(&count)
+	if count != 0 {
+		t.Errorf("got %d items in library", count)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(lib.Dir), "escaped.png")); err == nil {
+		t.Error("file written outside library")
+	}
+}
+
+func TestImportSkipsDeletedFolders(t *testing.T) {
+	lib := openLibrary(t)
+
+	// 资料库中有同 ID 但已删除的文件夹
+	deleted := dbcommon.Folder{ID: uuid.Must(uuid.NewV4()), Name: "Trips", CreatedAt: time.Now(), ModifiedAt: time.Now()}
+	if err := lib.DB.Create(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := lib.DB.Delete(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := Manifest{
+		Folders: []Node{{ID: deleted.ID, Name: "Trips"}},
+		Items:   []Item{{Name: "photo", Ext: "png", Folders: []uuid.UUID{deleted.ID}, CreatedAt: time.Now()}},
+	}
+	result, err := Import(context.Background(), lib, writePackage(t, manifest, [][]byte{pngBytes(t, color.White)}), ImportOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 1 || result.Folders.Created != 1 || result.Folders.Reused != 0 {
+		t.Fatalf("got %+v", result)
+	}
+
+	var item dbcommon.Item
+	if err := lib.DB.Preload("Folders").First(&item, "id = ?", manifest.Items[0].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "photo" || len(item.Folders) != 1 {
+		t.Fatalf("got item %q in %d folders", item.Name, len(item.Folders))
+	}
+	if item.Folders[0].ID == deleted.ID || item.Folders[0].Name != "Trips" {
+		t.Errorf("item attached to folder %v %q", item.Folders[0].ID, item.Folders[0].Name)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := openLibrary(t)
+	manifest := Manifest{
+		Folders: []Node{{ID: uuid.Must(uuid.NewV4()), Name: "Album"}},
+		Tags:    []Node{{ID: uuid.Must(uuid.NewV4()), Name: "red"}},
+	}
+	manifest.Items = []Item{{
+		Name: "red", Ext: "png", Star: 4, Annotation: "note", CreatedAt: time.Now(),
+		Folders: []uuid.UUID{manifest.Folders[0].ID}, Tags: []uuid.UUID{manifest.Tags[0].ID},
+	}}
+	if _, err := Import(context.Background(), src, writePackage(t, manifest, [][]byte{pngBytes(t, color.RGBA{255, 0, 0, 255})}), ImportOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg := filepath.Join(t.TempDir(), "export.zip")
+	exported, err := Export(context.Background(), src, Filter{}, Options{}, pkg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Items) != 1 || len(exported.Folders) != 1 || len(exported.Tags) != 1 {
+		t.Fatalf("got manifest %+v", exported)
+	}
+
+	dst := openLibrary(t)
+	result, err := Import(context.Background(), dst, pkg, ImportOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 1 || len(result.Failed) != 0 {
+		t.Fatalf("got %+v", result)
+	}
+
+	var item dbcommon.Item
+	if err := dst.DB.Preload("Folders").Preload("Tags").First(&item, "id = ?", exported.Items[0].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "red" || item.Star != 4 || item.Annotation != "note" || len(item.Folders) != 1 || len(item.Tags) != 1 {
+		t.Errorf("got %+v", item)
+	}
+
+	// 再次导入只合并
+	result, err = Import(context.Background(), dst, pkg, ImportOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Merged != 1 || result.Folders.Reused != 1 || result.Tags.Reused != 1 {
+		t.Errorf("second import: got %+v", result)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+
+// Package portable 把资料库中的一部分 item 导出为独立的 zip 包，并合并导入到其他资料库。
+// 包中 files/ 下是原始文件，vectors/ 下是可选的向量，manifest.json 记录 item、
+// 文件夹和标签的层级。导入时按 UUID 或同一父级下的名称合并文件夹和标签
+package portable
+
+import (
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// FormatVersion 导出包格式版本
+const FormatVersion = 1
+
+// 包中的路径
+const (
+	manifestName = "manifest.json"
+	fileDir      = "files"
+	vectorDir    = "vectors"
+)
+
+// Manifest 导出包的内容清单
+type Manifest struct {
+	Format    int       `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	Vectors   bool      `json:"vectors"` // 是否包含向量
+	Folders   []Node    `json:"folders"` // 父级在子级之前
+	Tags      []Node    `json:"tags"`    // 父级在子级之前
+	Items     []Item    `json:"items"`
+	Missing   []string  `json:"missing"` // 原始文件不存在而未导出的 item
+}
+
+// Node 文件夹或标签，Parent 为空 UUID 时位于顶层
+type Node struct {
+	ID          uuid.UUID `json:"id"`
+	Parent      uuid.UUID `json:"parent"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Icon        uint32    `json:"icon"`
+	IconColor   uint32    `json:"icon_color"`
+	IsExpand    bool      `json:"is_expand"`
+}
+
+// Item 导出的 item，File 和 Vector 为包中的路径
+type Item struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Ext        string      `json:"ext"`
+	Size       uint64      `json:"size"`
+	Url        string      `json:"url"`
+	Annotation string      `json:"annotation"`
+	Star       uint8       `json:"star"`
+	CreatedAt  time.Time   `json:"created_at"`
+	ImportedAt time.Time   `json:"imported_at"`
+	Tags       []uuid.UUID `json:"tags"`
+	Folders    []uuid.UUID `json:"folders"`
+	File       string      `json:"file"`
+	Vector     string      `json:"vector,omitempty"`
+}
+
+// Dir 资料库保存导出包和上传的导入包的目录
+func Dir(library string) string {
+	return filepath.Join(library, "packages")
+}
+
+// PackagePath 目录中指定导出包的路径，导出包以 UUID 命名
+func PackagePath(dir string, id uuid.UUID) string {
+	return filepath.Join(dir, id.String()+".zip")
+}
+
+var itemIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+func filePath(item Item) string {
+	if item.Ext == "" {
+		return fileDir + "/" + item.ID
+	}
+	return fileDir + "/" + item.ID + "." + item.Ext
+}
+
+func vectorPath(itemID string) string {
+	return vectorDir + "/" + itemID + ".json"
+}
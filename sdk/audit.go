@@ -0,0 +1,29 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package sdk
+
+import "synapforest/database/auditdb"
+
+// AuditEntry 审计日志中的一次修改
+type AuditEntry = auditdb.Entry
+
+// AuditFilter 审计日志的查询条件，为空的条件不限制
+type AuditFilter = auditdb.Filter
+
+// AuditLog 按条件查询审计日志，最新的在前，同时返回符合条件的总数
+func (l *Library) AuditLog(filter AuditFilter) ([]AuditEntry, int64, error) {
+	if err := l.acquire(); err != nil {
+		return nil, 0, err
+	}
+	defer l.lib.Release()
+	return auditdb.List(l.lib.DB, filter)
+}
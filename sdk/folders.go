@@ -0,0 +1,219 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package sdk
+
+import (
+	"errors"
+	"fmt"
+
+	"synapforest/database"
+	"synapforest/database/dbcommon"
+	"synapforest/database/folderdb"
+	"synapforest/database/tagdb"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// Folder 文件夹。顶层文件夹的 Parent 为 RootFolder
+type Folder struct {
+	ID          uuid.UUID
+	Parent      uuid.UUID
+	Name        string
+	Description string
+	Icon        uint32
+	IconColor   uint32
+}
+
+// Tag 标签。顶层标签的 Parent 为 uuid.Nil
+type Tag struct {
+	ID          uuid.UUID
+	Parent      uuid.UUID
+	Name        string
+	Description string
+	Icon        uint32
+	IconColor   uint32
+}
+
+// NodeOptions 新建文件夹或标签的选项
+type NodeOptions struct {
+	Parent      uuid.UUID // 为 uuid.Nil 时放在顶层
+	Description string
+	Icon        uint32
+	IconColor   uint32
+	Expand      bool // 在界面中默认展开
+}
+
+// CreateFolder 新建文件夹
+func (l *Library) CreateFolder(name string, opts NodeOptions) (*Folder, error) {
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.lib.Release()
+
+	if err := l.nodeExists(&dbcommon.Folder{}, "folder", opts.Parent); err != nil {
+		return nil, err
+	}
+	f, err := folderdb.CreateFolder(l.db(), &name, opts.Description, opts.Icon, opts.IconColor, opts.Parent, opts.Expand)
+	if err != nil {
+		return nil, err
+	}
+	return &Folder{ID: f.ID, Parent: f.ParentID, Name: f.Name, Description: f.Description, Icon: f.Icon, IconColor: f.IconColor}, nil
+}
+
+// Folders 列出全部文件夹，不包括根文件夹
+func (l *Library) Folders() ([]Folder, error) {
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.lib.Release()
+
+	var records []dbcommon.Folder
+	if err := l.lib.DB.Where("id != ?", RootFolder).Order("name ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	folders := make([]Folder, 0, len(records))
+	for _, f := range records {
+		folders = append(folders, Folder{ID: f.ID, Parent: f.ParentID, Name: f.Name, Description: f.Description, Icon: f.Icon, IconColor: f.IconColor})
+	}
+	return folders, nil
+}
+
+// DeleteFolder 删除文件夹及其子文件夹，其中的 item 保留
+func (l *Library) DeleteFolder(id uuid.UUID) error {
+	if err := l.acquire(); err != nil {
+		return err
+	}
+	defer l.lib.Release()
+
+	if id == RootFolder {
+		return fmt.Errorf("the root folder cannot be deleted")
+	}
+	if err := l.nodeExists(&dbcommon.Folder{}, "folder", id); err != nil {
+		return err
+	}
+	return folderdb.DeleteFolder(l.db(), id, nil, nil)
+}
+
+// AddToFolder 把 item 加入文件夹
+func (l *Library) AddToFolder(itemIDs []string, folderIDs ...uuid.UUID) error {
+	if err := l.acquire(); err != nil {
+		return err
+	}
+	defer l.lib.Release()
+
+	for _, id := range folderIDs {
+		if err := l.nodeExists(&dbcommon.Folder{}, "folder", id); err != nil {
+			return err
+		}
+	}
+	return database.AddFolderForItems(l.db(), itemIDs, folderIDs)
+}
+
+// RemoveFromFolder 把 item 移出文件夹
+func (l *Library) RemoveFromFolder(itemIDs []string, folderID uuid.UUID) error {
+	if err := l.acquire(); err != nil {
+		return err
+	}
+	defer l.lib.Release()
+	return database.RemoveFoldersForItems(l.db(), itemIDs, folderID)
+}
+
+// CreateTag 新建标签
+func (l *Library) CreateTag(name string, opts NodeOptions) (*Tag, error) {
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.lib.Release()
+
+	if err := l.nodeExists(&dbcommon.Tag{}, "tag", opts.Parent); err != nil {
+		return nil, err
+	}
+	t, err := tagdb.CreateTag(l.db(), &name, opts.Description, opts.Icon, opts.IconColor, opts.Parent, opts.Expand)
+	if err != nil {
+		return nil, err
+	}
+	return &Tag{ID: t.ID, Parent: t.ParentID, Name: t.Name, Description: t.Description, Icon: t.Icon, IconColor: t.IconColor}, nil
+}
+
+// Tags 列出全部标签
+func (l *Library) Tags() ([]Tag, error) {
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.lib.Release()
+
+	var records []dbcommon.Tag
+	if err := l.lib.DB.Order("name ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	tags := make([]Tag, 0, len(records))
+	for _, t := range records {
+		tags = append(tags, Tag{ID: t.ID, Parent: t.ParentID, Name: t.Name, Description: t.Description, Icon: t.Icon, IconColor: t.IconColor})
+	}
+	return tags, nil
+}
+
+// DeleteTag 删除标签及其子标签，带有标签的 item 保留
+func (l *Library) DeleteTag(id uuid.UUID) error {
+	if err := l.acquire(); err != nil {
+		return err
+	}
+	defer l.lib.Release()
+
+	if id == uuid.Nil {
+		return fmt.Errorf("tag %s: %w", id, ErrNotFound)
+	}
+	if err := l.nodeExists(&dbcommon.Tag{}, "tag", id); err != nil {
+		return err
+	}
+	return tagdb.DeleteTag(l.db(), id, nil, nil)
+}
+
+// Tag 给 item 添加标签，已有的标签不重复添加
+func (l *Library) Tag(itemIDs []string, tagIDs ...uuid.UUID) error {
+	if err := l.acquire(); err != nil {
+		return err
+	}
+	defer l.lib.Release()
+
+	for _, id := range tagIDs {
+		if id == uuid.Nil {
+			return fmt.Errorf("tag %s: %w", id, ErrNotFound)
+		}
+		if err := l.nodeExists(&dbcommon.Tag{}, "tag", id); err != nil {
+			return err
+		}
+	}
+	return database.AddTagsForItems(l.db(), itemIDs, tagIDs)
+}
+
+// Untag 移除 item 的标签
+func (l *Library) Untag(itemIDs []string, tagID uuid.UUID) error {
+	if err := l.acquire(); err != nil {
+		return err
+	}
+	defer l.lib.Release()
+	return database.RemoveTagForItems(l.db(), itemIDs, tagID)
+}
+
+// 检查文件夹或标签存在，uuid.Nil 表示顶层，总是存在
+func (l *Library) nodeExists(model interface{}, kind string, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return nil
+	}
+	err := l.lib.DB.Select("id").Where("id = ?", id).Take(model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s %s: %w", kind, id, ErrNotFound)
+	}
+	return err
+}
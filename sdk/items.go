@@ -0,0 +1,330 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"synapforest/database/dbcommon"
+	"synapforest/database/itemdb"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// Item 资料库中的一个文件
+type Item struct {
+	ID          string // 文件内容的 SHA256
+	Name        string
+	Ext         string
+	Width       uint32
+	Height      uint32
+	Size        uint64
+	URL         string
+	Annotation  string
+	Star        uint8
+	CreatedAt   time.Time
+	ImportedAt  time.Time
+	ModifiedAt  time.Time
+	Trashed     bool // 在回收站中
+	Tags        []uuid.UUID
+	Folders     []uuid.UUID
+	ImportError string // 导入时无法解码或超出限制的原因，此时没有预览图
+}
+
+func newItem(i dbcommon.Item) Item {
+	item := Item{
+		ID:          i.ID,
+		Name:        i.Name,
+		Ext:         i.Ext,
+		Width:       i.Width,
+		Height:      i.Height,
+		Size:        i.Size,
+		URL:         i.Url,
+		Annotation:  i.Annotation,
+		Star:        i.Star,
+		CreatedAt:   i.CreatedAt,
+		ImportedAt:  i.ImportedAt,
+		ModifiedAt:  i.ModifiedAt,
+		Trashed:     i.DeletedAt.Valid,
+		Tags:        make([]uuid.UUID, 0, len(i.Tags)),
+		Folders:     make([]uuid.UUID, 0, len(i.Folders)),
+		ImportError: i.ImportError,
+	}
+	for _, t := range i.Tags {
+		item.Tags = append(item.Tags, t.ID)
+	}
+	for _, f := range i.Folders {
+		item.Folders = append(item.Folders, f.ID)
+	}
+	return item
+}
+
+// ImportOptions 导入文件的选项，零值表示使用文件本身的信息
+type ImportOptions struct {
+	Name       string // 为空时使用文件名
+	URL        string
+	Annotation string
+	Star       uint8
+	CreatedAt  time.Time // 为零时使用导入时间
+	Tags       []uuid.UUID
+	Folders    []uuid.UUID
+	// Move 导入后删除源文件，默认保留
+	Move bool
+}
+
+// ImportResult 一个文件的导入结果
+type ImportResult struct {
+	Path string
+	ID   string // 导入或合并到的 item，失败时为空
+	Err  error
+}
+
+// Import 并发导入文件，返回与 paths 一一对应的结果。内容与已有 item 相同的文件
+// 合并到已有的 item：追加标签和文件夹，并用 opts 中非零的字段更新信息
+func (l *Library) Import(paths []string, opts ImportOptions) ([]ImportResult, error) {
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.lib.Release()
+
+	entries := make([]itemdb.ImportEntry, len(paths))
+	for i, path := range paths {
+		entry := itemdb.ImportEntry{
+			Path:       path,
+			Tags:       opts.Tags,
+			Folders:    opts.Folders,
+			KeepSource: !opts.Move,
+		}
+		if opts.Name != "" {
+			entry.Name = &opts.Name
+		}
+		if opts.URL != "" {
+			entry.Url = &opts.URL
+		}
+		if opts.Annotation != "" {
+			entry.Annotation = &opts.Annotation
+		}
+		if opts.Star != 0 {
+			entry.Star = &opts.Star
+		}
+		if !opts.CreatedAt.IsZero() {
+			entry.CreatedAt = &opts.CreatedAt
+		}
+		entries[i] = entry
+	}
+
+	ids, errs := itemdb.ImportItems(l.db(), entries)
+	results := make([]ImportResult, len(paths))
+	for i, path := range paths {
+		results[i] = ImportResult{Path: path, ID: ids[i], Err: errs[i]}
+		if errs[i] != nil {
+			results[i].ID = ""
+		}
+	}
+	return results, nil
+}
+
+// Get 返回 item，包括回收站中的 item
+func (l *Library) Get(id string) (*Item, error) {
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.lib.Release()
+
+	var record dbcommon.Item
+	err := l.lib.DB.Unscoped().Preload("Tags").Preload("Folders").First(&record, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("item %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	item := newItem(record)
+	return &item, nil
+}
+
+// SortField 搜索结果的排序字段
+type SortField string
+
+const (
+	SortByID         SortField = "id"
+	SortByName       SortField = "name"
+	SortBySize       SortField = "size"
+	SortByStar       SortField = "star"
+	SortByCreatedAt  SortField = "created_at"
+	SortByImportedAt SortField = "imported_at"
+	SortByModifiedAt SortField = "modified_at"
+)
+
+// BBox 经纬度矩形范围，West 大于 East 时表示跨越 180° 经线
+type BBox = itemdb.BBox
+
+// Radius 以某点为中心的圆形范围
+type Radius = itemdb.Radius
+
+// SearchOptions 搜索条件，零值的条件不参与筛选，多个条件同时满足
+type SearchOptions struct {
+	Keyword       string      // 匹配名称和提取出的文本内容
+	Exts          []string    // 扩展名，不带点
+	Tags          []uuid.UUID // 带有任一标签
+	Folders       []uuid.UUID // 在任一文件夹中，不包括子文件夹
+	Color         string      // 主色，#RRGGBB
+	ColorDistance float64     // 与 Color 的最大 Lab 距离，为零时使用默认值
+	BBox          *BBox
+	Radius        *Radius
+	Trashed       bool // 只搜索回收站中的 item
+
+	SortBy SortField // 默认为 SortByID
+	Desc   bool
+	Offset int
+	Limit  int // 为零时返回全部结果
+}
+
+// pageSize 与 ItemList 的单页上限一致
+const pageSize = 1000
+
+// Search 返回符合条件的 item
+func (l *Library) Search(opts SearchOptions) ([]Item, error) {
+	if err := l.acquire(); err != nil {
+		return nil, err
+	}
+	defer l.lib.Release()
+
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = SortByID
+	}
+	switch sortBy {
+	case SortByID, SortByName, SortBySize, SortByStar, SortByCreatedAt, SortByImportedAt, SortByModifiedAt:
+	default:
+		return nil, fmt.Errorf("invalid sort field %q", sortBy)
+	}
+	order := "items." + string(sortBy)
+	if opts.Desc {
+		order += " DESC"
+	}
+	// 排序字段相同时按 ID 排序，分页结果稳定
+	if sortBy != SortByID {
+		order += ", items.id ASC"
+	}
+
+	var keyword, color *string
+	var distance *float64
+	if opts.Keyword != "" {
+		keyword = &opts.Keyword
+	}
+	if opts.Color != "" {
+		color = &opts.Color
+	}
+	if opts.ColorDistance > 0 {
+		distance = &opts.ColorDistance
+	}
+	var area *itemdb.GeoFilter
+	if opts.BBox != nil || opts.Radius != nil {
+		area = &itemdb.GeoFilter{BBox: opts.BBox, Radius: opts.Radius}
+	}
+
+	// 按标签和文件夹连接查询时同一 item 可能出现多次，逐页读取并去重
+	items := []Item{}
+	seen := map[string]bool{}
+	skipped := 0
+	for page := 0; ; page++ {
+		size := pageSize
+		batch, err := itemdb.ItemList(l.lib.DB, &opts.Trashed, &order, &page, &size, opts.Exts, keyword,
+			opts.Tags, opts.Folders, color, distance, area)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range batch {
+			if seen[record.ID] {
+				continue
+			}
+			seen[record.ID] = true
+			if skipped < opts.Offset {
+				skipped++
+				continue
+			}
+			items = append(items, newItem(record))
+			if opts.Limit > 0 && len(items) >= opts.Limit {
+				return items, nil
+			}
+		}
+		if len(batch) < pageSize {
+			return items, nil
+		}
+	}
+}
+
+// ItemUpdate 要修改的字段，为 nil 的字段保持不变
+type ItemUpdate struct {
+	Name       *string
+	Ext        *string
+	URL        *string
+	Annotation *string
+	Star       *uint8
+	CreatedAt  *time.Time
+	Tags       *[]uuid.UUID // 替换全部标签
+	Folders    *[]uuid.UUID // 替换全部文件夹
+}
+
+// UpdateItem 修改 item 的信息，修改名称或扩展名时同时重命名原始文件
+func (l *Library) UpdateItem(id string, update ItemUpdate) error {
+	if err := l.acquire(); err != nil {
+		return err
+	}
+	defer l.lib.Release()
+
+	if err := l.exists(id); err != nil {
+		return err
+	}
+	var tags, folders []uuid.UUID
+	if update.Tags != nil {
+		tags = append([]uuid.UUID{}, *update.Tags...)
+	}
+	if update.Folders != nil {
+		folders = append([]uuid.UUID{}, *update.Folders...)
+	}
+	return itemdb.UpdateItem(l.db(), id, update.Name, update.Ext, update.URL, update.Annotation,
+		tags, folders, update.Star, update.CreatedAt)
+}
+
+// Trash 把 item 移入回收站
+func (l *Library) Trash(ids ...string) error {
+	if err := l.acquire(); err != nil {
+		return err
+	}
+	defer l.lib.Release()
+	return itemdb.ItemSoftDelete(l.db(), ids)
+}
+
+// Delete 永久删除 item 及其文件
+func (l *Library) Delete(ids ...string) error {
+	if err := l.acquire(); err != nil {
+		return err
+	}
+	defer l.lib.Release()
+	return itemdb.ItemHardDelete(l.db(), ids)
+}
+
+func (l *Library) exists(id string) error {
+	var count int64
+	if err := l.lib.DB.Unscoped().Model(&dbcommon.Item{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("item %s: %w", id, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+
+// Package sdk 不经过 HTTP 服务器，在 Go 程序中直接使用资料库，
+// 用于批量导入、迁移和统计等脚本。
+//
+// 同一个资料库目录同时只能被一个进程打开，使用前需要停止打开了该资料库的服务器。
+// Library 的方法可以在多个 goroutine 中同时调用
+package sdk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"synapforest/database"
+	"synapforest/database/auditdb"
+	"synapforest/database/itemdb"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrClosed 资料库已关闭
+	ErrClosed = errors.New("library is closed")
+	// ErrNotFound item、文件夹或标签不存在
+	ErrNotFound = errors.New("not found")
+)
+
+// RootFolder 根文件夹的 ID，新建文件夹默认放在根文件夹下
+var RootFolder = uuid.Nil
+
+// Library 一个打开的资料库
+type Library struct {
+	lib   *database.Library
+	actor auditdb.Actor // 审计日志中记录的发起方
+}
+
+// Open 打开 path 中的资料库，不存在时创建。打开时按需执行数据库迁移，
+// 并和服务器一样维护存储统计和元数据文件
+func Open(path string) (*Library, error) {
+	lib, err := database.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := itemdb.StartServices(lib); err != nil {
+		lib.Close()
+		return nil, err
+	}
+	return &Library{lib: lib, actor: auditdb.Actor{Name: "sdk", Source: filepath.Base(os.Args[0])}}, nil
+}
+
+// As 返回以 user 名义修改资料库的 Library，修改记录到审计日志。
+// 返回值与 l 共用同一个打开的资料库，只需要关闭其中一个
+func (l *Library) As(user string) *Library {
+	actor := l.actor
+	actor.Name = user
+	return &Library{lib: l.lib, actor: actor}
+}
+
+// Close 等待正在进行的调用结束后关闭资料库，之后的调用返回 ErrClosed
+func (l *Library) Close() error {
+	return l.lib.Close()
+}
+
+// Dir 资料库目录
+func (l *Library) Dir() string {
+	return l.lib.Dir
+}
+
+// 修改使用的数据库连接，记录发起方
+func (l *Library) db() *gorm.DB {
+	return auditdb.As(l.lib.DB, l.actor)
+}
+
+// 调用期间防止资料库被关闭
+func (l *Library) acquire() error {
+	if !l.lib.Acquire() {
+		return ErrClosed
+	}
+	return nil
+}
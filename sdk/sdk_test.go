@@ -0,0 +1,306 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package sdk
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"synapforest/database/auditdb"
+
+	"github.com/gofrs/uuid"
+)
+
+func openLibrary(t *testing.T, dir string) *Library {
+	t.Helper()
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func writePNG(t *testing.T, path string, c color.RGBA) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 32, 24))
+	for y := 0; y < 24; y++ {
+		for x := 0; x < 32; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func itemIDs(items []Item) []string {
+	ids := []string{}
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestImportAndSearch(t *testing.T) {
+	l := openLibrary(t, t.TempDir())
+	src := t.TempDir()
+
+	tag, err := l.CreateTag("sky", NodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder, err := l.CreateFolder("trip", NodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{filepath.Join(src, "red.png"), filepath.Join(src, "blue.png"), filepath.Join(src, "missing.png")}
+	writePNG(t, paths[0], color.RGBA{255, 0, 0, 255})
+	writePNG(t, paths[1], color.RGBA{0, 0, 255, 255})
+
+	results, err := l.Import(paths, ImportOptions{Tags: []uuid.UUID{tag.ID}, Folders: []uuid.UUID{folder.ID}, Star: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results[:2] {
+		if r.Err != nil || r.ID == "" || r.Path != paths[i] {
+			t.Fatalf("result %d = %+v", i, r)
+		}
+	}
+	if results[2].Err == nil || results[2].ID != "" {
+		t.Errorf("missing file = %+v", results[2])
+	}
+	if _, err := os.Stat(paths[0]); err != nil {
+		t.Errorf("source removed without Move: %v", err)
+	}
+	red, blue := results[0].ID, results[1].ID
+
+	item, err := l.Get(red)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "red" || item.Ext != "png" || item.Width != 32 || item.Height != 24 || item.Star != 3 ||
+		!slices.Equal(item.Tags, []uuid.UUID{tag.ID}) || !slices.Equal(item.Folders, []uuid.UUID{folder.ID}) {
+		t.Errorf("item = %+v", item)
+	}
+
+	// 内容相同的文件合并到已有 item
+	other, err := l.CreateTag("red", NodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	copyPath := filepath.Join(src, "copy.png")
+	writePNG(t, copyPath, color.RGBA{255, 0, 0, 255})
+	results, err = l.Import([]string{copyPath}, ImportOptions{Tags: []uuid.UUID{other.ID}, Annotation: "merged", Move: true})
+	if err != nil || results[0].Err != nil || results[0].ID != red {
+		t.Fatalf("merge = %+v, %v", results, err)
+	}
+	if _, err := os.Stat(copyPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("source kept with Move: %v", err)
+	}
+	item, _ = l.Get(red)
+	if item.Annotation != "merged" || len(item.Tags) != 2 {
+		t.Errorf("merged item = %+v", item)
+	}
+
+	items, err := l.Search(SearchOptions{SortBy: SortByName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := itemIDs(items); !slices.Equal(ids, []string{blue, red}) {
+		t.Errorf("by name = %v", ids)
+	}
+	items, _ = l.Search(SearchOptions{SortBy: SortByName, Desc: true, Limit: 1})
+	if ids := itemIDs(items); !slices.Equal(ids, []string{red}) {
+		t.Errorf("desc limit 1 = %v", ids)
+	}
+	items, _ = l.Search(SearchOptions{SortBy: SortByName, Offset: 1})
+	if ids := itemIDs(items); !slices.Equal(ids, []string{red}) {
+		t.Errorf("offset 1 = %v", ids)
+	}
+	items, _ = l.Search(SearchOptions{Tags: []uuid.UUID{other.ID}})
+	if ids := itemIDs(items); !slices.Equal(ids, []string{red}) {
+		t.Errorf("by tag = %v", ids)
+	}
+	items, _ = l.Search(SearchOptions{Keyword: "blu"})
+	if ids := itemIDs(items); !slices.Equal(ids, []string{blue}) {
+		t.Errorf("by keyword = %v", ids)
+	}
+	if _, err := l.Search(SearchOptions{SortBy: "size; DROP TABLE items"}); err == nil {
+		t.Error("invalid sort field accepted")
+	}
+}
+
+func TestUpdateTrashDelete(t *testing.T) {
+	l := openLibrary(t, t.TempDir())
+	path := filepath.Join(t.TempDir(), "a.png")
+	writePNG(t, path, color.RGBA{0, 128, 0, 255})
+	results, err := l.Import([]string{path}, ImportOptions{})
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("import = %+v, %v", results, err)
+	}
+	id := results[0].ID
+
+	name := "renamed"
+	tags := []uuid.UUID{}
+	if err := l.UpdateItem(id, ItemUpdate{Name: &name, Tags: &tags}); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := l.Get(id); item.Name != "renamed" {
+		t.Errorf("name = %q", item.Name)
+	}
+	if err := l.UpdateItem("missing", ItemUpdate{Name: &name}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update missing: %v", err)
+	}
+
+	if err := l.Trash(id); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := l.Search(SearchOptions{}); len(items) != 0 {
+		t.Errorf("trashed item listed: %+v", items)
+	}
+	items, _ := l.Search(SearchOptions{Trashed: true})
+	if len(items) != 1 || !items[0].Trashed {
+		t.Errorf("trash = %+v", items)
+	}
+
+	if err := l.Delete(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get deleted: %v", err)
+	}
+}
+
+func TestFoldersAndTags(t *testing.T) {
+	l := openLibrary(t, t.TempDir())
+	path := filepath.Join(t.TempDir(), "a.png")
+	writePNG(t, path, color.RGBA{10, 20, 30, 255})
+	results, _ := l.Import([]string{path}, ImportOptions{})
+	id := results[0].ID
+
+	parent, err := l.CreateFolder("parent", NodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := l.CreateFolder("child", NodeOptions{Parent: parent.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.CreateFolder("orphan", NodeOptions{Parent: uuid.Must(uuid.NewV4())}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing parent: %v", err)
+	}
+	if folders, _ := l.Folders(); len(folders) != 2 || folders[0].ID != child.ID || folders[0].Parent != parent.ID {
+		t.Errorf("folders = %+v", folders)
+	}
+
+	if err := l.AddToFolder([]string{id}, child.ID); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := l.Get(id); !slices.Equal(item.Folders, []uuid.UUID{child.ID}) {
+		t.Errorf("folders of item = %v", item.Folders)
+	}
+	if err := l.RemoveFromFolder([]string{id}, child.ID); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := l.Get(id); len(item.Folders) != 0 {
+		t.Errorf("folders after remove = %v", item.Folders)
+	}
+
+	// 删除文件夹时子文件夹一起删除，item 保留
+	if err := l.AddToFolder([]string{id}, child.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.DeleteFolder(parent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if folders, _ := l.Folders(); len(folders) != 0 {
+		t.Errorf("folders after delete = %+v", folders)
+	}
+	if _, err := l.Get(id); err != nil {
+		t.Errorf("item removed with folder: %v", err)
+	}
+	if err := l.DeleteFolder(RootFolder); err == nil {
+		t.Error("root folder deleted")
+	}
+
+	tag, err := l.CreateTag("tag", NodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Tag([]string{id}, tag.ID); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := l.Get(id); !slices.Equal(item.Tags, []uuid.UUID{tag.ID}) {
+		t.Errorf("tags = %v", item.Tags)
+	}
+	if err := l.Untag([]string{id}, tag.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.DeleteTag(tag.ID); err != nil {
+		t.Fatal(err)
+	}
+	if tags, _ := l.Tags(); len(tags) != 0 {
+		t.Errorf("tags after delete = %+v", tags)
+	}
+}
+
+func TestAuditActorAndClose(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := l.As("alice")
+	if _, err := alice.CreateFolder("shared", NodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.CreateTag("mine", NodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, total, err := l.AuditLog(AuditFilter{Actor: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || entries[0].Operation != auditdb.FolderCreate {
+		t.Errorf("alice's entries = %+v", entries)
+	}
+	if _, total, _ := l.AuditLog(AuditFilter{Actor: "sdk"}); total != 1 {
+		t.Errorf("sdk entries = %d", total)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.Folders(); !errors.Is(err, ErrClosed) {
+		t.Errorf("after close: %v", err)
+	}
+
+	// 重新打开后数据仍在
+	l = openLibrary(t, dir)
+	if folders, _ := l.Folders(); len(folders) != 1 || folders[0].Name != "shared" {
+		t.Errorf("folders after reopen = %+v", folders)
+	}
+}
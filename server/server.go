@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"synapforest/api"
+	"synapforest/api/auditapi"
+	"synapforest/api/backupapi"
+	"synapforest/api/folderapi"
+	"synapforest/api/graphql"
+	"synapforest/api/itemapi"
+	"synapforest/api/jobapi"
+	"synapforest/api/libraryapi"
+	"synapforest/api/openapi"
+	"synapforest/api/packageapi"
+	"synapforest/api/settingapi"
+	"synapforest/api/statsapi"
+	"synapforest/api/tagapi"
+	"synapforest/api/vectorapi"
+	"synapforest/database"
+	"synapforest/database/itemdb"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// Options 服务器设置
+type Options struct {
+	Token string // 私有接口的 Authorization 请求头
+}
+
+// Server 一组资料库及访问它们的 HTTP 接口。资料库注册表和令牌由 Server 持有，
+// 接口处理函数从请求上下文中取得资料库，因此同一进程中可以同时运行多个。
+// 后台任务、导入锁和统计缓存由打开的资料库持有，
+// 多个 Server 不能打开同一个资料库目录
+type Server struct {
+	Libraries *database.Registry
+	Handler   http.Handler
+
+	tempDir string // NewTemp 创建的目录，关闭时删除
+}
+
+// New 打开注册表中的资料库并构建路由
+func New(libraries *database.Registry, opts Options) (*Server, error) {
+	libraries.OnOpen = itemdb.StartServices
+	if err := libraries.OpenAll(); err != nil {
+		return nil, err
+	}
+	handler, err := newRouter(libraries, opts)
+	if err != nil {
+		libraries.CloseAll()
+		return nil, err
+	}
+	return &Server{Libraries: libraries, Handler: handler}, nil
+}
+
+// NewTemp 在临时目录中创建只有默认资料库的服务器，用于嵌入和测试，Close 时删除目录
+func NewTemp(opts Options) (*Server, error) {
+	dir, err := os.MkdirTemp("", "synapforest-")
+	if err != nil {
+		return nil, err
+	}
+	s, err := New(database.NewRegistry(filepath.Join(dir, "default"), filepath.Join(dir, "libraries")), opts)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	s.tempDir = dir
+	return s, nil
+}
+
+// Run 在 addr 上处理请求
+func (s *Server) Run(addr string) error {
+	return http.ListenAndServe(addr, s.Handler)
+}
+
+// Close 关闭全部资料库
+func (s *Server) Close() error {
+	s.Libraries.CloseAll()
+	if s.tempDir != "" {
+		return os.RemoveAll(s.tempDir)
+	}
+	return nil
+}
+
+// 构建路由并检查 OpenAPI 规范是否与之一致
+func newRouter(libraries *database.Registry, opts Options) (*gin.Engine, error) {
+	r := gin.Default()
+
+	r.Use(cors.New(cors.Config{
+		AllowOrigins:     []string{"*"}, // 允许所有域名
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", api.LibraryHeader, api.UserHeader},
+		ExposeHeaders:    []string{"Content-Length"},
+		AllowCredentials: true,
+	}))
+	r.Use(api.RegistryMiddleware(libraries))
+
+	r.GET(openapi.Path, openapi.Serve)
+
+	libraryRoutes := r.Group("/api/library")
+	libraryRoutes.Use(api.AuthMiddleware(opts.Token))
+	{
+		libraryRoutes.POST("/list", libraryapi.List)
+		libraryRoutes.POST("/create", libraryapi.Create)
+		libraryRoutes.POST("/open", libraryapi.Open)
+		libraryRoutes.POST("/rename", libraryapi.Rename)
+		libraryRoutes.POST("/close", libraryapi.Close)
+	}
+
+	// 不带前缀的路由按请求头选择资料库，默认为默认资料库
+	registerRoutes(r.Group(""), opts)
+	registerRoutes(r.Group("/lib/:library"), opts)
+
+	if err := openapi.Validate(r.Routes(), "/lib/:library"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// 注册资料库范围内的路由
+func registerRoutes(g *gin.RouterGroup, opts Options) {
+	publicRoutes := g.Group("/public")
+	publicRoutes.Use(api.LibraryMiddleware())
+	{
+		publicRoutes.GET("/thumbnails/:id", api.ServeThumbnails)
+		publicRoutes.GET("/raw_files/:id", api.ServeRawFile)
+		publicRoutes.GET("/previews/:id", api.ServePreviews)
+		publicRoutes.GET("/images/:id", api.ServeResized)
+		publicRoutes.GET("/renditions/:name/:id", api.ServeRendition)
+
+		publicRoutes.POST("/vectorize/:id", vectorapi.HandleVectorize)
+	}
+
+	privateRoutes := g.Group("/api")
+	privateRoutes.Use(api.AuthMiddleware(opts.Token), api.LibraryMiddleware())
+	{
+		privateRoutes.POST("/uploadfiles", api.Uploadfiles)
+
+		privateRoutes.POST("/folder/create", folderapi.CreateFolder)
+		privateRoutes.POST("/folder/list", folderapi.ListFolder)
+		privateRoutes.POST("/folder/update", folderapi.UpdateFolder)
+		privateRoutes.POST("/folder/updateParent", folderapi.UpdateFoldersParent)
+		privateRoutes.POST("/folder/delete", folderapi.DeleteFolder)
+
+		privateRoutes.POST("/tag/create", tagapi.CreateTag)
+		privateRoutes.POST("/tag/list", tagapi.ListTag)
+		privateRoutes.POST("/tag/update", tagapi.UpdateTag)
+		privateRoutes.POST("/tag/updateParent", tagapi.UpdateTagsParent)
+		privateRoutes.POST("/tag/delete", tagapi.DeleteTag)
+
+		privateRoutes.POST("/item/addFromUrls", itemapi.AddFromUrls)
+		privateRoutes.POST("/item/addFromPaths", itemapi.AddFromPaths)
+		privateRoutes.POST("/item/info", itemapi.Info)
+		privateRoutes.POST("/item/moveToTrash", itemapi.MoveToTrash)
+		privateRoutes.POST("/item/update", itemapi.Update)
+		privateRoutes.POST("/item/edit", itemapi.Edit)
+		privateRoutes.POST("/item/replaceFile", itemapi.ReplaceFile)
+		privateRoutes.POST("/item/versions", itemapi.Versions)
+		privateRoutes.POST("/item/restoreVersion", itemapi.RestoreVersion)
+		privateRoutes.GET("/item/versionFile/:id", api.ServeVersionFile)
+		privateRoutes.POST("/item/list", itemapi.List)
+		privateRoutes.POST("/item/geoClusters", itemapi.GeoClusters)
+
+		privateRoutes.POST("/item/remove-folder", api.RemoveFolderForItems)
+		privateRoutes.POST("/item/add-folder", api.AddFolderForItems)
+
+		privateRoutes.POST("/setting/renditions", settingapi.ListRenditions)
+		privateRoutes.POST("/setting/updateRenditions", settingapi.UpdateRenditions)
+		privateRoutes.POST("/setting/importLimits", settingapi.GetImportLimits)
+		privateRoutes.POST("/setting/updateImportLimits", settingapi.UpdateImportLimits)
+		privateRoutes.POST("/setting/blobStore", settingapi.GetBlobStore)
+		privateRoutes.POST("/setting/updateBlobStore", settingapi.UpdateBlobStore)
+
+		privateRoutes.POST("/job/start", jobapi.StartJob)
+		privateRoutes.POST("/job/list", jobapi.ListJob)
+		privateRoutes.POST("/job/info", jobapi.InfoJob)
+		privateRoutes.POST("/job/cancel", jobapi.CancelJob)
+		privateRoutes.GET("/job/report/:id", jobapi.Report)
+
+		privateRoutes.POST("/stats/storage", statsapi.Storage)
+
+		privateRoutes.POST("/audit/list", auditapi.List)
+
+		privateRoutes.POST("/backup/list", backupapi.List)
+		privateRoutes.GET("/backup/download/:id", backupapi.Download)
+
+		privateRoutes.POST("/package/upload", packageapi.Upload)
+		privateRoutes.GET("/package/download/:id", packageapi.Download)
+	}
+
+	gqlHandler := gin.WrapH(graphql.NewHandler())
+	gqlRoutes := g.Group("/graphql")
+	gqlRoutes.Use(api.LibraryMiddleware())
+	{
+		gqlRoutes.GET("", gqlHandler)
+		gqlRoutes.POST("", gqlHandler)
+	}
+}
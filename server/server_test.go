@@ -0,0 +1,238 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testServer struct {
+	t     *testing.T
+	url   string
+	token string
+}
+
+func newTestServer(t *testing.T, token string) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	s, err := NewTemp(Options{Token: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s.Handler)
+	t.Cleanup(func() {
+		srv.Close()
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return &testServer{t: t, url: srv.URL, token: token}
+}
+
+func (s *testServer) request(method, path, contentType string, body io.Reader) (int, []byte) {
+	s.t.Helper()
+	req, err := http.NewRequest(method, s.url+path, body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", s.token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+// 调用返回 {"status":"success","data":...} 的接口，data 解码到 out
+func (s *testServer) post(path string, in, out interface{}) {
+	s.t.Helper()
+	body, _ := json.Marshal(in)
+	code, data := s.request(http.MethodPost, path, "application/json", bytes.NewReader(body))
+	var resp struct {
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil || code != http.StatusOK || resp.Status != "success" {
+		s.t.Fatalf("%s: %d %s", path, code, data)
+	}
+	if out != nil {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			s.t.Fatalf("%s: %v", path, err)
+		}
+	}
+}
+
+func (s *testServer) upload(name string, data []byte) {
+	s.t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, _ := w.CreateFormFile("files", name)
+	part.Write(data)
+	w.Close()
+	if code, resp := s.request(http.MethodPost, "/api/uploadfiles", w.FormDataContentType(), &body); code != http.StatusOK {
+		s.t.Fatalf("upload: %d %s", code, resp)
+	}
+	s.post("/api/item/addFromPaths", map[string]interface{}{"fileNames": []string{name}}, nil)
+}
+
+func testPNG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), 200, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type listedItem struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Width  uint32 `json:"width"`
+	Height uint32 `json:"height"`
+}
+
+// 同一进程中的两个服务器各自使用自己的资料库和令牌
+func TestTwoServersInOneProcess(t *testing.T) {
+	a := newTestServer(t, "token-a")
+	b := newTestServer(t, "token-b")
+
+	a.upload("photo.png", testPNG(t))
+
+	var items []listedItem
+	a.post("/api/item/list", map[string]interface{}{}, &items)
+	if len(items) != 1 || items[0].Name != "photo" || items[0].Width != 64 || items[0].Height != 48 {
+		t.Fatalf("items on a = %+v", items)
+	}
+	b.post("/api/item/list", map[string]interface{}{}, &items)
+	if len(items) != 0 {
+		t.Errorf("items on b = %+v", items)
+	}
+
+	// 公开的文件接口不需要令牌
+	resp, err := http.Get(a.url + "/public/thumbnails/" + firstItem(t, a).ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") == "" {
+		t.Errorf("thumbnail = %s %q", resp.Status, resp.Header.Get("Content-Type"))
+	}
+
+	// 令牌只对自己的服务器有效
+	wrong := &testServer{t: t, url: b.url, token: a.token}
+	if code, _ := wrong.request(http.MethodPost, "/api/item/list", "application/json", bytes.NewReader([]byte("{}"))); code != http.StatusUnauthorized {
+		t.Errorf("a's token on b = %d", code)
+	}
+
+	// 任务只在启动它的资料库中可见
+	var job struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	a.post("/api/job/start", map[string]interface{}{"type": "palette_backfill"}, &job)
+	var jobs []struct {
+		ID string `json:"id"`
+	}
+	b.post("/api/job/list", map[string]interface{}{}, &jobs)
+	if len(jobs) != 0 {
+		t.Errorf("jobs on b = %+v", jobs)
+	}
+	body, _ := json.Marshal(map[string]string{"id": job.ID})
+	if code, _ := b.request(http.MethodPost, "/api/job/info", "application/json", bytes.NewReader(body)); code != http.StatusNotFound {
+		t.Errorf("a's job on b = %d", code)
+	}
+	for deadline := time.Now().Add(10 * time.Second); job.Status != "done"; {
+		if time.Now().After(deadline) {
+			t.Fatalf("job = %+v", job)
+		}
+		time.Sleep(20 * time.Millisecond)
+		a.post("/api/job/info", map[string]string{"id": job.ID}, &job)
+	}
+}
+
+func firstItem(t *testing.T, s *testServer) listedItem {
+	t.Helper()
+	var items []listedItem
+	s.post("/api/item/list", map[string]interface{}{}, &items)
+	if len(items) == 0 {
+		t.Fatal("no items")
+	}
+	return items[0]
+}
+
+// 历史版本的 ID 是连续整数，文件只能通过私有接口下载
+func TestVersionFileRequiresToken(t *testing.T) {
+	s := newTestServer(t, "token")
+	s.upload("photo.png", testPNG(t))
+	id := firstItem(t, s).ID
+
+	replacement := testPNG(t)
+	replacement = append(replacement[:len(replacement):len(replacement)], 0) // 内容不同的同一张图
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, _ := w.CreateFormFile("files", "new.png")
+	part.Write(replacement)
+	w.Close()
+	if code, resp := s.request(http.MethodPost, "/api/uploadfiles", w.FormDataContentType(), &body); code != http.StatusOK {
+		t.Fatalf("upload: %d %s", code, resp)
+	}
+	s.post("/api/item/replaceFile", map[string]string{"id": id, "fileName": "new.png"}, nil)
+
+	var versions []struct {
+		ID  uint   `json:"id"`
+		URL string `json:"url"`
+	}
+	s.post("/api/item/versions", map[string]string{"id": firstItem(t, s).ID}, &versions)
+	if len(versions) != 1 || versions[0].URL != "/api/item/versionFile/1" {
+		t.Fatalf("versions = %+v", versions)
+	}
+
+	code, data := s.request(http.MethodGet, versions[0].URL, "", nil)
+	if code != http.StatusOK || !bytes.Equal(data, testPNG(t)) {
+		t.Errorf("version file = %d, %d bytes", code, len(data))
+	}
+	for path, want := range map[string]int{versions[0].URL: http.StatusUnauthorized, "/public/versions/1": http.StatusNotFound} {
+		resp, err := http.Get(s.url + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s without token = %s", path, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package textrender
+
+import (
+	"image/color"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenPlain tokenKind = iota
+	tokenKeyword
+	tokenString
+	tokenComment
+	tokenNumber
+	tokenHeading
+)
+
+var tokenColors = map[tokenKind]color.RGBA{
+	tokenPlain:   {0xd4, 0xd4, 0xd4, 0xff},
+	tokenKeyword: {0x56, 0x9c, 0xd6, 0xff},
+	tokenString:  {0xce, 0x91, 0x78, 0xff},
+	tokenComment: {0x6a, 0x99, 0x55, 0xff},
+	tokenNumber:  {0xb5, 0xce, 0xa8, 0xff},
+	tokenHeading: {0x4e, 0xc9, 0xb0, 0xff},
+}
+
+type span struct {
+	kind tokenKind
+	text string
+}
+
+// 各语言的注释语法，只区分常见的几类
+type syntax struct {
+	lineComment  string
+	blockStart   string
+	blockEnd     string
+	quotes       string
+	markdown     bool
+	highlighting bool
+}
+
+var (
+	cLike    = syntax{lineComment: "//", blockStart: "/*", blockEnd: "*/", quotes: "\"'`", highlighting: true}
+	hashLike = syntax{lineComment: "#", quotes: "\"'", highlighting: true}
+	dashLike = syntax{lineComment: "--", blockStart: "/*", blockEnd: "*/", quotes: "\"'", highlighting: true}
+	markup   = syntax{blockStart: "<!--", blockEnd: "-->", quotes: "\"'", highlighting: true}
+	markdown = syntax{markdown: true}
+	plain    = syntax{}
+)
+
+var syntaxByExt = map[string]syntax{
+	"go": cLike, "c": cLike, "h": cLike, "cpp": cLike, "cc": cLike, "hpp": cLike, "cs": cLike,
+	"java": cLike, "kt": cLike, "swift": cLike, "rs": cLike, "php": cLike,
+	"js": cLike, "jsx": cLike, "ts": cLike, "tsx": cLike, "css": cLike, "scss": cLike, "less": cLike, "json": cLike,
+	"py": hashLike, "rb": hashLike, "pl": hashLike, "r": hashLike, "sh": hashLike, "bash": hashLike, "zsh": hashLike,
+	"yaml": hashLike, "yml": hashLike, "toml": hashLike, "conf": hashLike, "ini": hashLike,
+	"sql": dashLike, "lua": dashLike,
+	"html": markup, "htm": markup, "xml": markup, "svg": markup, "vue": markup,
+	"md": markdown, "markdown": markdown, "rst": markdown,
+}
+
+// 多种语言关键字的并集，用于基础着色
+var keywords = map[string]bool{}
+
+func init() {
+	for _, kw := range strings.Fields(`
+		break case catch class const continue def default defer do elif else enum export extends
+		false fn for from func function go if impl import in interface let match mod module new nil
+		none null package private protected pub public return select self static struct super switch
+		this throw throws true try type use var void while with yield async await lambda pass raise
+		except finally int string bool float double char long unsigned
+		SELECT FROM WHERE INSERT UPDATE DELETE INTO VALUES CREATE TABLE JOIN ON AND OR NOT NULL AS
+		ORDER BY GROUP LIMIT local then end`) {
+		keywords[kw] = true
+	}
+}
+
+type highlighter struct {
+	syntax  syntax
+	inBlock bool
+	inFence bool
+}
+
+func newHighlighter(ext string) *highlighter {
+	s, ok := syntaxByExt[strings.ToLower(strings.TrimPrefix(ext, "."))]
+	if !ok {
+		s = plain
+	}
+	return &highlighter{syntax: s}
+}
+
+// line 将一行文本切分为带颜色类别的片段，块注释状态在行之间保留
+func (h *highlighter) line(text string) []span {
+	if h.syntax.markdown {
+		return h.markdownLine(text)
+	}
+	if !h.syntax.highlighting {
+		return []span{{tokenPlain, text}}
+	}
+
+	var spans []span
+	s := h.syntax
+	for len(text) > 0 {
+		if h.inBlock {
+			end := strings.Index(text, s.blockEnd)
+			if end < 0 {
+				spans = append(spans, span{tokenComment, text})
+				return spans
+			}
+			spans = append(spans, span{tokenComment, text[:end+len(s.blockEnd)]})
+			text = text[end+len(s.blockEnd):]
+			h.inBlock = false
+			continue
+		}
+
+		switch {
+		case s.blockStart != "" && strings.HasPrefix(text, s.blockStart):
+			h.inBlock = true
+		case s.lineComment != "" && strings.HasPrefix(text, s.lineComment):
+			spans = append(spans, span{tokenComment, text})
+			return spans
+		case strings.ContainsRune(s.quotes, rune(text[0])):
+			end := closingQuote(text)
+			spans = append(spans, span{tokenString, text[:end]})
+			text = text[end:]
+		case isIdentStart(rune(text[0])):
+			end := 1
+			for end < len(text) && isIdentPart(rune(text[end])) {
+				end++
+			}
+			kind := tokenPlain
+			if keywords[text[:end]] {
+				kind = tokenKeyword
+			} else if unicode.IsDigit(rune(text[0])) {
+				kind = tokenNumber
+			}
+			spans = append(spans, span{kind, text[:end]})
+			text = text[end:]
+		default:
+			_, size := utf8.DecodeRuneInString(text)
+			spans = append(spans, span{tokenPlain, text[:size]})
+			text = text[size:]
+		}
+	}
+	return spans
+}
+
+func (h *highlighter) markdownLine(text string) []span {
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+		h.inFence = !h.inFence
+		return []span{{tokenComment, text}}
+	case h.inFence:
+		return []span{{tokenString, text}}
+	case strings.HasPrefix(trimmed, "#"):
+		return []span{{tokenHeading, text}}
+	case strings.HasPrefix(trimmed, ">"):
+		return []span{{tokenComment, text}}
+	case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") || strings.HasPrefix(trimmed, "+ "):
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		return []span{{tokenPlain, text[:indent]}, {tokenKeyword, text[indent : indent+1]}, {tokenPlain, text[indent+1:]}}
+	}
+
+	// 行内代码用字符串颜色显示
+	var spans []span
+	for len(text) > 0 {
+		start := strings.IndexByte(text, '`')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start+1:], '`')
+		if end < 0 {
+			break
+		}
+		end += start + 2
+		spans = append(spans, span{tokenPlain, text[:start]}, span{tokenString, text[start:end]})
+		text = text[end:]
+	}
+	return append(spans, span{tokenPlain, text})
+}
+
+// 返回字符串字面量的结束位置（包含结束引号），未闭合时到行尾
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(text)
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r)
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package textrender
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nfnt/resize"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// 保存到数据库中的文本内容上限
+const MaxContentSize = 1 << 20
+
+// 字形放大倍数，7x13 的点阵字体放大后在预览图中更易阅读
+const glyphScale = 2
+
+const tabWidth = 4
+
+var textExts = map[string]bool{
+	"txt": true, "log": true, "csv": true, "tsv": true,
+	"md": true, "markdown": true, "rst": true,
+	"go": true, "c": true, "h": true, "cpp": true, "cc": true, "hpp": true, "cs": true,
+	"java": true, "kt": true, "swift": true, "rs": true, "php": true,
+	"js": true, "jsx": true, "ts": true, "tsx": true, "vue": true,
+	"css": true, "scss": true, "less": true, "html": true, "htm": true, "xml": true, "svg": true,
+	"json": true, "yaml": true, "yml": true, "toml": true, "ini": true, "conf": true,
+	"py": true, "rb": true, "pl": true, "r": true, "sh": true, "bash": true, "zsh": true,
+	"sql": true, "lua": true,
+}
+
+// IsTextExt 判断扩展名是否为已知的文本/代码格式
+func IsTextExt(ext string) bool {
+	return textExts[strings.ToLower(strings.TrimPrefix(ext, "."))]
+}
+
+// IsTextFile 根据扩展名判断，未知扩展名时嗅探文件开头的内容
+func IsTextFile(path string, ext string) bool {
+	if IsTextExt(ext) {
+		return true
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if n == 0 {
+		return false
+	}
+	return strings.HasPrefix(http.DetectContentType(head[:n]), "text/plain")
+}
+
+// ReadText 读取文本文件，最多 maxBytes 字节，并截断到完整的 UTF-8 字符边界
+func ReadText(path string, maxBytes int) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open text file failed: %v", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, int64(maxBytes)))
+	if err != nil {
+		return "", fmt.Errorf("read text file failed: %v", err)
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	for len(data) > 0 && !utf8.Valid(data) {
+		r, size := utf8.DecodeLastRune(data)
+		if r != utf8.RuneError || size > 1 {
+			// 中间存在非法字节，说明不是 UTF-8 文本
+			return "", fmt.Errorf("file is not valid utf-8 text")
+		}
+		data = data[:len(data)-1]
+	}
+
+	return string(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))), nil
+}
+
+// Render 将文本的第一屏渲染为等宽字体图像，并按语言做简单的语法着色
+func Render(text string, ext string, width int, height int) image.Image {
+	face := basicfont.Face7x13
+	canvasW, canvasH := width/glyphScale, height/glyphScale
+
+	canvas := image.NewRGBA(image.Rect(0, 0, canvasW, canvasH))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{themeBackground}, image.Point{}, draw.Src)
+
+	const padding = 4
+	cols := (canvasW - 2*padding) / face.Advance
+	rows := (canvasH - 2*padding) / face.Height
+
+	drawer := &font.Drawer{Dst: canvas, Face: face}
+	hl := newHighlighter(ext)
+
+	lines := strings.Split(text, "\n")
+	for row := 0; row < rows && row < len(lines); row++ {
+		line := strings.ReplaceAll(lines[row], "\t", strings.Repeat(" ", tabWidth))
+		baseline := padding + row*face.Height + face.Ascent
+
+		col := 0
+		for _, span := range hl.line(line) {
+			if col >= cols {
+				break
+			}
+			drawer.Src = &image.Uniform{tokenColors[span.kind]}
+			for _, r := range span.text {
+				if col >= cols {
+					break
+				}
+				drawer.Dot = fixed.P(padding+col*face.Advance, baseline)
+				drawer.DrawString(string(r))
+				col++
+			}
+		}
+	}
+
+	return resize.Resize(uint(canvasW*glyphScale), uint(canvasH*glyphScale), canvas, resize.NearestNeighbor)
+}
+
+var themeBackground = color.RGBA{0x1e, 0x1e, 0x1e, 0xff}
@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2025 AirFortressIlikara
+ * SynapForest is licensed under Mulan PubL v2.
+ * You can use this software according to the terms and conditions of the Mulan PubL v2.
+ * You may obtain a copy of Mulan PubL v2 at:
+ *          http://license.coscl.org.cn/MulanPubL-2.0
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PubL v2 for more details.
+ */
+package textrender
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestIsTextFile(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		data []byte
+		want bool
+	}{
+		{"a.go", "go", []byte{0, 1, 2}, true}, // 已知扩展名不嗅探内容
+		{"a.GO", ".GO", nil, true},
+		{"README", "", []byte("plain text\n"), true},
+		{"a.bin", "bin", []byte{0x89, 'P', 'N', 'G', 0, 0, 0, 0}, false},
+		{"empty", "", nil, false},
+	}
+	for _, tt := range tests {
+		path := writeFile(t, tt.name, tt.data)
+		if got := IsTextFile(path, tt.ext); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadText(t *testing.T) {
+	// 去掉 BOM，换行统一为 \n
+	path := writeFile(t, "a.txt", []byte("\xef\xbb\xbfline 1\r\nline 2\r\n"))
+	if text, err := ReadText(path, 1024); err != nil || text != "line 1\nline 2\n" {
+		t.Errorf("got %q, %v", text, err)
+	}
+
+	// 截断在多字节字符中间时退回到完整字符
+	path = writeFile(t, "b.txt", []byte("ab中文"))
+	if text, err := ReadText(path, 4); err != nil || text != "ab" {
+		t.Errorf("truncated: got %q, %v", text, err)
+	}
+
+	path = writeFile(t, "c.txt", []byte("ab\xffcd"))
+	if _, err := ReadText(path, 1024); err == nil {
+		t.Error("invalid utf-8 accepted")
+	}
+}
+
+func TestHighlightCode(t *testing.T) {
+	h := newHighlighter("go")
+	got := h.line(`func f() { return "x" // done`)
+	want := []span{
+		{tokenKeyword, "func"}, {tokenPlain, " "}, {tokenPlain, "f"}, {tokenPlain, "("}, {tokenPlain, ")"},
+		{tokenPlain, " "}, {tokenPlain, "{"}, {tokenPlain, " "}, {tokenKeyword, "return"}, {tokenPlain, " "},
+		{tokenString, `"x"`}, {tokenPlain, " "}, {tokenComment, "// done"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v", got)
+	}
+
+	// 块注释跨行
+	if got := h.line("x := 1 /* start"); !reflect.DeepEqual(got[len(got)-1], span{tokenComment, "/* start"}) {
+		t.Errorf("block start: %+v", got)
+	}
+	if got := h.line("end */ 42"); !reflect.DeepEqual(got, []span{{tokenComment, "end */"}, {tokenPlain, " "}, {tokenNumber, "42"}}) {
+		t.Errorf("block end: %+v", got)
+	}
+
+	// 未知扩展名不着色
+	if got := newHighlighter("txt").line("func // x"); !reflect.DeepEqual(got, []span{{tokenPlain, "func // x"}}) {
+		t.Errorf("plain: %+v", got)
+	}
+}
+
+func TestHighlightMarkdown(t *testing.T) {
+	h := newHighlighter("md")
+	lines := []string{"# Title", "text `code` end", "```", "func x", "```", "- item"}
+	want := [][]span{
+		{{tokenHeading, "# Title"}},
+		{{tokenPlain, "text "}, {tokenString, "`code`"}, {tokenPlain, " end"}},
+		{{tokenComment, "```"}},
+		{{tokenString, "func x"}},
+		{{tokenComment, "```"}},
+		{{tokenPlain, ""}, {tokenKeyword, "-"}, {tokenPlain, " item"}},
+	}
+	for i, line := range lines {
+		if got := h.line(line); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("%q: got %+v", line, got)
+		}
+	}
+}
+
+// 统计图像中各颜色的像素数量
+func countColors(t *testing.T, text, ext string) map[color.RGBA]int {
+	t.Helper()
+	img := Render(text, ext, 200, 100)
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+		t.Fatalf("size = %v", b)
+	}
+	counts := map[color.RGBA]int{}
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			counts[color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)]++
+		}
+	}
+	return counts
+}
+
+func TestRender(t *testing.T) {
+	counts := countColors(t, "func main() {}\n// comment", "go")
+	for _, kind := range []tokenKind{tokenKeyword, tokenPlain, tokenComment} {
+		if counts[tokenColors[kind]] == 0 {
+			t.Errorf("no pixels of token kind %d", kind)
+		}
+	}
+	if counts[tokenColors[tokenString]] != 0 {
+		t.Error("unexpected string color")
+	}
+
+	// 同样的文本按纯文本渲染时不着色
+	counts = countColors(t, "func main() {}\n// comment", "txt")
+	if counts[tokenColors[tokenKeyword]] != 0 || counts[tokenColors[tokenComment]] != 0 || counts[tokenColors[tokenPlain]] == 0 {
+		t.Errorf("plain text colors = %v", counts)
+	}
+
+	// 空文本只有背景
+	if counts := countColors(t, "", "go"); len(counts) != 1 || counts[themeBackground] != 200*100 {
+		t.Errorf("empty text colors = %d", len(counts))
+	}
+}